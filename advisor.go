@@ -0,0 +1,167 @@
+package simplejsondb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+)
+
+// defaultAdvisorMaxIDs - ids listed per advice unless configured
+const defaultAdvisorMaxIDs = 100
+
+type (
+	// AdvisorOptions - CompressionAdvisor configuration
+	AdvisorOptions struct {
+		// MaxIDs - ids listed per advice, defaults to 100; the byte
+		// estimates still cover every sampled record
+		MaxIDs int
+	}
+
+	// AdvisorReport - records whose storage format costs space
+	AdvisorReport struct {
+		Version int `json:"version"`
+		// Records - records in the collection, Sampled of them measured
+		Records int `json:"records"`
+		Sampled int `json:"sampled"`
+		// Failed - sampled records which could not be measured
+		Failed int `json:"failed"`
+		// Compress - sampled plain records which gzip would shrink,
+		// CompressBytes what that saves on the sample
+		Compress      []string `json:"compress,omitempty"`
+		CompressBytes int64    `json:"compress_bytes"`
+		// Decompress - sampled gzipped records larger than their content,
+		// DecompressBytes what storing them plain saves on the sample
+		Decompress      []string `json:"decompress,omitempty"`
+		DecompressBytes int64    `json:"decompress_bytes"`
+		// EstimatedCompressBytes and EstimatedDecompressBytes - the sample
+		// savings scaled to the whole collection
+		EstimatedCompressBytes   int64 `json:"estimated_compress_bytes"`
+		EstimatedDecompressBytes int64 `json:"estimated_decompress_bytes"`
+	}
+)
+
+// CompressionAdvisor - measures a random sample fraction of the records
+// and reports which would be smaller in the other storage format
+//
+// Plain records are compressed in memory and discarded, nothing is
+// written. Records are read one at a time under their shared lock and the
+// scan stops with the partial report once ctx is done.
+func (c *_collection) CompressionAdvisor(ctx context.Context, sample float64, options ...AdvisorOptions) (AdvisorReport, error) {
+	return compressionAdvisor(ctx, c, sample, options)
+}
+
+// CompressionAdvisor - measures visible records in the layer holding them
+func (c *_overlayCollection) CompressionAdvisor(ctx context.Context, sample float64, options ...AdvisorOptions) (AdvisorReport, error) {
+	return compressionAdvisor(ctx, c, sample, options)
+}
+
+// measure - measures a visible record in the layer holding it
+func (c *_overlayCollection) measure(key string) (compress, decompress int64, err error) {
+	if c.upper.has(key) {
+		return c.upper.measure(key)
+	}
+	return c.base.measure(key)
+}
+
+// measure - bytes saved by compressing a plain record or by storing a
+// gzipped one plain, at most one of them is positive
+func (c *_collection) measure(key string) (compress, decompress int64, err error) {
+	defer c.rlock(key)()
+	filename, codec, err := c.resolve(key)
+	if err != nil {
+		return 0, 0, err
+	}
+	if c.expiry.expired(c.lockPath(key)) {
+		return 0, 0, expiredError(filename)
+	}
+	stored, err := os.ReadFile(filename)
+	if err != nil {
+		return 0, 0, err
+	}
+	if codec != PlainCodec {
+		if codec != GzipCodec {
+			// stored by a codec of its own, not a gzip decision
+			return 0, 0, nil
+		}
+		content, err := UnGzip(stored)
+		if err != nil {
+			return 0, 0, fmt.Errorf("%w: %w", ErrCorruptRecord, err)
+		}
+		return 0, saved(len(stored), len(content)), nil
+	}
+	compressed, err := c.Gzip(stored)
+	if err != nil {
+		return 0, 0, err
+	}
+	return saved(len(stored), len(compressed)), 0, nil
+}
+
+// saved - bytes saved by storing after instead of before, never negative
+func saved(before, after int) int64 {
+	if after >= before {
+		return 0
+	}
+	return int64(before - after)
+}
+
+func compressionAdvisor(ctx context.Context, c _layer, sample float64, options []AdvisorOptions) (report AdvisorReport, err error) {
+	report.Version = ReportSchemaVersion
+	if sample <= 0 || sample > 1 {
+		return report, fmt.Errorf("sample must be in (0, 1], got %v", sample)
+	}
+	opts := AdvisorOptions{}
+	if options != nil {
+		opts = options[0]
+	}
+	if opts.MaxIDs <= 0 {
+		opts.MaxIDs = defaultAdvisorMaxIDs
+	}
+	keys, err := c.keys()
+	if err != nil {
+		return report, err
+	}
+	report.Records = len(keys)
+	n := int(math.Ceil(sample * float64(len(keys))))
+	picked := rand.Perm(len(keys))[:n]
+	sort.Ints(picked)
+
+	defer func() {
+		if report.Sampled > 0 {
+			report.EstimatedCompressBytes = report.CompressBytes * int64(report.Records) / int64(report.Sampled)
+			report.EstimatedDecompressBytes = report.DecompressBytes * int64(report.Records) / int64(report.Sampled)
+		}
+	}()
+	for _, i := range picked {
+		if err = ctx.Err(); err != nil {
+			return report, err
+		}
+		key := keys[i]
+		compress, decompress, err := c.measure(key)
+		if errors.Is(err, os.ErrNotExist) {
+			// deleted since listing
+			continue
+		}
+		if err != nil {
+			report.Failed++
+			continue
+		}
+		report.Sampled++
+		if compress > 0 {
+			report.CompressBytes += compress
+			if len(report.Compress) < opts.MaxIDs {
+				report.Compress = append(report.Compress, key)
+			}
+		}
+		if decompress > 0 {
+			report.DecompressBytes += decompress
+			if len(report.Decompress) < opts.MaxIDs {
+				report.Decompress = append(report.Decompress, key)
+			}
+		}
+	}
+	return report, nil
+}
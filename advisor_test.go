@@ -0,0 +1,67 @@
+package simplejsondb_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestCompressionAdvisor(t *testing.T) {
+	_, collection := dbtest.NewDB(t, nil)
+	c := collection("mixed")
+	// plain and compressible
+	compressible := []byte(`{"text":"` + string(bytes.Repeat([]byte("abcd"), 256)) + `"}`)
+	// gzipped although the header outweighs any saving
+	incompressible := []byte(`{"n":1}`)
+	for i := 0; i < 10; i++ {
+		if err := c.Create(fmt.Sprint("plain", i), compressible); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Create(fmt.Sprint("zipped", i), incompressible, simplejsondb.CreateOptions{UseGzip: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report, err := c.CompressionAdvisor(context.Background(), 1, simplejsondb.AdvisorOptions{MaxIDs: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Records != 20 || report.Sampled != 20 {
+		t.Error("Test failed - ", report.Records, report.Sampled)
+	}
+	if len(report.Compress) != 4 || len(report.Decompress) != 4 {
+		t.Error("Test failed - id lists not capped", report.Compress, report.Decompress)
+	}
+	if report.CompressBytes < 10*int64(len(compressible))/2 {
+		t.Error("Test failed - compression savings underestimated", report.CompressBytes)
+	}
+	if report.DecompressBytes <= 0 || report.EstimatedDecompressBytes != report.DecompressBytes {
+		t.Error("Test failed - ", report.DecompressBytes, report.EstimatedDecompressBytes)
+	}
+
+	// a sample measures only its share and scales the estimate
+	report, err = c.CompressionAdvisor(context.Background(), 0.25)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Sampled != 5 {
+		t.Error("Test failed - sample not bounded", report.Sampled)
+	}
+	if want := report.CompressBytes * 4; report.EstimatedCompressBytes != want {
+		t.Error("Test failed - ", report.EstimatedCompressBytes, want)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if report, err = c.CompressionAdvisor(ctx, 1); !errors.Is(err, context.Canceled) || report.Sampled != 0 {
+		t.Error("Test failed - ", report.Sampled, err)
+	}
+	if _, err = c.CompressionAdvisor(context.Background(), 0); err == nil {
+		t.Error("Test failed - empty sample accepted")
+	}
+}
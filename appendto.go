@@ -0,0 +1,70 @@
+package simplejsondb
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrNotArray - AppendTo found a record which is not a JSON array
+var ErrNotArray = errors.New("record is not a JSON array")
+
+// AppendOptions - AppendTo configuration
+type AppendOptions struct {
+	// MaxLen - elements kept at most, the oldest are dropped; 0 keeps all
+	MaxLen int
+}
+
+// AppendTo - appends element to the JSON array record, a missing record
+// is an empty array
+//
+// The record is read and rewritten under its exclusive lock like Update.
+// Existing elements keep their bytes, a record which is not an array
+// fails with ErrNotArray and an element which is not JSON with
+// ErrInvalidJSON.
+func (c *_collection) AppendTo(key string, element []byte, options ...AppendOptions) error {
+	return appendTo(c, key, element, options)
+}
+
+// AppendTo - appends element to the visible array, writing the overlay
+func (c *_overlayCollection) AppendTo(key string, element []byte, options ...AppendOptions) error {
+	return appendTo(c, key, element, options)
+}
+
+func appendTo(c Collection, key string, element []byte, options []AppendOptions) error {
+	opts := AppendOptions{}
+	if options != nil {
+		opts = options[0]
+	}
+	element = bytes.TrimSpace(element)
+	if !json.Valid(element) {
+		return fmt.Errorf("%w: element for %s", ErrInvalidJSON, key)
+	}
+	return c.Update(key, func(current []byte) ([]byte, error) {
+		var elements []json.RawMessage
+		if current != nil {
+			trimmed := bytes.TrimSpace(current)
+			if len(trimmed) == 0 || trimmed[0] != '[' {
+				return nil, fmt.Errorf("%w: %s", ErrNotArray, key)
+			}
+			if err := json.Unmarshal(trimmed, &elements); err != nil {
+				return nil, fmt.Errorf("%w: %s: %w", ErrNotArray, key, err)
+			}
+		}
+		elements = append(elements, element)
+		if opts.MaxLen > 0 && len(elements) > opts.MaxLen {
+			elements = elements[len(elements)-opts.MaxLen:]
+		}
+		var out bytes.Buffer
+		out.WriteByte('[')
+		for i, e := range elements {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			out.Write(e)
+		}
+		out.WriteByte(']')
+		return out.Bytes(), nil
+	})
+}
@@ -0,0 +1,63 @@
+package simplejsondb_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestAppendTo(t *testing.T) {
+	_, collection := dbtest.NewDB(t, nil)
+	c := collection("events")
+
+	if err := c.AppendTo("dev1", []byte(` {"e": 1} `)); err != nil {
+		t.Fatal(err)
+	}
+	dbtest.RequireRecord(t, c, "dev1", []byte(`[{"e": 1}]`))
+	for i := 2; i <= 4; i++ {
+		if err := c.AppendTo("dev1", []byte(fmt.Sprint(i)), simplejsondb.AppendOptions{MaxLen: 3}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dbtest.RequireRecord(t, c, "dev1", []byte(`[2,3,4]`))
+
+	dbtest.Seed(t, c, map[string][]byte{"object": []byte(`{"a": 1}`), "spaced": []byte(`[ 1 ]`)})
+	if err := c.AppendTo("object", []byte(`1`)); !errors.Is(err, simplejsondb.ErrNotArray) {
+		t.Error("Test failed - ", err)
+	}
+	dbtest.RequireRecord(t, c, "object", []byte(`{"a": 1}`))
+	if err := c.AppendTo("dev1", []byte(`{`)); !errors.Is(err, simplejsondb.ErrInvalidJSON) {
+		t.Error("Test failed - ", err)
+	}
+	if err := c.AppendTo("spaced", []byte(`2`)); err != nil {
+		t.Error("Test failed - ", err)
+	}
+	dbtest.RequireRecord(t, c, "spaced", []byte(`[1,2]`))
+}
+
+func TestAppendToConcurrent(t *testing.T) {
+	_, collection := dbtest.NewDB(t, nil)
+	c := collection("events")
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := c.AppendTo("log", []byte(fmt.Sprint(i))); err != nil {
+				t.Error("Test failed - ", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	var elements []int
+	if err := c.GetObject("log", &elements); err != nil {
+		t.Fatal(err)
+	}
+	if len(elements) != 50 {
+		t.Error("Test failed - ", len(elements))
+	}
+}
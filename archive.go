@@ -0,0 +1,346 @@
+package simplejsondb
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// archiveMagic - marks both ends of an indexed archive
+var archiveMagic = []byte("SJDBARC1")
+
+// ErrCorruptArchive - an archive or one of its entries failed validation
+var ErrCorruptArchive = errors.New("corrupt archive")
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+type (
+	// ExportOptions - indexed export configuration
+	ExportOptions struct {
+		// Compress - gzip every record inside the archive
+		Compress bool
+	}
+
+	// ArchiveManifest - describes how an archive was written
+	ArchiveManifest struct {
+		WrittenBy     string `json:"written_by"`
+		LayoutVersion int    `json:"layout_version"`
+		Compressed    bool   `json:"compressed,omitempty"`
+	}
+
+	// ArchiveReader - random access to an indexed archive
+	ArchiveReader interface {
+		Manifest() ArchiveManifest
+		// Warnings lists problems that do not prevent reading, such as an
+		// archive written by a newer version of this package
+		Warnings() []string
+		Collections() []string
+		List(string) ([]string, error)
+		Get(string, string) ([]byte, error)
+		Close() error
+	}
+
+	_archiveEntry struct {
+		Collection string `json:"collection"`
+		ID         string `json:"id"`
+		Offset     int64  `json:"offset"`
+		Length     int64  `json:"length"`
+		Checksum   uint32 `json:"checksum"`
+		Gzip       bool   `json:"gzip,omitempty"`
+	}
+
+	_indexLine struct {
+		Manifest *ArchiveManifest `json:"manifest,omitempty"`
+		_archiveEntry
+	}
+
+	_archive struct {
+		f        *os.File
+		manifest ArchiveManifest
+		warnings []string
+		entries  map[string]map[string]_archiveEntry
+	}
+
+	// _source - a database whose collections can be enumerated
+	_source interface {
+		DB
+		collections() ([]string, error)
+		root() string
+	}
+)
+
+// ExportIndexed - writes every collection into an indexed archive at path
+func (db *_db) ExportIndexed(path string, options ...ExportOptions) (err error) {
+	if err = db.life.begin(); err != nil {
+		return err
+	}
+	defer db.life.end()
+	return exportIndexed(db, path, options...)
+}
+
+// collections - returns the sorted collection names of the database
+func (db *_db) collections() (names []string, err error) {
+	entries, err := os.ReadDir(db.path)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if e.IsDir() && e.Name()[0] != '.' {
+			names = append(names, e.Name())
+		}
+	}
+	return names, nil
+}
+
+// exportIndexed - streams records into path followed by a trailer index
+//
+// Records are written sequentially while index lines are spilled to a temp
+// file, so memory stays bounded by the largest record. The archive is
+// renamed into place only once complete.
+func exportIndexed(db _source, path string, options ...ExportOptions) (err error) {
+	opts := ExportOptions{}
+	if options != nil {
+		opts = options[0]
+	}
+	names, err := db.collections()
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(path)
+	out, err := createTemp(dir, 0644)
+	if err != nil {
+		return err
+	}
+	spill, err := createTemp(dir, 0600)
+	if err != nil {
+		out.Close()
+		os.Remove(out.Name())
+		return err
+	}
+	defer func() {
+		spill.Close()
+		os.Remove(spill.Name())
+		if err != nil {
+			out.Close()
+			os.Remove(out.Name())
+		}
+	}()
+
+	w := bufio.NewWriter(out)
+	index := bufio.NewWriter(spill)
+	enc := json.NewEncoder(index)
+	offset := int64(len(archiveMagic))
+	if _, err = w.Write(archiveMagic); err != nil {
+		return err
+	}
+	err = enc.Encode(_indexLine{Manifest: &ArchiveManifest{
+		WrittenBy:     Version,
+		LayoutVersion: LayoutVersion,
+		Compressed:    opts.Compress,
+	}})
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		coll, err := db.Collection(name)
+		if err != nil {
+			return err
+		}
+		layer, ok := coll.(_layer)
+		if !ok {
+			return fmt.Errorf("collection %s cannot be listed", name)
+		}
+		keys, err := layer.keys()
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			data, err := coll.Get(key)
+			if errors.Is(err, os.ErrNotExist) {
+				// deleted while exporting
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if opts.Compress {
+				data, err = gzipBytes(data)
+				if err != nil {
+					return err
+				}
+			}
+			if _, err = w.Write(data); err != nil {
+				return err
+			}
+			err = enc.Encode(_archiveEntry{
+				Collection: name,
+				ID:         key,
+				Offset:     offset,
+				Length:     int64(len(data)),
+				Checksum:   crc32.Checksum(data, crcTable),
+				Gzip:       opts.Compress,
+			})
+			if err != nil {
+				return err
+			}
+			offset += int64(len(data))
+		}
+	}
+
+	// trailer: index lines, index offset, magic
+	if err = index.Flush(); err != nil {
+		return err
+	}
+	if _, err = spill.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err = io.Copy(w, spill); err != nil {
+		return err
+	}
+	var footer [8]byte
+	binary.BigEndian.PutUint64(footer[:], uint64(offset))
+	if _, err = w.Write(footer[:]); err != nil {
+		return err
+	}
+	if _, err = w.Write(archiveMagic); err != nil {
+		return err
+	}
+	if err = w.Flush(); err != nil {
+		return err
+	}
+	if err = out.Sync(); err != nil {
+		return err
+	}
+	if err = out.Close(); err != nil {
+		return err
+	}
+	return os.Rename(out.Name(), path)
+}
+
+// OpenArchive - opens an indexed archive reading only its trailer index
+func OpenArchive(path string) (ArchiveReader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	a, err := readArchiveIndex(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return a, nil
+}
+
+func readArchiveIndex(f *os.File) (*_archive, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	footerSize := int64(8 + len(archiveMagic))
+	size := info.Size()
+	if size < int64(len(archiveMagic))+footerSize {
+		return nil, fmt.Errorf("%w: too short", ErrCorruptArchive)
+	}
+	footer := make([]byte, footerSize)
+	if _, err = f.ReadAt(footer, size-footerSize); err != nil {
+		return nil, err
+	}
+	if string(footer[8:]) != string(archiveMagic) {
+		return nil, fmt.Errorf("%w: missing trailer", ErrCorruptArchive)
+	}
+	start := int64(binary.BigEndian.Uint64(footer[:8]))
+	if start < int64(len(archiveMagic)) || start > size-footerSize {
+		return nil, fmt.Errorf("%w: bad index offset", ErrCorruptArchive)
+	}
+
+	a := &_archive{f: f, entries: make(map[string]map[string]_archiveEntry)}
+	dec := json.NewDecoder(io.NewSectionReader(f, start, size-footerSize-start))
+	for {
+		var line _indexLine
+		err = dec.Decode(&line)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrCorruptArchive, err)
+		}
+		if line.Manifest != nil {
+			a.manifest = *line.Manifest
+			if compareVersions(a.manifest.WrittenBy, Version) > 0 {
+				a.warnings = append(a.warnings, fmt.Sprintf("archive written by %s, newer than %s", a.manifest.WrittenBy, Version))
+			}
+			continue
+		}
+		e := line._archiveEntry
+		if a.entries[e.Collection] == nil {
+			a.entries[e.Collection] = make(map[string]_archiveEntry)
+		}
+		a.entries[e.Collection][e.ID] = e
+	}
+	return a, nil
+}
+
+// Manifest - how the archive was written
+func (a *_archive) Manifest() ArchiveManifest {
+	return a.manifest
+}
+
+// Warnings - non fatal problems found while opening the archive
+func (a *_archive) Warnings() []string {
+	return a.warnings
+}
+
+// Collections - returns the sorted collection names in the archive
+func (a *_archive) Collections() (names []string) {
+	for name := range a.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return
+}
+
+// List - returns the sorted record ids of a collection in the archive
+func (a *_archive) List(collection string) (ids []string, err error) {
+	entries, ok := a.entries[collection]
+	if !ok {
+		return nil, &os.PathError{Op: "list", Path: collection, Err: os.ErrNotExist}
+	}
+	for id := range entries {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Get - reads a single record, verifying its checksum
+func (a *_archive) Get(collection, id string) (data []byte, err error) {
+	e, ok := a.entries[collection][id]
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: filepath.Join(collection, id), Err: os.ErrNotExist}
+	}
+	data = make([]byte, e.Length)
+	if _, err = a.f.ReadAt(data, e.Offset); err != nil {
+		return nil, err
+	}
+	if crc32.Checksum(data, crcTable) != e.Checksum {
+		return nil, fmt.Errorf("%w: checksum mismatch for %s/%s", ErrCorruptArchive, collection, id)
+	}
+	if e.Gzip {
+		return UnGzip(data)
+	}
+	return data, nil
+}
+
+// Close - closes the archive file
+func (a *_archive) Close() error {
+	return a.f.Close()
+}
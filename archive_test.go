@@ -0,0 +1,131 @@
+package simplejsondb_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func seedArchiveDB(t *testing.T) simplejsondb.DB {
+	db, collection := dbtest.NewDB(t, nil)
+	for _, name := range []string{"users", "orders"} {
+		c := collection(name)
+		for i := 0; i < 10; i++ {
+			data := fmt.Sprintf(`{"collection": %q, "n": %d}`, name, i)
+			err := c.Create(fmt.Sprintf("%s-%d", name, i), []byte(data), simplejsondb.CreateOptions{UseGzip: i%2 == 0})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return db
+}
+
+func TestExportIndexed(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "archive.sjdb")
+	db := seedArchiveDB(t)
+
+	for _, opts := range []simplejsondb.ExportOptions{{}, {Compress: true}} {
+		err := db.ExportIndexed(archive, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		a, err := simplejsondb.OpenArchive(archive)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names := a.Collections()
+		if len(names) != 2 || names[0] != "orders" || names[1] != "users" {
+			t.Error("Test failed - collections", names)
+		}
+		ids, err := a.List("users")
+		if err != nil || len(ids) != 10 {
+			t.Error("Test failed - list", ids, err)
+		}
+		for i := 9; i >= 0; i-- {
+			data, err := a.Get("orders", fmt.Sprintf("orders-%d", i))
+			want := fmt.Sprintf(`{"collection": "orders", "n": %d}`, i)
+			if err != nil || string(data) != want {
+				t.Error("Test failed - ", string(data), err)
+			}
+		}
+		if _, err = a.Get("orders", "missing"); !errors.Is(err, os.ErrNotExist) {
+			t.Error("Test failed - missing entry", err)
+		}
+		if _, err = a.List("missing"); !errors.Is(err, os.ErrNotExist) {
+			t.Error("Test failed - missing collection", err)
+		}
+		a.Close()
+	}
+}
+
+func TestExportIndexedCorruption(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "archive.sjdb")
+	db := seedArchiveDB(t)
+	err := db.ExportIndexed(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the first record starts right after the 8 byte header
+	f, err := os.OpenFile(archive, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = f.WriteAt([]byte("X"), 10)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := simplejsondb.OpenArchive(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	if _, err = a.Get("orders", "orders-0"); !errors.Is(err, simplejsondb.ErrCorruptArchive) {
+		t.Error("Test failed - corruption not detected", err)
+	}
+	if _, err = a.Get("orders", "orders-1"); err != nil {
+		t.Error("Test failed - intact entry", err)
+	}
+
+	err = os.WriteFile(archive, []byte("not an archive"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = simplejsondb.OpenArchive(archive); !errors.Is(err, simplejsondb.ErrCorruptArchive) {
+		t.Error("Test failed - bad archive opened", err)
+	}
+}
+
+func TestOverlayExportIndexed(t *testing.T) {
+	base, overlay := t.TempDir(), t.TempDir()
+	archive := filepath.Join(t.TempDir(), "overlay.sjdb")
+	db, _ := newOverlay(t, base, overlay)
+	c, err := db.Collection("collection1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = c.Delete("key1")
+	_ = c.Create("key4", []byte(`{"layer": "overlay"}`))
+
+	err = db.ExportIndexed(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := simplejsondb.OpenArchive(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	ids, _ := a.List("collection1")
+	if fmt.Sprint(ids) != "[key2 key3 key4]" {
+		t.Error("Test failed - ", ids)
+	}
+}
@@ -0,0 +1,198 @@
+package simplejsondb
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrExists - the record already exists
+	ErrExists = errors.New("record already exists")
+	// ErrIDExhausted - CreateAuto gave up after too many colliding ids
+	ErrIDExhausted = errors.New("generated ids exhausted")
+)
+
+type (
+	// GeneratorPolicy - what CreateAuto does when a generated id is taken
+	GeneratorPolicy struct {
+		overwrite bool
+		attempts  int
+	}
+
+	// AutoOptions - CreateAuto configuration
+	AutoOptions struct {
+		UseGzip bool
+		// Generator - produces candidate ids, defaults to the Generate of
+		// the id policy
+		Generator func() string
+		// Policy - collision handling, defaults to RetryN(10)
+		Policy *GeneratorPolicy
+	}
+
+	// AutoResult - outcome of CreateAuto
+	AutoResult struct {
+		ID       string
+		Attempts int
+	}
+)
+
+var (
+	// FailFast - return ErrExists on the first collision
+	FailFast = GeneratorPolicy{attempts: 1}
+	// Overwrite - replace an existing record with the generated id, only
+	// safe when the generator can never hand out an id twice by mistake
+	Overwrite = GeneratorPolicy{overwrite: true, attempts: 1}
+)
+
+// RetryN - try up to n fresh ids before failing with ErrIDExhausted
+func RetryN(n int) GeneratorPolicy {
+	if n < 1 {
+		n = 1
+	}
+	return GeneratorPolicy{attempts: n}
+}
+
+// ULID - a 26 character ULID, the default generator of the built-in id
+// policies
+//
+// The first 48 bits are the millisecond timestamp, so ids sort by creation
+// time, the other 80 bits are random. Ids generated within the same
+// millisecond by this process increment the random part and sort in call
+// order too.
+func ULID() string {
+	ulidMu.Lock()
+	defer ulidMu.Unlock()
+	now := uint64(time.Now().UnixMilli())
+	if now <= ulidLast.ms {
+		// same millisecond or a clock step back, stay monotonic
+		now = ulidLast.ms
+		if !ulidLast.increment() {
+			now++
+			_, _ = rand.Read(ulidLast.entropy[:])
+		}
+	} else {
+		_, _ = rand.Read(ulidLast.entropy[:])
+	}
+	ulidLast.ms = now
+
+	// 128 bits as 26 base32 digits, the first takes the top 3 bits
+	hi := now<<16 | uint64(ulidLast.entropy[0])<<8 | uint64(ulidLast.entropy[1])
+	lo := binary.BigEndian.Uint64(ulidLast.entropy[2:])
+	var out [26]byte
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
+
+// _ulidState - the last ULID handed out
+type _ulidState struct {
+	ms      uint64
+	entropy [10]byte
+}
+
+// increment - adds one to the random part, false when it overflowed
+func (s *_ulidState) increment() bool {
+	for i := len(s.entropy) - 1; i >= 0; i-- {
+		s.entropy[i]++
+		if s.entropy[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// crockford - base32 digits of ULIDs, in ascending byte order so ids
+// compare like the numbers they encode
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+var (
+	ulidMu   sync.Mutex
+	ulidLast _ulidState
+)
+
+// createAuto - writes data under the first free generated id
+//
+// tryCreate checks and writes a candidate atomically under its lock and
+// reports whether it was free, every retry asks for a fresh id.
+func createAuto(g *_callbacks, ids IDPolicy, options []AutoOptions, tryCreate func(id string, useGzip, overwrite bool) (bool, error)) (result AutoResult, err error) {
+	opts := AutoOptions{}
+	if options != nil {
+		opts = options[0]
+	}
+	if opts.Generator == nil {
+		opts.Generator = ids.Generate
+	}
+	policy := RetryN(10)
+	if opts.Policy != nil {
+		policy = *opts.Policy
+	}
+
+	for result.Attempts < policy.attempts {
+		err = g.guard("Generator", func() error {
+			result.ID = opts.Generator()
+			return nil
+		})
+		if err != nil {
+			return result, err
+		}
+		result.Attempts++
+		created, err := tryCreate(result.ID, opts.UseGzip, policy.overwrite)
+		if err != nil || created {
+			return result, err
+		}
+	}
+	if policy.attempts == 1 {
+		return result, fmt.Errorf("%w: %s", ErrExists, result.ID)
+	}
+	return result, fmt.Errorf("%w: %d attempts", ErrIDExhausted, result.Attempts)
+}
+
+// CreateAuto - saves data under a generated id
+func (c *_collection) CreateAuto(data []byte, options ...AutoOptions) (AutoResult, error) {
+	return createAuto(c.callbacks, c.ids, options, func(id string, useGzip, overwrite bool) (bool, error) {
+		if err := c.checkID(id); err != nil {
+			return false, err
+		}
+		if err := c.life.begin(); err != nil {
+			return false, err
+		}
+		defer c.life.end()
+		unlock := c.lock(id)
+		defer unlock()
+		if !overwrite {
+			_, _, err := c.resolve(id)
+			if err == nil {
+				return false, nil
+			}
+			if !errors.Is(err, os.ErrNotExist) {
+				return false, err
+			}
+		}
+		codec := c.codec
+		if useGzip {
+			codec = c.enc.wrap(GzipCodec)
+		}
+		return true, c.write(id, data, codec, c.expiresAt(0), nil)
+	})
+}
+
+// CreateAuto - saves data into the overlay under a generated id
+func (c *_overlayCollection) CreateAuto(data []byte, options ...AutoOptions) (AutoResult, error) {
+	return createAuto(c.upper.callbacks, c.upper.ids, options, func(id string, useGzip, overwrite bool) (bool, error) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if !overwrite && c.has(id) {
+			return false, nil
+		}
+		return true, c.create(context.Background(), id, data, CreateOptions{UseGzip: useGzip})
+	})
+}
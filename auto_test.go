@@ -0,0 +1,119 @@
+package simplejsondb_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+// collidingGenerator - returns "dup" k times, then fresh ids
+func collidingGenerator(k int) func() string {
+	n := 0
+	return func() string {
+		n++
+		if n <= k {
+			return "dup"
+		}
+		return fmt.Sprint("fresh-", n)
+	}
+}
+
+func TestCreateAutoPolicies(t *testing.T) {
+	dir := t.TempDir()
+	_, collection := dbtest.Open(t, dir, nil)
+	c := collection("collection1")
+	_ = c.Create("dup", []byte(`"original"`))
+
+	retry := simplejsondb.RetryN(5)
+	result, err := c.CreateAuto([]byte(`"retried"`), simplejsondb.AutoOptions{Generator: collidingGenerator(3), Policy: &retry})
+	if err != nil || result.Attempts != 4 || result.ID != "fresh-4" {
+		t.Error("Test failed - retry", result, err)
+	}
+
+	retry = simplejsondb.RetryN(2)
+	result, err = c.CreateAuto([]byte(`"exhausted"`), simplejsondb.AutoOptions{Generator: collidingGenerator(3), Policy: &retry})
+	if !errors.Is(err, simplejsondb.ErrIDExhausted) || result.Attempts != 2 {
+		t.Error("Test failed - exhausted", result, err)
+	}
+
+	result, err = c.CreateAuto([]byte(`"failfast"`), simplejsondb.AutoOptions{Generator: collidingGenerator(3), Policy: &simplejsondb.FailFast})
+	if !errors.Is(err, simplejsondb.ErrExists) || result.Attempts != 1 {
+		t.Error("Test failed - fail fast", result, err)
+	}
+	data, _ := c.Get("dup")
+	if string(data) != `"original"` {
+		t.Error("Test failed - collision overwrote", string(data))
+	}
+
+	result, err = c.CreateAuto([]byte(`"overwritten"`), simplejsondb.AutoOptions{Generator: collidingGenerator(3), Policy: &simplejsondb.Overwrite})
+	if err != nil || result.ID != "dup" {
+		t.Error("Test failed - overwrite", result, err)
+	}
+	data, _ = c.Get("dup")
+	if string(data) != `"overwritten"` {
+		t.Error("Test failed - overwrite", string(data))
+	}
+
+	// failed attempts leave nothing behind
+	entries, _ := os.ReadDir(filepath.Join(dir, "collection1"))
+	if len(entries) != 2 {
+		t.Error("Test failed - orphan files", len(entries))
+	}
+}
+
+func TestCreateAutoDefault(t *testing.T) {
+	_, collection := dbtest.NewDB(t, nil)
+	c := collection("collection1")
+	seen := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		result, err := c.CreateAuto([]byte(`{}`), simplejsondb.AutoOptions{UseGzip: i%2 == 0})
+		if err != nil || result.Attempts != 1 || seen[result.ID] {
+			t.Error("Test failed - ", result, err)
+		}
+		seen[result.ID] = true
+		if _, err = c.Get(result.ID); err != nil {
+			t.Error("Test failed - ", err)
+		}
+	}
+}
+
+func TestULID(t *testing.T) {
+	ids := make([]string, 1000)
+	for i := range ids {
+		ids[i] = simplejsondb.ULID()
+	}
+	for i, id := range ids {
+		if len(id) != 26 || id[0] > '7' {
+			t.Fatal("Test failed - ", id)
+		}
+		if i > 0 && id <= ids[i-1] {
+			t.Fatal("Test failed - not increasing", ids[i-1], id)
+		}
+	}
+	// the timestamp is the first 10 characters, milliseconds since epoch
+	var ms uint64
+	for _, ch := range ids[0][:10] {
+		ms = ms<<5 | uint64(strings.IndexRune("0123456789ABCDEFGHJKMNPQRSTVWXYZ", ch))
+	}
+	if d := time.Since(time.UnixMilli(int64(ms))); d < 0 || d > time.Minute {
+		t.Error("Test failed - ", ids[0], d)
+	}
+
+	_, collection := dbtest.NewDB(t, nil)
+	c := collection("collection1")
+	first, err := c.CreateAuto([]byte(`1`))
+	second, err2 := c.CreateAuto([]byte(`2`), simplejsondb.AutoOptions{UseGzip: true})
+	if err != nil || err2 != nil || first.ID >= second.ID {
+		t.Error("Test failed - ", first, second, err, err2)
+	}
+	if keys := c.Keys(); len(keys) != 2 || keys[0] != first.ID {
+		t.Error("Test failed - ", keys)
+	}
+}
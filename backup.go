@@ -0,0 +1,120 @@
+package simplejsondb
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+)
+
+// Backup - streams the records of the collection as a tar.gz into w
+//
+// Every record is read under its own read lock, so concurrent writes never
+// leave a torn record or a temp file in the archive. Entries are named
+// <collection>/<id>.json and hold the plain JSON, gzip records included.
+func (c *_collection) Backup(w io.Writer) error {
+	return backup(w, []Collection{c})
+}
+
+// Backup - streams the visible records of the collection as a tar.gz
+func (c *_overlayCollection) Backup(w io.Writer) error {
+	return backup(w, []Collection{c})
+}
+
+// Backup - streams every collection into one tar.gz written to w
+func (db *_db) Backup(w io.Writer) (err error) {
+	if err = db.life.begin(); err != nil {
+		return err
+	}
+	defer db.life.end()
+	return backupAll(db, w)
+}
+
+// Backup - streams the merged view of every collection as a tar.gz
+func (o *_overlay) Backup(w io.Writer) (err error) {
+	if err = o.upper.life.begin(); err != nil {
+		return err
+	}
+	defer o.upper.life.end()
+	return backupAll(o, w)
+}
+
+func backupAll(db _source, w io.Writer) error {
+	names, err := db.collections()
+	if err != nil {
+		return err
+	}
+	colls := make([]Collection, 0, len(names))
+	for _, name := range names {
+		c, err := db.Collection(name)
+		if err != nil {
+			return err
+		}
+		colls = append(colls, c)
+	}
+	return backup(w, colls)
+}
+
+// backup - writes the records of colls into a tar.gz stream
+func backup(w io.Writer, colls []Collection) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	for _, c := range colls {
+		if err := backupCollection(tw, c); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func backupCollection(tw *tar.Writer, c Collection) error {
+	layer, ok := c.(_layer)
+	if !ok {
+		return fmt.Errorf("collection %s cannot be listed", c.Name())
+	}
+	keys, err := layer.keys()
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		err = backupRecord(tw, c, layer, key)
+		if errors.Is(err, os.ErrNotExist) {
+			// deleted while backing up
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backupRecord - appends one record as <collection>/<id>.json
+func backupRecord(tw *tar.Writer, c Collection, layer _layer, key string) error {
+	modified, err := layer.modTime(key)
+	if err != nil {
+		return err
+	}
+	data, err := c.Get(key)
+	if err != nil {
+		return err
+	}
+	err = tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     path.Join(c.Name(), key+".json"),
+		Mode:     0644,
+		Size:     int64(len(data)),
+		ModTime:  modified,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = tw.Write(data)
+	return err
+}
@@ -0,0 +1,99 @@
+package simplejsondb_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+// readBackup - the entries of a tar.gz backup by name
+func readBackup(t *testing.T, data []byte) map[string][]byte {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := make(map[string][]byte)
+	tr := tar.NewReader(gz)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return entries
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries[h.Name], err = io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestBackup(t *testing.T) {
+	db, collection := dbtest.NewDB(t, nil)
+	c := collection("orders")
+	dbtest.SeedN(t, c, 20)
+	if err := c.Create("zipped", []byte(`{"z": 1}`), simplejsondb.CreateOptions{UseGzip: true}); err != nil {
+		t.Fatal(err)
+	}
+	dbtest.Seed(t, collection("users"), map[string][]byte{"u1": []byte(`{"name": "a"}`)})
+
+	// backups taken while writing hold only whole records
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				data := fmt.Sprintf(`{"w": %d, "pad": %q}`, w, strings.Repeat("x", i%500))
+				if err := c.Create(fmt.Sprintf("new-%d-%d", w, i%50), []byte(data)); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(w)
+	}
+	for i := 0; i < 10; i++ {
+		var buf bytes.Buffer
+		if err := c.Backup(&buf); err != nil {
+			t.Fatal(err)
+		}
+		for name, data := range readBackup(t, buf.Bytes()) {
+			if !strings.HasPrefix(name, "orders/") || !strings.HasSuffix(name, ".json") {
+				t.Error("Test failed - entry", name)
+			}
+			if !json.Valid(data) {
+				t.Error("Test failed - invalid JSON", name, string(data))
+			}
+		}
+	}
+	close(done)
+	wg.Wait()
+
+	var buf bytes.Buffer
+	if err := db.Backup(&buf); err != nil {
+		t.Fatal(err)
+	}
+	entries := readBackup(t, buf.Bytes())
+	if string(entries["orders/zipped.json"]) != `{"z": 1}` || string(entries["users/u1.json"]) != `{"name": "a"}` {
+		t.Error("Test failed - ", len(entries))
+	}
+	if n := len(c.Keys()) + 1; len(entries) != n {
+		t.Error("Test failed - ", len(entries), n)
+	}
+}
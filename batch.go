@@ -0,0 +1,267 @@
+package simplejsondb
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrBatchDone - the batch was already committed or discarded
+var ErrBatchDone = errors.New("batch already committed or discarded")
+
+const (
+	// batchDir - per collection directory of the files staged by batches,
+	// renamed from there into the collection on Commit
+	batchDir = ".batch"
+	// orphanAge - staged files older than this were left by a process
+	// which died before Commit or Discard and are removed by New
+	orphanAge = time.Hour
+)
+
+// Batch - record writes and deletes of one collection committed together
+//
+// Put encodes and writes each record to a staged file right away, Commit
+// then only renames and removes files while holding the collection and
+// every record of the batch exclusively: a Get of a batch record waits for
+// the whole batch and concurrent writers to the collection wait too.
+//
+// The renames are not jointly atomic, a crash during Commit can leave part
+// of the batch applied; use DB.Begin when that matters. A batch is not
+// safe for concurrent use.
+type Batch interface {
+	Put(id string, data []byte) error
+	// Delete removes the record on Commit, a missing record is no error
+	Delete(id string) error
+	Commit() error
+	// Discard drops the batch and its staged files
+	Discard() error
+}
+
+type (
+	_batch struct {
+		c    *_collection
+		ops  map[string]*_batchOp
+		done bool
+	}
+
+	// _batchOp - a staged write or a delete, staged is the file not yet
+	// moved into place
+	_batchOp struct {
+		del     bool
+		staged  string
+		content []byte
+		data    []byte
+		codec   Codec
+	}
+)
+
+// NewBatch - starts a batch of writes to the collection
+func (c *_collection) NewBatch() Batch {
+	return &_batch{c: c, ops: make(map[string]*_batchOp)}
+}
+
+// Put - stages a record write in the collection codec, replacing an
+// earlier Put or Delete of the id in this batch
+func (b *_batch) Put(key string, data []byte) (err error) {
+	if b.done {
+		return ErrBatchDone
+	}
+	c := b.c
+	if err = c.checkID(key); err != nil {
+		return err
+	}
+	if err = c.validate(key, data); err != nil {
+		return err
+	}
+	op := &_batchOp{content: data, data: data, codec: c.codec}
+	if op.codec != PlainCodec {
+		if op.data, err = op.codec.Encode(data); err != nil {
+			return err
+		}
+	}
+	dir := filepath.Join(c.path, batchDir)
+	if _, err = getOrCreateDir(dir); err != nil {
+		return err
+	}
+	f, err := createTemp(dir, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	op.staged = f.Name()
+	_, err = f.Write(op.data)
+	if err == nil && !c.noFsync {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(op.staged)
+		return err
+	}
+	b.stage(key, op)
+	return nil
+}
+
+// Delete - stages a record removal
+func (b *_batch) Delete(key string) error {
+	if b.done {
+		return ErrBatchDone
+	}
+	if err := b.c.checkID(key); err != nil {
+		return err
+	}
+	b.stage(key, &_batchOp{del: true})
+	return nil
+}
+
+// stage - records op for key, dropping the file of the op it replaces
+func (b *_batch) stage(key string, op *_batchOp) {
+	if prev, ok := b.ops[key]; ok && prev.staged != "" {
+		os.Remove(prev.staged)
+	}
+	b.ops[key] = op
+}
+
+// Commit - moves the staged records into place and deletes the others,
+// stopping at the first failure
+func (b *_batch) Commit() (err error) {
+	if b.done {
+		return ErrBatchDone
+	}
+	b.done = true
+	defer b.cleanup()
+	c := b.c
+	if err = c.life.begin(); err != nil {
+		return err
+	}
+	defer c.life.end()
+
+	keys := make([]string, 0, len(b.ops))
+	for key := range b.ops {
+		keys = append(keys, key)
+	}
+	// one order for every batch, the collection lock is exclusive anyway
+	sort.Strings(keys)
+	collection := c.collectionLockPath()
+	acquire(collection, true)
+	defer release(collection, true)
+	for _, key := range keys {
+		path := c.lockPath(key)
+		acquire(path, true)
+		defer release(path, true)
+	}
+
+	for _, key := range keys {
+		op := b.ops[key]
+		if op.del {
+			err = c.remove(key)
+			if errors.Is(err, os.ErrNotExist) {
+				err = nil
+			}
+		} else {
+			err = c.storeStaged(key, op.content, op.data, op.codec, c.expiresAt(0), nil, op.staged)
+			if err == nil {
+				op.staged = ""
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if c.noFsync {
+		return nil
+	}
+	return syncDir(c.path)
+}
+
+// Discard - drops the staged operations
+func (b *_batch) Discard() error {
+	if b.done {
+		return ErrBatchDone
+	}
+	b.done = true
+	b.cleanup()
+	return nil
+}
+
+// cleanup - removes the staged files not moved into place
+func (b *_batch) cleanup() {
+	for _, op := range b.ops {
+		if op.staged != "" {
+			os.Remove(op.staged)
+			op.staged = ""
+		}
+	}
+}
+
+// NewBatch - starts a batch of writes to the overlay
+func (c *_overlayCollection) NewBatch() Batch {
+	return &_overlayBatch{c: c, upper: c.upper.NewBatch().(*_batch)}
+}
+
+// _overlayBatch - a batch of the upper layer, Commit then hides deleted
+// base records and uncovers written ones
+type _overlayBatch struct {
+	c     *_overlayCollection
+	upper *_batch
+}
+
+func (b *_overlayBatch) Put(key string, data []byte) error { return b.upper.Put(key, data) }
+func (b *_overlayBatch) Delete(key string) error           { return b.upper.Delete(key) }
+func (b *_overlayBatch) Discard() error                    { return b.upper.Discard() }
+
+// Commit - commits the upper batch and updates the whiteouts
+func (b *_overlayBatch) Commit() (err error) {
+	c := b.c
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err = b.upper.Commit(); err != nil {
+		return err
+	}
+	for key, op := range b.upper.ops {
+		if !op.del {
+			err = c.written(key)
+		} else if c.inBase(key) {
+			err = os.WriteFile(c.whiteoutPath(key), nil, os.ModePerm)
+			if err != nil {
+				c.logger.Error("unable to update whiteout", zap.Error(err))
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeOrphans - deletes the staged batch files crashed processes left
+// in the collections of the database
+func (db *_db) removeOrphans() {
+	names, err := db.collections()
+	if err != nil {
+		db.logger.Error("unable to list collections", zap.Error(err))
+		return
+	}
+	for _, name := range names {
+		dir := filepath.Join(db.path, name, batchDir)
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			info, err := e.Info()
+			if err != nil || time.Since(info.ModTime()) < orphanAge {
+				continue
+			}
+			err = os.Remove(filepath.Join(dir, e.Name()))
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				db.logger.Error("unable to remove orphaned batch file", zap.Error(err))
+			}
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package simplejsondb_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestBatch(t *testing.T) {
+	dir := t.TempDir()
+	_, collection := dbtest.Open(t, dir, &simplejsondb.Options{UseGzip: true})
+	c := collection("orders")
+	dbtest.Seed(t, c, map[string][]byte{"old": []byte(`{}`)})
+
+	b := c.NewBatch()
+	for _, id := range []string{"a", "b", "c"} {
+		if err := b.Put(id, []byte(`{"id": "`+id+`"}`)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = b.Put("c", []byte(`{"id": "c2"}`))
+	_ = b.Delete("old")
+	_ = b.Delete("missing")
+	if n := c.Len(); n != 1 {
+		t.Error("Test failed - staged records visible", n)
+	}
+	if err := b.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	dbtest.RequireRecord(t, c, "a", []byte(`{"id": "a"}`))
+	dbtest.RequireRecord(t, c, "c", []byte(`{"id": "c2"}`))
+	if _, err := c.Get("old"); !errors.Is(err, os.ErrNotExist) {
+		t.Error("Test failed - ", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "orders", "b.json.gz")); err != nil {
+		t.Error("Test failed - ", err)
+	}
+	if staged, _ := os.ReadDir(filepath.Join(dir, "orders", ".batch")); len(staged) != 0 {
+		t.Error("Test failed - ", len(staged))
+	}
+	if err := b.Put("d", []byte(`{}`)); !errors.Is(err, simplejsondb.ErrBatchDone) {
+		t.Error("Test failed - ", err)
+	}
+
+	b = c.NewBatch()
+	_ = b.Put("d", []byte(`{}`))
+	_ = b.Delete("a")
+	if err := b.Discard(); err != nil {
+		t.Error("Test failed - ", err)
+	}
+	if staged, _ := os.ReadDir(filepath.Join(dir, "orders", ".batch")); len(staged) != 0 {
+		t.Error("Test failed - ", len(staged))
+	}
+	if keys := c.Keys(); len(keys) != 3 {
+		t.Error("Test failed - ", keys)
+	}
+	if err := b.Commit(); !errors.Is(err, simplejsondb.ErrBatchDone) {
+		t.Error("Test failed - ", err)
+	}
+}
+
+func TestBatchOrphans(t *testing.T) {
+	dir := t.TempDir()
+	_, collection := dbtest.Open(t, dir, nil)
+	_ = collection("orders")
+	staging := filepath.Join(dir, "orders", ".batch")
+	_ = os.MkdirAll(staging, os.ModePerm)
+	orphan, fresh := filepath.Join(staging, ".tmp-orphan"), filepath.Join(staging, ".tmp-fresh")
+	_ = os.WriteFile(orphan, []byte(`{}`), 0644)
+	_ = os.WriteFile(fresh, []byte(`{}`), 0644)
+	old := time.Now().Add(-2 * time.Hour)
+	_ = os.Chtimes(orphan, old, old)
+
+	dbtest.Open(t, dir, nil)
+	if _, err := os.Stat(orphan); !errors.Is(err, os.ErrNotExist) {
+		t.Error("Test failed - ", err)
+	}
+	if _, err := os.Stat(fresh); err != nil {
+		t.Error("Test failed - staged file of a live batch removed", err)
+	}
+}
+
+func TestBatchOverlay(t *testing.T) {
+	bdb, collection := dbtest.NewDB(t, nil)
+	dbtest.Seed(t, collection("users"), map[string][]byte{"u1": []byte(`{}`), "u2": []byte(`{}`)})
+	db, err := simplejsondb.NewOverlay(bdb, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	users, _ := db.Collection("users")
+	_ = users.Delete("u2")
+
+	b := users.NewBatch()
+	_ = b.Delete("u1")
+	_ = b.Put("u2", []byte(`{"back": true}`))
+	if err = b.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if keys := users.Keys(); len(keys) != 1 || keys[0] != "u2" {
+		t.Error("Test failed - ", keys)
+	}
+	dbtest.RequireRecord(t, users, "u2", []byte(`{"back": true}`))
+	dbtest.RequireRecord(t, collection("users"), "u1", []byte(`{}`))
+}
@@ -0,0 +1,388 @@
+package simplejsondb
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrCacheFull - pinning would take the cache over its byte budget
+var ErrCacheFull = errors.New("cache full")
+
+// defaultCacheMaxEntryFraction - share of the budget a single entry may take
+const defaultCacheMaxEntryFraction = 0.1
+
+// Cache revalidation modes reported by CacheStats
+const (
+	// RevalidateNone - entries stay valid until a write of this process,
+	// writes of other processes to the same directory go unnoticed
+	RevalidateNone = "none"
+	// RevalidateTTL - entries are reread once older than Options.CacheTTL
+	RevalidateTTL = "ttl"
+)
+
+type (
+	// CacheStats - read cache usage
+	CacheStats struct {
+		Bytes   int64 `json:"bytes"`
+		Budget  int64 `json:"budget"`
+		Entries int   `json:"entries"`
+		// MaxEntries - Options.CacheSize, Options.DegradedCacheSize while
+		// degraded, 0 when only bytes are limited
+		MaxEntries int     `json:"max_entries,omitempty"`
+		Pinned     int     `json:"pinned"`
+		Hits       uint64  `json:"hits"`
+		Misses     uint64  `json:"misses"`
+		Evictions  uint64  `json:"evictions"`
+		HitRatio   float64 `json:"hit_ratio"`
+		// Revalidation - how cached records learn about writes of other
+		// processes, RevalidateNone or RevalidateTTL
+		Revalidation string `json:"revalidation"`
+		// Revalidations counts entries reread after CacheTTL passed
+		Revalidations uint64 `json:"revalidations"`
+	}
+
+	// Stats - database usage counters
+	Stats struct {
+		Cache      CacheStats      `json:"cache"`
+		Contention ContentionStats `json:"contention"`
+		// RecoveredPanics counts callback panics returned as errors
+		RecoveredPanics uint64 `json:"recovered_panics"`
+		// Collections - record counts by name of the collections opened
+		// with Options.MaxRecords set
+		Collections map[string]CollectionStats `json:"collections,omitempty"`
+		// Timings - percentiles by phase with Options.DetailedTimings
+		Timings map[string]PhaseTimings `json:"timings,omitempty"`
+	}
+
+	// _cache - read cache of decoded records weighted by their size
+	//
+	// Entries are keyed by record lock path and only changed by holders of
+	// the record lock, so a cached record is never older than the file as
+	// long as this process is the only writer. Other writers are only seen
+	// once an entry outlives ttl.
+	_cache struct {
+		mu        sync.Mutex
+		budget    int64
+		maxEntry  int64
+		size      int
+		ttl       time.Duration
+		bytes     int64
+		lru       *list.List
+		entries   map[string]*list.Element
+		pinned    int
+		hits      uint64
+		misses    uint64
+		evictions uint64
+		// revalidations - entries found older than ttl
+		revalidations uint64
+		// degradedSize replaces size while degraded is set
+		degradedSize int
+		degraded     bool
+	}
+
+	_cacheEntry struct {
+		path   string
+		data   []byte
+		pinned bool
+		stored time.Time
+	}
+)
+
+// newCache - a cache holding up to budget bytes and size records, or
+// degradedSize records while the database is degraded; a zero limit is
+// unbounded and nil is returned when budget and size are
+func newCache(budget int64, size int, maxEntryFraction float64, ttl time.Duration, degradedSize int) *_cache {
+	if budget <= 0 && size <= 0 {
+		return nil
+	}
+	if maxEntryFraction <= 0 || maxEntryFraction > 1 {
+		maxEntryFraction = defaultCacheMaxEntryFraction
+	}
+	if budget < 0 {
+		budget = 0
+	}
+	if size < 0 {
+		size = 0
+	}
+	return &_cache{
+		budget:   budget,
+		maxEntry: int64(float64(budget) * maxEntryFraction),
+		size:     size,
+		ttl:      ttl,
+		lru:      list.New(),
+		entries:  make(map[string]*list.Element),
+		// the limit is only enforced while degraded
+		degradedSize: degradedSize,
+	}
+}
+
+// degrade - switches to the degraded record limit and back, evicting
+// down to it right away
+func (c *_cache) degrade(on bool) {
+	if c == nil || c.degradedSize <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.degraded = on
+	c.evict()
+}
+
+// maxEntries - the record limit in force, the caller holds mu
+func (c *_cache) maxEntries() int {
+	if c.degraded && (c.size == 0 || c.degradedSize < c.size) {
+		return c.degradedSize
+	}
+	return c.size
+}
+
+// get - the cached record, a copy unless shared is set
+func (c *_cache) get(path string, shared bool) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[path]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+	entry := e.Value.(*_cacheEntry)
+	if c.ttl > 0 && time.Since(entry.stored) >= c.ttl {
+		// reread, the put of the caller refreshes pinned entries in place
+		c.revalidations++
+		c.misses++
+		if !entry.pinned {
+			c.drop(e)
+		}
+		return nil, false
+	}
+	c.hits++
+	c.lru.MoveToFront(e)
+	data := entry.data
+	if shared {
+		return data, true
+	}
+	return append([]byte(nil), data...), true
+}
+
+// put - caches a record read from disk, the caller holds the record lock
+func (c *_cache) put(path string, data []byte) {
+	if c == nil || (c.budget > 0 && int64(len(data)) > c.maxEntry) {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(path, data, false)
+	c.evict()
+}
+
+// written - refreshes pinned records and forgets others after a write,
+// the caller holds the record lock
+func (c *_cache) written(path string, data []byte) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[path]
+	if !ok {
+		return
+	}
+	if !e.Value.(*_cacheEntry).pinned {
+		c.drop(e)
+		return
+	}
+	c.set(path, data, true)
+	c.evict()
+}
+
+// remove - forgets a deleted record including its pin
+func (c *_cache) remove(path string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[path]; ok {
+		c.drop(e)
+	}
+}
+
+// removeDir - forgets every record of a dropped collection
+func (c *_cache) removeDir(dir string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	prefix := dir + string(filepath.Separator)
+	for path, e := range c.entries {
+		if strings.HasPrefix(path, prefix) {
+			c.drop(e)
+		}
+	}
+}
+
+// pin - keeps a record cached until unpinned
+func (c *_cache) pin(path string, data []byte) error {
+	if c == nil {
+		return fmt.Errorf("%w: cache disabled", ErrCacheFull)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var pinnedBytes int64
+	pinned := 1
+	for _, e := range c.entries {
+		entry := e.Value.(*_cacheEntry)
+		if entry.pinned && entry.path != path {
+			pinnedBytes += int64(len(entry.data))
+			pinned++
+		}
+	}
+	if c.budget > 0 && pinnedBytes+int64(len(data)) > c.budget {
+		return fmt.Errorf("%w: %d pinned bytes, budget %d", ErrCacheFull, pinnedBytes+int64(len(data)), c.budget)
+	}
+	if c.size > 0 && pinned > c.size {
+		return fmt.Errorf("%w: %d pinned records, size %d", ErrCacheFull, pinned, c.size)
+	}
+	c.set(path, data, true)
+	c.evict()
+	return nil
+}
+
+// unpin - makes a pinned record evictable again
+func (c *_cache) unpin(path string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[path]
+	if !ok {
+		return
+	}
+	entry := e.Value.(*_cacheEntry)
+	if entry.pinned {
+		entry.pinned = false
+		c.pinned--
+	}
+	c.evict()
+}
+
+// set - inserts or replaces an entry at the front, the caller holds mu
+func (c *_cache) set(path string, data []byte, pinned bool) {
+	data = append([]byte(nil), data...)
+	if e, ok := c.entries[path]; ok {
+		entry := e.Value.(*_cacheEntry)
+		c.bytes += int64(len(data)) - int64(len(entry.data))
+		entry.data = data
+		entry.stored = time.Now()
+		if pinned && !entry.pinned {
+			c.pinned++
+		}
+		entry.pinned = entry.pinned || pinned
+		c.lru.MoveToFront(e)
+		return
+	}
+	c.entries[path] = c.lru.PushFront(&_cacheEntry{path: path, data: data, pinned: pinned, stored: time.Now()})
+	c.bytes += int64(len(data))
+	if pinned {
+		c.pinned++
+	}
+}
+
+// evict - drops least recently used unpinned entries until within budget
+// and size
+func (c *_cache) evict() {
+	for e := c.lru.Back(); e != nil && c.over(); {
+		prev := e.Prev()
+		if !e.Value.(*_cacheEntry).pinned {
+			c.drop(e)
+			c.evictions++
+		}
+		e = prev
+	}
+}
+
+// over - whether the cache exceeds one of its limits, the caller holds mu
+func (c *_cache) over() bool {
+	size := c.maxEntries()
+	return (c.budget > 0 && c.bytes > c.budget) || (size > 0 && len(c.entries) > size)
+}
+
+// drop - removes an entry, the caller holds mu
+func (c *_cache) drop(e *list.Element) {
+	entry := c.lru.Remove(e).(*_cacheEntry)
+	delete(c.entries, entry.path)
+	c.bytes -= int64(len(entry.data))
+	if entry.pinned {
+		c.pinned--
+	}
+}
+
+// stats - a snapshot of the cache counters
+func (c *_cache) stats() (s CacheStats) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s = CacheStats{
+		Bytes:         c.bytes,
+		Budget:        c.budget,
+		Entries:       len(c.entries),
+		MaxEntries:    c.maxEntries(),
+		Pinned:        c.pinned,
+		Hits:          c.hits,
+		Misses:        c.misses,
+		Evictions:     c.evictions,
+		Revalidation:  RevalidateNone,
+		Revalidations: c.revalidations,
+	}
+	if c.ttl > 0 {
+		s.Revalidation = RevalidateTTL
+	}
+	if c.hits+c.misses > 0 {
+		s.HitRatio = float64(c.hits) / float64(c.hits+c.misses)
+	}
+	return s
+}
+
+// Stats - usage counters of the database
+func (db *_db) Stats() Stats {
+	return Stats{Cache: db.cache.stats(), Contention: contentionStats(), RecoveredPanics: db.callbacks.recoveredPanics(), Collections: db.collectionStats(), Timings: db.timings.stats()}
+}
+
+// Pin - loads a record into the cache and keeps it there until Unpin
+func (c *_collection) Pin(key string) (err error) {
+	if err = c.checkID(key); err != nil {
+		return err
+	}
+	if err = c.life.begin(); err != nil {
+		return err
+	}
+	defer c.life.end()
+	unlock := c.lock(key)
+	defer unlock()
+	filename, codec, err := c.resolve(key)
+	if err != nil {
+		return err
+	}
+	data, err := c.read(key, filename, codec, nil)
+	if err != nil {
+		return err
+	}
+	return c.cache.pin(c.lockPath(key), data)
+}
+
+// Unpin - lets a pinned record be evicted again
+func (c *_collection) Unpin(key string) {
+	c.cache.unpin(c.lockPath(key))
+}
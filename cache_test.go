@@ -0,0 +1,213 @@
+package simplejsondb_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestCacheBytes(t *testing.T) {
+	const budget = 10000
+	db, collection := dbtest.NewDB(t, &simplejsondb.Options{CacheBytes: budget, CacheMaxEntryFraction: 0.25})
+	c := collection("sized")
+	sizes := []int{100, 2400, 300, 1800, 50, 2000, 900, 2500, 10}
+	for i, size := range sizes {
+		data := []byte(`"` + string(bytes.Repeat([]byte("x"), size-2)) + `"`)
+		if err := c.Create(fmt.Sprint("r", i), data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	big := []byte(`"` + string(bytes.Repeat([]byte("b"), budget/4)) + `"`)
+	if err := c.Create("big", big); err != nil {
+		t.Fatal(err)
+	}
+
+	var gets uint64
+	for round := 0; round < 3; round++ {
+		for i := range sizes {
+			if _, err := c.Get(fmt.Sprint("r", i)); err != nil {
+				t.Fatal(err)
+			}
+			gets++
+			if s := db.Stats().Cache; s.Bytes > budget+2500 {
+				t.Error("Test failed - budget exceeded", s.Bytes)
+			}
+		}
+	}
+	s := db.Stats().Cache
+	if s.Bytes > budget || s.Budget != budget {
+		t.Error("Test failed - ", s.Bytes, s.Budget)
+	}
+	if s.Hits+s.Misses != gets || s.Misses < uint64(len(sizes)) || s.Evictions == 0 {
+		t.Error("Test failed - hit accounting", s)
+	}
+
+	// entries over the configured fraction are never cached
+	before := db.Stats().Cache
+	if data, err := c.Get("big"); err != nil || !bytes.Equal(data, big) {
+		t.Error("Test failed - ", err)
+	}
+	if _, err := c.Get("big"); err != nil {
+		t.Error("Test failed - ", err)
+	}
+	if after := db.Stats().Cache; after.Misses != before.Misses+2 {
+		t.Error("Test failed - oversized entry cached", after)
+	}
+
+	// writes are visible through the cache
+	if _, err := c.Get("r0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Create("r0", []byte(`"fresh"`)); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := c.Get("r0"); string(data) != `"fresh"` {
+		t.Error("Test failed - stale cache", string(data))
+	}
+}
+
+func TestCachePin(t *testing.T) {
+	db, collection := dbtest.NewDB(t, &simplejsondb.Options{CacheBytes: 1000})
+	c := collection("pinned")
+	record := []byte(`"` + string(bytes.Repeat([]byte("p"), 398)) + `"`)
+	for _, id := range []string{"a", "b", "c"} {
+		if err := c.Create(id, record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.Pin("a"); err != nil {
+		t.Error("Test failed - ", err)
+	}
+	if err := c.Pin("b"); err != nil {
+		t.Error("Test failed - ", err)
+	}
+	if err := c.Pin("c"); !errors.Is(err, simplejsondb.ErrCacheFull) {
+		t.Error("Test failed - pinned beyond budget", err)
+	}
+	if s := db.Stats().Cache; s.Pinned != 2 || s.Bytes != 800 {
+		t.Error("Test failed - ", s)
+	}
+
+	// pinned records are refreshed by writes rather than dropped
+	if err := c.Create("a", []byte(`"small"`)); err != nil {
+		t.Fatal(err)
+	}
+	if s := db.Stats().Cache; s.Pinned != 2 || s.Bytes != 407 {
+		t.Error("Test failed - ", s)
+	}
+	if data, _ := c.Get("a"); string(data) != `"small"` {
+		t.Error("Test failed - ", string(data))
+	}
+
+	c.Unpin("b")
+	if err := c.Pin("c"); err != nil {
+		t.Error("Test failed - ", err)
+	}
+	if err := c.Delete("a"); err != nil {
+		t.Fatal(err)
+	}
+	if s := db.Stats().Cache; s.Pinned != 1 {
+		t.Error("Test failed - delete kept the pin", s)
+	}
+}
+
+func TestCacheTTL(t *testing.T) {
+	// two instances over one directory stand in for two processes
+	dir := t.TempDir()
+	_, writer := dbtest.Open(t, dir, nil)
+	a := writer("shared")
+	dbtest.Seed(t, a, map[string][]byte{"hot": []byte(`1`), "pinned": []byte(`1`)})
+
+	const ttl = 200 * time.Millisecond
+	reader, collection := dbtest.Open(t, dir, &simplejsondb.Options{CacheBytes: 1 << 20, CacheTTL: ttl})
+	b := collection("shared")
+	if err := b.Pin("pinned"); err != nil {
+		t.Fatal(err)
+	}
+	dbtest.RequireRecord(t, b, "hot", []byte(`1`))
+
+	dbtest.Seed(t, a, map[string][]byte{"hot": []byte(`2`), "pinned": []byte(`2`)})
+	// within the TTL the cached bytes are served
+	dbtest.RequireRecord(t, b, "hot", []byte(`1`))
+	dbtest.RequireRecord(t, b, "pinned", []byte(`1`))
+
+	time.Sleep(ttl)
+	dbtest.RequireRecord(t, b, "hot", []byte(`2`))
+	dbtest.RequireRecord(t, b, "pinned", []byte(`2`))
+	s := reader.Stats().Cache
+	if s.Revalidation != simplejsondb.RevalidateTTL || s.Revalidations != 2 || s.Pinned != 1 {
+		t.Error("Test failed - ", s)
+	}
+
+	db, _ := dbtest.NewDB(t, &simplejsondb.Options{CacheBytes: 1 << 20})
+	if mode := db.Stats().Cache.Revalidation; mode != simplejsondb.RevalidateNone {
+		t.Error("Test failed - ", mode)
+	}
+}
+
+func TestCacheSize(t *testing.T) {
+	db, collection := dbtest.NewDB(t, &simplejsondb.Options{CacheSize: 3})
+	c := collection("counted")
+	dbtest.SeedN(t, c, 5)
+	for round := 0; round < 2; round++ {
+		for i := 0; i < 5; i++ {
+			if _, err := c.Get(fmt.Sprint("record", i)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	s := db.Stats().Cache
+	if s.Entries != 3 || s.MaxEntries != 3 || s.Evictions != 7 || s.Hits != 0 {
+		t.Error("Test failed - ", s)
+	}
+	for i := 2; i < 5; i++ {
+		_, _ = c.Get(fmt.Sprint("record", i))
+	}
+	if s = db.Stats().Cache; s.Hits != 3 {
+		t.Error("Test failed - ", s)
+	}
+	for i := 0; i < 3; i++ {
+		if err := c.Pin(fmt.Sprint("record", i)); err != nil {
+			t.Error("Test failed - ", err)
+		}
+	}
+	if err := c.Pin("record3"); !errors.Is(err, simplejsondb.ErrCacheFull) {
+		t.Error("Test failed - ", err)
+	}
+}
+
+func TestCacheReadYourWrites(t *testing.T) {
+	_, collection := dbtest.NewDB(t, &simplejsondb.Options{CacheSize: 8})
+	c := collection("interleaved")
+	var wg sync.WaitGroup
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			// ids are shared by pairs of goroutines so entries are also
+			// evicted and replaced under contention
+			id := fmt.Sprint("r", g%8)
+			for i := 0; i < 50; i++ {
+				want := fmt.Sprintf(`{"g": %d, "i": %d}`, g, i)
+				if err := c.Create(fmt.Sprint(id, "-", g), []byte(want)); err != nil {
+					t.Error("Test failed - ", err)
+					return
+				}
+				data, err := c.Get(fmt.Sprint(id, "-", g))
+				if err != nil || string(data) != want {
+					t.Error("Test failed - stale read", string(data), want, err)
+					return
+				}
+				_ = c.Create(id, []byte(want))
+				_, _ = c.Get(id)
+			}
+		}(g)
+	}
+	wg.Wait()
+}
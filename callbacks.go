@@ -0,0 +1,45 @@
+package simplejsondb
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// ErrCallbackPanic - a user callback panicked and RecoverCallbacks is set
+var ErrCallbackPanic = errors.New("callback panicked")
+
+// Every user callback (UpdateIf funcs, TransformAll funcs and progress,
+// generators, Redactor, OnOperation, OnVisible) runs while locks taken
+// with deferred releases are held, so a panic unwinds through the
+// releases and leaves every record lockable. guard additionally turns the
+// panic into an error when RecoverCallbacks is set.
+
+// _callbacks - how panics of user callbacks are handled
+type _callbacks struct {
+	recover   bool
+	recovered atomic.Uint64
+}
+
+// guard - runs a user callback, a panic is returned as ErrCallbackPanic
+// when recovering and propagates otherwise
+func (g *_callbacks) guard(name string, fn func() error) (err error) {
+	if g == nil || !g.recover {
+		return fn()
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			g.recovered.Add(1)
+			err = fmt.Errorf("%w: %s: %v", ErrCallbackPanic, name, r)
+		}
+	}()
+	return fn()
+}
+
+// recoveredPanics - panics turned into errors so far
+func (g *_callbacks) recoveredPanics() uint64 {
+	if g == nil {
+		return 0
+	}
+	return g.recovered.Load()
+}
@@ -0,0 +1,140 @@
+package simplejsondb_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+// callbackCases - one panicking use of every callback API, each touching
+// the record "r1"
+var callbackCases = []struct {
+	name string
+	opts func(o *simplejsondb.Options)
+	call func(dir string, c simplejsondb.Collection) error
+}{
+	{"UpdateIf condition", nil, func(_ string, c simplejsondb.Collection) error {
+		_, err := c.UpdateIf("r1", func([]byte) (bool, error) { panic("boom") }, func(b []byte) ([]byte, error) { return b, nil })
+		return err
+	}},
+	{"UpdateIf mutate", nil, func(_ string, c simplejsondb.Collection) error {
+		_, err := c.UpdateIf("r1", func([]byte) (bool, error) { return true, nil }, func([]byte) ([]byte, error) { panic("boom") })
+		return err
+	}},
+	{"TransformAll fn", nil, func(_ string, c simplejsondb.Collection) error {
+		report, err := c.TransformAll(func(string, []byte) ([]byte, bool, error) { panic("boom") }, simplejsondb.TransformOptions{Parallelism: 2, FailFast: true})
+		if err == nil && report.Failed == 0 {
+			return errors.New("no failure reported")
+		}
+		return err
+	}},
+	{"TransformAll Progress", nil, func(_ string, c simplejsondb.Collection) error {
+		_, err := c.TransformAll(func(_ string, b []byte) ([]byte, bool, error) { return append(b, ' '), false, nil },
+			simplejsondb.TransformOptions{Parallelism: 2, Progress: func(int, int) { panic("boom") }})
+		return err
+	}},
+	{"CreateAuto Generator", nil, func(_ string, c simplejsondb.Collection) error {
+		_, err := c.CreateAuto([]byte(`{}`), simplejsondb.AutoOptions{Generator: func() string { panic("boom") }})
+		return err
+	}},
+	{"OnVisible", func(o *simplejsondb.Options) {
+		o.OnVisible = func(simplejsondb.VisibleInfo) error { panic("boom") }
+	}, func(_ string, c simplejsondb.Collection) error {
+		return c.Create("r1", []byte(`{"v": 2}`))
+	}},
+	{"OnOperation", func(o *simplejsondb.Options) {
+		o.DetailedTimings = true
+		o.OnOperation = func(simplejsondb.OpTimings) { panic("boom") }
+	}, func(_ string, c simplejsondb.Collection) error {
+		_, err := c.Get("r1")
+		return err
+	}},
+	{"Redactor", func(o *simplejsondb.Options) {
+		o.Redactor = func(string, []byte) []byte { panic("boom") }
+	}, func(dir string, c simplejsondb.Collection) error {
+		if err := os.WriteFile(filepath.Join(dir, "things", "r1.json.gz"), []byte("not gzip"), 0644); err != nil {
+			return err
+		}
+		_ = os.Remove(filepath.Join(dir, "things", "r1.json"))
+		_, err := c.Get("r1")
+		if errors.Is(err, simplejsondb.ErrCorruptRecord) {
+			return nil
+		}
+		return err
+	}},
+}
+
+func openCallbacks(t *testing.T, dir string, recover bool, configure func(o *simplejsondb.Options)) (simplejsondb.DB, simplejsondb.Collection) {
+	opts := simplejsondb.Options{RecoverCallbacks: recover}
+	seed, collection := dbtest.Open(t, dir, &opts)
+	c := collection("things")
+	dbtest.Seed(t, c, map[string][]byte{"r1": []byte(`{"v": 1}`)})
+	if configure == nil {
+		return seed, c
+	}
+	configure(&opts)
+	db, collection := dbtest.Open(t, dir, &opts)
+	return db, collection("things")
+}
+
+// stillUsable - the record is lockable and readable after the panic,
+// through a handle without the panicking hooks
+func stillUsable(t *testing.T, dir string) {
+	_, collection := dbtest.Open(t, dir, nil)
+	c := collection("things")
+	done := make(chan error, 1)
+	go func() {
+		err := c.Create("r1", []byte(`{"v": 3}`))
+		if err == nil {
+			_, err = c.Get("r1")
+		}
+		done <- err
+	}()
+	if err := <-done; err != nil {
+		t.Error("Test failed - ", err)
+	}
+}
+
+func TestCallbackPanicPropagates(t *testing.T) {
+	for _, tc := range callbackCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			_, c := openCallbacks(t, dir, false, tc.opts)
+			func() {
+				defer func() {
+					if recover() == nil {
+						t.Error("Test failed - panic swallowed")
+					}
+				}()
+				_ = tc.call(dir, c)
+			}()
+			stillUsable(t, dir)
+		})
+	}
+}
+
+func TestCallbackPanicRecovered(t *testing.T) {
+	for _, tc := range callbackCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			db, c := openCallbacks(t, dir, true, tc.opts)
+			err := tc.call(dir, c)
+			hook := tc.opts != nil
+			if !hook && !errors.Is(err, simplejsondb.ErrCallbackPanic) {
+				t.Error("Test failed - ", err)
+			}
+			// hooks never fail the operation, their panic is only logged
+			if hook && err != nil {
+				t.Error("Test failed - ", err)
+			}
+			if db.Stats().RecoveredPanics == 0 {
+				t.Error("Test failed - panic not counted")
+			}
+			stillUsable(t, dir)
+		})
+	}
+}
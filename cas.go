@@ -0,0 +1,37 @@
+package simplejsondb
+
+import (
+	"bytes"
+)
+
+// CompareAndSwap - writes new only while the record holds old byte for
+// byte, a nil old requiring the record to be missing; false without an
+// error when it does not
+//
+// The comparison uses the decoded record, so gzip records compare by
+// their content, and runs under the exclusive record lock like UpdateIf.
+func (c *_collection) CompareAndSwap(key string, old, new []byte) (bool, error) {
+	if err := c.validate(key, new); err != nil {
+		return false, err
+	}
+	return compareAndSwap(c, key, old, new)
+}
+
+// CompareAndSwap - swaps the visible record, writing into the overlay
+func (c *_overlayCollection) CompareAndSwap(key string, old, new []byte) (bool, error) {
+	if err := c.upper.validate(key, new); err != nil {
+		return false, err
+	}
+	return compareAndSwap(c, key, old, new)
+}
+
+func compareAndSwap(c Collection, key string, old, new []byte) (bool, error) {
+	return c.UpdateIf(key, func(current []byte) (bool, error) {
+		if old == nil || current == nil {
+			return old == nil && current == nil, nil
+		}
+		return bytes.Equal(current, old), nil
+	}, func([]byte) ([]byte, error) {
+		return new, nil
+	})
+}
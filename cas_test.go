@@ -0,0 +1,71 @@
+package simplejsondb_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestCompareAndSwap(t *testing.T) {
+	_, collection := dbtest.NewDB(t, nil)
+	c := collection("leases")
+
+	swapped, err := c.CompareAndSwap("leader", nil, []byte(`"a"`))
+	if err != nil || !swapped {
+		t.Error("Test failed - ", swapped, err)
+	}
+	if swapped, err = c.CompareAndSwap("leader", nil, []byte(`"b"`)); err != nil || swapped {
+		t.Error("Test failed - ", swapped, err)
+	}
+	if swapped, err = c.CompareAndSwap("leader", []byte(`"x"`), []byte(`"b"`)); err != nil || swapped {
+		t.Error("Test failed - ", swapped, err)
+	}
+	if swapped, err = c.CompareAndSwap("leader", []byte(`"a"`), []byte(`"b"`)); err != nil || !swapped {
+		t.Error("Test failed - ", swapped, err)
+	}
+	dbtest.RequireRecord(t, c, "leader", []byte(`"b"`))
+
+	// an empty old is an empty record, not a missing one
+	if swapped, _ = c.CompareAndSwap("empty", []byte{}, []byte(`1`)); swapped {
+		t.Error("Test failed - missing record matched empty old")
+	}
+
+	_ = c.Create("zipped", []byte(`{"v": 1}`), simplejsondb.CreateOptions{UseGzip: true})
+	if swapped, err = c.CompareAndSwap("zipped", []byte(`{"v": 1}`), []byte(`{"v": 2}`)); err != nil || !swapped {
+		t.Error("Test failed - ", swapped, err)
+	}
+	dbtest.RequireRecord(t, c, "zipped", []byte(`{"v": 2}`))
+}
+
+func TestCompareAndSwapElection(t *testing.T) {
+	_, collection := dbtest.NewDB(t, nil)
+	c := collection("leases")
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		leaders []int
+	)
+	for n := 0; n < 20; n++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			won, err := c.CompareAndSwap("leader", nil, []byte(fmt.Sprint(n)))
+			if err != nil {
+				t.Error("Test failed - ", err)
+			}
+			if won {
+				mu.Lock()
+				leaders = append(leaders, n)
+				mu.Unlock()
+			}
+		}(n)
+	}
+	wg.Wait()
+	if len(leaders) != 1 {
+		t.Fatal("Test failed - ", leaders)
+	}
+	dbtest.RequireRecord(t, c, "leader", []byte(fmt.Sprint(leaders[0])))
+}
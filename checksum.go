@@ -0,0 +1,172 @@
+package simplejsondb
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// checksumDir - reserved directory holding a CRC-32C of every record file,
+// one file per record file name
+var checksumDir string = ".sums"
+
+const featureChecksums = "checksums"
+
+// ErrChecksumMismatch - a record file differs from its recorded checksum
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// VerifyReport - outcome of a Verify run, every list is sorted by id
+type VerifyReport struct {
+	Version int `json:"version"`
+	Checked int `json:"checked"`
+	// Unchecked - records without a checksum, written before checksums
+	// were enabled or by another process
+	Unchecked int `json:"unchecked"`
+	// Corrupt - records whose file cannot be decoded, such as truncated
+	// gzip
+	Corrupt []string `json:"corrupt,omitempty"`
+	// Mismatched - records whose file differs from its checksum
+	Mismatched []string `json:"mismatched,omitempty"`
+	// Unparseable - records decoding to invalid JSON
+	Unparseable []string `json:"unparseable,omitempty"`
+	// BOM - records starting with a UTF-8 byte order mark, as hand edited
+	// files do; low severity as reads drop it, not counted as corrupt
+	BOM []string `json:"bom,omitempty"`
+	// Quarantined - ids whose reads are refused, see QuarantineAfter
+	Quarantined []string         `json:"quarantined,omitempty"`
+	Errors      map[string]error `json:"errors,omitempty"`
+}
+
+// MarshalJSON - renders the collected errors as messages
+func (r VerifyReport) MarshalJSON() ([]byte, error) {
+	type report VerifyReport
+	return json.Marshal(struct {
+		report
+		Errors map[string]string `json:"errors,omitempty"`
+	}{report(r), errorMessages(r.Errors)})
+}
+
+// checksumPath - the checksum file of a record file
+func (c *_collection) checksumPath(filename string) string {
+	return filepath.Join(c.path, checksumDir, filepath.Base(filename))
+}
+
+// setChecksum - records the checksum of a record file written with
+// stored, the caller holds the lock
+func (c *_collection) setChecksum(filename string, stored []byte) error {
+	if !c.checksums {
+		return nil
+	}
+	sum := c.checksumPath(filename)
+	err := os.MkdirAll(filepath.Dir(sum), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	return writeAtomic(sum, []byte(strconv.FormatUint(uint64(crc32.Checksum(stored, crcTable)), 16)), 0644)
+}
+
+// removeChecksum - forgets the checksum of a record file
+func (c *_collection) removeChecksum(filename string) error {
+	if !c.checksums {
+		return nil
+	}
+	err := os.Remove(c.checksumPath(filename))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// checkSum - compares a record file read as stored with its checksum,
+// found is false when none is recorded
+func (c *_collection) checkSum(filename string, stored []byte) (found bool, err error) {
+	data, err := os.ReadFile(c.checksumPath(filename))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	want, err := strconv.ParseUint(strings.TrimSpace(string(data)), 16, 32)
+	if err != nil {
+		return true, fmt.Errorf("%w: unreadable checksum of %s", ErrChecksumMismatch, filepath.Base(filename))
+	}
+	if uint32(want) != crc32.Checksum(stored, crcTable) {
+		return true, fmt.Errorf("%w: %s", ErrChecksumMismatch, filepath.Base(filename))
+	}
+	return true, nil
+}
+
+// Verify - checks every record against its checksum, its codec and the
+// JSON syntax without modifying anything
+func (c *_collection) Verify() (report VerifyReport, err error) {
+	report.Version = ReportSchemaVersion
+	keys, err := c.keys()
+	if err != nil {
+		return report, err
+	}
+	for _, key := range keys {
+		err := c.verify(key, &report)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			if report.Errors == nil {
+				report.Errors = make(map[string]error)
+			}
+			report.Errors[key] = err
+		}
+	}
+	report.Quarantined = c.Quarantined()
+	return report, nil
+}
+
+// Verify - checks the records of the overlay, the base is verified
+// through its own database
+func (c *_overlayCollection) Verify() (VerifyReport, error) {
+	return c.upper.Verify()
+}
+
+// verify - checks one record under its read lock and files it in report,
+// other failures such as permissions are returned
+func (c *_collection) verify(key string, report *VerifyReport) error {
+	if err := c.life.begin(); err != nil {
+		return err
+	}
+	defer c.life.end()
+	defer c.rlock(key)()
+	filename, codec, err := c.resolve(key)
+	if err != nil {
+		return err
+	}
+	stored, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	report.Checked++
+	found, err := c.checkSum(filename, stored)
+	switch {
+	case errors.Is(err, ErrChecksumMismatch):
+		report.Mismatched = append(report.Mismatched, key)
+		return nil
+	case err != nil:
+		return err
+	case !found:
+		report.Unchecked++
+	}
+	content, err := codec.Decode(stored)
+	if err != nil {
+		report.Corrupt = append(report.Corrupt, key)
+		return nil
+	}
+	if bytes.HasPrefix(content, utf8BOM) {
+		report.BOM = append(report.BOM, key)
+	}
+	if !json.Valid(c.normalize(content)) {
+		report.Unparseable = append(report.Unparseable, key)
+	}
+	return nil
+}
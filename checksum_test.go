@@ -0,0 +1,89 @@
+package simplejsondb_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestVerify(t *testing.T) {
+	dir := t.TempDir()
+	_, collection := dbtest.Open(t, dir, &simplejsondb.Options{Checksums: true, VerifyChecksums: true, QuarantineAfter: 1})
+	c := collection("sensors")
+	dbtest.SeedN(t, c, 3)
+	if err := c.Create("zipped", []byte(`{"z": 1}`), simplejsondb.CreateOptions{UseGzip: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Create("garbled", []byte(`{"g": `)); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Create("edited", []byte("\xef\xbb\xbf{\"e\": 1}"), simplejsondb.CreateOptions{UseGzip: true}); err != nil {
+		t.Fatal(err)
+	}
+	path := func(name string) string { return filepath.Join(dir, "sensors", name) }
+
+	// a truncated gzip file and a record edited behind our back
+	stored, err := os.ReadFile(path("zipped.json.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(path("zipped.json.gz"), stored[:len(stored)/2], 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(path("record1.json"), []byte(`{"n": 100}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// files of another process carry no checksum
+	if err = os.WriteFile(path("foreign.json.gz"), []byte("not gzip"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = c.Get("record1"); !errors.Is(err, simplejsondb.ErrChecksumMismatch) || !errors.Is(err, simplejsondb.ErrCorruptRecord) {
+		t.Error("Test failed - ", err)
+	}
+	dbtest.RequireRecord(t, c, "record0", []byte(`{"n": 0}`))
+
+	report, err := c.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := simplejsondb.VerifyReport{
+		Version:     simplejsondb.ReportSchemaVersion,
+		Checked:     7,
+		Unchecked:   1,
+		Corrupt:     []string{"foreign"},
+		Mismatched:  []string{"record1", "zipped"},
+		Unparseable: []string{"garbled"},
+		BOM:         []string{"edited"},
+		Quarantined: []string{"record1"},
+	}
+	if !reflect.DeepEqual(report, want) {
+		t.Error("Test failed - ", report)
+	}
+
+	// a rewrite refreshes the checksum, a delete drops it
+	if err = c.Create("record1", []byte(`{"n": 1}`)); err != nil {
+		t.Fatal(err)
+	}
+	dbtest.RequireRecord(t, c, "record1", []byte(`{"n": 1}`))
+	if err = c.Delete("zipped"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(filepath.Join(dir, "sensors", ".sums", "zipped.json.gz")); !errors.Is(err, os.ErrNotExist) {
+		t.Error("Test failed - ", err)
+	}
+
+	// the collection keeps its checksums when reopened without the option
+	_, reopened := dbtest.Open(t, dir, nil)
+	if err = reopened("sensors").Create("record2", []byte(`{"n": 200}`)); err != nil {
+		t.Fatal(err)
+	}
+	if report, _ = c.Verify(); len(report.Mismatched) != 0 {
+		t.Error("Test failed - ", report)
+	}
+}
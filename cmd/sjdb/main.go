@@ -0,0 +1,375 @@
+// Command sjdb - inspects and edits a simplejsondb directory
+//
+//	sjdb ls <db> [collection]             collections, or ids of a collection
+//	sjdb get <db> <collection> <id>       the record, gzip decoded
+//	sjdb put [-gzip] <db> <collection> <id> [file]
+//	                                      writes the record from file or stdin
+//	sjdb rm <db> <collection> <id>        deletes the record
+//	sjdb verify <db> [collection]         checks records, exit status 1 on
+//	                                      any problem
+//	sjdb version                          version and capabilities of the
+//	                                      library, simplejsondb.Info
+//
+// Every command takes the flags
+//
+//	--format=json             the output format, JSON is the only one and
+//	                          the default; reports are the library structs
+//	                          with their versioned JSON shape
+//	--collection-path <dir>   a collection directory opened with
+//	                          simplejsondb.OpenCollection, replacing the
+//	                          <db> <collection> arguments: ls then lists ids
+//	                          and verify reports the one collection
+//
+// before or after the command name.
+//
+// Output is JSON on stdout. Everything goes through the library, so
+// writes are atomic renames and a live process never sees a partial
+// record; record locks only exclude other users within this process.
+//
+// put stores the record gzipped when -gzip is given, when file ends in
+// .gz (and is decoded first) or when the record is already gzipped.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+)
+
+const usage = `usage:
+  sjdb [flags] ls <db> [collection]
+  sjdb [flags] get <db> <collection> <id>
+  sjdb [flags] put [-gzip] <db> <collection> <id> [file]
+  sjdb [flags] rm <db> <collection> <id>
+  sjdb [flags] verify <db> [collection]
+  sjdb [flags] version
+flags:
+  --format=json            output format, json only
+  --collection-path <dir>  the collection directory, replacing <db> <collection>
+`
+
+// errUsage - the arguments do not match any command
+var errUsage = errors.New("invalid arguments")
+
+// _flags - the flags every command takes
+type _flags struct {
+	format         string
+	collectionPath string
+}
+
+// register - adds the flags to set, keeping values parsed before the
+// command name
+func (f *_flags) register(set *flag.FlagSet) {
+	set.StringVar(&f.format, "format", f.format, "output format, json only")
+	set.StringVar(&f.collectionPath, "collection-path", f.collectionPath, "the collection directory, replacing <db> <collection>")
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run - executes a command, the exit status is 1 on failure or a failed
+// verify and 2 on invalid arguments
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	f := &_flags{format: "json"}
+	global := flag.NewFlagSet("sjdb", flag.ContinueOnError)
+	global.SetOutput(stderr)
+	f.register(global)
+	if global.Parse(args) != nil || global.NArg() == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	cmd, args := global.Arg(0), global.Args()[1:]
+	flags := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	f.register(flags)
+	useGzip := false
+	if cmd == "put" {
+		flags.BoolVar(&useGzip, "gzip", false, "store the record gzipped")
+	}
+	err := flags.Parse(args)
+	args = flags.Args()
+	if err != nil || f.format != "json" {
+		err = errUsage
+	}
+	ok := true
+	switch {
+	case err != nil:
+	case cmd == "ls":
+		err = f.ls(args, stdout)
+	case cmd == "get":
+		err = f.get(args, stdout)
+	case cmd == "put":
+		err = f.put(args, useGzip, stdin)
+	case cmd == "rm":
+		err = f.rm(args)
+	case cmd == "verify":
+		ok, err = f.verify(args, stdout)
+	case cmd == "version":
+		err = version(args, stdout)
+	default:
+		err = errUsage
+	}
+	switch {
+	case errors.Is(err, errUsage):
+		fmt.Fprint(stderr, usage)
+		return 2
+	case err != nil:
+		fmt.Fprintln(stderr, "sjdb:", err)
+		return 1
+	case !ok:
+		return 1
+	}
+	return 0
+}
+
+// open - an existing database, never creating the directory
+func open(path string) (simplejsondb.DB, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a database directory", path)
+	}
+	return simplejsondb.New(path, &simplejsondb.Options{ValidateJSON: true})
+}
+
+// collection - an existing collection of an existing database
+func collection(path, name string) (simplejsondb.DB, simplejsondb.Collection, error) {
+	db, err := open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !db.HasCollection(name) {
+		db.Close()
+		return nil, nil, fmt.Errorf("%w: %s", simplejsondb.ErrCollectionNotFound, name)
+	}
+	c, err := db.Collection(name)
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	return db, c, nil
+}
+
+// target - the collection of the arguments or of --collection-path, which
+// takes the place of <db> <collection>, followed by the arguments left;
+// done closes what was opened. Only create makes a missing collection.
+func (f *_flags) target(args []string, create bool) (c simplejsondb.Collection, rest []string, done func(), err error) {
+	if f.collectionPath != "" {
+		dir := filepath.Clean(f.collectionPath)
+		if create {
+			// put may make the collection but not its parent
+			dir = filepath.Dir(dir)
+		}
+		if info, err := os.Stat(dir); err != nil {
+			return nil, nil, nil, err
+		} else if !info.IsDir() {
+			return nil, nil, nil, fmt.Errorf("%s is not a directory", dir)
+		}
+		// the database OpenCollection opens lives until the process exits
+		c, err = simplejsondb.OpenCollection(f.collectionPath, &simplejsondb.Options{ValidateJSON: true})
+		return c, args, func() {}, err
+	}
+	if len(args) < 2 {
+		return nil, nil, nil, errUsage
+	}
+	var db simplejsondb.DB
+	if create {
+		if db, err = open(args[0]); err == nil {
+			if c, err = db.Collection(args[1]); err != nil {
+				db.Close()
+			}
+		}
+	} else {
+		db, c, err = collection(args[0], args[1])
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return c, args[2:], func() { db.Close() }, nil
+}
+
+// collections - the sorted collection names, dot directories are internal
+func collections(path string, db simplejsondb.DB) ([]string, error) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() && !strings.HasPrefix(name, ".") && db.HasCollection(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func (f *_flags) ls(args []string, stdout io.Writer) error {
+	if f.collectionPath == "" && len(args) == 1 {
+		db, err := open(args[0])
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		names, err := collections(args[0], db)
+		if err != nil {
+			return err
+		}
+		return writeJSON(stdout, names)
+	}
+	c, args, done, err := f.target(args, false)
+	if err != nil {
+		return err
+	}
+	defer done()
+	if len(args) != 0 {
+		return errUsage
+	}
+	ids := c.Keys()
+	if ids == nil {
+		ids = []string{}
+	}
+	return writeJSON(stdout, ids)
+}
+
+func (f *_flags) get(args []string, stdout io.Writer) error {
+	c, args, done, err := f.target(args, false)
+	if err != nil {
+		return err
+	}
+	defer done()
+	if len(args) != 1 {
+		return errUsage
+	}
+	data, err := c.Get(args[0])
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 || data[len(data)-1] != '\n' {
+		data = append(data, '\n')
+	}
+	_, err = stdout.Write(data)
+	return err
+}
+
+func (f *_flags) put(args []string, useGzip bool, stdin io.Reader) error {
+	c, args, done, err := f.target(args, true)
+	if err != nil {
+		return err
+	}
+	defer done()
+	if len(args) != 1 && len(args) != 2 {
+		return errUsage
+	}
+	var data []byte
+	if len(args) == 2 && args[1] != "-" {
+		data, err = os.ReadFile(args[1])
+		if err == nil && filepath.Ext(args[1]) == ".gz" {
+			useGzip = true
+			data, err = simplejsondb.UnGzip(data)
+		}
+	} else {
+		data, err = io.ReadAll(stdin)
+	}
+	if err != nil {
+		return err
+	}
+	if info, err := c.Stat(args[0]); err == nil && info.Ext == simplejsondb.GZipExt {
+		useGzip = true
+	}
+	return c.Create(args[0], data, simplejsondb.CreateOptions{UseGzip: useGzip})
+}
+
+func (f *_flags) rm(args []string) error {
+	c, args, done, err := f.target(args, false)
+	if err != nil {
+		return err
+	}
+	defer done()
+	if len(args) != 1 {
+		return errUsage
+	}
+	return c.Delete(args[0])
+}
+
+// version - simplejsondb.Info of the library sjdb was built with
+func version(args []string, stdout io.Writer) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+	return writeJSON(stdout, simplejsondb.Info())
+}
+
+// verify - prints a report per collection, ok is false when any record is
+// corrupt, mismatched or unparseable
+func (f *_flags) verify(args []string, stdout io.Writer) (ok bool, err error) {
+	if f.collectionPath != "" {
+		c, args, done, err := f.target(args, false)
+		if err != nil {
+			return false, err
+		}
+		defer done()
+		if len(args) != 0 {
+			return false, errUsage
+		}
+		report, err := c.Verify()
+		if err != nil {
+			return false, err
+		}
+		return clean(report), writeJSON(stdout, map[string]simplejsondb.VerifyReport{c.Name(): report})
+	}
+	if len(args) != 1 && len(args) != 2 {
+		return false, errUsage
+	}
+	db, err := open(args[0])
+	if err != nil {
+		return false, err
+	}
+	defer db.Close()
+	names := args[1:]
+	if len(names) == 0 {
+		if names, err = collections(args[0], db); err != nil {
+			return false, err
+		}
+	} else if !db.HasCollection(names[0]) {
+		return false, fmt.Errorf("%w: %s", simplejsondb.ErrCollectionNotFound, names[0])
+	}
+	reports := make(map[string]simplejsondb.VerifyReport, len(names))
+	ok = true
+	for _, name := range names {
+		c, err := db.Collection(name)
+		if err != nil {
+			return false, err
+		}
+		report, err := c.Verify()
+		if err != nil {
+			return false, err
+		}
+		reports[name] = report
+		ok = ok && clean(report)
+	}
+	return ok, writeJSON(stdout, reports)
+}
+
+// clean - the report lists no corrupt, mismatched or unparseable record
+func clean(report simplejsondb.VerifyReport) bool {
+	return len(report.Corrupt)+len(report.Mismatched)+len(report.Unparseable)+len(report.Errors) == 0
+}
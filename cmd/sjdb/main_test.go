@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func sjdb(t *testing.T, stdin string, args ...string) (code int, stdout, stderr string) {
+	t.Helper()
+	var out, errOut bytes.Buffer
+	code = run(args, strings.NewReader(stdin), &out, &errOut)
+	return code, out.String(), errOut.String()
+}
+
+func TestCLI(t *testing.T) {
+	dir := t.TempDir()
+	_, collection := dbtest.Open(t, dir, nil)
+	c := collection("users")
+	dbtest.Seed(t, c, map[string][]byte{"u1": []byte(`{"a": 1}`)})
+	if err := c.Create("z1", []byte(`{"z": 1}`), simplejsondb.CreateOptions{UseGzip: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	if code, out, _ := sjdb(t, "", "ls", dir); code != 0 || out != "[\n  \"users\"\n]\n" {
+		t.Error("Test failed - ", code, out)
+	}
+	if code, out, _ := sjdb(t, "", "ls", dir, "users"); code != 0 || out != "[\n  \"u1\",\n  \"z1\"\n]\n" {
+		t.Error("Test failed - ", code, out)
+	}
+	if code, out, _ := sjdb(t, "", "get", dir, "users", "z1"); code != 0 || out != "{\"z\": 1}\n" {
+		t.Error("Test failed - ", code, out)
+	}
+
+	if code, _, errOut := sjdb(t, `{"b": 2}`, "put", dir, "users", "u2"); code != 0 {
+		t.Error("Test failed - ", code, errOut)
+	}
+	dbtest.RequireRecord(t, c, "u2", []byte(`{"b": 2}`))
+	if code, _, _ := sjdb(t, `{"z": 2}`, "put", dir, "users", "z1"); code != 0 {
+		t.Error("Test failed - ", code)
+	}
+	if info, err := c.Stat("z1"); err != nil || info.Ext != simplejsondb.GZipExt {
+		t.Error("Test failed - gzip not kept", info, err)
+	}
+	if code, _, _ := sjdb(t, `{`, "put", dir, "users", "u3"); code != 1 {
+		t.Error("Test failed - invalid JSON stored", code)
+	}
+
+	if code, _, _ := sjdb(t, "", "rm", dir, "users", "u1"); code != 0 {
+		t.Error("Test failed - ", code)
+	}
+	if code, _, errOut := sjdb(t, "", "get", dir, "users", "u1"); code != 1 || !strings.Contains(errOut, "record not found") {
+		t.Error("Test failed - ", code, errOut)
+	}
+	if code, _, _ := sjdb(t, "", "get", dir, "missing", "u1"); code != 1 || dbHas(dir, "missing") {
+		t.Error("Test failed - ", code)
+	}
+	if code, _, _ := sjdb(t, "", "get", dir); code != 2 {
+		t.Error("Test failed - ", code)
+	}
+	if code, _, _ := sjdb(t, "", "ls", filepath.Join(dir, "nope")); code != 1 {
+		t.Error("Test failed - ", code)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "nope")); err == nil {
+		t.Error("Test failed - ls created a database")
+	}
+}
+
+func dbHas(dir, name string) bool {
+	_, err := os.Stat(filepath.Join(dir, name))
+	return err == nil
+}
+
+func TestCLIPutGzipFile(t *testing.T) {
+	dir := t.TempDir()
+	_, collection := dbtest.Open(t, dir, nil)
+	c := collection("users")
+
+	var b bytes.Buffer
+	zw := gzip.NewWriter(&b)
+	zw.Write([]byte(`{"g": 1}`))
+	zw.Close()
+	file := filepath.Join(t.TempDir(), "record.json.gz")
+	if err := os.WriteFile(file, b.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if code, _, errOut := sjdb(t, "", "put", dir, "users", "g1", file); code != 0 {
+		t.Fatal("Test failed - ", code, errOut)
+	}
+	dbtest.RequireRecord(t, c, "g1", []byte(`{"g": 1}`))
+	if info, err := c.Stat("g1"); err != nil || info.Ext != simplejsondb.GZipExt {
+		t.Error("Test failed - ", info, err)
+	}
+	if code, _, _ := sjdb(t, `{}`, "put", "-gzip", dir, "users", "g2"); code != 0 {
+		t.Error("Test failed - ", code)
+	}
+	if info, err := c.Stat("g2"); err != nil || info.Ext != simplejsondb.GZipExt {
+		t.Error("Test failed - ", info, err)
+	}
+}
+
+func TestCLIVerify(t *testing.T) {
+	dir := t.TempDir()
+	_, collection := dbtest.Open(t, dir, nil)
+	dbtest.SeedN(t, collection("users"), 3)
+
+	if code, out, _ := sjdb(t, "", "verify", dir); code != 0 || !strings.Contains(out, `"users"`) {
+		t.Error("Test failed - ", code, out)
+	}
+	err := os.WriteFile(filepath.Join(dir, "users", "bad"+simplejsondb.GZipExt), []byte("not gzip"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code, out, _ := sjdb(t, "", "verify", dir, "users"); code != 1 || !strings.Contains(out, `"bad"`) {
+		t.Error("Test failed - ", code, out)
+	}
+}
+
+func TestCLIFlags(t *testing.T) {
+	dir := t.TempDir()
+	_, collection := dbtest.Open(t, dir, nil)
+	dbtest.Seed(t, collection("users"), map[string][]byte{"u1": []byte(`{"a": 1}`)})
+
+	if code, out, _ := sjdb(t, "", "--format=json", "ls", dir); code != 0 || out != "[\n  \"users\"\n]\n" {
+		t.Error("Test failed - ", code, out)
+	}
+	if code, out, _ := sjdb(t, "", "get", "--format=json", dir, "users", "u1"); code != 0 || out != "{\"a\": 1}\n" {
+		t.Error("Test failed - ", code, out)
+	}
+	if code, _, _ := sjdb(t, "", "--format=text", "ls", dir); code != 2 {
+		t.Error("Test failed - ", code)
+	}
+
+	code, out, _ := sjdb(t, "", "version")
+	var info simplejsondb.BuildInfo
+	if err := json.Unmarshal([]byte(out), &info); code != 0 || err != nil || info.Version != simplejsondb.Version {
+		t.Error("Test failed - ", code, out, err)
+	}
+	if code, _, _ := sjdb(t, "", "version", dir); code != 2 {
+		t.Error("Test failed - ", code)
+	}
+}
+
+func TestCLICollectionPath(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "files")
+	if code, _, errOut := sjdb(t, `{"a": 1}`, "--collection-path", dir, "put", "u1"); code != 0 {
+		t.Fatal("Test failed - ", code, errOut)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "u1"+simplejsondb.Ext)); err != nil {
+		t.Error("Test failed - ", err)
+	}
+	if code, out, _ := sjdb(t, `{}`, "put", "-gzip", "--collection-path="+dir, "u2"); code != 0 {
+		t.Error("Test failed - ", code, out)
+	}
+	if code, out, _ := sjdb(t, "", "--collection-path", dir, "ls"); code != 0 || out != "[\n  \"u1\",\n  \"u2\"\n]\n" {
+		t.Error("Test failed - ", code, out)
+	}
+	if code, out, _ := sjdb(t, "", "--collection-path", dir, "get", "u1"); code != 0 || out != "{\"a\": 1}\n" {
+		t.Error("Test failed - ", code, out)
+	}
+	if code, out, _ := sjdb(t, "", "--collection-path", dir, "verify"); code != 0 || !strings.Contains(out, `"files"`) {
+		t.Error("Test failed - ", code, out)
+	}
+	if code, _, _ := sjdb(t, "", "--collection-path", dir, "rm", "u1"); code != 0 {
+		t.Error("Test failed - ", code)
+	}
+	if code, _, _ := sjdb(t, "", "--collection-path", dir, "get", "u1"); code != 1 {
+		t.Error("Test failed - ", code)
+	}
+	if code, _, _ := sjdb(t, "", "--collection-path", dir, "get"); code != 2 {
+		t.Error("Test failed - ", code)
+	}
+
+	// reads never create the directory
+	missing := filepath.Join(t.TempDir(), "missing")
+	if code, _, _ := sjdb(t, "", "--collection-path", missing, "ls"); code != 1 {
+		t.Error("Test failed - ", code)
+	}
+	if _, err := os.Stat(missing); err == nil {
+		t.Error("Test failed - ls created the collection")
+	}
+}
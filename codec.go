@@ -0,0 +1,110 @@
+package simplejsondb
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Codec - encodes records into the bytes of their files
+//
+// Each codec owns a file extension, such as ".json" or ".json.gz", which
+// tells reads how to decode a file. Codecs are compared with ==, so they
+// must be comparable values.
+//
+// Only plain and gzip are built in. The zstdcodec and snappycodec
+// subpackages provide zstd and snappy under ZstdExt and SnappyExt; passed
+// as Options.Codec new records are written with them. Listing them in
+// Options.Codecs keeps collections which mix formats readable.
+type Codec interface {
+	Encode(data []byte) ([]byte, error)
+	Decode(stored []byte) ([]byte, error)
+	Ext() string
+}
+
+type (
+	_plainCodec struct{}
+	_gzipCodec  struct{}
+)
+
+var (
+	// PlainCodec - records stored as is under Ext
+	PlainCodec Codec = _plainCodec{}
+	// GzipCodec - records stored gzipped under GZipExt
+	GzipCodec Codec = _gzipCodec{}
+)
+
+func (_plainCodec) Encode(data []byte) ([]byte, error)   { return data, nil }
+func (_plainCodec) Decode(stored []byte) ([]byte, error) { return stored, nil }
+func (_plainCodec) Ext() string                          { return Ext }
+
+func (_gzipCodec) Encode(data []byte) ([]byte, error)   { return gzipBytes(data) }
+func (_gzipCodec) Decode(stored []byte) ([]byte, error) { return UnGzip(stored) }
+func (_gzipCodec) Ext() string                          { return GZipExt }
+
+// codecList - the codecs a collection reads in order, the built-in ones
+// first followed by extra and the write codec when not listed yet
+func codecList(write Codec, extra []Codec) ([]Codec, error) {
+	codecs := []Codec{PlainCodec, GzipCodec}
+	for _, c := range append(extra, write) {
+		if c == nil {
+			continue
+		}
+		known := false
+		for _, k := range codecs {
+			if k == c {
+				known = true
+				break
+			}
+			if k.Ext() == c.Ext() {
+				return nil, fmt.Errorf("%w: codecs share the extension %q", ErrIncompatibleOptions, c.Ext())
+			}
+		}
+		if !known {
+			if c.Ext() == "" {
+				return nil, fmt.Errorf("%w: codec without an extension", ErrIncompatibleOptions)
+			}
+			codecs = append(codecs, c)
+		}
+	}
+	return codecs, nil
+}
+
+// writeCodec - the codec new records are written with
+func writeCodec(opts Options) Codec {
+	switch {
+	case opts.Codec != nil:
+		return opts.Codec
+	case opts.UseGzip:
+		return GzipCodec
+	}
+	return PlainCodec
+}
+
+// reads - whether the collection decodes files of codec
+func (c *_collection) reads(codec Codec) bool {
+	for _, k := range c.codecs {
+		if k == codec {
+			return true
+		}
+	}
+	return false
+}
+
+// codecOf - splits a record file name into its key and codec
+func (c *_collection) codecOf(name string) (key string, codec Codec, ok bool) {
+	return splitExt(c.codecs, name)
+}
+
+// splitExt - the codec owning the extension of name, the longest matching
+// extension wins so .json.gz is not taken for .json
+func splitExt(codecs []Codec, name string) (key string, codec Codec, ok bool) {
+	for _, k := range codecs {
+		if strings.HasSuffix(name, k.Ext()) && (codec == nil || len(k.Ext()) > len(codec.Ext())) {
+			codec = k
+		}
+	}
+	if codec == nil {
+		return "", nil, false
+	}
+	return strings.TrimSuffix(name, codec.Ext()), codec, true
+}
@@ -0,0 +1,83 @@
+package simplejsondb_test
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+// b64Codec - a stand-in for msgpack or CBOR with an extension of its own
+type b64Codec struct{}
+
+func (b64Codec) Encode(data []byte) ([]byte, error) {
+	return []byte(base64.StdEncoding.EncodeToString(data)), nil
+}
+
+func (b64Codec) Decode(stored []byte) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(string(stored))
+}
+
+func (b64Codec) Ext() string { return ".json.b64" }
+
+func TestCodec(t *testing.T) {
+	dir := t.TempDir()
+	db, collection := dbtest.Open(t, dir, &simplejsondb.Options{Codec: b64Codec{}})
+	c := collection("mixed")
+	if err := c.Create("encoded", []byte(`{"b": 1}`)); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := os.ReadFile(filepath.Join(dir, "mixed", "encoded.json.b64"))
+	if err != nil || string(stored) != base64.StdEncoding.EncodeToString([]byte(`{"b": 1}`)) {
+		t.Fatal("Test failed - ", string(stored), err)
+	}
+	if err = c.Create("zipped", []byte(`{"z": 1}`), simplejsondb.CreateOptions{UseGzip: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(dir, "mixed", "plain.json"), []byte(`{"p": 1}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// every file decodes by its extension
+	all := c.GetAll()
+	if len(all) != 3 || string(all[0]) != `{"b": 1}` || string(all[1]) != `{"p": 1}` || string(all[2]) != `{"z": 1}` {
+		t.Error("Test failed - ", all)
+	}
+	// a rewrite keeps the format, Delete removes every variant
+	if err = c.Update("plain", func([]byte) ([]byte, error) { return []byte(`{"p": 2}`), nil }); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(filepath.Join(dir, "mixed", "plain.json")); err != nil {
+		t.Error("Test failed - ", err)
+	}
+	if err = c.Delete("encoded"); err != nil {
+		t.Fatal(err)
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "mixed", "encoded*")); len(files) != 0 {
+		t.Error("Test failed - ", files)
+	}
+
+	// per collection codecs
+	plain, err := db.Collection("plain", simplejsondb.Options{Codec: simplejsondb.PlainCodec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = plain.Create("a", []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(filepath.Join(dir, "plain", "a.json")); err != nil {
+		t.Error("Test failed - ", err)
+	}
+
+	_, err = simplejsondb.New(t.TempDir(), &simplejsondb.Options{Codecs: []simplejsondb.Codec{b64Codec{}, clashCodec{}}})
+	if !errors.Is(err, simplejsondb.ErrIncompatibleOptions) {
+		t.Error("Test failed - ", err)
+	}
+}
+
+// clashCodec - claims the extension of b64Codec
+type clashCodec struct{ b64Codec }
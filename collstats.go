@@ -0,0 +1,89 @@
+package simplejsondb
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+)
+
+// minGzipSize - bytes of a gzip header and trailer around empty content
+const minGzipSize = 18
+
+// Stats - aggregates of the collection computed in one directory walk
+//
+// Only file metadata is read: a truncated gzip file is spotted by its
+// size alone, Verify reads every record to find the rest. A record left
+// in two formats counts twice.
+func (c *_collection) Stats() (stats CollectionStats, err error) {
+	stats.MaxRecords = c.records.max()
+	err = c.walkRecords(func(name string, codec Codec, info fs.FileInfo) error {
+		size := info.Size()
+		if size == 0 || (codec == GzipCodec && size < minGzipSize) {
+			stats.Unreadable++
+			return nil
+		}
+		key, _, _ := c.codecOf(name)
+		stats.add(c.logicalKey(key), codec, info)
+		return nil
+	})
+	if err != nil {
+		return stats, err
+	}
+	stats.Quarantined = uint64(len(c.Quarantined()))
+	return stats, nil
+}
+
+// Stats - aggregates of the visible records, read through Stat
+func (c *_overlayCollection) Stats() (stats CollectionStats, err error) {
+	stats.MaxRecords = c.upper.records.max()
+	keys, err := c.keys()
+	if err != nil {
+		return stats, err
+	}
+	for _, key := range keys {
+		info, err := c.Stat(key)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			stats.Unreadable++
+			continue
+		}
+		stats.addInfo(info)
+	}
+	stats.Quarantined = uint64(len(c.Quarantined()))
+	return stats, nil
+}
+
+// add - counts a record file
+func (s *CollectionStats) add(key string, codec Codec, info fs.FileInfo) {
+	s.addInfo(RecordInfo{
+		ID:      key,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		Ext:     codec.Ext(),
+	})
+}
+
+// addInfo - counts a record
+func (s *CollectionStats) addInfo(info RecordInfo) {
+	s.Records++
+	s.Bytes += uint64(info.Size)
+	if info.Size > s.LargestBytes {
+		s.LargestID, s.LargestBytes = info.ID, info.Size
+	}
+	if s.Oldest.IsZero() || info.ModTime.Before(s.Oldest) {
+		s.Oldest = info.ModTime
+	}
+	if info.ModTime.After(s.Newest) {
+		s.Newest = info.ModTime
+	}
+	switch info.Ext {
+	case Ext:
+		s.Plain++
+	case GZipExt:
+		s.Gzip++
+	default:
+		s.Encoded++
+	}
+}
@@ -0,0 +1,49 @@
+package simplejsondb_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestCollectionStats(t *testing.T) {
+	dir := t.TempDir()
+	_, collection := dbtest.Open(t, dir, &simplejsondb.Options{MaxRecords: 100})
+	c := collection("planning")
+	dbtest.SeedN(t, c, 3)
+	big := []byte(`{"pad": "` + strings.Repeat("x", 500) + `"}`)
+	if err := c.Create("big", big); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Create("zipped", []byte(`{"z": 1}`), simplejsondb.CreateOptions{UseGzip: true}); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(filepath.Join(dir, "planning", "record0.json"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	// a truncated gzip file is spotted by its size
+	if err := os.WriteFile(filepath.Join(dir, "planning", "torn.json.gz"), []byte{0x1f, 0x8b}, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := c.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	size, _ := c.Size()
+	if stats.Records != 5 || stats.MaxRecords != 100 || stats.Bytes != size-2 || stats.Plain != 4 || stats.Gzip != 1 || stats.Unreadable != 1 {
+		t.Error("Test failed - ", stats)
+	}
+	if stats.LargestID != "big" || stats.LargestBytes != int64(len(big)) {
+		t.Error("Test failed - ", stats.LargestID, stats.LargestBytes)
+	}
+	if !stats.Oldest.Equal(old) || !stats.Newest.After(old) {
+		t.Error("Test failed - ", stats.Oldest, stats.Newest)
+	}
+}
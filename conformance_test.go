@@ -0,0 +1,31 @@
+package simplejsondb_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+// testConformance - the behavior every DB implementation must share
+func testConformance(t *testing.T, db simplejsondb.DB) {
+	c, err := db.Collection("conformance")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dbtest.Conformance(t, c)
+}
+
+func TestConformance(t *testing.T) {
+	db, _ := dbtest.NewDB(t, nil)
+	testConformance(t, db)
+}
+
+func TestConformanceOpenCollection(t *testing.T) {
+	c, err := simplejsondb.OpenCollection(filepath.Join(t.TempDir(), "conformance"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dbtest.Conformance(t, c)
+}
@@ -0,0 +1,59 @@
+package simplejsondb
+
+import (
+	"errors"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// ErrConcurrentModification - another process kept replacing or holding a
+// record while it was being written
+var ErrConcurrentModification = errors.New("concurrent modification")
+
+const (
+	// renameAttempts - tries of a rename refused by a sharing violation
+	renameAttempts = 5
+	// renameBackoff - grows linearly between rename attempts
+	renameBackoff = 10 * time.Millisecond
+)
+
+var (
+	// renameFile - replaced by tests to simulate contention
+	renameFile = replaceFile
+
+	// errSimulatedSharingViolation - counts as a sharing violation on
+	// every platform so the retry path can be tested anywhere
+	errSimulatedSharingViolation = errors.New("simulated sharing violation")
+
+	contention struct {
+		retries  atomic.Uint64
+		failures atomic.Uint64
+	}
+)
+
+// ContentionStats - renames disturbed by external writers, process wide
+type ContentionStats struct {
+	// Retries counts renames tried again with a fresh temp file
+	Retries uint64 `json:"retries"`
+	// Failures counts writes which gave up with ErrConcurrentModification
+	Failures uint64 `json:"failures"`
+}
+
+// isRenameContention - whether a write failed renaming over a record
+// another process holds
+func isRenameContention(err error) bool {
+	var le *os.LinkError
+	if !errors.As(err, &le) {
+		return false
+	}
+	return isSharingViolation(le.Err) || errors.Is(le.Err, errSimulatedSharingViolation)
+}
+
+// contentionStats - a snapshot of the contention counters
+func contentionStats() ContentionStats {
+	return ContentionStats{
+		Retries:  contention.retries.Load(),
+		Failures: contention.failures.Load(),
+	}
+}
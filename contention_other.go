@@ -0,0 +1,19 @@
+//go:build !unix
+
+package simplejsondb
+
+import (
+	"errors"
+	"syscall"
+)
+
+const (
+	errorAccessDenied     syscall.Errno = 5
+	errorSharingViolation syscall.Errno = 32
+	errorLockViolation    syscall.Errno = 33
+)
+
+// isSharingViolation - the rename target is held open by another process
+func isSharingViolation(err error) bool {
+	return errors.Is(err, errorAccessDenied) || errors.Is(err, errorSharingViolation) || errors.Is(err, errorLockViolation)
+}
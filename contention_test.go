@@ -0,0 +1,88 @@
+package simplejsondb_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestRenameContentionRetry(t *testing.T) {
+	dir := t.TempDir()
+	db, collection := dbtest.Open(t, dir, nil)
+	c := collection("contended")
+
+	refusals := 2
+	restore := simplejsondb.SetRenameFile(func(from, to string) error {
+		if refusals > 0 {
+			refusals--
+			return &os.LinkError{Op: "rename", Old: from, New: to, Err: simplejsondb.ErrSimulatedSharingViolation}
+		}
+		return os.Rename(from, to)
+	})
+	defer restore()
+
+	before := db.Stats().Contention
+	if err := c.Create("key1", []byte(`{}`)); err != nil {
+		t.Error("Test failed - transient contention not retried", err)
+	}
+	after := db.Stats().Contention
+	if after.Retries != before.Retries+2 || after.Failures != before.Failures {
+		t.Error("Test failed - ", before, after)
+	}
+
+	refusals = 100
+	err := c.Create("key2", []byte(`{}`))
+	if !errors.Is(err, simplejsondb.ErrConcurrentModification) {
+		t.Error("Test failed - ", err)
+	}
+	if db.Stats().Contention.Failures != after.Failures+1 {
+		t.Error("Test failed - failure not counted")
+	}
+	entries, _ := os.ReadDir(filepath.Join(dir, "contended"))
+	for _, e := range entries {
+		if e.Name() != "key1.json" {
+			t.Error("Test failed - left behind", e.Name())
+		}
+	}
+
+	// other rename failures are not retried
+	refusals = 0
+	restore2 := simplejsondb.SetRenameFile(func(from, to string) error {
+		return &os.LinkError{Op: "rename", Old: from, New: to, Err: os.ErrInvalid}
+	})
+	defer restore2()
+	err = c.Create("key3", []byte(`{}`))
+	if err == nil || errors.Is(err, simplejsondb.ErrConcurrentModification) {
+		t.Error("Test failed - ", err)
+	}
+}
+
+func TestRenameSharingViolationWindows(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		t.Skip("renames over open files only fail on windows")
+	}
+	dir := t.TempDir()
+	_, collection := dbtest.Open(t, dir, nil)
+	c := collection("contended")
+	if err := c.Create("key1", []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+	// an open handle without delete sharing blocks the replacing rename
+	f, err := os.Open(filepath.Join(dir, "contended", "key1.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.Create("key1", []byte(`{"v": 2}`))
+	f.Close()
+	if !errors.Is(err, simplejsondb.ErrConcurrentModification) {
+		t.Error("Test failed - ", err)
+	}
+	if err = c.Create("key1", []byte(`{"v": 2}`)); err != nil {
+		t.Error("Test failed - ", err)
+	}
+}
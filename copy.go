@@ -0,0 +1,144 @@
+package simplejsondb
+
+import (
+	"fmt"
+	"os"
+
+	"go.uber.org/zap"
+)
+
+// CopyTo - writes the record into dst under the same id
+//
+// The source stays read locked and the destination write locked for the
+// whole copy. The record must pass the ValidateJSON and MaxRecordSize
+// checks of dst. The stored file is carried over as is and only
+// recompressed when the gzip settings of the two collections differ.
+func (c *_collection) CopyTo(key string, dst Collection) error {
+	return c.copyTo(key, dst, false)
+}
+
+// MoveTo - copies the record into dst and deletes it from the collection,
+// both under exclusive locks so no reader sees it in both or neither
+func (c *_collection) MoveTo(key string, dst Collection) error {
+	return c.copyTo(key, dst, true)
+}
+
+func (c *_collection) copyTo(key string, dst Collection, move bool) (err error) {
+	if err = c.checkID(key); err != nil {
+		return err
+	}
+	if err = c.life.begin(); err != nil {
+		return err
+	}
+	defer c.life.end()
+	d, ok := dst.(*_collection)
+	if !ok {
+		return c.copyToForeign(key, dst, move)
+	}
+	if d.path == c.path {
+		return fmt.Errorf("cannot copy %s onto itself", key)
+	}
+	if err = d.checkID(key); err != nil {
+		return err
+	}
+	// collections are locked in path order so opposite copies cannot
+	// deadlock
+	lockSource := c.rlock
+	if move {
+		lockSource = c.lock
+	}
+	var unlockFirst, unlockSecond func()
+	if c.path < d.path {
+		unlockFirst, unlockSecond = lockSource(key), d.lock(key)
+	} else {
+		unlockFirst, unlockSecond = d.lock(key), lockSource(key)
+	}
+	defer unlockFirst()
+	defer unlockSecond()
+
+	filename, from, err := c.resolve(key)
+	if err != nil {
+		return err
+	}
+	if c.expiry.expired(c.lockPath(key)) {
+		return expiredError(filename)
+	}
+	stored, err := os.ReadFile(filename)
+	if err != nil {
+		c.logger.Error("unable to read the record", zap.Error(err))
+		return err
+	}
+	content, err := from.Decode(stored)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrCorruptRecord, err)
+	}
+	content = c.normalize(content)
+	// dst may be stricter than the collection the record was written to
+	if err = d.validate(key, content); err != nil {
+		return err
+	}
+
+	codec := from
+	if c.codec != d.codec || !d.reads(from) {
+		codec = d.codec
+	}
+	if codec != from {
+		stored, err = codec.Encode(content)
+		if err != nil {
+			d.logger.Error("unable to encode the record", zap.Error(err))
+			return err
+		}
+	}
+	// the record keeps its expiry, one without picks up the TTL of dst
+	expires := c.expiry.expiresOf(c.lockPath(key))
+	if expires.IsZero() {
+		expires = d.expiresAt(0)
+	}
+	err = d.store(key, content, stored, codec, expires, nil)
+	if err != nil || !move {
+		return err
+	}
+	return c.remove(key)
+}
+
+// copyToForeign - copies into a collection of another kind through its
+// public API while the source stays locked
+func (c *_collection) copyToForeign(key string, dst Collection, move bool) error {
+	lockSource := c.rlock
+	if move {
+		lockSource = c.lock
+	}
+	defer lockSource(key)()
+	filename, codec, err := c.resolve(key)
+	if err != nil {
+		return err
+	}
+	if c.expiry.expired(c.lockPath(key)) {
+		return expiredError(filename)
+	}
+	data, err := c.read(key, filename, codec, nil)
+	if err != nil {
+		return err
+	}
+	if err = dst.Create(key, data); err != nil || !move {
+		return err
+	}
+	return c.remove(key)
+}
+
+// CopyTo - writes the visible record into dst
+func (c *_overlayCollection) CopyTo(key string, dst Collection) error {
+	data, err := c.Get(key)
+	if err != nil {
+		return err
+	}
+	return dst.Create(key, data)
+}
+
+// MoveTo - writes the visible record into dst and hides it in the overlay
+func (c *_overlayCollection) MoveTo(key string, dst Collection) error {
+	if err := c.CopyTo(key, dst); err != nil {
+		return err
+	}
+	return c.Delete(key)
+}
@@ -0,0 +1,118 @@
+package simplejsondb_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestCopyTo(t *testing.T) {
+	dir, gzdir := t.TempDir(), t.TempDir()
+	_, collection := dbtest.Open(t, dir, &simplejsondb.Options{MaxNameLength: 80, HashLongIDs: true})
+	_, gzcollection := dbtest.Open(t, gzdir, &simplejsondb.Options{UseGzip: true, MaxNameLength: 80, HashLongIDs: true})
+	active, archive, zipped := collection("active"), collection("archive"), gzcollection("archive")
+
+	long := strings.Repeat("long", 30)
+	for _, key := range []string{"plain", "packed", long} {
+		err := active.Create(key, []byte(`{"id": "`+key+`"}`), simplejsondb.CreateOptions{UseGzip: key == "packed"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// same settings keep the stored format
+	for _, key := range []string{"plain", "packed", long} {
+		if err := active.CopyTo(key, archive); err != nil {
+			t.Error("Test failed - ", key, err)
+		}
+		if data, err := archive.Get(key); err != nil || string(data) != `{"id": "`+key+`"}` {
+			t.Error("Test failed - ", key, string(data), err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "archive", "packed"+simplejsondb.GZipExt)); err != nil {
+		t.Error("Test failed - format not kept", err)
+	}
+	// the hashed id stays listable under its logical name
+	if keys := archive.Keys(); len(keys) != 3 || keys[0] != long {
+		t.Error("Test failed - ", keys)
+	}
+
+	// differing settings recompress
+	if err := active.CopyTo("plain", zipped); err != nil {
+		t.Error("Test failed - ", err)
+	}
+	if _, err := os.Stat(filepath.Join(gzdir, "archive", "plain"+simplejsondb.GZipExt)); err != nil {
+		t.Error("Test failed - not recompressed", err)
+	}
+	if data, err := zipped.Get("plain"); err != nil || string(data) != `{"id": "plain"}` {
+		t.Error("Test failed - ", string(data), err)
+	}
+
+	if err := active.MoveTo("packed", zipped); err != nil {
+		t.Error("Test failed - ", err)
+	}
+	if _, err := active.Get("packed"); !errors.Is(err, os.ErrNotExist) {
+		t.Error("Test failed - source kept after move", err)
+	}
+	if _, err := zipped.Get("packed"); err != nil {
+		t.Error("Test failed - ", err)
+	}
+
+	if err := active.CopyTo("missing", archive); !errors.Is(err, os.ErrNotExist) {
+		t.Error("Test failed - ", err)
+	}
+	if err := active.CopyTo("plain", active); err == nil {
+		t.Error("Test failed - copied onto itself")
+	}
+}
+
+func TestCopyToConcurrentOpposite(t *testing.T) {
+	db, _ := dbtest.NewDB(t, nil)
+	a, _ := db.Collection("a")
+	b, _ := db.Collection("b")
+	_ = a.Create("r1", []byte(`{}`))
+	_ = b.Create("r1", []byte(`{}`))
+
+	done := make(chan bool)
+	for _, pair := range [][2]simplejsondb.Collection{{a, b}, {b, a}} {
+		go func(src, dst simplejsondb.Collection) {
+			for i := 0; i < 200; i++ {
+				if err := src.CopyTo("r1", dst); err != nil {
+					t.Error("Test failed - ", err)
+				}
+			}
+			done <- true
+		}(pair[0], pair[1])
+	}
+	<-done
+	<-done
+}
+
+// the destination checks apply even though the record skips Create
+func TestCopyToValidates(t *testing.T) {
+	_, collection := dbtest.NewDB(t, nil)
+	_, strict := dbtest.NewDB(t, &simplejsondb.Options{ValidateJSON: true, MaxRecordSize: 16})
+	src, dst := collection("loose"), strict("strict")
+	dbtest.Seed(t, src, map[string][]byte{"bad": []byte(`{`), "big": []byte(`{"big": "0123456789"}`), "ok": []byte(`{}`)})
+
+	if err := src.CopyTo("bad", dst); !errors.Is(err, simplejsondb.ErrInvalidJSON) {
+		t.Error("Test failed - ", err)
+	}
+	if err := src.MoveTo("big", dst); !errors.Is(err, simplejsondb.ErrRecordTooLarge) {
+		t.Error("Test failed - ", err)
+	}
+	if _, err := src.Get("big"); err != nil {
+		t.Error("Test failed - source removed by a refused move", err)
+	}
+	if keys := dst.Keys(); len(keys) != 0 {
+		t.Error("Test failed - ", keys)
+	}
+	if err := src.CopyTo("ok", dst); err != nil {
+		t.Error("Test failed - ", err)
+	}
+}
@@ -0,0 +1,55 @@
+package simplejsondb_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestCreateNX(t *testing.T) {
+	db, collection := dbtest.NewDB(t, nil)
+	c := collection("users")
+	if err := c.Create("zipped", []byte(`{"a":1}`), simplejsondb.CreateOptions{UseGzip: true}); err != nil {
+		t.Fatal(err)
+	}
+	// either file variant makes the id taken
+	if err := c.CreateNX("zipped", []byte(`{"a":2}`)); !errors.Is(err, simplejsondb.ErrExists) {
+		t.Error("Test failed - ", err)
+	}
+	dbtest.RequireRecord(t, c, "zipped", []byte(`{"a":1}`))
+
+	var wg sync.WaitGroup
+	var wins int32
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c, err := db.Collection("users")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			err = c.CreateNX("alice", []byte(fmt.Sprint(i)))
+			switch {
+			case err == nil:
+				atomic.AddInt32(&wins, 1)
+			case !errors.Is(err, simplejsondb.ErrExists):
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if wins != 1 {
+		t.Error("Test failed - expected exactly one registration", wins)
+	}
+
+	// Create keeps overwriting
+	if err := c.Create("alice", []byte(`"again"`)); err != nil {
+		t.Error("Test failed - ", err)
+	}
+}
@@ -0,0 +1,112 @@
+package simplejsondb
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"go.uber.org/zap"
+)
+
+// The ctx variants check ctx before touching the disk, after waiting for
+// locks and between the records of a scan. A write that has started is
+// always finished: the record is written to a temp file and renamed into
+// place, so cancellation never leaves a partial record behind.
+
+// GetCtx - Get unless ctx is done
+func (c *_collection) GetCtx(ctx context.Context, key string) (data []byte, err error) {
+	_, span := c.startSpan(ctx, SpanGet, key, false)
+	defer func() { endSpan(span, len(data), err) }()
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.get(ctx, key)
+}
+
+// CreateCtx - Create unless ctx is done before the record lock is held
+func (c *_collection) CreateCtx(ctx context.Context, key string, data []byte, options ...CreateOptions) error {
+	return c.create(ctx, key, data, false, options)
+}
+
+// CreateCtx - Create into the overlay unless ctx is done first
+func (c *_overlayCollection) CreateCtx(ctx context.Context, key string, data []byte, options ...CreateOptions) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.create(ctx, key, data, options...)
+}
+
+// DeleteCtx - Delete unless ctx is done before the record lock is held
+func (c *_collection) DeleteCtx(ctx context.Context, key string) (err error) {
+	ctx, span := c.startSpan(ctx, SpanDelete, key, false)
+	defer func() { endSpan(span, 0, err) }()
+	defer c.observeOp("delete", c.metricsStart(), &err)
+	defer func() { err = wrapNotFound(ErrRecordNotFound, key, err) }()
+	if err = c.checkID(key); err != nil {
+		return err
+	}
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+	if err = c.life.begin(); err != nil {
+		return err
+	}
+	defer c.life.end()
+	lockStart := c.metricsStart()
+	unlock := c.lock(key)
+	c.observeLockWait(lockStart)
+	defer unlock()
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+	return c.remove(key)
+}
+
+// GetAllCtx - returns all records ordered by id, stopping with ctx.Err()
+// once ctx is done
+func (c *_collection) GetAllCtx(ctx context.Context) (data [][]byte, err error) {
+	ctx, span := c.startSpan(ctx, SpanGetAll, "", false)
+	defer func() {
+		size := 0
+		for _, record := range data {
+			size += len(record)
+		}
+		endSpan(span, size, err)
+	}()
+	return getAll(ctx, c, c.logger)
+}
+
+// GetAllCtx - returns the visible records ordered by id until ctx is done
+func (c *_overlayCollection) GetAllCtx(ctx context.Context) ([][]byte, error) {
+	return getAll(ctx, c, c.logger)
+}
+
+func getAll(ctx context.Context, c _layer, logger Logger) (data [][]byte, err error) {
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+	keys, err := c.keys()
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+		record, err := c.GetCtx(ctx, key)
+		// a wrong key must not pass for an empty collection
+		if errors.Is(err, ErrRecordTooLarge) || errors.Is(err, ErrDecrypt) {
+			return nil, err
+		}
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				logger.Error("unable to read record", zap.String("id", key), zap.Error(err))
+			}
+			continue
+		}
+		data = append(data, record)
+	}
+	return data, nil
+}
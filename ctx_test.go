@@ -0,0 +1,75 @@
+package simplejsondb_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestCtxCancelled(t *testing.T) {
+	dir := t.TempDir()
+	_, collection := dbtest.Open(t, dir, nil)
+	c := collection("admin")
+	dbtest.SeedN(t, c, 3)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	records, err := c.GetAllCtx(ctx)
+	if err != nil || len(records) != 3 {
+		t.Error("Test failed - ", len(records), err)
+	}
+	if data, err := c.GetCtx(ctx, "record1"); err != nil || string(data) != `{"n": 1}` {
+		t.Error("Test failed - ", string(data), err)
+	}
+
+	cancel()
+	if _, err := c.GetAllCtx(ctx); !errors.Is(err, context.Canceled) {
+		t.Error("Test failed - ", err)
+	}
+	if _, err := c.GetCtx(ctx, "record1"); !errors.Is(err, context.Canceled) {
+		t.Error("Test failed - ", err)
+	}
+	if err := c.CreateCtx(ctx, "new", []byte(`{}`)); !errors.Is(err, context.Canceled) {
+		t.Error("Test failed - ", err)
+	}
+	if err := c.DeleteCtx(ctx, "record1"); !errors.Is(err, context.Canceled) {
+		t.Error("Test failed - ", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "admin", "new.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Error("Test failed - ", err)
+	}
+	dbtest.RequireRecord(t, c, "record1", []byte(`{"n": 1}`))
+}
+
+func TestCreateCtxCancelledWhileLocked(t *testing.T) {
+	dir := t.TempDir()
+	_, collection := dbtest.Open(t, dir, nil)
+	c := collection("admin")
+	ctx, cancel := context.WithCancel(context.Background())
+
+	locked, done := make(chan struct{}), make(chan error)
+	go func() {
+		_ = c.Update("busy", func([]byte) ([]byte, error) {
+			close(locked)
+			// holds the record lock until CreateCtx waits and is cancelled
+			time.Sleep(50 * time.Millisecond)
+			cancel()
+			return []byte(`{"by": "update"}`), nil
+		})
+	}()
+	<-locked
+	go func() { done <- c.CreateCtx(ctx, "busy", []byte(`{"by": "create"}`)) }()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Error("Test failed - ", err)
+	}
+	dbtest.RequireRecord(t, c, "busy", []byte(`{"by": "update"}`))
+
+	entries, _ := os.ReadDir(filepath.Join(dir, "admin"))
+	if len(entries) != 1 {
+		t.Error("Test failed - ", len(entries))
+	}
+}
@@ -0,0 +1,108 @@
+// Package dbtest - helpers for testing code built on simplejsondb and for
+// checking that every database implementation behaves alike
+package dbtest
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+)
+
+// Conformance - the behavior every collection must share, c must be a
+// new collection named "conformance"
+func Conformance(t testing.TB, c simplejsondb.Collection) {
+	t.Helper()
+	if c.Name() != "conformance" {
+		t.Error("Test failed - name", c.Name())
+	}
+	if len(c.GetAll()) != 0 {
+		t.Error("Test failed - new collection not empty")
+	}
+
+	_, err := c.Get("key1")
+	if !errors.Is(err, os.ErrNotExist) || !errors.Is(err, simplejsondb.ErrRecordNotFound) {
+		t.Error("Test failed - missing record", err)
+	}
+
+	err = c.Create("key1", []byte(`{"key": 1}`))
+	if err != nil {
+		t.Error("Test failed - ", err)
+	}
+	err = c.Create("key2", []byte(`{"key": 2}`), simplejsondb.CreateOptions{UseGzip: true})
+	if err != nil {
+		t.Error("Test failed - ", err)
+	}
+	data, err := c.Get("key1")
+	if err != nil || string(data) != `{"key": 1}` {
+		t.Error("Test failed - ", string(data), err)
+	}
+	data, err = c.Get("key2")
+	if err != nil || string(data) != `{"key": 2}` {
+		t.Error("Test failed - ", string(data), err)
+	}
+
+	err = c.Create("key1", []byte(`{"key": 3}`))
+	if err != nil {
+		t.Error("Test failed - ", err)
+	}
+	data, _ = c.Get("key1")
+	if string(data) != `{"key": 3}` {
+		t.Error("Test failed - overwrite", string(data))
+	}
+	if len(c.GetAll()) != 2 {
+		t.Error("Test failed - expected 2 records", len(c.GetAll()))
+	}
+	if keys := c.Keys(); len(keys) != 2 || keys[0] != "key1" || keys[1] != "key2" {
+		t.Error("Test failed - keys", keys)
+	}
+	if n, err := c.LenPrefix("key"); err != nil || n != 2 {
+		t.Error("Test failed - len", n, err)
+	}
+	if keys, err := c.KeysPrefix("key", 1, "key1"); err != nil || len(keys) != 1 || keys[0] != "key2" {
+		t.Error("Test failed - keys page", keys, err)
+	}
+	records, err := c.GetMany("key1", "key2", "key9")
+	var missing *simplejsondb.MissingError
+	if !errors.As(err, &missing) || len(records) != 2 || string(records["key2"]) != `{"key": 2}` {
+		t.Error("Test failed - get many", records, err)
+	}
+
+	err = c.Update("key2", func(current []byte) ([]byte, error) {
+		if string(current) != `{"key": 2}` {
+			return nil, errors.New("unexpected current record " + string(current))
+		}
+		return []byte(`{"key": 2, "updated": true}`), nil
+	})
+	if err != nil {
+		t.Error("Test failed - ", err)
+	}
+	data, _ = c.Get("key2")
+	if string(data) != `{"key": 2, "updated": true}` {
+		t.Error("Test failed - update", string(data))
+	}
+
+	err = c.Delete("key1")
+	if err != nil {
+		t.Error("Test failed - ", err)
+	}
+	err = c.Delete("key1")
+	if !errors.Is(err, simplejsondb.ErrRecordNotFound) {
+		t.Error("Test failed - double delete", err)
+	}
+	_, err = c.Get("key1")
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Error("Test failed - deleted record", err)
+	}
+	if len(c.GetAll()) != 1 {
+		t.Error("Test failed - expected 1 record", len(c.GetAll()))
+	}
+
+	if err = c.Truncate(); err != nil {
+		t.Error("Test failed - ", err)
+	}
+	if keys := c.Keys(); len(keys) != 0 {
+		t.Error("Test failed - truncate", keys)
+	}
+}
@@ -0,0 +1,72 @@
+package dbtest
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+)
+
+// NewDB - a database in a fresh temporary directory, closed and removed
+// by the test cleanup however the test ends, and a factory opening its
+// collections
+func NewDB(t testing.TB, opts *simplejsondb.Options) (simplejsondb.DB, func(name string) simplejsondb.Collection) {
+	t.Helper()
+	return Open(t, t.TempDir(), opts)
+}
+
+// Open - like NewDB for a given directory, mostly to reopen a database
+// with other options; the caller owns the directory
+func Open(t testing.TB, dir string, opts *simplejsondb.Options) (simplejsondb.DB, func(name string) simplejsondb.Collection) {
+	t.Helper()
+	db, err := simplejsondb.New(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return db, func(name string) simplejsondb.Collection {
+		t.Helper()
+		c, err := db.Collection(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+}
+
+// Seed - creates the records, failing the test on the first error
+func Seed(t testing.TB, c simplejsondb.Collection, records map[string][]byte) {
+	t.Helper()
+	for id, data := range records {
+		if err := c.Create(id, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// SeedN - creates n records "record0" to "record<n-1>" holding their
+// number and returns their ids in that order
+func SeedN(t testing.TB, c simplejsondb.Collection, n int) []string {
+	t.Helper()
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = fmt.Sprint("record", i)
+		if err := c.Create(ids[i], []byte(fmt.Sprintf(`{"n": %d}`, i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return ids
+}
+
+// RequireRecord - fails the test unless the record reads back as want
+func RequireRecord(t testing.TB, c simplejsondb.Collection, id string, want []byte) {
+	t.Helper()
+	data, err := c.Get(id)
+	if err != nil {
+		t.Fatalf("Test failed - %s: %v", id, err)
+	}
+	if !bytes.Equal(data, want) {
+		t.Fatalf("Test failed - %s: got %s, want %s", id, data, want)
+	}
+}
@@ -0,0 +1,78 @@
+package dbtest_test
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestNewDB(t *testing.T) {
+	var dir string
+	t.Run("fixture", func(t *testing.T) {
+		dir = t.TempDir()
+		db, collection := dbtest.Open(t, dir, nil)
+		users := collection("users")
+		if !db.HasCollection("users") {
+			t.Error("Test failed - collection not created")
+		}
+		dbtest.Seed(t, users, map[string][]byte{"a": []byte(`"a"`), "b": []byte(`"b"`)})
+		ids := dbtest.SeedN(t, users, 3)
+		if len(ids) != 3 || ids[2] != "record2" {
+			t.Error("Test failed - ", ids)
+		}
+		if keys := users.Keys(); len(keys) != 5 {
+			t.Error("Test failed - ", keys)
+		}
+		dbtest.RequireRecord(t, users, "a", []byte(`"a"`))
+		dbtest.RequireRecord(t, users, "record1", []byte(`{"n": 1}`))
+	})
+	if _, err := os.Stat(dir); !errors.Is(err, os.ErrNotExist) {
+		t.Error("Test failed - directory left behind", err)
+	}
+
+	// fresh databases do not share records
+	_, first := dbtest.NewDB(t, nil)
+	_, second := dbtest.NewDB(t, nil)
+	dbtest.SeedN(t, first("users"), 1)
+	if keys := second("users").Keys(); len(keys) != 0 {
+		t.Error("Test failed - ", keys)
+	}
+}
+
+// recorder - captures fatal failures of the helpers without stopping the
+// enclosing test
+type recorder struct {
+	testing.TB
+	failed bool
+}
+
+func (r *recorder) Fatal(...any)          { r.failed = true; panic(r) }
+func (r *recorder) Fatalf(string, ...any) { r.failed = true; panic(r) }
+
+func failsFatally(r *recorder, fn func()) (failed bool) {
+	defer func() {
+		if v := recover(); v != nil && v != r {
+			panic(v)
+		}
+		failed = r.failed
+	}()
+	fn()
+	return false
+}
+
+func TestRequireRecord(t *testing.T) {
+	_, collection := dbtest.NewDB(t, nil)
+	users := collection("users")
+	dbtest.Seed(t, users, map[string][]byte{"a": []byte(`"a"`)})
+
+	r := &recorder{TB: t}
+	if !failsFatally(r, func() { dbtest.RequireRecord(r, users, "a", []byte(`"b"`)) }) {
+		t.Error("Test failed - mismatch accepted")
+	}
+	r = &recorder{TB: t}
+	if !failsFatally(r, func() { dbtest.RequireRecord(r, users, "missing", nil) }) {
+		t.Error("Test failed - missing record accepted")
+	}
+}
@@ -0,0 +1,89 @@
+package dbtest
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+)
+
+// Dimension - an option turned on by some runs of the matrix
+type Dimension struct {
+	Name  string
+	Apply func(*simplejsondb.Options)
+}
+
+// Dimensions - every option which changes how records are stored, read or
+// written
+var Dimensions = []Dimension{
+	{"gzip", func(o *simplejsondb.Options) { o.UseGzip = true }},
+	{"cache", func(o *simplejsondb.Options) { o.CacheBytes = 1 << 20 }},
+	{"hashids", func(o *simplejsondb.Options) { o.HashLongIDs = true }},
+	{"shortnames", func(o *simplejsondb.Options) { o.MaxNameLength = 32 }},
+	{"hashedids", func(o *simplejsondb.Options) { o.IDPolicy = simplejsondb.HashedIDs }},
+	{"checksums", func(o *simplejsondb.Options) { o.Checksums, o.VerifyChecksums = true, true }},
+	{"encrypted", func(o *simplejsondb.Options) { o.EncryptionKey = []byte("0123456789abcdef0123456789abcdef") }},
+	{"serial", func(o *simplejsondb.Options) { o.SerializeWrites = true }},
+	{"compressedfirst", func(o *simplejsondb.Options) { o.ReadPreference = simplejsondb.PreferCompressed }},
+	{"indexpaths", func(o *simplejsondb.Options) { o.IndexPaths = true }},
+	{"zerocopy", func(o *simplejsondb.Options) { o.ZeroCopy = true }},
+	{"quarantine", func(o *simplejsondb.Options) { o.QuarantineAfter = 2 }},
+	{"timings", func(o *simplejsondb.Options) {
+		o.DetailedTimings = true
+		o.OnOperation = func(simplejsondb.OpTimings) {}
+	}},
+}
+
+// Combinations - every subset of dims when full is set, otherwise every
+// pair together with the single dimensions and none at all
+func Combinations(dims []Dimension, full bool) (combos [][]Dimension) {
+	if full {
+		for mask := 0; mask < 1<<len(dims); mask++ {
+			var combo []Dimension
+			for i, d := range dims {
+				if mask&(1<<i) != 0 {
+					combo = append(combo, d)
+				}
+			}
+			combos = append(combos, combo)
+		}
+		return combos
+	}
+	combos = append(combos, nil)
+	for i := range dims {
+		combos = append(combos, []Dimension{dims[i]})
+		for j := i + 1; j < len(dims); j++ {
+			combos = append(combos, []Dimension{dims[i], dims[j]})
+		}
+	}
+	return combos
+}
+
+// RunMatrix - runs fn as a subtest for each combination of dims, the full
+// product outside -short. Combinations New rejects with
+// ErrIncompatibleOptions are skipped, any other New failure fails.
+func RunMatrix(t *testing.T, dims []Dimension, fn func(*testing.T, simplejsondb.DB)) {
+	for _, combo := range Combinations(dims, !testing.Short()) {
+		opts := simplejsondb.Options{}
+		names := []string{"plain"}
+		if len(combo) > 0 {
+			names = names[:0]
+		}
+		for _, d := range combo {
+			d.Apply(&opts)
+			names = append(names, d.Name)
+		}
+		t.Run(strings.Join(names, "+"), func(t *testing.T) {
+			db, err := simplejsondb.New(filepath.Join(t.TempDir(), "db"), &opts)
+			if errors.Is(err, simplejsondb.ErrIncompatibleOptions) {
+				t.Skip(err)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			fn(t, db)
+		})
+	}
+}
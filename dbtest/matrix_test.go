@@ -0,0 +1,42 @@
+package dbtest_test
+
+import (
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestCombinations(t *testing.T) {
+	dims := dbtest.Dimensions[:4]
+	if n := len(dbtest.Combinations(dims, true)); n != 16 {
+		t.Error("Test failed - full", n)
+	}
+	pairs := dbtest.Combinations(dims, false)
+	if len(pairs) != 1+4+6 {
+		t.Error("Test failed - pairwise", len(pairs))
+	}
+	seen := make(map[string]bool)
+	for _, combo := range pairs {
+		if len(combo) == 2 {
+			seen[combo[0].Name+"+"+combo[1].Name] = true
+		}
+	}
+	for i := range dims {
+		for j := i + 1; j < len(dims); j++ {
+			if !seen[dims[i].Name+"+"+dims[j].Name] {
+				t.Error("Test failed - missing pair", dims[i].Name, dims[j].Name)
+			}
+		}
+	}
+}
+
+func TestConformanceMatrix(t *testing.T) {
+	dbtest.RunMatrix(t, dbtest.Dimensions, func(t *testing.T, db simplejsondb.DB) {
+		c, err := db.Collection("conformance")
+		if err != nil {
+			t.Fatal(err)
+		}
+		dbtest.Conformance(t, c)
+	})
+}
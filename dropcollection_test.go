@@ -0,0 +1,76 @@
+package simplejsondb_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestDropCollection(t *testing.T) {
+	dir := t.TempDir()
+	db, collection := dbtest.Open(t, dir, &simplejsondb.Options{CacheBytes: 1 << 20})
+	c := collection("doomed")
+	dbtest.Seed(t, c, map[string][]byte{"key1": []byte(`{}`)})
+	dbtest.RequireRecord(t, c, "key1", []byte(`{}`))
+
+	if err := db.DropCollection("doomed"); err != nil {
+		t.Error("Test failed - ", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "doomed")); !errors.Is(err, os.ErrNotExist) {
+		t.Error("Test failed - directory left behind", err)
+	}
+	if s := db.Stats().Cache; s.Entries != 0 {
+		t.Error("Test failed - cached records left behind", s.Entries)
+	}
+	if err := db.DropCollection("doomed"); !errors.Is(err, os.ErrNotExist) {
+		t.Error("Test failed - ", err)
+	}
+	journal := filepath.Join(dir, ".journal", "pending.journal")
+	if err := os.MkdirAll(filepath.Dir(journal), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(journal, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"", ".", "..", "../doomed", "a/b", ".journal", ".sequences", "LOCK"} {
+		if err := db.DropCollection(name); err == nil || errors.Is(err, os.ErrNotExist) {
+			t.Error("Test failed - ", name, err)
+		}
+	}
+	if _, err := os.Stat(journal); err != nil {
+		t.Error("Test failed - internal directory removed", err)
+	}
+	if _, err := os.Stat(dir); err != nil {
+		t.Error("Test failed - root removed", err)
+	}
+
+	c = collection("doomed")
+	if _, err := c.Get("key1"); !errors.Is(err, os.ErrNotExist) {
+		t.Error("Test failed - stale record after drop", err)
+	}
+}
+
+func TestHasCollection(t *testing.T) {
+	dir := t.TempDir()
+	db, collection := dbtest.Open(t, dir, nil)
+	if db.HasCollection("legacy") {
+		t.Error("Test failed - missing collection reported")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "legacy")); !errors.Is(err, os.ErrNotExist) {
+		t.Error("Test failed - probe created the directory", err)
+	}
+	collection("legacy")
+	if !db.HasCollection("legacy") {
+		t.Error("Test failed - existing collection not reported")
+	}
+	_ = os.WriteFile(filepath.Join(dir, "file"), []byte("x"), 0644)
+	for _, name := range []string{"file", "", ".", "..", "../legacy", "legacy/x", ".journal"} {
+		if db.HasCollection(name) {
+			t.Error("Test failed - ", name)
+		}
+	}
+}
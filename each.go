@@ -0,0 +1,44 @@
+package simplejsondb
+
+import (
+	"errors"
+	"os"
+)
+
+// Each - calls fn with every record in id order, reading one record at a
+// time; the first error of fn stops the iteration and is returned
+//
+// Only the id list is held in memory. No lock is held while fn runs, so
+// fn may modify or delete the current record. Records deleted before
+// their turn are skipped, other read failures stop the iteration.
+func (c *_collection) Each(fn func(id string, data []byte) error) error {
+	return each(c, c.callbacks, fn)
+}
+
+// Each - calls fn with every visible record in id order
+func (c *_overlayCollection) Each(fn func(id string, data []byte) error) error {
+	return each(c, c.upper.callbacks, fn)
+}
+
+func each(c _layer, g *_callbacks, fn func(id string, data []byte) error) error {
+	keys, err := c.keys()
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		data, err := c.Get(key)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		err = g.guard("Each", func() error {
+			return fn(key, data)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package simplejsondb_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestEach(t *testing.T) {
+	_, collection := dbtest.NewDB(t, nil)
+	c := collection("stream")
+	ids := dbtest.SeedN(t, c, 10)
+	if err := c.Create("zipped", []byte(`{"z": 1}`), simplejsondb.CreateOptions{UseGzip: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	var seen []string
+	err := c.Each(func(id string, data []byte) error {
+		seen = append(seen, id)
+		if id == "zipped" && string(data) != `{"z": 1}` {
+			t.Error("Test failed - ", string(data))
+		}
+		// deleting the current and a later record is safe
+		if id == "record1" {
+			if err := c.Delete(id); err != nil {
+				t.Error(err)
+			}
+			return c.Delete("record2")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) != len(ids) || seen[len(seen)-1] != "zipped" {
+		t.Error("Test failed - ", seen)
+	}
+	for _, id := range seen {
+		if id == "record2" {
+			t.Error("Test failed - deleted record visited")
+		}
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err = c.Each(func(id string, data []byte) error {
+		calls++
+		if calls == 3 {
+			return fmt.Errorf("at %s: %w", id, stop)
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) || calls != 3 {
+		t.Error("Test failed - ", calls, err)
+	}
+}
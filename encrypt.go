@@ -0,0 +1,99 @@
+package simplejsondb
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// EncryptedExt - appended to the extension of encrypted record files
+var EncryptedExt string = ".enc"
+
+// ErrDecrypt - a record file failed authentication with the encryption key
+var ErrDecrypt = errors.New("unable to decrypt record")
+
+type (
+	// _encryption - seals the files of every codec it wraps with AES-GCM,
+	// nil when Options.EncryptionKey is unset
+	_encryption struct {
+		aead   cipher.AEAD
+		mu     sync.Mutex
+		codecs map[Codec]*_encryptedCodec
+	}
+
+	// _encryptedCodec - encodes with inner, then seals the result behind
+	// a random nonce
+	_encryptedCodec struct {
+		inner Codec
+		aead  cipher.AEAD
+	}
+)
+
+// newEncryption - the AES-256-GCM encryption of key, nil for no key
+func newEncryption(key []byte) (*_encryption, error) {
+	if key == nil {
+		return nil, nil
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("%w: EncryptionKey must be 32 bytes, got %d", ErrIncompatibleOptions, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &_encryption{aead: aead, codecs: make(map[Codec]*_encryptedCodec)}, nil
+}
+
+// wrap - the encrypting variant of codec, always the same value for one
+// codec so variants compare equal; codec itself without encryption
+func (e *_encryption) wrap(codec Codec) Codec {
+	if e == nil {
+		return codec
+	}
+	if _, ok := codec.(*_encryptedCodec); ok {
+		return codec
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	wrapped, ok := e.codecs[codec]
+	if !ok {
+		wrapped = &_encryptedCodec{inner: codec, aead: e.aead}
+		e.codecs[codec] = wrapped
+	}
+	return wrapped
+}
+
+func (c *_encryptedCodec) Encode(data []byte) ([]byte, error) {
+	data, err := c.inner.Encode(data)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, c.aead.NonceSize(), c.aead.NonceSize()+len(data)+c.aead.Overhead())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return c.aead.Seal(nonce, nonce, data, nil), nil
+}
+
+func (c *_encryptedCodec) Decode(stored []byte) ([]byte, error) {
+	n := c.aead.NonceSize()
+	if len(stored) < n {
+		return nil, fmt.Errorf("%w: %d bytes", ErrDecrypt, len(stored))
+	}
+	data, err := c.aead.Open(nil, stored[:n], stored[n:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDecrypt, err)
+	}
+	return c.inner.Decode(data)
+}
+
+func (c *_encryptedCodec) Ext() string {
+	return c.inner.Ext() + EncryptedExt
+}
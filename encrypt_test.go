@@ -0,0 +1,61 @@
+package simplejsondb_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestEncryption(t *testing.T) {
+	dir := t.TempDir()
+	key := bytes.Repeat([]byte{7}, 32)
+	_, collection := dbtest.Open(t, dir, &simplejsondb.Options{EncryptionKey: key})
+	c := collection("pii")
+	secret := []byte(`{"ssn": "123-45-6789"}`)
+	if err := c.Create("plain", secret); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Create("zipped", secret, simplejsondb.CreateOptions{UseGzip: true}); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"plain.json.enc", "zipped.json.gz.enc"} {
+		stored, err := os.ReadFile(filepath.Join(dir, "pii", name))
+		if err != nil || bytes.Contains(stored, []byte("ssn")) {
+			t.Error("Test failed - ", name, err)
+		}
+	}
+	// records written before encryption stay readable
+	if err := os.WriteFile(filepath.Join(dir, "pii", "legacy.json"), []byte(`{"old": true}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dbtest.RequireRecord(t, c, "plain", secret)
+	dbtest.RequireRecord(t, c, "zipped", secret)
+	dbtest.RequireRecord(t, c, "legacy", []byte(`{"old": true}`))
+	if all := c.GetAll(); len(all) != 3 {
+		t.Error("Test failed - ", len(all))
+	}
+
+	// another key fails loudly instead of skipping records
+	_, other := dbtest.Open(t, dir, &simplejsondb.Options{EncryptionKey: bytes.Repeat([]byte{8}, 32)})
+	if _, err := other("pii").Get("plain"); !errors.Is(err, simplejsondb.ErrDecrypt) {
+		t.Error("Test failed - ", err)
+	}
+	err := other("pii").Each(func(string, []byte) error { return nil })
+	if !errors.Is(err, simplejsondb.ErrDecrypt) {
+		t.Error("Test failed - ", err)
+	}
+	if all, err := other("pii").GetAllCtx(context.Background()); !errors.Is(err, simplejsondb.ErrDecrypt) || all != nil {
+		t.Error("Test failed - ", len(all), err)
+	}
+
+	_, err = simplejsondb.New(t.TempDir(), &simplejsondb.Options{EncryptionKey: key[:16]})
+	if !errors.Is(err, simplejsondb.ErrIncompatibleOptions) {
+		t.Error("Test failed - ", err)
+	}
+}
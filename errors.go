@@ -0,0 +1,27 @@
+package simplejsondb
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+)
+
+// Get, Delete, Create and Collection wrap their failures with these
+// sentinels or with ErrExists and ErrCorruptRecord, so callers match them
+// with errors.Is whichever file variant failed. A missing record or
+// collection still matches os.ErrNotExist through errors.Is, os.IsNotExist
+// no longer sees through the wrapping.
+var (
+	// ErrRecordNotFound - the record does not exist or has expired
+	ErrRecordNotFound = errors.New("record not found")
+	// ErrCollectionNotFound - the collection directory does not exist
+	ErrCollectionNotFound = errors.New("collection not found")
+)
+
+// wrapNotFound - err wrapped with sentinel when a file of name is missing
+func wrapNotFound(sentinel error, name string, err error) error {
+	if err == nil || !errors.Is(err, fs.ErrNotExist) || errors.Is(err, sentinel) {
+		return err
+	}
+	return fmt.Errorf("%w: %s: %w", sentinel, name, err)
+}
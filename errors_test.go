@@ -0,0 +1,96 @@
+package simplejsondb_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestErrRecordNotFound(t *testing.T) {
+	_, collection := dbtest.NewDB(t, nil)
+	c := collection("users")
+	dbtest.Seed(t, c, map[string][]byte{"gone": []byte(`{}`)})
+	if err := c.Create("zipped", []byte(`{}`), simplejsondb.CreateOptions{UseGzip: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Create("short", []byte(`{}`), simplejsondb.CreateOptions{TTL: time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	for _, key := range []string{"zipped", "gone"} {
+		if err := c.Delete(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, key := range []string{"missing", "zipped", "gone", "short"} {
+		_, err := c.Get(key)
+		if !errors.Is(err, simplejsondb.ErrRecordNotFound) || !errors.Is(err, os.ErrNotExist) {
+			t.Error("Test failed - ", key, err)
+		}
+	}
+	err := c.Delete("missing")
+	if !errors.Is(err, simplejsondb.ErrRecordNotFound) || !errors.Is(err, os.ErrNotExist) {
+		t.Error("Test failed - ", err)
+	}
+	if _, err = c.Get("bad/id"); errors.Is(err, simplejsondb.ErrRecordNotFound) {
+		t.Error("Test failed - invalid id reported missing", err)
+	}
+}
+
+func TestErrCollectionNotFound(t *testing.T) {
+	dir := t.TempDir()
+	db, _ := dbtest.Open(t, dir, nil)
+	c, err := db.Collection("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.DropCollection("users"); err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.Create("u1", []byte(`{}`))
+	if !errors.Is(err, simplejsondb.ErrCollectionNotFound) || !errors.Is(err, os.ErrNotExist) {
+		t.Error("Test failed - ", err)
+	}
+	err = db.DropCollection("users")
+	if !errors.Is(err, simplejsondb.ErrCollectionNotFound) || !errors.Is(err, os.ErrNotExist) {
+		t.Error("Test failed - ", err)
+	}
+
+	if err = os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Collection("orders")
+	if !errors.Is(err, simplejsondb.ErrCollectionNotFound) || !errors.Is(err, os.ErrNotExist) {
+		t.Error("Test failed - ", err)
+	}
+	if _, err = os.Stat(filepath.Join(dir, "orders")); !errors.Is(err, os.ErrNotExist) {
+		t.Error("Test failed - ", err)
+	}
+}
+
+func TestErrExistsAndCorrupt(t *testing.T) {
+	dir := t.TempDir()
+	db, _ := dbtest.Open(t, dir, nil)
+	c, err := db.Collection("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dbtest.Seed(t, c, map[string][]byte{"u1": []byte(`{}`)})
+	if err = c.CreateNX("u1", []byte(`{}`)); !errors.Is(err, simplejsondb.ErrExists) {
+		t.Error("Test failed - ", err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "users", "bad"+simplejsondb.GZipExt), []byte("not gzip"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.Get("bad"); !errors.Is(err, simplejsondb.ErrCorruptRecord) || errors.Is(err, simplejsondb.ErrRecordNotFound) {
+		t.Error("Test failed - ", err)
+	}
+}
@@ -0,0 +1,84 @@
+package simplejsondb
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// ErrVersionMismatch - the record changed since its version was read
+var ErrVersionMismatch = errors.New("record version mismatch")
+
+// RecordVersion - an opaque version of a record's content, usable as a
+// strong ETag; the zero value stands for a missing record
+//
+// It is derived from the decoded content, so it survives format changes
+// such as Recompress and rewrites with identical content keep it.
+type RecordVersion string
+
+// versionOf - the version of a record, nil for a missing one
+func versionOf(data []byte) RecordVersion {
+	if data == nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return RecordVersion(`"` + hex.EncodeToString(sum[:16]) + `"`)
+}
+
+// GetWithVersion - the record together with its version
+func (c *_collection) GetWithVersion(key string) ([]byte, RecordVersion, error) {
+	return getWithVersion(c, key)
+}
+
+// GetWithVersion - the visible record together with its version
+func (c *_overlayCollection) GetWithVersion(key string) ([]byte, RecordVersion, error) {
+	return getWithVersion(c, key)
+}
+
+// CreateIfVersion - writes the record only while its version is still
+// expected, the zero version only creates a missing record; otherwise
+// ErrVersionMismatch
+//
+// The version is checked and the record written under the exclusive
+// record lock, like UpdateIf, keeping the stored format.
+func (c *_collection) CreateIfVersion(key string, data []byte, expected RecordVersion) error {
+	if err := c.validate(key, data); err != nil {
+		return err
+	}
+	return createIfVersion(c, key, data, expected)
+}
+
+// CreateIfVersion - writes the record into the overlay while the visible
+// version is still expected
+func (c *_overlayCollection) CreateIfVersion(key string, data []byte, expected RecordVersion) error {
+	if err := c.upper.validate(key, data); err != nil {
+		return err
+	}
+	return createIfVersion(c, key, data, expected)
+}
+
+func getWithVersion(c Collection, key string) ([]byte, RecordVersion, error) {
+	data, err := c.Get(key)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, versionOf(data), nil
+}
+
+func createIfVersion(c Collection, key string, data []byte, expected RecordVersion) error {
+	var found RecordVersion
+	applied, err := c.UpdateIf(key, func(current []byte) (bool, error) {
+		found = versionOf(current)
+		return found == expected, nil
+	}, func([]byte) ([]byte, error) {
+		return data, nil
+	})
+	if err != nil {
+		return err
+	}
+	if !applied {
+		return fmt.Errorf("%w: %s is at %s, expected %s", ErrVersionMismatch, key, found, expected)
+	}
+	return nil
+}
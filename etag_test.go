@@ -0,0 +1,78 @@
+package simplejsondb_test
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestCreateIfVersion(t *testing.T) {
+	_, collection := dbtest.NewDB(t, nil)
+	c := collection("accounts")
+
+	if err := c.CreateIfVersion("acc", []byte(`{"n": 0}`), ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.CreateIfVersion("acc", []byte(`{"n": 9}`), ""); !errors.Is(err, simplejsondb.ErrVersionMismatch) {
+		t.Error("Test failed - ", err)
+	}
+	_, first, err := c.GetWithVersion("acc")
+	_, second, _ := c.GetWithVersion("acc")
+	if err != nil || first == "" || first != second {
+		t.Error("Test failed - ", first, second, err)
+	}
+	if err = c.CreateIfVersion("acc", []byte(`{"n": 1}`), first); err != nil {
+		t.Error("Test failed - ", err)
+	}
+	if err = c.CreateIfVersion("acc", []byte(`{"n": 2}`), second); !errors.Is(err, simplejsondb.ErrVersionMismatch) {
+		t.Error("Test failed - lost update", err)
+	}
+	dbtest.RequireRecord(t, c, "acc", []byte(`{"n": 1}`))
+
+	// gzip records are versioned by their content
+	_ = c.Create("zipped", []byte(`{"n": 1}`), simplejsondb.CreateOptions{UseGzip: true})
+	if _, v, _ := c.GetWithVersion("zipped"); v == "" {
+		t.Error("Test failed - ", v)
+	} else if _, acc, _ := c.GetWithVersion("acc"); acc != v {
+		t.Error("Test failed - ", acc, v)
+	}
+}
+
+func TestCreateIfVersionConcurrent(t *testing.T) {
+	_, collection := dbtest.NewDB(t, nil)
+	c := collection("counters")
+	_ = c.Create("hits", []byte(`{"n": 0}`))
+
+	const writers = 20
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				data, version, err := c.GetWithVersion("hits")
+				if err != nil {
+					t.Error("Test failed - ", err)
+					return
+				}
+				var v struct{ N int }
+				_ = json.Unmarshal(data, &v)
+				next, _ := json.Marshal(map[string]int{"n": v.N + 1})
+				err = c.CreateIfVersion("hits", next, version)
+				if err == nil {
+					return
+				}
+				if !errors.Is(err, simplejsondb.ErrVersionMismatch) {
+					t.Error("Test failed - ", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	dbtest.RequireRecord(t, c, "hits", []byte(`{"n":20}`))
+}
@@ -0,0 +1,45 @@
+package simplejsondb
+
+import "os"
+
+// SetWriteFile - swaps the file writer used by Create, returns a restore func
+func SetWriteFile(fn func(string, []byte, os.FileMode) error) func() {
+	prev := writeFile
+	writeFile = func(name string, data []byte, perm os.FileMode, _ *OpTimings) error {
+		return fn(name, data, perm)
+	}
+	return func() { writeFile = prev }
+}
+
+// SetWaitForHook - installs a func run inside the WaitFor race window
+func SetWaitForHook(fn func()) func() {
+	prev := waitForHook
+	waitForHook = fn
+	return func() { waitForHook = prev }
+}
+
+// ErrSimulatedSharingViolation - a rename error retried on every platform
+var ErrSimulatedSharingViolation = errSimulatedSharingViolation
+
+// SetRenameFile - swaps the rename used by atomic writes, returns a
+// restore func
+func SetRenameFile(fn func(string, string) error) func() {
+	prev := renameFile
+	renameFile = fn
+	return func() { renameFile = prev }
+}
+
+// LockCount - record and collection locks currently registered
+func LockCount() int {
+	locksMu.Lock()
+	defer locksMu.Unlock()
+	return len(locks)
+}
+
+// SetSyncHook - installs a func seeing every path synced, returns a
+// restore func
+func SetSyncHook(fn func(path string)) func() {
+	prev := syncHook
+	syncHook = fn
+	return func() { syncHook = prev }
+}
@@ -0,0 +1,78 @@
+// Package expvarmetrics - a simplejsondb.Metrics publishing operation
+// counters and latency histograms through expvar
+//
+// Every value is an expvar.Int in one expvar.Map, keyed by collection and
+// operation:
+//
+//	users.get.count       operations
+//	users.get.errors      failed operations
+//	users.get.nanos       total latency
+//	users.get.le_10ms     operations taking at most 10ms and more than the
+//	                      previous bucket, le_inf for the slowest
+//
+// Lock waits use the operation name lock_wait.
+package expvarmetrics
+
+import (
+	"expvar"
+	"time"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+)
+
+// Buckets - upper bounds of the latency histogram buckets
+var Buckets = []time.Duration{
+	100 * time.Microsecond,
+	time.Millisecond,
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+}
+
+// Metrics - simplejsondb.Metrics backed by an expvar.Map
+type Metrics struct {
+	m *expvar.Map
+}
+
+var _ simplejsondb.Metrics = (*Metrics)(nil)
+
+// New - Metrics published under name, which like every expvar name must
+// be unique in the process
+func New(name string) *Metrics {
+	return &Metrics{m: expvar.NewMap(name)}
+}
+
+// Map - the published values
+func (m *Metrics) Map() *expvar.Map {
+	return m.m
+}
+
+// ObserveOp - counts the operation, its failure and its latency
+func (m *Metrics) ObserveOp(collection, op string, dur time.Duration, err error) {
+	prefix := collection + "." + op + "."
+	m.observe(prefix, dur)
+	if err != nil {
+		m.m.Add(prefix+"errors", 1)
+	}
+}
+
+// ObserveLockWait - counts the wait and its length
+func (m *Metrics) ObserveLockWait(collection string, wait time.Duration) {
+	m.observe(collection+".lock_wait.", wait)
+}
+
+func (m *Metrics) observe(prefix string, dur time.Duration) {
+	m.m.Add(prefix+"count", 1)
+	m.m.Add(prefix+"nanos", int64(dur))
+	m.m.Add(prefix+bucket(dur), 1)
+}
+
+// bucket - the histogram key of dur
+func bucket(dur time.Duration) string {
+	for _, b := range Buckets {
+		if dur <= b {
+			return "le_" + b.String()
+		}
+	}
+	return "le_inf"
+}
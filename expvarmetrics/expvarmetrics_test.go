@@ -0,0 +1,69 @@
+package expvarmetrics_test
+
+import (
+	"errors"
+	"expvar"
+	"os"
+	"testing"
+	"time"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+	"github.com/pnkj-kmr/simple-json-db/expvarmetrics"
+)
+
+func value(t *testing.T, m *expvar.Map, key string) int64 {
+	t.Helper()
+	v, ok := m.Get(key).(*expvar.Int)
+	if !ok {
+		return 0
+	}
+	return v.Value()
+}
+
+func TestMetrics(t *testing.T) {
+	m := expvarmetrics.New("simplejsondb_test")
+	_, collection := dbtest.NewDB(t, &simplejsondb.Options{Metrics: m})
+	c := collection("users")
+
+	if err := c.Create("u1", []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get("u1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get("missing"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatal(err)
+	}
+
+	vars := m.Map()
+	if expvar.Get("simplejsondb_test") != vars {
+		t.Error("Test failed - not published")
+	}
+	for key, want := range map[string]int64{
+		"users.create.count":    1,
+		"users.create.errors":   0,
+		"users.get.count":       2,
+		"users.get.errors":      1,
+		"users.lock_wait.count": 3,
+	} {
+		if got := value(t, vars, key); got != want {
+			t.Error("Test failed - ", key, got, want)
+		}
+	}
+	var buckets int64
+	for _, b := range expvarmetrics.Buckets {
+		buckets += value(t, vars, "users.get.le_"+b.String())
+	}
+	if buckets+value(t, vars, "users.get.le_inf") != 2 {
+		t.Error("Test failed - histogram", buckets)
+	}
+	if value(t, vars, "users.get.nanos") <= 0 {
+		t.Error("Test failed - latency not recorded")
+	}
+
+	m.ObserveOp("slow", "get", 2*time.Second, nil)
+	if value(t, vars, "slow.get.le_inf") != 1 {
+		t.Error("Test failed - ", vars.String())
+	}
+}
@@ -0,0 +1,89 @@
+package simplejsondb
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// maxIndexedPaths - paths remembered per collection before starting over
+const maxIndexedPaths = 1 << 16
+
+type (
+	// _recordPaths - the lock path and plain file name of a record
+	_recordPaths struct {
+		lock  string
+		plain string
+	}
+
+	// _pathIndex - remembered record paths of a collection, shared by its
+	// handles when Options.IndexPaths is set
+	_pathIndex struct {
+		mu    sync.RWMutex
+		paths map[string]_recordPaths
+	}
+)
+
+// paths - the paths of a record, from the index when enabled and
+// otherwise from a single allocation when the id needs no cleaning or
+// hashing, always matching lockPath and getFullPath
+func (c *_collection) paths(key string) _recordPaths {
+	if c.pathIndex != nil {
+		c.pathIndex.mu.RLock()
+		p, ok := c.pathIndex.paths[key]
+		c.pathIndex.mu.RUnlock()
+		if ok {
+			return p
+		}
+	}
+	p := c.joinPaths(key)
+	if c.pathIndex != nil {
+		c.pathIndex.mu.Lock()
+		if len(c.pathIndex.paths) >= maxIndexedPaths {
+			c.pathIndex.paths = make(map[string]_recordPaths)
+		}
+		c.pathIndex.paths[key] = p
+		c.pathIndex.mu.Unlock()
+	}
+	return p
+}
+
+func (c *_collection) joinPaths(key string) _recordPaths {
+	sep := string(filepath.Separator)
+	if key == "" || key == "." || key == ".." || strings.ContainsAny(key, `/\`) ||
+		c.path == "" || c.path == "." || strings.HasSuffix(c.path, sep) ||
+		c.fileKey(key) != key {
+		return _recordPaths{lock: c.lockPath(key), plain: c.getFullPath(key, PlainCodec)}
+	}
+	full := c.path + sep + key + Ext
+	return _recordPaths{lock: full[:len(full)-len(Ext)], plain: full}
+}
+
+// pathIndex - the shared path index of a collection directory
+func (db *_db) pathIndex(dir string) *_pathIndex {
+	if !db.indexPaths {
+		return nil
+	}
+	db.indexMu.Lock()
+	defer db.indexMu.Unlock()
+	index, ok := db.indexes[dir]
+	if !ok {
+		index = &_pathIndex{paths: make(map[string]_recordPaths)}
+		db.indexes[dir] = index
+	}
+	return index
+}
+
+// readPlain - opens the plain record directly, saving the stat of a
+// separate resolve; ok is false when the caller has to resolve instead
+func (c *_collection) readPlain(filename string, tm *OpTimings) (data []byte, ok bool, err error) {
+	start := tm.begin()
+	data, ok, err = readPlainFile(filename)
+	tm.end(phaseRead, start)
+	if err != nil {
+		c.logger.Error("unable to read the record", zap.Error(err))
+	}
+	return c.normalize(data), ok, err
+}
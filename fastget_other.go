@@ -0,0 +1,28 @@
+//go:build !unix
+
+package simplejsondb
+
+import (
+	"io"
+	"os"
+)
+
+// readPlainFile - reads a whole file sized from a stat of the open file
+func readPlainFile(filename string) (data []byte, ok bool, err error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, false, nil
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		return nil, false, nil
+	}
+	// records are replaced by rename, so the size of an open file is final
+	data = make([]byte, info.Size())
+	_, err = io.ReadFull(f, data)
+	if err != nil {
+		return nil, true, err
+	}
+	return data, true, nil
+}
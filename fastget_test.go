@@ -0,0 +1,95 @@
+package simplejsondb_test
+
+import (
+	"runtime"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func smallRecord(t testing.TB, opts *simplejsondb.Options) simplejsondb.Collection {
+	_, collection := dbtest.NewDB(t, opts)
+	c := collection("small")
+	dbtest.Seed(t, c, map[string][]byte{"key1": []byte(`{"small": true}`)})
+	return c
+}
+
+func TestGetAllocs(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the allocation free read path is unix only")
+	}
+	c := smallRecord(t, &simplejsondb.Options{IndexPaths: true})
+	if n := testing.AllocsPerRun(100, func() { _, _ = c.Get("key1") }); n > 2 {
+		t.Error("Test failed - allocations per Get", n)
+	}
+	c = smallRecord(t, &simplejsondb.Options{IndexPaths: true, CacheBytes: 1 << 20, ZeroCopy: true})
+	if n := testing.AllocsPerRun(100, func() { _, _ = c.Get("key1") }); n > 0 {
+		t.Error("Test failed - allocations per zero copy Get", n)
+	}
+}
+
+func TestGetAliasing(t *testing.T) {
+	for _, opts := range []*simplejsondb.Options{nil, {IndexPaths: true, CacheBytes: 1 << 20}} {
+		c := smallRecord(t, opts)
+		a, _ := c.Get("key1")
+		b, _ := c.Get("key1")
+		a[0] = 'X'
+		if b[0] != '{' {
+			t.Error("Test failed - results share memory")
+		}
+		if data, _ := c.Get("key1"); string(data) != `{"small": true}` {
+			t.Error("Test failed - caller modified stored record", string(data))
+		}
+	}
+
+	c := smallRecord(t, &simplejsondb.Options{CacheBytes: 1 << 20, ZeroCopy: true})
+	_, _ = c.Get("key1")
+	a, _ := c.Get("key1")
+	b, _ := c.Get("key1")
+	if &a[0] != &b[0] {
+		t.Error("Test failed - zero copy hit was copied")
+	}
+	if err := c.Create("key1", []byte(`{"small": false}`)); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := c.Get("key1"); string(data) != `{"small": false}` {
+		t.Error("Test failed - ", string(data))
+	}
+	if string(a) != `{"small": true}` {
+		t.Error("Test failed - shared result changed by a write", string(a))
+	}
+}
+
+func BenchmarkGet(b *testing.B) {
+	c := smallRecord(b, nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := c.Get("key1"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetIndexed(b *testing.B) {
+	c := smallRecord(b, &simplejsondb.Options{IndexPaths: true})
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := c.Get("key1"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetZeroCopy(b *testing.B) {
+	c := smallRecord(b, &simplejsondb.Options{IndexPaths: true, CacheBytes: 1 << 20, ZeroCopy: true})
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := c.Get("key1"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
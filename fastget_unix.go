@@ -0,0 +1,55 @@
+//go:build unix
+
+package simplejsondb
+
+import (
+	"sync"
+	"syscall"
+)
+
+// maxPooledRead - larger read buffers are left to the garbage collector
+const maxPooledRead = 1 << 20
+
+var readBuffers = sync.Pool{New: func() any {
+	b := make([]byte, 0, 4096)
+	return &b
+}}
+
+// readPlainFile - reads a whole file through a pooled buffer, the only
+// allocations are the path conversion and the returned copy
+func readPlainFile(filename string) (data []byte, ok bool, err error) {
+	fd, err := syscall.Open(filename, syscall.O_RDONLY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, false, nil
+	}
+	defer syscall.Close(fd)
+
+	bp := readBuffers.Get().(*[]byte)
+	buf := (*bp)[:0]
+	for {
+		if len(buf) == cap(buf) {
+			buf = append(buf, 0)[:len(buf)]
+		}
+		n, err := syscall.Read(fd, buf[len(buf):cap(buf)])
+		if err == syscall.EINTR {
+			continue
+		}
+		if err == syscall.EISDIR {
+			return nil, false, nil
+		}
+		if err != nil {
+			return nil, true, err
+		}
+		if n == 0 {
+			break
+		}
+		buf = buf[:len(buf)+n]
+	}
+	data = make([]byte, len(buf))
+	copy(data, buf)
+	if cap(buf) <= maxPooledRead {
+		*bp = buf
+		readBuffers.Put(bp)
+	}
+	return data, true, nil
+}
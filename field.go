@@ -0,0 +1,163 @@
+package simplejsondb
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrFieldNotFound - the JSON pointer of GetField matches no value
+var ErrFieldNotFound = errors.New("field not found")
+
+// FieldError - the JSON pointer which matched no value of a record
+type FieldError struct {
+	ID      string
+	Pointer string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %q in %s", ErrFieldNotFound, e.Pointer, e.ID)
+}
+
+// Unwrap - lets errors.Is match ErrFieldNotFound
+func (e *FieldError) Unwrap() error {
+	return ErrFieldNotFound
+}
+
+// GetField - the raw JSON value of a record at an RFC 6901 pointer such
+// as /metrics/cpu, the empty pointer selects the whole record
+//
+// The record is walked token by token, values before the selected one are
+// skipped without being decoded into maps.
+func (c *_collection) GetField(key, pointer string) ([]byte, error) {
+	return getField(c, key, pointer)
+}
+
+// GetField - the value at pointer of the visible record
+func (c *_overlayCollection) GetField(key, pointer string) ([]byte, error) {
+	return getField(c, key, pointer)
+}
+
+func getField(c Collection, key, pointer string) ([]byte, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	data, err := c.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	value, found, err := walkPointer(data, tokens)
+	if err != nil {
+		return nil, fmt.Errorf("record %s is not JSON: %w", key, err)
+	}
+	if !found {
+		return nil, &FieldError{ID: key, Pointer: pointer}
+	}
+	return value, nil
+}
+
+// parsePointer - the unescaped reference tokens of a JSON pointer
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if pointer[0] != '/' {
+		return nil, fmt.Errorf("invalid JSON pointer %q: must start with /", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		// ~1 first so ~01 becomes ~1 rather than /
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// walkPointer - descends through tokens, found is false when a member or
+// index is missing or a scalar is in the way
+func walkPointer(data []byte, tokens []string) (value []byte, found bool, err error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	for _, token := range tokens {
+		t, err := d.Token()
+		if err != nil {
+			return nil, false, err
+		}
+		switch t {
+		case json.Delim('{'):
+			found, err = seekMember(d, token)
+		case json.Delim('['):
+			found, err = seekIndex(d, token)
+		default:
+			return nil, false, nil
+		}
+		if err != nil || !found {
+			return nil, false, err
+		}
+	}
+	var raw json.RawMessage
+	if err = d.Decode(&raw); err != nil {
+		return nil, false, err
+	}
+	return raw, true, nil
+}
+
+// seekMember - advances an object to the value of name
+func seekMember(d *json.Decoder, name string) (bool, error) {
+	for d.More() {
+		t, err := d.Token()
+		if err != nil {
+			return false, err
+		}
+		if t == name {
+			return true, nil
+		}
+		if err = skipValue(d); err != nil {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// seekIndex - advances an array to its element at index token
+func seekIndex(d *json.Decoder, token string) (bool, error) {
+	// leading zeros and "-" (past the end) never match an element
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return false, nil
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 {
+		return false, nil
+	}
+	for i := 0; d.More(); i++ {
+		if i == index {
+			return true, nil
+		}
+		if err = skipValue(d); err != nil {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// skipValue - consumes the next value including any nested ones
+func skipValue(d *json.Decoder) error {
+	depth := 0
+	for {
+		t, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
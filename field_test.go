@@ -0,0 +1,53 @@
+package simplejsondb_test
+
+import (
+	"errors"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestGetField(t *testing.T) {
+	_, collection := dbtest.NewDB(t, nil)
+	c := collection("hosts")
+	record := []byte(`{"status": "up", "tags": ["a", {"b": [1, 2]}], "metrics": {"load": [0.5, 0.7], "cpu": {"user": 12.5}}, "a/b": 1, "m~n": 2, "": 3}`)
+	dbtest.Seed(t, c, map[string][]byte{"plain": record})
+	if err := c.Create("zipped", record, simplejsondb.CreateOptions{UseGzip: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]string{
+		"":                  string(record),
+		"/status":           `"up"`,
+		"/metrics/cpu":      `{"user": 12.5}`,
+		"/metrics/cpu/user": `12.5`,
+		"/metrics/load/1":   `0.7`,
+		"/tags/1/b/0":       `1`,
+		"/a~1b":             `1`,
+		"/m~0n":             `2`,
+		"/":                 `3`,
+	}
+	for _, id := range []string{"plain", "zipped"} {
+		for pointer, want := range cases {
+			data, err := c.GetField(id, pointer)
+			if err != nil || string(data) != want {
+				t.Errorf("Test failed - %s %q: %s %v", id, pointer, data, err)
+			}
+		}
+	}
+
+	for _, pointer := range []string{"/missing", "/status/x", "/tags/2", "/tags/-", "/tags/01", "/metrics/load/x"} {
+		_, err := c.GetField("plain", pointer)
+		var fe *simplejsondb.FieldError
+		if !errors.Is(err, simplejsondb.ErrFieldNotFound) || !errors.As(err, &fe) || fe.Pointer != pointer {
+			t.Error("Test failed - ", pointer, err)
+		}
+	}
+	if _, err := c.GetField("plain", "status"); err == nil || errors.Is(err, simplejsondb.ErrFieldNotFound) {
+		t.Error("Test failed - invalid pointer accepted", err)
+	}
+	if _, err := c.GetField("absent", "/status"); errors.Is(err, simplejsondb.ErrFieldNotFound) || err == nil {
+		t.Error("Test failed - ", err)
+	}
+}
@@ -0,0 +1,47 @@
+package simplejsondb
+
+import (
+	"go.uber.org/zap"
+)
+
+// Find - the records for which match returns true, by id
+//
+// Records are read one at a time through Get, under their shared lock and
+// decompressed, so match never sees a partially written record. Records
+// which cannot be read are skipped.
+func (c *_collection) Find(match func(id string, data []byte) bool) map[string][]byte {
+	return find(c, c.callbacks, c.logger, match)
+}
+
+// Find - the visible records for which match returns true
+func (c *_overlayCollection) Find(match func(id string, data []byte) bool) map[string][]byte {
+	return find(c, c.upper.callbacks, c.logger, match)
+}
+
+func find(c _layer, g *_callbacks, logger Logger, match func(id string, data []byte) bool) map[string][]byte {
+	keys, err := c.keys()
+	if err != nil {
+		logger.Error("no data available", zap.Error(err))
+		return nil
+	}
+	found := make(map[string][]byte)
+	for _, key := range keys {
+		data, err := c.Get(key)
+		if err != nil {
+			continue
+		}
+		var ok bool
+		err = g.guard("match", func() error {
+			ok = match(key, data)
+			return nil
+		})
+		if err != nil {
+			logger.Error("find predicate failed", zap.String("id", key), zap.Error(err))
+			continue
+		}
+		if ok {
+			found[key] = data
+		}
+	}
+	return found
+}
@@ -0,0 +1,35 @@
+package simplejsondb_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestFind(t *testing.T) {
+	dir := t.TempDir()
+	_, collection := dbtest.Open(t, dir, nil)
+	c := collection("orders")
+	dbtest.Seed(t, c, map[string][]byte{
+		"o1": []byte(`{"status": "paid"}`),
+		"o2": []byte(`{"status": "new"}`),
+	})
+	if err := c.Create("o3", []byte(`{"status": "paid"}`), simplejsondb.CreateOptions{UseGzip: true}); err != nil {
+		t.Fatal(err)
+	}
+	_ = os.WriteFile(filepath.Join(dir, "orders", "broken.json.gz"), []byte("not gzip"), 0644)
+
+	found := c.Find(func(id string, data []byte) bool {
+		return bytes.Contains(data, []byte(`"paid"`))
+	})
+	if len(found) != 2 || string(found["o1"]) != `{"status": "paid"}` || string(found["o3"]) != `{"status": "paid"}` {
+		t.Error("Test failed - ", found)
+	}
+	if found = c.Find(func(string, []byte) bool { return false }); len(found) != 0 {
+		t.Error("Test failed - ", found)
+	}
+}
@@ -3,12 +3,10 @@ module github.com/pnkj-kmr/simple-json-db
 go 1.20
 
 require (
-	github.com/pnkj-kmr/zap-rotate-logger v1.0.0
 	go.uber.org/zap v1.24.0
 )
 
 require (
 	go.uber.org/atomic v1.10.0 // indirect
 	go.uber.org/multierr v1.11.0 // indirect
-	gopkg.in/natefinch/lumberjack.v2 v2.2.1 // indirect
 )
@@ -0,0 +1,66 @@
+package simplejsondb
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrDiskFull - a write failed because the disk is full
+var ErrDiskFull = errors.New("disk full")
+
+// writeFile - replaced by tests to inject filesystem failures
+var writeFile = writeTimed
+
+type _health struct {
+	mu        sync.Mutex
+	degraded  bool
+	successes int
+	recover   int
+	// cache - shrunk to its degraded size while degraded
+	cache *_cache
+}
+
+func newHealth(recover int, cache *_cache) *_health {
+	if recover <= 0 {
+		recover = 3
+	}
+	return &_health{recover: recover, cache: cache}
+}
+
+// observe - feeds a write outcome into the degradation state machine
+//
+// A classified disk-full error enters the degraded mode, which is left
+// only after the configured number of consecutive successful writes so
+// a disk hovering at its limit does not flap in and out. A collection
+// over its QuotaBytes says nothing about the disk and is left alone.
+func (h *_health) observe(err error) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err == nil {
+		if h.degraded {
+			h.successes++
+			if h.successes >= h.recover {
+				h.degraded = false
+				h.successes = 0
+				h.cache.degrade(false)
+			}
+		}
+		return nil
+	}
+	if !isDiskFull(err) {
+		return err
+	}
+	if !h.degraded {
+		h.degraded = true
+		h.cache.degrade(true)
+	}
+	h.successes = 0
+	return fmt.Errorf("%w: %w", ErrDiskFull, err)
+}
+
+func (h *_health) isDegraded() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.degraded
+}
@@ -0,0 +1,133 @@
+package simplejsondb_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestDegradedMode(t *testing.T) {
+	db, collection := dbtest.NewDB(t, &simplejsondb.Options{DegradedRecoverWrites: 2})
+	c := collection("collection1")
+
+	full := true
+	restore := simplejsondb.SetWriteFile(func(name string, data []byte, perm os.FileMode) error {
+		if full {
+			return &os.PathError{Op: "write", Path: name, Err: syscall.ENOSPC}
+		}
+		return os.WriteFile(name, data, perm)
+	})
+	defer restore()
+
+	if db.Degraded() {
+		t.Error("Test failed - degraded before any failure")
+	}
+	err := c.Create("key1", []byte(`{}`))
+	if !errors.Is(err, simplejsondb.ErrDiskFull) || !errors.Is(err, syscall.ENOSPC) {
+		t.Error("Test failed - disk full not classified", err)
+	}
+	if !db.Degraded() {
+		t.Error("Test failed - not degraded after disk full")
+	}
+
+	// hysteresis: one success is not enough to leave degraded mode
+	full = false
+	if err = c.Create("key1", []byte(`{}`)); err != nil {
+		t.Error("Test failed - ", err)
+	}
+	if !db.Degraded() {
+		t.Error("Test failed - left degraded mode too early")
+	}
+	full = true
+	_ = c.Create("key1", []byte(`{}`))
+	full = false
+	_ = c.Create("key1", []byte(`{}`))
+	if !db.Degraded() {
+		t.Error("Test failed - failure did not reset recovery")
+	}
+	_ = c.Create("key2", []byte(`{}`))
+	if db.Degraded() {
+		t.Error("Test failed - still degraded after recovery writes")
+	}
+}
+
+func TestDegradedOtherErrors(t *testing.T) {
+	db, collection := dbtest.NewDB(t, nil)
+	c := collection("collection1")
+	restore := simplejsondb.SetWriteFile(func(name string, data []byte, perm os.FileMode) error {
+		return &os.PathError{Op: "write", Path: name, Err: syscall.EACCES}
+	})
+	defer restore()
+
+	err := c.Create("key1", []byte(`{}`))
+	if err == nil || errors.Is(err, simplejsondb.ErrDiskFull) {
+		t.Error("Test failed - ", err)
+	}
+	if db.Degraded() {
+		t.Error("Test failed - degraded on a non disk full error")
+	}
+}
+
+// a collection over its quota says nothing about the disk
+func TestQuotaNotDegraded(t *testing.T) {
+	db, collection := dbtest.NewDB(t, &simplejsondb.Options{QuotaBytes: 20})
+	c := collection("quota")
+	dbtest.Seed(t, c, map[string][]byte{"a": []byte(`{"k": "0123456789"}`)})
+	err := c.Create("b", []byte(`{"k": "0123456789"}`))
+	if !errors.Is(err, simplejsondb.ErrQuotaExceeded) || errors.Is(err, simplejsondb.ErrDiskFull) {
+		t.Error("Test failed - ", err)
+	}
+	if db.Degraded() {
+		t.Error("Test failed - quota degraded the database")
+	}
+}
+
+func TestDegradedCacheSize(t *testing.T) {
+	db, collection := dbtest.NewDB(t, &simplejsondb.Options{CacheSize: 10, DegradedCacheSize: 2, DegradedRecoverWrites: 1})
+	c := collection("cached")
+	dbtest.SeedN(t, c, 5)
+	for i := 0; i < 5; i++ {
+		if _, err := c.Get(fmt.Sprintf("record%d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s := db.Stats().Cache; s.Entries != 5 || s.MaxEntries != 10 {
+		t.Error("Test failed - ", s)
+	}
+
+	full := true
+	defer simplejsondb.SetWriteFile(func(name string, data []byte, perm os.FileMode) error {
+		if full {
+			return &os.PathError{Op: "write", Path: name, Err: syscall.ENOSPC}
+		}
+		return os.WriteFile(name, data, perm)
+	})()
+	if err := c.Create("record0", []byte(`{}`)); !errors.Is(err, simplejsondb.ErrDiskFull) {
+		t.Fatal("Test failed - ", err)
+	}
+	if s := db.Stats().Cache; s.Entries != 2 || s.MaxEntries != 2 {
+		t.Error("Test failed - cache not shrunk", s)
+	}
+	for i := 0; i < 5; i++ {
+		c.Get(fmt.Sprintf("record%d", i))
+	}
+	if s := db.Stats().Cache; s.Entries != 2 {
+		t.Error("Test failed - cache grew while degraded", s)
+	}
+
+	full = false
+	if err := c.Create("record9", []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		c.Get(fmt.Sprintf("record%d", i))
+	}
+	if s := db.Stats().Cache; db.Degraded() || s.Entries < 5 || s.MaxEntries != 10 {
+		t.Error("Test failed - cache not restored", s)
+	}
+}
@@ -0,0 +1,311 @@
+// Package httpapi - a REST handler exposing the collections of a
+// simplejsondb.DB
+//
+//	GET    /{collection}/{id}   the record
+//	PUT    /{collection}/{id}   creates or replaces the record
+//	DELETE /{collection}/{id}   removes the record
+//	GET    /{collection}        a page of records, ?offset=0&limit=100
+//
+// Records stored gzipped are sent with Content-Encoding: gzip to clients
+// accepting it, and a PUT with Content-Encoding: gzip is stored gzipped.
+// Bodies over MaxBodyBytes, sent or decompressed, are refused with 413.
+// Every response names the library version in VersionHeader.
+// Path segments are unescaped one by one, so an id holding an escaped /
+// reaches the id policy of the database and is rejected there.
+package httpapi
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+)
+
+const (
+	// DefaultLimit - records of a listing page without ?limit
+	DefaultLimit = 100
+	// MaxLimit - the largest ?limit accepted
+	MaxLimit = 1000
+	// VersionHeader - response header holding simplejsondb.Version
+	VersionHeader = "X-Simplejsondb-Version"
+	// MaxBodyBytes - the largest PUT body accepted, both as sent and once
+	// a gzip body is decompressed
+	MaxBodyBytes = 32 << 20
+)
+
+// errBodyTooLarge - a gzip body decompresses to more than MaxBodyBytes
+var errBodyTooLarge = errors.New("decompressed body too large")
+
+type (
+	// Item - a record of a listing page, Data is the raw record when it
+	// is JSON and a string otherwise
+	Item struct {
+		ID   string `json:"id"`
+		Data any    `json:"data"`
+	}
+
+	// Page - a listing page, Next is the offset of the following page and
+	// omitted on the last one
+	Page struct {
+		Items []Item `json:"items"`
+		Next  int    `json:"next,omitempty"`
+	}
+
+	_handler struct {
+		db simplejsondb.DB
+	}
+)
+
+// Handler - serves the collections of db
+func Handler(db simplejsondb.DB) http.Handler {
+	return &_handler{db: db}
+}
+
+func (h *_handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(VersionHeader, simplejsondb.Version)
+	segments, err := split(r.URL.EscapedPath())
+	if err != nil || len(segments) == 0 || len(segments) > 2 {
+		http.NotFound(w, r)
+		return
+	}
+	name := segments[0]
+	if !simplejsondb.IsCollectionName(name) {
+		http.Error(w, "invalid collection name", http.StatusBadRequest)
+		return
+	}
+	if len(segments) == 1 {
+		if r.Method != http.MethodGet {
+			methodNotAllowed(w, http.MethodGet)
+			return
+		}
+		h.list(w, r, name)
+		return
+	}
+	id := segments[1]
+	switch r.Method {
+	case http.MethodGet:
+		h.get(w, r, name, id)
+	case http.MethodPut:
+		h.put(w, r, name, id)
+	case http.MethodDelete:
+		h.delete(w, name, id)
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodPut, http.MethodDelete)
+	}
+}
+
+// split - the unescaped segments of an escaped path
+func split(path string) ([]string, error) {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil, nil
+	}
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		var err error
+		segments[i], err = url.PathUnescape(s)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return segments, nil
+}
+
+// collection - an existing collection, reads never create one
+func (h *_handler) collection(w http.ResponseWriter, name string) (simplejsondb.Collection, bool) {
+	if !h.db.HasCollection(name) {
+		http.Error(w, "collection not found", http.StatusNotFound)
+		return nil, false
+	}
+	c, err := h.db.Collection(name)
+	if err != nil {
+		fail(w, err)
+		return nil, false
+	}
+	return c, true
+}
+
+func (h *_handler) get(w http.ResponseWriter, r *http.Request, name, id string) {
+	c, ok := h.collection(w, name)
+	if !ok {
+		return
+	}
+	info, err := c.Stat(id)
+	if err != nil {
+		fail(w, err)
+		return
+	}
+	data, err := c.Get(id)
+	if err != nil {
+		fail(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Add("Vary", "Accept-Encoding")
+	if info.Ext == simplejsondb.GZipExt && acceptsGzip(r) {
+		w.Header().Set("Content-Encoding", "gzip")
+		zw := gzip.NewWriter(w)
+		zw.Write(data)
+		zw.Close()
+		return
+	}
+	w.Write(data)
+}
+
+func (h *_handler) put(w http.ResponseWriter, r *http.Request, name, id string) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxBodyBytes))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	useGzip := false
+	switch r.Header.Get("Content-Encoding") {
+	case "", "identity":
+	case "gzip":
+		useGzip = true
+		if body, err = unGzipBody(body); errors.Is(err, errBodyTooLarge) {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		} else if err != nil {
+			http.Error(w, "invalid gzip body", http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "unsupported Content-Encoding", http.StatusUnsupportedMediaType)
+		return
+	}
+	c, err := h.db.Collection(name)
+	if err != nil {
+		fail(w, err)
+		return
+	}
+	if err = c.Create(id, body, simplejsondb.CreateOptions{UseGzip: useGzip}); err != nil {
+		fail(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *_handler) delete(w http.ResponseWriter, name, id string) {
+	c, ok := h.collection(w, name)
+	if !ok {
+		return
+	}
+	if err := c.Delete(id); err != nil {
+		fail(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *_handler) list(w http.ResponseWriter, r *http.Request, name string) {
+	offset, err := param(r, "offset", 0)
+	if err != nil || offset < 0 {
+		http.Error(w, "invalid offset", http.StatusBadRequest)
+		return
+	}
+	limit, err := param(r, "limit", DefaultLimit)
+	if err != nil || limit <= 0 || limit > MaxLimit {
+		http.Error(w, "invalid limit", http.StatusBadRequest)
+		return
+	}
+	c, ok := h.collection(w, name)
+	if !ok {
+		return
+	}
+	// one extra record tells whether a next page exists
+	ids, data, err := c.GetPage(offset, limit+1)
+	if err != nil {
+		fail(w, err)
+		return
+	}
+	page := Page{Items: make([]Item, 0, len(ids))}
+	if len(ids) > limit {
+		ids, data = ids[:limit], data[:limit]
+		page.Next = offset + limit
+	}
+	for i, id := range ids {
+		item := Item{ID: id, Data: string(data[i])}
+		if json.Valid(data[i]) {
+			item.Data = json.RawMessage(data[i])
+		}
+		page.Items = append(page.Items, item)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// param - an integer query parameter, def when absent
+func param(r *http.Request, name string, def int) (int, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return def, nil
+	}
+	return strconv.Atoi(v)
+}
+
+// acceptsGzip - the request lists gzip in Accept-Encoding
+func acceptsGzip(r *http.Request) bool {
+	for _, v := range r.Header.Values("Accept-Encoding") {
+		for _, enc := range strings.Split(v, ",") {
+			enc, q, _ := strings.Cut(enc, ";")
+			if strings.TrimSpace(enc) == "gzip" && strings.ReplaceAll(q, " ", "") != "q=0" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// unGzipBody - the decompressed body, read no further than MaxBodyBytes so
+// a small body cannot expand into an unbounded allocation
+func unGzipBody(body []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	data, err := io.ReadAll(io.LimitReader(zr, MaxBodyBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxBodyBytes {
+		return nil, fmt.Errorf("%w: over %d bytes", errBodyTooLarge, MaxBodyBytes)
+	}
+	return data, nil
+}
+
+func methodNotAllowed(w http.ResponseWriter, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+}
+
+// fail - the status matching err, only client errors are described as
+// the others may name files of the server
+func fail(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, simplejsondb.ErrRecordNotFound), errors.Is(err, fs.ErrNotExist):
+		status = http.StatusNotFound
+	case errors.Is(err, simplejsondb.ErrInvalidID), errors.Is(err, simplejsondb.ErrIDTooLong), errors.Is(err, simplejsondb.ErrInvalidJSON):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, simplejsondb.ErrRecordTooLarge):
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	case errors.Is(err, simplejsondb.ErrCollectionFull), errors.Is(err, simplejsondb.ErrDiskFull), errors.Is(err, simplejsondb.ErrQuotaExceeded):
+		status = http.StatusInsufficientStorage
+	case errors.Is(err, simplejsondb.ErrClosed):
+		status = http.StatusServiceUnavailable
+	}
+	http.Error(w, http.StatusText(status), status)
+}
@@ -0,0 +1,256 @@
+package httpapi_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+	"github.com/pnkj-kmr/simple-json-db/httpapi"
+)
+
+func serve(t *testing.T, h http.Handler, method, path string, body []byte, header http.Header) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(method, path, bytes.NewReader(body))
+	for k, v := range header {
+		r.Header[k] = v
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func gzipped(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	zw := gzip.NewWriter(&b)
+	zw.Write(data)
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestGet(t *testing.T) {
+	db, collection := dbtest.NewDB(t, nil)
+	c := collection("users")
+	dbtest.Seed(t, c, map[string][]byte{"u1": []byte(`{"a": 1}`)})
+	h := httpapi.Handler(db)
+
+	w := serve(t, h, http.MethodGet, "/users/u1", nil, nil)
+	if w.Code != http.StatusOK || w.Body.String() != `{"a": 1}` || w.Header().Get("Content-Type") != "application/json" {
+		t.Error("Test failed - ", w.Code, w.Body.String())
+	}
+	for _, path := range []string{"/users/missing", "/nope/u1", "/"} {
+		if w = serve(t, h, http.MethodGet, path, nil, nil); w.Code != http.StatusNotFound {
+			t.Error("Test failed - ", path, w.Code)
+		}
+	}
+	if db.HasCollection("nope") {
+		t.Error("Test failed - GET created a collection")
+	}
+	if w = serve(t, h, http.MethodPost, "/users/u1", nil, nil); w.Code != http.StatusMethodNotAllowed {
+		t.Error("Test failed - ", w.Code)
+	}
+}
+
+func TestPut(t *testing.T) {
+	db, collection := dbtest.NewDB(t, nil)
+	h := httpapi.Handler(db)
+
+	w := serve(t, h, http.MethodPut, "/users/u1", []byte(`{"a": 1}`), nil)
+	if w.Code != http.StatusNoContent {
+		t.Error("Test failed - ", w.Code, w.Body.String())
+	}
+	dbtest.RequireRecord(t, collection("users"), "u1", []byte(`{"a": 1}`))
+
+	w = serve(t, h, http.MethodPut, "/users/u1", []byte(`{"a": 2}`), nil)
+	if w.Code != http.StatusNoContent {
+		t.Error("Test failed - ", w.Code)
+	}
+	dbtest.RequireRecord(t, collection("users"), "u1", []byte(`{"a": 2}`))
+
+	w = serve(t, h, http.MethodPut, "/users/u1", []byte(`{}`), http.Header{"Content-Encoding": {"br"}})
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Error("Test failed - ", w.Code)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	db, collection := dbtest.NewDB(t, nil)
+	c := collection("users")
+	dbtest.Seed(t, c, map[string][]byte{"u1": []byte(`{}`)})
+	h := httpapi.Handler(db)
+
+	if w := serve(t, h, http.MethodDelete, "/users/u1", nil, nil); w.Code != http.StatusNoContent {
+		t.Error("Test failed - ", w.Code)
+	}
+	if _, err := c.Get("u1"); err == nil {
+		t.Error("Test failed - record left behind")
+	}
+	if w := serve(t, h, http.MethodDelete, "/users/u1", nil, nil); w.Code != http.StatusNotFound {
+		t.Error("Test failed - ", w.Code)
+	}
+}
+
+func TestList(t *testing.T) {
+	db, collection := dbtest.NewDB(t, nil)
+	c := collection("users")
+	ids := dbtest.SeedN(t, c, 5)
+	dbtest.Seed(t, c, map[string][]byte{"text": []byte("not json")})
+	h := httpapi.Handler(db)
+
+	var items []httpapi.Item
+	next := "/users?limit=2"
+	for pages := 0; next != ""; pages++ {
+		if pages > 3 {
+			t.Fatal("Test failed - paging does not end")
+		}
+		w := serve(t, h, http.MethodGet, next, nil, nil)
+		if w.Code != http.StatusOK {
+			t.Fatal("Test failed - ", w.Code, w.Body.String())
+		}
+		var page struct {
+			Items []httpapi.Item `json:"items"`
+			Next  int            `json:"next"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+			t.Fatal(err)
+		}
+		items = append(items, page.Items...)
+		next = ""
+		if page.Next != 0 {
+			next = "/users?limit=2&offset=" + strconv.Itoa(page.Next)
+		}
+	}
+	if len(items) != len(ids)+1 {
+		t.Fatal("Test failed - ", len(items))
+	}
+	if items[0].ID != "record0" || items[0].Data.(map[string]any)["n"] != float64(0) {
+		t.Error("Test failed - ", items[0])
+	}
+	if last := items[len(items)-1]; last.ID != "text" || last.Data != "not json" {
+		t.Error("Test failed - ", last)
+	}
+
+	for _, path := range []string{"/users?limit=0", "/users?offset=-1", "/users?limit=x"} {
+		if w := serve(t, h, http.MethodGet, path, nil, nil); w.Code != http.StatusBadRequest {
+			t.Error("Test failed - ", path, w.Code)
+		}
+	}
+}
+
+func TestGzip(t *testing.T) {
+	db, collection := dbtest.NewDB(t, nil)
+	h := httpapi.Handler(db)
+
+	body := gzipped(t, []byte(`{"big": true}`))
+	w := serve(t, h, http.MethodPut, "/users/z1", body, http.Header{"Content-Encoding": {"gzip"}})
+	if w.Code != http.StatusNoContent {
+		t.Fatal("Test failed - ", w.Code, w.Body.String())
+	}
+	info, err := collection("users").Stat("z1")
+	if err != nil || info.Ext != simplejsondb.GZipExt {
+		t.Error("Test failed - not stored gzipped", info, err)
+	}
+
+	w = serve(t, h, http.MethodGet, "/users/z1", nil, http.Header{"Accept-Encoding": {"br, gzip"}})
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatal("Test failed - ", w.Header())
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil || string(data) != `{"big": true}` {
+		t.Error("Test failed - ", string(data), err)
+	}
+
+	w = serve(t, h, http.MethodGet, "/users/z1", nil, nil)
+	if w.Header().Get("Content-Encoding") != "" || w.Body.String() != `{"big": true}` {
+		t.Error("Test failed - ", w.Header(), w.Body.String())
+	}
+	if w = serve(t, h, http.MethodPut, "/users/z2", []byte("plain"), http.Header{"Content-Encoding": {"gzip"}}); w.Code != http.StatusBadRequest {
+		t.Error("Test failed - ", w.Code)
+	}
+}
+
+func TestVersionHeader(t *testing.T) {
+	db, _ := dbtest.NewDB(t, nil)
+	h := httpapi.Handler(db)
+	for _, path := range []string{"/users/missing", "/users", "/.hidden"} {
+		if w := serve(t, h, http.MethodGet, path, nil, nil); w.Header().Get(httpapi.VersionHeader) != simplejsondb.Version {
+			t.Error("Test failed - ", path, w.Header())
+		}
+	}
+}
+
+func TestGzipBomb(t *testing.T) {
+	db, collection := dbtest.NewDB(t, nil)
+	h := httpapi.Handler(db)
+
+	// a few kilobytes on the wire, over MaxBodyBytes once decompressed
+	body := gzipped(t, make([]byte, httpapi.MaxBodyBytes+1))
+	w := serve(t, h, http.MethodPut, "/users/bomb", body, http.Header{"Content-Encoding": {"gzip"}})
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Error("Test failed - ", w.Code, w.Body.String())
+	}
+	if _, err := collection("users").Get("bomb"); err == nil {
+		t.Error("Test failed - bomb stored")
+	}
+}
+
+func TestRecordTooLarge(t *testing.T) {
+	db, _ := dbtest.NewDB(t, &simplejsondb.Options{MaxRecordSize: 8})
+	h := httpapi.Handler(db)
+	w := serve(t, h, http.MethodPut, "/users/big", []byte(`{"big": true}`), nil)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Error("Test failed - ", w.Code, w.Body.String())
+	}
+}
+
+func TestQuotaExceeded(t *testing.T) {
+	db, _ := dbtest.NewDB(t, &simplejsondb.Options{QuotaBytes: 8})
+	h := httpapi.Handler(db)
+	w := serve(t, h, http.MethodPut, "/users/big", []byte(`{"big": true}`), nil)
+	if w.Code != http.StatusInsufficientStorage {
+		t.Error("Test failed - ", w.Code, w.Body.String())
+	}
+}
+
+func TestTraversal(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "db")
+	db, _ := dbtest.Open(t, root, nil)
+	h := httpapi.Handler(db)
+
+	for _, path := range []string{
+		"/users/..%2F..%2Fescape",
+		"/users/%2E%2E%2Fescape",
+		"/..%2Fescape/u1",
+		"/%2E%2E/u1",
+		"/.sequences/n",
+		"/users/.hidden",
+	} {
+		w := serve(t, h, http.MethodPut, path, []byte(`{}`), nil)
+		if w.Code != http.StatusBadRequest && w.Code != http.StatusNotFound {
+			t.Error("Test failed - ", path, w.Code)
+		}
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 {
+		t.Error("Test failed - written outside the database", entries, err)
+	}
+	if _, err = os.Stat(filepath.Join(root, "escape.json")); err == nil {
+		t.Error("Test failed - written outside the collection")
+	}
+}
@@ -0,0 +1,266 @@
+package simplejsondb
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrIDTooLong - the record file name would exceed the file name limit
+var ErrIDTooLong = errors.New("record id too long")
+
+// defaultMaxNameLength - conservative file name limit of common filesystems
+const defaultMaxNameLength = 255
+
+var (
+	// hashedPrefix - marks file names derived from a hashed record id
+	hashedPrefix string = "~"
+	// idsDir - reserved directory mapping hashed file names back to ids
+	idsDir string = ".ids"
+)
+
+const featureHashedIDs = "hashed_ids"
+
+// errEncodedName - a file name the policy cannot map back by itself, the
+// id is then looked up in idsDir
+var errEncodedName = errors.New("encoded file name")
+
+// IDPolicy - the rules mapping record ids to file names
+//
+// Every operation taking an id validates it first. Ids whose encoded name
+// differs from the id are remembered in a reserved directory, so Decode
+// may fail for names it cannot map back by itself.
+type IDPolicy interface {
+	// Validate - rejects ids the policy cannot store
+	Validate(id string) error
+	// Encode - the file name stem of a valid id
+	Encode(id string) string
+	// Decode - the id of a file name stem
+	Decode(name string) (string, error)
+	// Generate - a fresh id for CreateAuto
+	Generate() string
+}
+
+type (
+	// _defaultIDs - ids are file names up to a length limit, longer ones
+	// are rejected or hashed; extLen is the longest file extension the
+	// name has to leave room for
+	_defaultIDs struct {
+		maxNameLength int
+		hashLongIDs   bool
+		extLen        int
+	}
+
+	// _strictIDs - ids are always used verbatim as file names
+	_strictIDs struct {
+		maxNameLength int
+	}
+
+	// _hashedIDs - ids are always hashed
+	_hashedIDs struct{}
+)
+
+// ErrInvalidID - an id the id policy cannot store
+var ErrInvalidID = errors.New("invalid record id")
+
+var (
+	// StrictFilename - rejects every id which is not usable verbatim as a
+	// portable file name of at most 255 bytes
+	StrictFilename IDPolicy = _strictIDs{maxNameLength: defaultMaxNameLength}
+	// HashedIDs - stores every id under a hashed file name, allowing any
+	// id at the cost of a lookup when listing
+	HashedIDs IDPolicy = _hashedIDs{}
+)
+
+// idPolicy - Options.IDPolicy or the default built from the name options
+func idPolicy(opts *Options) IDPolicy {
+	if opts.IDPolicy != nil {
+		return opts.IDPolicy
+	}
+	maxNameLength := opts.MaxNameLength
+	if maxNameLength <= 0 {
+		maxNameLength = defaultMaxNameLength
+	}
+	return _defaultIDs{maxNameLength: maxNameLength, hashLongIDs: opts.HashLongIDs, extLen: len(GZipExt)}
+}
+
+// fitCodecs - the default policy leaving room for the longest extension
+// of codecs, encrypted ones included; other policies as they are
+func fitCodecs(ids IDPolicy, codecs []Codec) IDPolicy {
+	p, ok := ids.(_defaultIDs)
+	if !ok {
+		return ids
+	}
+	for _, codec := range codecs {
+		if n := len(codec.Ext()); n > p.extLen {
+			p.extLen = n
+		}
+	}
+	return p
+}
+
+// Validate - rejects ids which are empty, would leave the collection
+// directory, could clash with its dot-prefixed bookkeeping files or with
+// the names of hashed ids, or whose file name would be too long
+func (p _defaultIDs) Validate(id string) error {
+	switch {
+	case id == "":
+		return fmt.Errorf("%w: %q", ErrInvalidID, id)
+	case strings.HasPrefix(id, hashedPrefix), strings.HasPrefix(id, "."):
+		return fmt.Errorf("%w: %q has a reserved prefix", ErrInvalidID, id)
+	case strings.ContainsAny(id, "/\\\x00"):
+		return fmt.Errorf("%w: %q has a path separator or NUL", ErrInvalidID, id)
+	case p.hashLongIDs || p.fitsName(id):
+		return nil
+	}
+	return fmt.Errorf("%w: %d bytes, limit %d", ErrIDTooLong, len(id)+p.extLen, p.maxNameLength)
+}
+
+// fitsName - whether every file name variant of id is within the limit
+func (p _defaultIDs) fitsName(id string) bool {
+	return len(id)+p.extLen <= p.maxNameLength
+}
+
+// Encode - the id itself unless it is too long and hashing is enabled
+func (p _defaultIDs) Encode(id string) string {
+	if !p.hashLongIDs || p.fitsName(id) {
+		return id
+	}
+	return hashName(id)
+}
+
+// Decode - the name itself unless it is a hashed one
+func (p _defaultIDs) Decode(name string) (string, error) {
+	if strings.HasPrefix(name, hashedPrefix) {
+		return "", errEncodedName
+	}
+	return name, nil
+}
+
+// Generate - a time ordered ULID
+func (_defaultIDs) Generate() string {
+	return ULID()
+}
+
+// Validate - accepts only ids which are plain portable file names
+func (p _strictIDs) Validate(id string) error {
+	switch {
+	case id == "", id == ".", id == "..":
+		return fmt.Errorf("%w: %q", ErrInvalidID, id)
+	case len(id)+len(GZipExt) > p.maxNameLength:
+		return fmt.Errorf("%w: %d bytes, limit %d", ErrIDTooLong, len(id)+len(GZipExt), p.maxNameLength)
+	case strings.HasPrefix(id, hashedPrefix), strings.HasPrefix(id, "."):
+		return fmt.Errorf("%w: %q has a reserved prefix", ErrInvalidID, id)
+	case strings.ContainsAny(id, `/\:*?"<>|`):
+		return fmt.Errorf("%w: %q has a path or reserved character", ErrInvalidID, id)
+	}
+	for _, r := range id {
+		if r < 0x20 || r == 0x7f {
+			return fmt.Errorf("%w: %q has a control character", ErrInvalidID, id)
+		}
+	}
+	return nil
+}
+
+// Encode - the id itself
+func (_strictIDs) Encode(id string) string {
+	return id
+}
+
+// Decode - the name itself
+func (_strictIDs) Decode(name string) (string, error) {
+	return name, nil
+}
+
+// Generate - a time ordered ULID
+func (_strictIDs) Generate() string {
+	return ULID()
+}
+
+// Validate - accepts every non empty id
+func (_hashedIDs) Validate(id string) error {
+	if id == "" {
+		return fmt.Errorf("%w: empty id", ErrInvalidID)
+	}
+	return nil
+}
+
+// Encode - the hashed name of id
+func (_hashedIDs) Encode(id string) string {
+	return hashName(id)
+}
+
+// Decode - always looked up, hashes cannot be reversed
+func (_hashedIDs) Decode(string) (string, error) {
+	return "", errEncodedName
+}
+
+// Generate - a time ordered ULID
+func (_hashedIDs) Generate() string {
+	return ULID()
+}
+
+// hashName - the hashed file name stem of id
+func hashName(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return hashedPrefix + hex.EncodeToString(sum[:])
+}
+
+// checkID - rejects ids the policy cannot store
+func (c *_collection) checkID(key string) error {
+	return c.ids.Validate(key)
+}
+
+// fileKey - the file name stem of a record
+func (c *_collection) fileKey(key string) string {
+	return c.ids.Encode(key)
+}
+
+// logicalKey - maps a file name stem back to its record id
+func (c *_collection) logicalKey(fileKey string) string {
+	key, err := c.ids.Decode(fileKey)
+	if err == nil {
+		return key
+	}
+	data, err := os.ReadFile(filepath.Join(c.path, idsDir, fileKey))
+	if err != nil {
+		return fileKey
+	}
+	return string(data)
+}
+
+// saveID - records the id of a hashed file name, the caller holds the lock
+func (c *_collection) saveID(key string) error {
+	fileKey := c.fileKey(key)
+	if fileKey == key {
+		return nil
+	}
+	// older versions would list the hashed file name as the id
+	err := requireLayout(c.path, featureHashedIDs)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(c.path, idsDir)
+	err = os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	return writeAtomic(filepath.Join(dir, fileKey), []byte(key), 0644)
+}
+
+// removeID - forgets the id of a hashed file name, the caller holds the lock
+func (c *_collection) removeID(key string) error {
+	fileKey := c.fileKey(key)
+	if fileKey == key {
+		return nil
+	}
+	err := os.Remove(filepath.Join(c.path, idsDir, fileKey))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,288 @@
+package simplejsondb_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestLongIDs(t *testing.T) {
+	limit := 255 - len(simplejsondb.GZipExt)
+	under := strings.Repeat("u", limit-1)
+	at := strings.Repeat("a", limit)
+	over := strings.Repeat("o", limit+1)
+
+	for _, useGzip := range []bool{false, true} {
+		dir := t.TempDir()
+		_, collection := dbtest.Open(t, dir, &simplejsondb.Options{UseGzip: useGzip})
+		c := collection("strict")
+		for _, id := range []string{under, at} {
+			if err := c.Create(id, []byte(`{}`)); err != nil {
+				t.Error("Test failed - ", len(id), err)
+			}
+		}
+		err := c.Create(over, []byte(`{}`))
+		if !errors.Is(err, simplejsondb.ErrIDTooLong) {
+			t.Error("Test failed - ", err)
+		}
+		if _, err = c.Get(over); !errors.Is(err, simplejsondb.ErrIDTooLong) {
+			t.Error("Test failed - ", err)
+		}
+		if err = c.Delete(over); !errors.Is(err, simplejsondb.ErrIDTooLong) {
+			t.Error("Test failed - ", err)
+		}
+
+		hashing, collection := dbtest.Open(t, dir, &simplejsondb.Options{UseGzip: useGzip, HashLongIDs: true})
+		h := collection("hashed")
+		for _, id := range []string{under, at, over} {
+			if err = h.Create(id, []byte(`"`+id[:1]+`"`)); err != nil {
+				t.Error("Test failed - ", len(id), err)
+			}
+		}
+		data, err := h.Get(over)
+		if err != nil || string(data) != `"o"` {
+			t.Error("Test failed - ", string(data), err)
+		}
+		keys, err := h.KeysPrefix("", 0, "")
+		if err != nil || len(keys) != 3 {
+			t.Fatal("Test failed - ", len(keys), err)
+		}
+		found := false
+		for _, key := range keys {
+			found = found || key == over
+		}
+		if !found {
+			t.Error("Test failed - hashed id not mapped back")
+		}
+
+		archive := filepath.Join(t.TempDir(), "ids.sjdb")
+		err = hashing.ExportIndexed(archive)
+		if err != nil {
+			t.Fatal(err)
+		}
+		a, err := simplejsondb.OpenArchive(archive)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err = a.Get("hashed", over)
+		a.Close()
+		if err != nil || string(data) != `"o"` {
+			t.Error("Test failed - export", string(data), err)
+		}
+
+		if err = h.Delete(over); err != nil {
+			t.Error("Test failed - ", err)
+		}
+		if _, err = h.Get(over); !errors.Is(err, os.ErrNotExist) {
+			t.Error("Test failed - ", err)
+		}
+		sidecars, _ := os.ReadDir(filepath.Join(dir, "hashed", ".ids"))
+		if len(sidecars) != 0 {
+			t.Error("Test failed - id sidecar left behind", len(sidecars))
+		}
+	}
+}
+
+func TestDefaultIDs(t *testing.T) {
+	dir := t.TempDir()
+	_, collection := dbtest.Open(t, dir, nil)
+	c := collection("ids")
+	for _, id := range []string{"", ".", "..", ".layout", "../../etc/passwd", "../escape", "nested/id", `nested\id`, "a\x00b", "a/../b", "~tilde"} {
+		if err := c.Create(id, []byte(`{}`)); !errors.Is(err, simplejsondb.ErrInvalidID) {
+			t.Error("Test failed - ", id, err)
+		}
+		if _, err := c.Get(id); !errors.Is(err, simplejsondb.ErrInvalidID) {
+			t.Error("Test failed - ", id, err)
+		}
+		if err := c.Delete(id); !errors.Is(err, simplejsondb.ErrInvalidID) {
+			t.Error("Test failed - ", id, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "escape.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Error("Test failed - ", err)
+	}
+
+	legal := []string{"a..b", "trailing.", "v1.2.3", "with space", "ünïcødé", "日本語", "a~b", "a:b"}
+	for _, id := range legal {
+		if err := c.Create(id, []byte(`"`+id+`"`)); err != nil {
+			t.Error("Test failed - ", id, err)
+		}
+	}
+	for _, id := range legal {
+		dbtest.RequireRecord(t, c, id, []byte(`"`+id+`"`))
+	}
+	if keys := c.Keys(); len(keys) != len(legal) {
+		t.Error("Test failed - ", keys)
+	}
+}
+
+func TestMaxNameLength(t *testing.T) {
+	_, collection := dbtest.NewDB(t, &simplejsondb.Options{MaxNameLength: 16})
+	c := collection("short")
+	if err := c.Create("12345678", []byte(`{}`)); err != nil {
+		t.Error("Test failed - ", err)
+	}
+	if err := c.Create("123456789", []byte(`{}`)); !errors.Is(err, simplejsondb.ErrIDTooLong) {
+		t.Error("Test failed - ", err)
+	}
+}
+
+func TestMaxNameLengthExtensions(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	for _, opts := range []*simplejsondb.Options{
+		{MaxNameLength: 20, EncryptionKey: key},
+		{MaxNameLength: 20, Codecs: []simplejsondb.Codec{b64Codec{}}},
+	} {
+		_, collection := dbtest.NewDB(t, opts)
+		c := collection("short")
+		// 12 bytes leave room for .json.gz but not .json.gz.enc or .json.b64
+		if err := c.Create("123456789012", []byte(`{}`)); !errors.Is(err, simplejsondb.ErrIDTooLong) {
+			t.Error("Test failed - ", err)
+		}
+		if err := c.Create("12345678", []byte(`{}`)); err != nil {
+			t.Error("Test failed - ", err)
+		}
+	}
+}
+
+// rejectAll - an id policy refusing every id
+type rejectAll struct{}
+
+var errRejected = errors.New("rejected by policy")
+
+func (rejectAll) Validate(string) error              { return errRejected }
+func (rejectAll) Encode(id string) string            { return id }
+func (rejectAll) Decode(name string) (string, error) { return name, nil }
+func (rejectAll) Generate() string                   { return "generated" }
+
+// TestIDPolicyEnforced - no API may accept an id the policy rejects
+func TestIDPolicyEnforced(t *testing.T) {
+	dir := t.TempDir()
+	db, collection := dbtest.Open(t, dir, &simplejsondb.Options{IDPolicy: rejectAll{}, CacheBytes: 1 << 20})
+	overlay, err := simplejsondb.NewOverlay(db, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = overlay.Close() })
+	oc, err := overlay.Collection("locked")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := collection("locked")
+	_, other := dbtest.NewDB(t, nil)
+	dst := other("dst")
+	record := filepath.Join(dir, "locked", "victim.json")
+	_ = os.WriteFile(record, []byte(`{"a":1}`), 0644)
+
+	for name, c := range map[string]simplejsondb.Collection{"plain": c, "overlay": oc} {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		calls := map[string]error{}
+		_, calls["Get"] = c.Get("victim")
+		_, calls["GetAndCompare"] = c.GetAndCompare("victim", nil)
+		_, calls["GetField"] = c.GetField("victim", "/a")
+		_, calls["GetMany"] = c.GetMany("victim")
+		_, calls["WaitFor"] = c.WaitFor(ctx, "victim")
+		calls["Create"] = c.Create("victim", []byte(`{}`))
+		calls["CreateNX"] = c.CreateNX("fresh", []byte(`{}`))
+		calls["CreateMany"] = c.CreateMany(map[string][]byte{"victim": []byte(`{}`)})["victim"]
+		_, calls["CreateAuto"] = c.CreateAuto([]byte(`{}`))
+		calls["Update"] = c.Update("victim", func([]byte) ([]byte, error) { return []byte(`{}`), nil })
+		calls["Merge"] = c.Merge("victim", []byte(`{"b":2}`))
+		calls["Pin"] = c.Pin("victim")
+		calls["CopyTo"] = c.CopyTo("victim", dst)
+		calls["MoveTo"] = c.MoveTo("victim", dst)
+		calls["Delete"] = c.Delete("victim")
+		calls["DeleteMany"] = c.DeleteMany("victim")["victim"]
+		cancel()
+		for call, err := range calls {
+			if !errors.Is(err, errRejected) {
+				t.Error("Test failed - accepted", name, call, err)
+			}
+		}
+	}
+	if data, err := os.ReadFile(record); err != nil || string(data) != `{"a":1}` {
+		t.Error("Test failed - record touched", string(data), err)
+	}
+	if keys := dst.Keys(); len(keys) != 0 {
+		t.Error("Test failed - ", keys)
+	}
+}
+
+func TestIDPolicies(t *testing.T) {
+	dir := t.TempDir()
+	_, collection := dbtest.Open(t, dir, &simplejsondb.Options{IDPolicy: simplejsondb.StrictFilename})
+	c := collection("strict")
+	for _, id := range []string{"order-1", "with space", "ünïcode", "a.b"} {
+		if err := c.Create(id, []byte(`{}`)); err != nil {
+			t.Error("Test failed - ", id, err)
+		}
+	}
+	for _, id := range []string{"", ".", "..", ".hidden", "~hash", "a/b", `a\b`, "a:b", "nul\x00", strings.Repeat("x", 250)} {
+		if err := c.Create(id, []byte(`{}`)); err == nil {
+			t.Error("Test failed - accepted", id)
+		}
+	}
+	if _, err := simplejsondb.New(t.TempDir(), &simplejsondb.Options{IDPolicy: simplejsondb.StrictFilename, HashLongIDs: true}); !errors.Is(err, simplejsondb.ErrIncompatibleOptions) {
+		t.Error("Test failed - ", err)
+	}
+
+	_, collection = dbtest.Open(t, dir, &simplejsondb.Options{IDPolicy: simplejsondb.HashedIDs})
+	h := collection("hashed")
+	for _, id := range []string{"a/b", "..", "short"} {
+		if err := h.Create(id, []byte(`"`+id+`"`)); err != nil {
+			t.Error("Test failed - ", id, err)
+		}
+		dbtest.RequireRecord(t, h, id, []byte(`"`+id+`"`))
+	}
+	if keys := h.Keys(); len(keys) != 3 || keys[0] != ".." || keys[1] != "a/b" {
+		t.Error("Test failed - ", keys)
+	}
+	entries, _ := os.ReadDir(filepath.Join(dir, "hashed"))
+	for _, e := range entries {
+		if !e.IsDir() && !strings.HasPrefix(e.Name(), "~") && e.Name() != ".layout.json" {
+			t.Error("Test failed - unhashed file", e.Name())
+		}
+	}
+}
+
+// a short id spelling the hashed name of a long one must not alias it
+func TestHashedNameNotAnID(t *testing.T) {
+	_, collection := dbtest.NewDB(t, &simplejsondb.Options{HashLongIDs: true, MaxNameLength: 100})
+	c := collection("ids")
+	long := strings.Repeat("x", 120)
+	if err := c.Create(long, []byte(`"long"`)); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte(long))
+	if err := c.Create("~"+hex.EncodeToString(sum[:]), []byte(`"short"`)); !errors.Is(err, simplejsondb.ErrInvalidID) {
+		t.Error("Test failed - ", err)
+	}
+	dbtest.RequireRecord(t, c, long, []byte(`"long"`))
+}
+
+func TestHashedIDsStayInCollection(t *testing.T) {
+	_, collection := dbtest.NewDB(t, &simplejsondb.Options{IDPolicy: simplejsondb.HashedIDs, CacheBytes: 1 << 20})
+	a, b := collection("a"), collection("b")
+	if err := a.Create("../b/x", []byte(`{"from": "a"}`)); err != nil {
+		t.Fatal(err)
+	}
+	dbtest.RequireRecord(t, a, "../b/x", []byte(`{"from": "a"}`))
+	if data, err := b.Get("x"); !errors.Is(err, simplejsondb.ErrRecordNotFound) {
+		t.Error("Test failed - read collection a through b", string(data), err)
+	}
+	if err := b.Create("x", []byte(`{"from": "b"}`)); err != nil {
+		t.Fatal(err)
+	}
+	dbtest.RequireRecord(t, b, "x", []byte(`{"from": "b"}`))
+	dbtest.RequireRecord(t, a, "../b/x", []byte(`{"from": "a"}`))
+}
@@ -0,0 +1,46 @@
+package simplejsondb
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// defaultIgnores - foreign files commonly dropped into data directories
+var defaultIgnores = []string{
+	"*~",
+	"*.swp",
+	"*.swo",
+	"#*#",
+	".DS_Store",
+	"Thumbs.db",
+	"desktop.ini",
+}
+
+// ignorePatterns - validates patterns and merges them with the defaults
+func ignorePatterns(patterns []string, noDefaults bool) ([]string, error) {
+	var merged []string
+	if !noDefaults {
+		merged = append(merged, defaultIgnores...)
+	}
+	for _, p := range patterns {
+		if _, err := filepath.Match(p, ""); err != nil {
+			return nil, err
+		}
+		merged = append(merged, p)
+	}
+	return merged, nil
+}
+
+// isIgnored - whether a directory entry is internal or foreign rather
+// than a record, patterns only ever see the bare file name
+func (c *_collection) isIgnored(name string) bool {
+	if strings.HasPrefix(name, ".") {
+		return true
+	}
+	for _, p := range c.ignore {
+		if ok, _ := filepath.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
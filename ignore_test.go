@@ -0,0 +1,58 @@
+package simplejsondb_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestIgnorePatterns(t *testing.T) {
+	root := t.TempDir()
+	db, collection := dbtest.Open(t, root, &simplejsondb.Options{IgnorePatterns: []string{"*.orig.json"}})
+	c := collection("foreign")
+	if err := c.Create("real", []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(root, "foreign")
+	for _, name := range []string{"real.json~", ".real.json.swp", "#real.json#", ".DS_Store", "Thumbs.db", "desktop.ini", "real.orig.json"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(`{}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	keys, err := c.KeysPrefix("", 0, "")
+	if err != nil || len(keys) != 1 || keys[0] != "real" {
+		t.Error("Test failed - ", keys, err)
+	}
+	if n, err := c.LenPrefix(""); err != nil || n != 1 {
+		t.Error("Test failed - ", n, err)
+	}
+	if records := c.GetAll(); len(records) != 1 {
+		t.Error("Test failed - ", len(records))
+	}
+	// ignored names are hidden from listings, not from direct reads
+	if data, err := c.Get("real.orig"); err != nil || string(data) != `{}` {
+		t.Error("Test failed - ", string(data), err)
+	}
+
+	// without the defaults only the custom pattern applies
+	db, err = simplejsondb.New(root, &simplejsondb.Options{IgnorePatterns: []string{"*.json~"}, NoDefaultIgnores: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err = db.Collection("foreign")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys, err = c.KeysPrefix("", 0, ""); err != nil || len(keys) != 2 {
+		t.Error("Test failed - ", keys, err)
+	}
+
+	_, err = simplejsondb.New(root, &simplejsondb.Options{IgnorePatterns: []string{"["}})
+	if err != filepath.ErrBadPattern {
+		t.Error("Test failed - ", err)
+	}
+}
@@ -0,0 +1,94 @@
+package simplejsondb
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// ErrNotNumber - Increment found a value which is not a JSON number
+var ErrNotNumber = errors.New("field is not a number")
+
+// Increment - adds delta to the number at an RFC 6901 pointer of the
+// record and returns the new value
+//
+// A missing record starts as {} and missing members as delta, objects on
+// the way are created as needed. The record is rewritten under its
+// exclusive lock like Update, numbers elsewhere keep their precision.
+func (c *_collection) Increment(key, pointer string, delta float64) (float64, error) {
+	return increment(c, key, pointer, delta)
+}
+
+// Increment - adds delta to the visible number, writing the overlay
+func (c *_overlayCollection) Increment(key, pointer string, delta float64) (float64, error) {
+	return increment(c, key, pointer, delta)
+}
+
+func increment(c Collection, key, pointer string, delta float64) (float64, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return 0, err
+	}
+	var result float64
+	err = c.Update(key, func(current []byte) ([]byte, error) {
+		var record any
+		if current == nil {
+			record = map[string]any{}
+		} else {
+			d := json.NewDecoder(bytes.NewReader(current))
+			d.UseNumber()
+			if err := d.Decode(&record); err != nil {
+				return nil, fmt.Errorf("record %s is not JSON: %w", key, err)
+			}
+		}
+		record, result, err = addAt(record, tokens, delta)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q in %s", err, pointer, key)
+		}
+		return json.Marshal(record)
+	})
+	return result, err
+}
+
+// addAt - v with delta added at tokens, nil stands for a missing value
+func addAt(v any, tokens []string, delta float64) (any, float64, error) {
+	if len(tokens) == 0 {
+		switch n := v.(type) {
+		case nil:
+			return delta, delta, nil
+		case json.Number:
+			f, err := n.Float64()
+			if err != nil {
+				return nil, 0, ErrNotNumber
+			}
+			return f + delta, f + delta, nil
+		}
+		return nil, 0, ErrNotNumber
+	}
+	switch container := v.(type) {
+	case nil:
+		return addAt(map[string]any{}, tokens, delta)
+	case map[string]any:
+		value, result, err := addAt(container[tokens[0]], tokens[1:], delta)
+		if err != nil {
+			return nil, 0, err
+		}
+		container[tokens[0]] = value
+		return container, result, nil
+	case []any:
+		// arrays are never grown, the element has to exist
+		index, err := strconv.Atoi(tokens[0])
+		if err != nil || index < 0 || index >= len(container) || (len(tokens[0]) > 1 && tokens[0][0] == '0') {
+			return nil, 0, ErrFieldNotFound
+		}
+		value, result, err := addAt(container[index], tokens[1:], delta)
+		if err != nil {
+			return nil, 0, err
+		}
+		container[index] = value
+		return container, result, nil
+	}
+	return nil, 0, ErrNotNumber
+}
@@ -0,0 +1,83 @@
+package simplejsondb_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestIncrement(t *testing.T) {
+	_, collection := dbtest.NewDB(t, nil)
+	c := collection("counters")
+
+	n, err := c.Increment("page", "/views/home", 1)
+	if err != nil || n != 1 {
+		t.Error("Test failed - ", n, err)
+	}
+	dbtest.RequireRecord(t, c, "page", []byte(`{"views":{"home":1}}`))
+	n, err = c.Increment("page", "/views/home", 2.5)
+	if err != nil || n != 3.5 {
+		t.Error("Test failed - ", n, err)
+	}
+
+	dbtest.Seed(t, c, map[string][]byte{
+		"big":  []byte(`{"id": 12345678901234567890, "hits": [1, "x"]}`),
+		"root": []byte(`7`),
+	})
+	n, err = c.Increment("big", "/hits/0", -1)
+	if err != nil || n != 0 {
+		t.Error("Test failed - ", n, err)
+	}
+	dbtest.RequireRecord(t, c, "big", []byte(`{"hits":[0,"x"],"id":12345678901234567890}`))
+	if _, err = c.Increment("big", "/hits/1", 1); !errors.Is(err, simplejsondb.ErrNotNumber) {
+		t.Error("Test failed - ", err)
+	}
+	if _, err = c.Increment("big", "/id/x", 1); !errors.Is(err, simplejsondb.ErrNotNumber) {
+		t.Error("Test failed - ", err)
+	}
+	if _, err = c.Increment("big", "/hits/2", 1); !errors.Is(err, simplejsondb.ErrFieldNotFound) {
+		t.Error("Test failed - ", err)
+	}
+	if _, err = c.Increment("big", "hits", 1); err == nil {
+		t.Error("Test failed - invalid pointer accepted")
+	}
+	n, err = c.Increment("root", "", 1)
+	if err != nil || n != 8 {
+		t.Error("Test failed - ", n, err)
+	}
+}
+
+func TestIncrementConcurrent(t *testing.T) {
+	_, collection := dbtest.NewDB(t, nil)
+	c := collection("counters")
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.Increment("page", "/views", 1); err != nil {
+				t.Error("Test failed - ", err)
+			}
+		}()
+	}
+	wg.Wait()
+	dbtest.RequireRecord(t, c, "page", []byte(`{"views":50}`))
+}
+
+func ExampleCollection_Increment() {
+	dir, _ := os.MkdirTemp("", "example")
+	defer os.RemoveAll(dir)
+	db, _ := simplejsondb.New(dir, nil)
+	defer db.Close()
+	c, _ := db.Collection("counters")
+
+	c.Increment("page", "/views", 1)
+	views, _ := c.Increment("page", "/views", 1)
+	fmt.Println(views)
+	// Output: 2
+}
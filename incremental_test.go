@@ -0,0 +1,91 @@
+package simplejsondb_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestIncrementalExport(t *testing.T) {
+	dir := t.TempDir()
+	src, collection := dbtest.Open(t, dir, nil)
+	for _, name := range []string{"users", "orders"} {
+		c := collection(name)
+		for i := 0; i < 10; i++ {
+			err := c.Create(fmt.Sprint(name, i), []byte(fmt.Sprintf(`{"n": %d}`, i)), simplejsondb.CreateOptions{UseGzip: i%3 == 0})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	// age the seeded files so they sit clearly before any cutoff
+	old := time.Now().Add(-time.Hour)
+	_ = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			_ = os.Chtimes(path, old, old)
+		}
+		return nil
+	})
+
+	var base bytes.Buffer
+	full, err := src.ExportChangedSince(&base, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !full.Cutoff.After(old) {
+		t.Error("Test failed - cutoff", full.Cutoff)
+	}
+
+	users, _ := src.Collection("users")
+	orders, _ := src.Collection("orders")
+	_ = users.Create("users1", []byte(`{"n": "changed"}`))
+	_ = users.Create("users-new", []byte(`{"n": "new"}`))
+	_ = orders.Delete("orders3")
+	_ = orders.Delete("orders0")
+
+	var incr bytes.Buffer
+	next, err := src.ExportChangedSince(&incr, full.Cutoff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !next.Since.Equal(full.Cutoff) || next.Cutoff.Before(full.Cutoff) {
+		t.Error("Test failed - manifest", next)
+	}
+	if n := strings.Count(incr.String(), `"type":"record"`); n != 2 {
+		t.Error("Test failed - expected only the changed records", n)
+	}
+
+	dst, _ := dbtest.NewDB(t, &simplejsondb.Options{UseGzip: true})
+	if _, err = dst.ApplyIncremental(&base); err != nil {
+		t.Fatal(err)
+	}
+	applied, err := dst.ApplyIncremental(&incr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !applied.Cutoff.Equal(next.Cutoff) {
+		t.Error("Test failed - ", applied)
+	}
+
+	for _, name := range []string{"users", "orders"} {
+		want, _ := src.Collection(name)
+		got, _ := dst.Collection(name)
+		if fmt.Sprint(want.Keys()) != fmt.Sprint(got.Keys()) {
+			t.Error("Test failed - ", name, want.Keys(), got.Keys())
+		}
+		for _, key := range want.Keys() {
+			a, _ := want.Get(key)
+			b, err := got.Get(key)
+			if err != nil || !bytes.Equal(a, b) {
+				t.Error("Test failed - ", key, string(a), string(b), err)
+			}
+		}
+	}
+}
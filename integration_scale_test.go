@@ -0,0 +1,8 @@
+//go:build !slow
+
+package simplejsondb_test
+
+// integrationScale - sizes of TestIntegration fit for every test run
+var integrationScale = struct {
+	users, writers, orders, readers int
+}{users: 50, writers: 4, orders: 50, readers: 2}
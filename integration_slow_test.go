@@ -0,0 +1,9 @@
+//go:build slow
+
+package simplejsondb_test
+
+// integrationScale - sizes of TestIntegration for nightly runs with
+// -tags slow
+var integrationScale = struct {
+	users, writers, orders, readers int
+}{users: 2000, writers: 16, orders: 1000, readers: 4}
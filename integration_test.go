@@ -0,0 +1,228 @@
+package simplejsondb_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+type (
+	user struct {
+		Name string `json:"name"`
+	}
+
+	order struct {
+		User   string `json:"user"`
+		Amount int    `json:"amount"`
+		Status string `json:"status"`
+	}
+)
+
+// TestIntegration - an orders/users/events workload with concurrent
+// writers, paginating readers, expiring sessions and backups taken while
+// writing, followed by a check of the invariants; scale is raised by the
+// slow build tag
+func TestIntegration(t *testing.T) {
+	const sessionTTL = 20 * time.Millisecond
+	dir := t.TempDir()
+	db, collection := dbtest.Open(t, dir, &simplejsondb.Options{CacheBytes: 1 << 20, DeleteExpired: true})
+	users, orders, events, sessions := collection("users"), collection("orders"), collection("events"), collection("sessions")
+
+	batch := make(map[string][]byte, integrationScale.users)
+	for i := 0; i < integrationScale.users; i++ {
+		data, _ := json.Marshal(user{Name: fmt.Sprint("user ", i)})
+		batch[fmt.Sprintf("user-%04d", i)] = data
+	}
+	if errs := users.CreateMany(batch); len(errs) != 0 {
+		t.Fatal("Test failed - ", errs)
+	}
+
+	var (
+		writers sync.WaitGroup
+		others  sync.WaitGroup
+		done    = make(chan struct{})
+	)
+	for w := 0; w < integrationScale.writers; w++ {
+		writers.Add(1)
+		go func(w int) {
+			defer writers.Done()
+			rnd := rand.New(rand.NewSource(int64(w)))
+			for i := 0; i < integrationScale.orders; i++ {
+				id := fmt.Sprintf("order-%02d-%04d", w, i)
+				data, _ := json.Marshal(order{User: fmt.Sprintf("user-%04d", rnd.Intn(integrationScale.users)), Amount: 1 + rnd.Intn(100), Status: "new"})
+				if err := orders.CreateNX(id, data); err != nil {
+					t.Error(err)
+					return
+				}
+				if _, err := events.CreateAuto([]byte(`{"order":"` + id + `"}`)); err != nil {
+					t.Error(err)
+				}
+				err := orders.Merge(id, []byte(`{"status":"paid"}`))
+				if err != nil {
+					t.Error(err)
+				}
+				if err = sessions.Create(fmt.Sprintf("session-%02d-%04d", w, i), []byte(`{}`), simplejsondb.CreateOptions{TTL: sessionTTL}); err != nil {
+					t.Error(err)
+				}
+			}
+		}(w)
+	}
+
+	// readers page through the orders while they are written
+	for r := 0; r < integrationScale.readers; r++ {
+		others.Add(1)
+		go func() {
+			defer others.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				after := ""
+				for {
+					ids, err := orders.KeysPrefix("order-", 50, after)
+					if err != nil || len(ids) == 0 {
+						break
+					}
+					records, err := orders.GetMany(ids...)
+					if err != nil && len(records) == 0 {
+						t.Error(err)
+					}
+					for id, data := range records {
+						var o order
+						if err = json.Unmarshal(data, &o); err != nil {
+							t.Error("Test failed - torn record", id, err)
+						}
+					}
+					after = ids[len(ids)-1]
+				}
+			}
+		}()
+	}
+
+	// Keys hides expired sessions, so the sweeper reads every session id
+	// which may exist and the reads remove the expired ones
+	sweep := func() {
+		for w := 0; w < integrationScale.writers; w++ {
+			for i := 0; i < integrationScale.orders; i++ {
+				_, _ = sessions.Get(fmt.Sprintf("session-%02d-%04d", w, i))
+			}
+		}
+	}
+	others.Add(1)
+	go func() {
+		defer others.Done()
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(sessionTTL):
+			}
+			sweep()
+		}
+	}()
+
+	// backups are taken while writing
+	backups := t.TempDir()
+	others.Add(1)
+	go func() {
+		defer others.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+			archive := filepath.Join(backups, fmt.Sprint("backup", i, ".sjdb"))
+			if err := db.ExportIndexed(archive); err != nil {
+				t.Error(err)
+				continue
+			}
+			verifyArchive(t, archive)
+		}
+	}()
+
+	writers.Wait()
+	close(done)
+	others.Wait()
+
+	// invariants
+	total := integrationScale.writers * integrationScale.orders
+	ids := orders.Keys()
+	if len(ids) != total {
+		t.Error("Test failed - orders", len(ids), total)
+	}
+	for _, id := range ids {
+		var o order
+		data, err := orders.Get(id)
+		if err == nil {
+			err = json.Unmarshal(data, &o)
+		}
+		if err != nil || o.Status != "paid" || o.Amount < 1 {
+			t.Error("Test failed - ", id, string(data), err)
+			continue
+		}
+		if _, err = users.Get(o.User); err != nil {
+			t.Error("Test failed - dangling user", id, o.User, err)
+		}
+	}
+	if n := len(events.Keys()); n != total {
+		t.Error("Test failed - events", n, total)
+	}
+	time.Sleep(sessionTTL)
+	if keys := sessions.Keys(); len(keys) != 0 {
+		t.Error("Test failed - sessions outlived their TTL", len(keys))
+	}
+	sweep()
+	if files, _ := filepath.Glob(filepath.Join(dir, "sessions", "session-*")); len(files) != 0 {
+		t.Error("Test failed - expired sessions not swept", len(files))
+	}
+	for _, c := range []simplejsondb.Collection{users, orders, events, sessions} {
+		if q := c.Quarantined(); len(q) != 0 {
+			t.Error("Test failed - corrupt records", c.Name(), q)
+		}
+	}
+	if db.Degraded() {
+		t.Error("Test failed - degraded")
+	}
+}
+
+// verifyArchive - every archived order refers to an archived user
+func verifyArchive(t *testing.T, path string) {
+	a, err := simplejsondb.OpenArchive(path)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer a.Close()
+	ids, err := a.List("orders")
+	if err != nil {
+		// taken before the first order
+		return
+	}
+	for _, id := range ids {
+		if !strings.HasPrefix(id, "order-") {
+			continue
+		}
+		var o order
+		data, err := a.Get("orders", id)
+		if err == nil {
+			err = json.Unmarshal(data, &o)
+		}
+		if err == nil {
+			_, err = a.Get("users", o.User)
+		}
+		if err != nil {
+			t.Error("Test failed - backup", path, id, err)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package simplejsondb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// _jsonlLine - one record of a JSON Lines dump, Raw holds a body which is
+// not valid JSON
+type _jsonlLine struct {
+	ID   string          `json:"_id"`
+	Data json.RawMessage `json:"data,omitempty"`
+	Raw  []byte          `json:"_raw,omitempty"`
+}
+
+// ExportJSONL - writes every record as a {"_id": ..., "data": ...} line
+//
+// Records are written compact in id order, gzip ones decompressed. A body
+// which is not valid JSON is written base64 encoded under _raw instead of
+// data so nothing is dropped.
+func (c *_collection) ExportJSONL(w io.Writer) error {
+	return exportJSONL(c, w)
+}
+
+// ExportJSONL - writes every visible record as a JSON line
+func (c *_overlayCollection) ExportJSONL(w io.Writer) error {
+	return exportJSONL(c, w)
+}
+
+// ImportJSONL - creates a record from every line of an ExportJSONL dump,
+// stopping at the first line which fails
+func (c *_collection) ImportJSONL(r io.Reader) error {
+	return importJSONL(c, r)
+}
+
+// ImportJSONL - creates the records of a JSON Lines dump in the overlay
+func (c *_overlayCollection) ImportJSONL(r io.Reader) error {
+	return importJSONL(c, r)
+}
+
+func exportJSONL(c Collection, w io.Writer) error {
+	out := bufio.NewWriter(w)
+	enc := json.NewEncoder(out)
+	var compact bytes.Buffer
+	err := c.Each(func(id string, data []byte) error {
+		line := _jsonlLine{ID: id}
+		compact.Reset()
+		if json.Compact(&compact, data) == nil {
+			line.Data = compact.Bytes()
+		} else {
+			line.Raw = data
+		}
+		return enc.Encode(line)
+	})
+	if err != nil {
+		return err
+	}
+	return out.Flush()
+}
+
+func importJSONL(c Collection, r io.Reader) error {
+	dec := json.NewDecoder(r)
+	for n := 1; ; n++ {
+		var line _jsonlLine
+		err := dec.Decode(&line)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("line %d: %w", n, err)
+		}
+		data := []byte(line.Data)
+		if line.Raw != nil {
+			data = line.Raw
+		}
+		if line.ID == "" || data == nil {
+			return fmt.Errorf("line %d: _id and data or _raw are required", n)
+		}
+		if err = c.Create(line.ID, data); err != nil {
+			return fmt.Errorf("line %d: %w", n, err)
+		}
+	}
+}
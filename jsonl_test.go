@@ -0,0 +1,48 @@
+package simplejsondb_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestJSONL(t *testing.T) {
+	_, collection := dbtest.NewDB(t, nil)
+	src := collection("src")
+	dbtest.Seed(t, src, map[string][]byte{
+		"pretty": []byte("{\n  \"a\": [1, 2]\n}\n"),
+		"broken": []byte(`{"a": `),
+	})
+	if err := src.Create("zipped", []byte(`{"z": 1}`), simplejsondb.CreateOptions{UseGzip: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := src.ExportJSONL(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"_id":"broken","_raw":"eyJhIjog"}
+{"_id":"pretty","data":{"a":[1,2]}}
+{"_id":"zipped","data":{"z":1}}
+`
+	if buf.String() != want {
+		t.Error("Test failed - ", buf.String())
+	}
+
+	dst := collection("dst")
+	if err := dst.ImportJSONL(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	dbtest.RequireRecord(t, dst, "broken", []byte(`{"a": `))
+	dbtest.RequireRecord(t, dst, "pretty", []byte(`{"a":[1,2]}`))
+	dbtest.RequireRecord(t, dst, "zipped", []byte(`{"z":1}`))
+
+	err := dst.ImportJSONL(strings.NewReader(`{"_id":"ok","data":{}}` + "\n" + `{"data":{}}` + "\n"))
+	if err == nil || !strings.HasPrefix(err.Error(), "line 2:") {
+		t.Error("Test failed - ", err)
+	}
+	dbtest.RequireRecord(t, dst, "ok", []byte(`{}`))
+}
@@ -0,0 +1,30 @@
+package simplejsondb_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestKeys(t *testing.T) {
+	root := t.TempDir()
+	_, collection := dbtest.Open(t, root, nil)
+	c := collection("listing")
+	if keys := c.Keys(); len(keys) != 0 {
+		t.Error("Test failed - ", keys)
+	}
+	dbtest.Seed(t, c, map[string][]byte{"b": []byte(`{}`), "a": []byte(`{}`), "c": []byte(`{}`)})
+	dir := filepath.Join(root, "listing")
+	// a stale gzip duplicate, a temp file and foreign files
+	_ = os.WriteFile(filepath.Join(dir, "a.json.gz"), []byte("x"), 0644)
+	_ = os.WriteFile(filepath.Join(dir, ".tmp-abc"), []byte("x"), 0644)
+	_ = os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("x"), 0644)
+	_ = os.Mkdir(filepath.Join(dir, "d.json"), os.ModePerm)
+
+	keys := c.Keys()
+	if len(keys) != 3 || keys[0] != "a" || keys[1] != "b" || keys[2] != "c" {
+		t.Error("Test failed - ", keys)
+	}
+}
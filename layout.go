@@ -0,0 +1,106 @@
+package simplejsondb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// LayoutVersion - newest collection layout this package understands
+const LayoutVersion = 1
+
+// layoutFile - per collection descriptor of the structural features in use
+var layoutFile string = ".layout.json"
+
+// ErrIncompatibleLayout - a collection uses a layout this package cannot read
+var ErrIncompatibleLayout = errors.New("incompatible layout")
+
+const featureWhiteouts = "whiteouts"
+
+// layoutFeatures - structural features this package supports
+var layoutFeatures = map[string]bool{
+	featureWhiteouts: true,
+	featureExpiry:    true,
+	featureChecksums: true,
+	featureHashedIDs: true,
+}
+
+type _layout struct {
+	Version   int      `json:"version"`
+	Features  []string `json:"features,omitempty"`
+	WrittenBy string   `json:"written_by,omitempty"`
+}
+
+// readLayout - loads the collection descriptor, collections without one
+// are version 0 plain directories
+func readLayout(dir string) (layout _layout, err error) {
+	data, err := os.ReadFile(filepath.Join(dir, layoutFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return layout, nil
+	}
+	if err != nil {
+		return layout, err
+	}
+	err = json.Unmarshal(data, &layout)
+	if err != nil {
+		return layout, fmt.Errorf("%w: unreadable %s: %w", ErrIncompatibleLayout, layoutFile, err)
+	}
+	return layout, nil
+}
+
+// checkLayout - refuses collections written with an unknown layout
+func checkLayout(dir string) (layout _layout, err error) {
+	layout, err = readLayout(dir)
+	if err != nil {
+		return layout, err
+	}
+	if layout.Version > LayoutVersion {
+		return layout, fmt.Errorf("%w: version %d, supported up to %d", ErrIncompatibleLayout, layout.Version, LayoutVersion)
+	}
+	for _, feature := range layout.Features {
+		if !layoutFeatures[feature] {
+			return layout, fmt.Errorf("%w: unsupported feature %q", ErrIncompatibleLayout, feature)
+		}
+	}
+	return layout, nil
+}
+
+// has - whether the collection uses feature
+func (l _layout) has(feature string) bool {
+	for _, f := range l.Features {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
+
+// requireLayout - records a structural feature in the collection descriptor
+func requireLayout(dir string, feature string) error {
+	layout, err := readLayout(dir)
+	if err != nil {
+		return err
+	}
+	for _, f := range layout.Features {
+		if f == feature && layout.Version == LayoutVersion {
+			return nil
+		}
+	}
+	features := map[string]bool{feature: true}
+	for _, f := range layout.Features {
+		features[f] = true
+	}
+	layout = _layout{Version: LayoutVersion, WrittenBy: Version}
+	for f := range features {
+		layout.Features = append(layout.Features, f)
+	}
+	sort.Strings(layout.Features)
+	data, err := json.Marshal(layout)
+	if err != nil {
+		return err
+	}
+	return writeAtomic(filepath.Join(dir, layoutFile), data, 0644)
+}
@@ -0,0 +1,91 @@
+package simplejsondb_test
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestLayoutRefusal(t *testing.T) {
+	root := t.TempDir()
+	db, _ := dbtest.Open(t, root, nil)
+	if _, err := db.Collection("plain"); err != nil {
+		t.Error("Test failed - version 0 collection", err)
+	}
+
+	descriptors := map[string]string{
+		"future":  `{"version": 99}`,
+		"sharded": `{"version": 1, "features": ["sharding"]}`,
+		"garbage": `{`,
+	}
+	for name, descriptor := range descriptors {
+		dir := filepath.Join(root, name)
+		_ = os.MkdirAll(dir, os.ModePerm)
+		err := os.WriteFile(filepath.Join(dir, ".layout.json"), []byte(descriptor), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = db.Collection(name)
+		if !errors.Is(err, simplejsondb.ErrIncompatibleLayout) {
+			t.Error("Test failed - ", name, err)
+		}
+	}
+	_, err := db.Collection("sharded")
+	if err == nil || !strings.Contains(err.Error(), "sharding") {
+		t.Error("Test failed - missing feature not named", err)
+	}
+}
+
+func TestLayoutOverlayDescriptor(t *testing.T) {
+	base, overlay := t.TempDir(), t.TempDir()
+	db, _ := newOverlay(t, base, overlay)
+	c, err := db.Collection("collection1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(overlay, "collection1", ".layout.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var layout struct {
+		Version  int      `json:"version"`
+		Features []string `json:"features"`
+	}
+	err = json.Unmarshal(data, &layout)
+	if err != nil || layout.Version != simplejsondb.LayoutVersion || len(layout.Features) != 1 || layout.Features[0] != "whiteouts" {
+		t.Error("Test failed - ", string(data), err)
+	}
+	if _, err = os.Stat(filepath.Join(base, "collection1", ".layout.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Error("Test failed - descriptor written into the base", err)
+	}
+	if len(c.GetAll()) != 3 {
+		t.Error("Test failed - descriptor listed as a record")
+	}
+}
+
+// the first hashed id is recorded so older readers refuse the collection
+func TestLayoutHashedIDs(t *testing.T) {
+	root := t.TempDir()
+	_, collection := dbtest.Open(t, root, &simplejsondb.Options{MaxNameLength: 80, HashLongIDs: true})
+	c := collection("hashed")
+	if err := c.Create("short", []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+	descriptor := filepath.Join(root, "hashed", ".layout.json")
+	if _, err := os.Stat(descriptor); !errors.Is(err, os.ErrNotExist) {
+		t.Error("Test failed - descriptor without a hashed id", err)
+	}
+	if err := c.Create(strings.Repeat("long", 30), []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(descriptor)
+	if err != nil || !strings.Contains(string(data), `"hashed_ids"`) {
+		t.Error("Test failed - ", string(data), err)
+	}
+}
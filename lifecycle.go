@@ -0,0 +1,74 @@
+package simplejsondb
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// ErrClosed - the database was closed
+var ErrClosed = errors.New("database closed")
+
+// closePoll - how often Close checks for operations still running
+const closePoll = time.Millisecond
+
+// _lifecycle - open state of a database, shared by its collection handles
+type _lifecycle struct {
+	closed   atomic.Bool
+	inflight atomic.Int64
+}
+
+// begin - registers a running operation, refused once closed
+func (l *_lifecycle) begin() error {
+	l.inflight.Add(1)
+	if l.closed.Load() {
+		l.inflight.Add(-1)
+		return ErrClosed
+	}
+	return nil
+}
+
+// end - an operation registered by begin finished
+func (l *_lifecycle) end() {
+	l.inflight.Add(-1)
+}
+
+// close - refuses new operations and waits for the running ones, false
+// when already closed
+func (l *_lifecycle) close() bool {
+	if l.closed.Swap(true) {
+		return false
+	}
+	for l.inflight.Load() > 0 {
+		time.Sleep(closePoll)
+	}
+	return true
+}
+
+// Close - refuses further use of the database and its collections with
+// ErrClosed, waits for running operations and drops its cached state
+//
+// Writes are synchronous so nothing is pending once the running
+// operations returned, which also releases every record lock they held
+// as the lock registry only keeps locks in use. The LOCK file of
+// ExclusiveOwner is removed last. Closing twice is a no-op.
+func (db *_db) Close() error {
+	if !db.life.close() {
+		return nil
+	}
+	db.cache.removeDir(db.path)
+	db.quarantine.clearRoot(db.path)
+	db.indexMu.Lock()
+	db.indexes = make(map[string]*_pathIndex)
+	db.expiry = make(map[string]*_expiries)
+	db.records = make(map[string]*_recordCount)
+	db.usage = make(map[string]*_usage)
+	db.indexMu.Unlock()
+	return db.owner.release()
+}
+
+// Close - closes the overlay layer, base stays open as it is not owned
+// by the overlay
+func (o *_overlay) Close() error {
+	return o.upper.Close()
+}
@@ -0,0 +1,100 @@
+package simplejsondb_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestClose(t *testing.T) {
+	db, collection := dbtest.NewDB(t, &simplejsondb.Options{CacheBytes: 1 << 20})
+	c := collection("users")
+	dbtest.SeedN(t, c, 3)
+	dbtest.RequireRecord(t, c, "record0", []byte(`{"n": 0}`))
+
+	// a write in flight finishes before Close returns
+	started, finish := make(chan bool), make(chan bool)
+	restore := simplejsondb.SetWriteFile(func(name string, data []byte, perm os.FileMode) error {
+		started <- true
+		<-finish
+		return os.WriteFile(name, data, 0644)
+	})
+	written := make(chan error)
+	go func() { written <- c.Create("late", []byte(`{}`)) }()
+	<-started
+	closed := make(chan error)
+	go func() { closed <- db.Close() }()
+	select {
+	case <-closed:
+		t.Error("Test failed - Close did not wait for the running write")
+	case <-time.After(20 * time.Millisecond):
+	}
+	finish <- true
+	if err := <-written; err != nil {
+		t.Error("Test failed - ", err)
+	}
+	if err := <-closed; err != nil {
+		t.Error("Test failed - ", err)
+	}
+	restore()
+
+	if err := db.Close(); err != nil {
+		t.Error("Test failed - second Close", err)
+	}
+	if n := simplejsondb.LockCount(); n != 0 {
+		t.Error("Test failed - locks left registered", n)
+	}
+	if s := db.Stats().Cache; s.Entries != 0 {
+		t.Error("Test failed - cached records kept", s.Entries)
+	}
+
+	if _, err := c.Get("record0"); !errors.Is(err, simplejsondb.ErrClosed) {
+		t.Error("Test failed - Get", err)
+	}
+	if err := c.Create("record9", []byte(`{}`)); !errors.Is(err, simplejsondb.ErrClosed) {
+		t.Error("Test failed - Create", err)
+	}
+	if err := c.Delete("record0"); !errors.Is(err, simplejsondb.ErrClosed) {
+		t.Error("Test failed - Delete", err)
+	}
+	if _, err := c.LenPrefix(""); !errors.Is(err, simplejsondb.ErrClosed) {
+		t.Error("Test failed - LenPrefix", err)
+	}
+	if _, err := c.WaitFor(context.Background(), "record0"); !errors.Is(err, simplejsondb.ErrClosed) {
+		t.Error("Test failed - WaitFor", err)
+	}
+	if _, err := db.Collection("users"); !errors.Is(err, simplejsondb.ErrClosed) {
+		t.Error("Test failed - Collection", err)
+	}
+	if err := db.DropCollection("users"); !errors.Is(err, simplejsondb.ErrClosed) {
+		t.Error("Test failed - DropCollection", err)
+	}
+	if db.HasCollection("users") {
+		t.Error("Test failed - HasCollection after close")
+	}
+}
+
+func TestCloseOverlay(t *testing.T) {
+	base, overlay := t.TempDir(), t.TempDir()
+	db, bc := newOverlay(t, base, overlay)
+	c, err := db.Collection("collection1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.Get("key1"); !errors.Is(err, simplejsondb.ErrClosed) {
+		t.Error("Test failed - base read through a closed overlay", err)
+	}
+	if err = c.Create("key4", []byte(`{}`)); !errors.Is(err, simplejsondb.ErrClosed) {
+		t.Error("Test failed - ", err)
+	}
+	// the base belongs to the caller and stays open
+	dbtest.RequireRecord(t, bc, "key1", []byte(`{"layer": "base"}`))
+}
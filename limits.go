@@ -0,0 +1,225 @@
+package simplejsondb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// ErrCollectionFull - a new id would take the collection over MaxRecords
+var ErrCollectionFull = errors.New("collection full")
+
+type (
+	// CollectionStats - usage of a collection, DB.Stats fills Records and
+	// MaxRecords from its counts while Collection.Stats walks the
+	// directory for everything
+	CollectionStats struct {
+		Records    uint64 `json:"records"`
+		MaxRecords uint64 `json:"max_records"`
+		// Bytes - size of the record files on disk
+		Bytes uint64 `json:"bytes,omitempty"`
+		// Largest - id and file size of the largest record
+		LargestID    string `json:"largest_id,omitempty"`
+		LargestBytes int64  `json:"largest_bytes,omitempty"`
+		// Oldest and Newest - modification times of the least and most
+		// recently written records
+		Oldest time.Time `json:"oldest,omitempty"`
+		Newest time.Time `json:"newest,omitempty"`
+		// Gzip, Plain and Encoded - records by storage format, Encoded
+		// covers encrypted records and those of other codecs
+		Gzip    uint64 `json:"gzip,omitempty"`
+		Plain   uint64 `json:"plain,omitempty"`
+		Encoded uint64 `json:"encoded,omitempty"`
+		// Unreadable - record files which cannot be stat'ed or are too
+		// short to hold a record in their format, not counted as Records
+		Unreadable uint64 `json:"unreadable,omitempty"`
+		// Quarantined - records refused after repeated corrupt reads
+		Quarantined uint64 `json:"quarantined,omitempty"`
+	}
+
+	// _recordCount - records of a collection directory, shared by its
+	// handles
+	//
+	// With Options.MaxRecords the count is taken when the collection is
+	// first opened, otherwise by the first Len. From then on it is kept up
+	// to date by the writes of this database under their record locks.
+	// Writes of other processes make it drift until RecalculateUsage, Len
+	// notices them by the directory mtime and recounts.
+	_recordCount struct {
+		n     atomic.Uint64
+		limit uint64
+		// loaded - n holds the count, writes only maintain a loaded count
+		loaded atomic.Bool
+		// dirMod - directory mtime once this database's last change to it
+		// was done
+		dirMod atomic.Int64
+	}
+)
+
+// recordCount - the shared record count of a collection directory,
+// counted right away only when a limit is set
+func (db *_db) recordCount(c *_collection) (*_recordCount, error) {
+	db.indexMu.Lock()
+	defer db.indexMu.Unlock()
+	count, ok := db.records[c.path]
+	if ok {
+		return count, nil
+	}
+	count = &_recordCount{limit: db.maxRecords}
+	if db.maxRecords > 0 {
+		mod, err := dirModTime(c.path)
+		if err != nil {
+			return nil, err
+		}
+		keys, err := c.keys()
+		if err != nil {
+			return nil, err
+		}
+		count.n.Store(uint64(len(keys)))
+		count.dirMod.Store(mod)
+		count.loaded.Store(true)
+	}
+	db.records[c.path] = count
+	return count, nil
+}
+
+// dirModTime - the mtime of a directory in nanoseconds
+func dirModTime(dir string) (int64, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return 0, err
+	}
+	return info.ModTime().UnixNano(), nil
+}
+
+// max - the MaxRecords limit, 0 when unlimited
+func (r *_recordCount) max() uint64 {
+	if r == nil {
+		return 0
+	}
+	return r.limit
+}
+
+// reserve - counts a record about to be written, refusing new ids over
+// the limit; added tells whether undo has to run on a failed write. The
+// caller holds the record lock.
+func (r *_recordCount) reserve(c *_collection, key string) (added bool, err error) {
+	if r == nil || !r.loaded.Load() {
+		return false, nil
+	}
+	if _, _, err = c.resolve(key); err == nil {
+		// overwrites never change the count
+		return false, nil
+	}
+	for {
+		n := r.n.Load()
+		if r.limit > 0 && n >= r.limit {
+			return false, fmt.Errorf("%w: %d records, limit %d", ErrCollectionFull, n, r.limit)
+		}
+		if r.n.CompareAndSwap(n, n+1) {
+			return true, nil
+		}
+	}
+}
+
+// removed - uncounts a deleted record, also used to undo a reserve
+func (r *_recordCount) removed() {
+	if r == nil || !r.loaded.Load() {
+		return
+	}
+	for {
+		n := r.n.Load()
+		if n == 0 || r.n.CompareAndSwap(n, n-1) {
+			return
+		}
+	}
+}
+
+// set - replaces the count after a truncate, the caller holds the
+// exclusive collection lock
+func (r *_recordCount) set(n uint64) {
+	if r != nil {
+		r.n.Store(n)
+		r.loaded.Store(true)
+	}
+}
+
+// touched - notes a change of this database to dir, so Len does not take
+// it for one of another process; the caller holds the record lock
+func (r *_recordCount) touched(dir string) {
+	if r == nil || !r.loaded.Load() {
+		return
+	}
+	if mod, err := dirModTime(dir); err == nil {
+		r.dirMod.Store(mod)
+	}
+}
+
+// count - the tracked count, recounted when not taken yet or when the
+// directory changed behind this database
+func (r *_recordCount) count(c *_collection) (uint64, error) {
+	if r != nil && r.loaded.Load() {
+		mod, err := dirModTime(c.path)
+		if err == nil && mod == r.dirMod.Load() {
+			return r.n.Load(), nil
+		}
+	}
+	return r.recount(c)
+}
+
+// recount - counts the records on disk under the exclusive collection
+// lock, so no write of this database is half done
+func (r *_recordCount) recount(c *_collection) (uint64, error) {
+	collection := c.collectionLockPath()
+	acquire(collection, true)
+	defer release(collection, true)
+	mod, err := dirModTime(c.path)
+	if err != nil {
+		return 0, err
+	}
+	keys, err := c.keys()
+	if err != nil {
+		return 0, err
+	}
+	if r != nil {
+		r.n.Store(uint64(len(keys)))
+		r.dirMod.Store(mod)
+		r.loaded.Store(true)
+	}
+	return uint64(len(keys)), nil
+}
+
+// RecalculateUsage - recounts the records on disk, repairing a tracked
+// count drifted by other processes, and returns it; the bytes of Usage
+// are sized again by the next write or Usage
+func (c *_collection) RecalculateUsage() (uint64, error) {
+	c.usage.reset()
+	return c.records.recount(c)
+}
+
+// RecalculateUsage - recounts the records of the overlay layer, which is
+// what MaxRecords limits
+func (c *_overlayCollection) RecalculateUsage() (uint64, error) {
+	return c.upper.RecalculateUsage()
+}
+
+// collectionStats - tracked usage by collection name
+func (db *_db) collectionStats() map[string]CollectionStats {
+	db.indexMu.Lock()
+	defer db.indexMu.Unlock()
+	if len(db.records) == 0 {
+		return nil
+	}
+	stats := make(map[string]CollectionStats, len(db.records))
+	for path, count := range db.records {
+		if count.limit == 0 {
+			// only counted for Len
+			continue
+		}
+		stats[filepath.Base(path)] = CollectionStats{Records: count.n.Load(), MaxRecords: count.limit}
+	}
+	return stats
+}
@@ -0,0 +1,88 @@
+package simplejsondb_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestMaxRecords(t *testing.T) {
+	const limit = 10
+	dir := t.TempDir()
+	db, collection := dbtest.Open(t, dir, &simplejsondb.Options{MaxRecords: limit})
+	c := collection("events")
+	dbtest.SeedN(t, c, 3)
+
+	var wg sync.WaitGroup
+	var created, full int32
+	for i := 0; i < 40; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// handles share the count
+			c, err := db.Collection("events")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			err = c.Create(fmt.Sprint("event", i), []byte(`{}`))
+			switch {
+			case err == nil:
+				atomic.AddInt32(&created, 1)
+			case errors.Is(err, simplejsondb.ErrCollectionFull):
+				atomic.AddInt32(&full, 1)
+			default:
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	// writes of one database never overshoot
+	if created != limit-3 || full != 40-(limit-3) {
+		t.Error("Test failed - ", created, full)
+	}
+	if n := len(c.Keys()); n != limit {
+		t.Error("Test failed - records on disk", n)
+	}
+	if s := db.Stats().Collections["events"]; s.Records != limit || s.MaxRecords != limit {
+		t.Error("Test failed - ", s)
+	}
+
+	// overwrites keep working, deletes make room
+	if err := c.Create("record0", []byte(`{"n": 100}`)); err != nil {
+		t.Error("Test failed - overwrite refused", err)
+	}
+	if _, err := c.UpdateIf("new", func([]byte) (bool, error) { return true, nil }, func([]byte) ([]byte, error) { return []byte(`{}`), nil }); !errors.Is(err, simplejsondb.ErrCollectionFull) {
+		t.Error("Test failed - ", err)
+	}
+	_ = c.Delete("record1")
+	if err := c.Create("new", []byte(`{}`)); err != nil {
+		t.Error("Test failed - ", err)
+	}
+
+	// another process deletes behind our back
+	_ = os.Remove(filepath.Join(dir, "events", "record2.json"))
+	if err := c.Create("newer", []byte(`{}`)); !errors.Is(err, simplejsondb.ErrCollectionFull) {
+		t.Error("Test failed - drift not tracked yet", err)
+	}
+	if n, err := c.RecalculateUsage(); err != nil || n != limit-1 {
+		t.Error("Test failed - ", n, err)
+	}
+	if err := c.Create("newer", []byte(`{}`)); err != nil {
+		t.Error("Test failed - ", err)
+	}
+
+	if err := c.Truncate(); err != nil {
+		t.Fatal(err)
+	}
+	if s := db.Stats().Collections["events"]; s.Records != 0 {
+		t.Error("Test failed - ", s)
+	}
+}
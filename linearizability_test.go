@@ -0,0 +1,105 @@
+package simplejsondb_test
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+type histOp struct {
+	kind       string // create, delete, get
+	key        string
+	value      string // written or observed value, "" when not found
+	start, end time.Time
+}
+
+// explainable - whether get could have observed its value in some
+// sequential order consistent with the real time order of ops
+func explainable(get histOp, history []histOp) bool {
+	var candidates []histOp
+	if get.value == "" {
+		candidates = append(candidates, histOp{kind: "initial"})
+	}
+	for _, op := range history {
+		if op.key != get.key || !op.start.Before(get.end) {
+			continue
+		}
+		if (op.kind == "create" && op.value == get.value) || (op.kind == "delete" && get.value == "") {
+			candidates = append(candidates, op)
+		}
+	}
+	for _, w := range candidates {
+		overwritten := false
+		for _, op := range history {
+			if op.key != get.key || op.kind == "get" {
+				continue
+			}
+			// op certainly ran after w and before get started
+			if (w.kind == "initial" || w.end.Before(op.start)) && op.end.Before(get.start) {
+				overwritten = true
+				break
+			}
+		}
+		if !overwritten {
+			return true
+		}
+	}
+	return false
+}
+
+func TestLinearizableRecordOps(t *testing.T) {
+	_, collection := dbtest.NewDB(t, nil)
+	c := collection("collection1")
+
+	var mu sync.Mutex
+	var history []histOp
+	var wg sync.WaitGroup
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			rnd := rand.New(rand.NewSource(int64(g)))
+			for i := 0; i < 100; i++ {
+				op := histOp{key: fmt.Sprint("k", rnd.Intn(3))}
+				op.start = time.Now()
+				switch rnd.Intn(3) {
+				case 0:
+					op.kind = "create"
+					op.value = strconv.Itoa(g*1000 + i)
+					if err := c.Create(op.key, []byte(op.value), simplejsondb.CreateOptions{UseGzip: rnd.Intn(2) == 0}); err != nil {
+						t.Error(err)
+					}
+				case 1:
+					op.kind = "delete"
+					_ = c.Delete(op.key)
+				default:
+					op.kind = "get"
+					data, err := c.Get(op.key)
+					if err != nil && !errors.Is(err, os.ErrNotExist) {
+						t.Error(err)
+					}
+					op.value = string(data)
+				}
+				op.end = time.Now()
+				mu.Lock()
+				history = append(history, op)
+				mu.Unlock()
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	for _, op := range history {
+		if op.kind == "get" && !explainable(op, history) {
+			t.Errorf("Test failed - get of %s observed %q which no sequential order explains", op.key, op.value)
+		}
+	}
+}
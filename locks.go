@@ -0,0 +1,100 @@
+package simplejsondb
+
+import (
+	"path/filepath"
+	"sync"
+)
+
+// Record locks are keyed by the logical record id rather than by file, so
+// every operation on an id (Get, Create, UpdateIf, Delete, ...) holds the
+// same lock while it resolves and touches the .json and .json.gz variants.
+// Concurrent operations on one id therefore behave as if they ran in some
+// sequential order: a Delete racing a Create either removes the new record
+// or finds nothing and leaves it in place, never a mix of both.
+
+type _lockEntry struct {
+	mu   sync.RWMutex
+	refs int
+}
+
+var (
+	// locks - registry of record locks shared by every collection handle
+	locks   = make(map[string]*_lockEntry)
+	locksMu sync.Mutex
+	// lockEntries - recycles released entries, most locks are short lived
+	lockEntries = sync.Pool{New: func() any { return &_lockEntry{} }}
+)
+
+// acquire - takes the record lock for path, exclusive when write is set
+func acquire(path string, write bool) {
+	locksMu.Lock()
+	e, ok := locks[path]
+	if !ok {
+		e = lockEntries.Get().(*_lockEntry)
+		locks[path] = e
+	}
+	e.refs++
+	locksMu.Unlock()
+
+	if write {
+		e.mu.Lock()
+	} else {
+		e.mu.RLock()
+	}
+}
+
+// release - releases a record lock taken by acquire
+func release(path string, write bool) {
+	locksMu.Lock()
+	defer locksMu.Unlock()
+	e, ok := locks[path]
+	if !ok {
+		return
+	}
+	if write {
+		e.mu.Unlock()
+	} else {
+		e.mu.RUnlock()
+	}
+	e.refs--
+	if e.refs == 0 {
+		delete(locks, path)
+		lockEntries.Put(e)
+	}
+}
+
+// Writers additionally share a collection lock, taken before the record
+// lock. SerializeWrites makes it exclusive so writes to different ids of a
+// collection run one at a time in the order they acquire it.
+
+// lock - takes the exclusive lock of a record, returns the release func
+func (c *_collection) lock(key string) func() {
+	collection, serial := c.collectionLockPath(), c.serializeWrites
+	acquire(collection, serial)
+	path := c.lockPath(key)
+	acquire(path, true)
+	return func() {
+		release(path, true)
+		release(collection, serial)
+	}
+}
+
+// rlock - takes the shared lock of a record, returns the release func
+func (c *_collection) rlock(key string) func() {
+	path := c.lockPath(key)
+	acquire(path, false)
+	return func() { release(path, false) }
+}
+
+// collectionLockPath - the trailing separator keeps it apart from any
+// record lock path
+func (c *_collection) collectionLockPath() string {
+	return c.path + string(filepath.Separator)
+}
+
+// lockPath - records are locked by file name stem so both file variants
+// share a lock, and an id reading as a path, as HashedIDs allows, never
+// names a record of another collection
+func (c *_collection) lockPath(key string) string {
+	return filepath.Join(c.path, c.fileKey(key))
+}
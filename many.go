@@ -0,0 +1,165 @@
+package simplejsondb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// manyParallelism - concurrent record operations of a batch call
+const manyParallelism = 8
+
+// MissingError - ids of a batch call which have no record
+type MissingError struct {
+	IDs []string
+}
+
+func (e *MissingError) Error() string {
+	return fmt.Sprintf("%d records not found: %s", len(e.IDs), strings.Join(e.IDs, ", "))
+}
+
+// Unwrap - lets errors.Is match os.ErrNotExist
+func (e *MissingError) Unwrap() error {
+	return os.ErrNotExist
+}
+
+// GetMany - reads a set of records concurrently
+func (c *_collection) GetMany(ids ...string) (map[string][]byte, error) {
+	return getMany(c.Get, ids)
+}
+
+// getMany - fetches ids with bounded parallelism, missing ids are reported
+// through a *MissingError unless another failure takes precedence
+func getMany(get func(string) ([]byte, error), ids []string) (records map[string][]byte, err error) {
+	records = make(map[string][]byte, len(ids))
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		missing  []string
+		failures = make(map[string]error)
+		slots    = make(chan struct{}, manyParallelism)
+		seen     = make(map[string]bool, len(ids))
+	)
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(id string) {
+			defer func() { <-slots; wg.Done() }()
+			data, err := get(id)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				records[id] = data
+			case errors.Is(err, os.ErrNotExist):
+				missing = append(missing, id)
+			default:
+				failures[id] = err
+			}
+		}(id)
+	}
+	wg.Wait()
+
+	if len(failures) > 0 {
+		failed := make([]string, 0, len(failures))
+		for id := range failures {
+			failed = append(failed, id)
+		}
+		sort.Strings(failed)
+		return records, fmt.Errorf("get %s: %w", failed[0], failures[failed[0]])
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return records, &MissingError{IDs: missing}
+	}
+	return records, nil
+}
+
+// CreateMany - writes a set of records concurrently, returning the errors
+// of the ids which failed
+//
+// Every record takes its own lock and is written like Create, the
+// collection directory is synced once at the end.
+func (c *_collection) CreateMany(records map[string][]byte, options ...CreateOptions) map[string]error {
+	failures := createMany(c.Create, records, options)
+	if c.noFsync {
+		return failures
+	}
+	if err := syncDir(c.path); err != nil {
+		c.logger.Error("unable to sync collection directory", zap.Error(err))
+		for id := range records {
+			if failures[id] == nil {
+				failures[id] = err
+			}
+		}
+	}
+	return failures
+}
+
+// createMany - runs create for every record with bounded parallelism
+func createMany(create func(string, []byte, ...CreateOptions) error, records map[string][]byte, options []CreateOptions) map[string]error {
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		failures = make(map[string]error)
+		slots    = make(chan struct{}, manyParallelism)
+	)
+	for id, data := range records {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(id string, data []byte) {
+			defer func() { <-slots; wg.Done() }()
+			if err := create(id, data, options...); err != nil {
+				mu.Lock()
+				failures[id] = err
+				mu.Unlock()
+			}
+		}(id, data)
+	}
+	wg.Wait()
+	return failures
+}
+
+// DeleteMany - removes a set of records, returning the errors of the ids
+// which failed including those not found
+func (c *_collection) DeleteMany(ids ...string) map[string]error {
+	return deleteMany(c.Delete, ids)
+}
+
+// deleteMany - runs del for every id with bounded parallelism
+func deleteMany(del func(string) error, ids []string) map[string]error {
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		failures = make(map[string]error)
+		slots    = make(chan struct{}, manyParallelism)
+		seen     = make(map[string]bool, len(ids))
+	)
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(id string) {
+			defer func() { <-slots; wg.Done() }()
+			if err := del(id); err != nil {
+				mu.Lock()
+				failures[id] = err
+				mu.Unlock()
+			}
+		}(id)
+	}
+	wg.Wait()
+	return failures
+}
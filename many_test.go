@@ -0,0 +1,141 @@
+package simplejsondb_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestGetMany(t *testing.T) {
+	dir := t.TempDir()
+	_, collection := dbtest.Open(t, dir, nil)
+	c := collection("batch")
+	var ids []string
+	for i := 0; i < 30; i++ {
+		id := fmt.Sprint("r", i)
+		ids = append(ids, id)
+		err := c.Create(id, []byte(fmt.Sprint(i)), simplejsondb.CreateOptions{UseGzip: i%2 == 0})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	records, err := c.GetMany(ids...)
+	if err != nil || len(records) != len(ids) {
+		t.Fatal("Test failed - ", len(records), err)
+	}
+	for i, id := range ids {
+		if string(records[id]) != fmt.Sprint(i) {
+			t.Error("Test failed - ", id, string(records[id]))
+		}
+	}
+
+	records, err = c.GetMany("r1", "nope2", "r2", "nope1", "r1")
+	var missing *simplejsondb.MissingError
+	if !errors.As(err, &missing) || !errors.Is(err, os.ErrNotExist) {
+		t.Fatal("Test failed - ", err)
+	}
+	if len(missing.IDs) != 2 || missing.IDs[0] != "nope1" || missing.IDs[1] != "nope2" {
+		t.Error("Test failed - ", missing.IDs)
+	}
+	if len(records) != 2 || string(records["r2"]) != "2" {
+		t.Error("Test failed - ", records)
+	}
+
+	// other failures take precedence over missing ids
+	_ = os.WriteFile(filepath.Join(dir, "batch", "r3.json.gz"), []byte("not gzip"), 0644)
+	_ = os.Remove(filepath.Join(dir, "batch", "r3.json"))
+	_, err = c.GetMany("r3", "nope")
+	if err == nil || errors.As(err, &missing) {
+		t.Error("Test failed - ", err)
+	}
+}
+
+func TestCreateMany(t *testing.T) {
+	dir := t.TempDir()
+	_, collection := dbtest.Open(t, dir, &simplejsondb.Options{MaxNameLength: 16})
+	c := collection("seed")
+	records := make(map[string][]byte)
+	for i := 0; i < 50; i++ {
+		records[fmt.Sprint("r", i)] = []byte(fmt.Sprint(i))
+	}
+	records["far-too-long-for-the-limit"] = []byte(`{}`)
+
+	failures := c.CreateMany(records, simplejsondb.CreateOptions{UseGzip: true})
+	if len(failures) != 1 || !errors.Is(failures["far-too-long-for-the-limit"], simplejsondb.ErrIDTooLong) {
+		t.Error("Test failed - ", failures)
+	}
+	if keys := c.Keys(); len(keys) != 50 {
+		t.Error("Test failed - ", len(keys))
+	}
+	if data, err := c.Get("r7"); err != nil || string(data) != "7" {
+		t.Error("Test failed - ", string(data), err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "seed", "r7.json.gz")); err != nil {
+		t.Error("Test failed - per call gzip not honored", err)
+	}
+}
+
+func benchmarkRecords(n int) map[string][]byte {
+	records := make(map[string][]byte, n)
+	for i := 0; i < n; i++ {
+		records[fmt.Sprint("r", i)] = []byte(`{"seeded": true}`)
+	}
+	return records
+}
+
+func BenchmarkCreateLoop(b *testing.B) {
+	_, collection := dbtest.NewDB(b, nil)
+	c := collection("seed")
+	records := benchmarkRecords(200)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for id, data := range records {
+			if err := c.Create(id, data); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkCreateMany(b *testing.B) {
+	_, collection := dbtest.NewDB(b, nil)
+	c := collection("seed")
+	records := benchmarkRecords(200)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if failures := c.CreateMany(records); len(failures) != 0 {
+			b.Fatal(failures)
+		}
+	}
+}
+
+func TestDeleteMany(t *testing.T) {
+	dir := t.TempDir()
+	_, collection := dbtest.Open(t, dir, nil)
+	c := collection("cleanup")
+	var ids []string
+	for i := 0; i < 20; i++ {
+		id := fmt.Sprint("r", i)
+		ids = append(ids, id)
+		if err := c.Create(id, []byte(`{}`), simplejsondb.CreateOptions{UseGzip: i%2 == 0}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	failures := c.DeleteMany(append(ids[:15], "nope", "r0")...)
+	if len(failures) != 1 || !errors.Is(failures["nope"], os.ErrNotExist) {
+		t.Error("Test failed - ", failures)
+	}
+	if keys := c.Keys(); len(keys) != 5 {
+		t.Error("Test failed - ", keys)
+	}
+	entries, _ := os.ReadDir(filepath.Join(dir, "cleanup"))
+	if len(entries) != 5 {
+		t.Error("Test failed - files left behind", len(entries))
+	}
+}
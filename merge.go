@@ -0,0 +1,90 @@
+package simplejsondb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// MergeOptions - Merge configuration
+type MergeOptions struct {
+	// MustExist - fail with os.ErrNotExist instead of creating a missing
+	// record from the patch
+	MustExist bool
+}
+
+// Merge - applies an RFC 7386 JSON merge patch to a record
+//
+// The record is read, patched and written back under its exclusive lock
+// keeping its storage format. Null members of patch remove keys, objects
+// merge recursively and any other value replaces the target. The result
+// is written compact with sorted keys.
+func (c *_collection) Merge(key string, patch []byte, options ...MergeOptions) error {
+	return merge(c, key, patch, options)
+}
+
+// Merge - applies a merge patch to the visible record into the overlay
+func (c *_overlayCollection) Merge(key string, patch []byte, options ...MergeOptions) error {
+	return merge(c, key, patch, options)
+}
+
+func merge(c Collection, key string, patch []byte, options []MergeOptions) error {
+	opts := MergeOptions{}
+	if options != nil {
+		opts = options[0]
+	}
+	p, err := decodeJSON(patch)
+	if err != nil {
+		return fmt.Errorf("invalid merge patch: %w", err)
+	}
+	_, err = c.UpdateIf(key, func(current []byte) (bool, error) {
+		if current == nil && opts.MustExist {
+			return false, &os.PathError{Op: "merge", Path: key, Err: os.ErrNotExist}
+		}
+		return true, nil
+	}, func(current []byte) ([]byte, error) {
+		var target any
+		if current != nil {
+			target, err = decodeJSON(current)
+			if err != nil {
+				return nil, fmt.Errorf("record %s is not JSON: %w", key, err)
+			}
+		}
+		return json.Marshal(mergePatch(target, p))
+	})
+	return err
+}
+
+// decodeJSON - decodes a single JSON value keeping numbers verbatim
+func decodeJSON(data []byte) (v any, err error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	if err = d.Decode(&v); err != nil {
+		return nil, err
+	}
+	if d.More() {
+		return nil, fmt.Errorf("trailing data after JSON value")
+	}
+	return v, nil
+}
+
+// mergePatch - the MergePatch function of RFC 7386
+func mergePatch(target, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]any)
+	if !ok {
+		t = make(map[string]any, len(p))
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+			continue
+		}
+		t[k] = mergePatch(t[k], v)
+	}
+	return t
+}
@@ -0,0 +1,48 @@
+package simplejsondb_test
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestMerge(t *testing.T) {
+	_, collection := dbtest.NewDB(t, nil)
+	c := collection("docs")
+	dbtest.Seed(t, c, map[string][]byte{"plain": []byte(`{"a":"b","c":{"d":"e","f":"g"},"n":12345678901234567890}`)})
+	if err := c.Create("zipped", []byte(`{"title":"Goodbye!","tags":["x"]}`), simplejsondb.CreateOptions{UseGzip: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the example of RFC 7386 section 1, numbers kept verbatim
+	if err := c.Merge("plain", []byte(`{"a":"z","c":{"f":null}}`)); err != nil {
+		t.Fatal(err)
+	}
+	dbtest.RequireRecord(t, c, "plain", []byte(`{"a":"z","c":{"d":"e"},"n":12345678901234567890}`))
+
+	if err := c.Merge("zipped", []byte(`{"title":"Hello!","tags":null,"author":{"name":"x"}}`)); err != nil {
+		t.Fatal(err)
+	}
+	dbtest.RequireRecord(t, c, "zipped", []byte(`{"author":{"name":"x"},"title":"Hello!"}`))
+
+	// missing records are created from the patch unless they must exist
+	if err := c.Merge("new", []byte(`{"a":1,"b":null}`)); err != nil {
+		t.Error("Test failed - ", err)
+	}
+	dbtest.RequireRecord(t, c, "new", []byte(`{"a":1}`))
+	if err := c.Merge("absent", []byte(`{"a":1}`), simplejsondb.MergeOptions{MustExist: true}); !errors.Is(err, os.ErrNotExist) {
+		t.Error("Test failed - ", err)
+	}
+
+	if err := c.Merge("plain", []byte(`{"a":`)); err == nil {
+		t.Error("Test failed - invalid patch applied")
+	}
+	dbtest.Seed(t, c, map[string][]byte{"text": []byte(`not json`)})
+	if err := c.Merge("text", []byte(`{"a":1}`)); err == nil {
+		t.Error("Test failed - merged into invalid JSON")
+	}
+	dbtest.RequireRecord(t, c, "text", []byte(`not json`))
+}
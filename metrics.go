@@ -0,0 +1,61 @@
+package simplejsondb
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Metrics - receives the outcome and latency of collection operations
+//
+// Methods run on the calling goroutine after the operation, so they must
+// be cheap and safe for concurrent use. The other Collection methods are
+// observed through the operation they are built on: GetAll, Find and
+// GetObject count as get, Update, Merge, AppendTo and Increment as update.
+type Metrics interface {
+	// ObserveOp - a finished get, create, delete or update, err is nil on
+	// success
+	ObserveOp(collection, op string, dur time.Duration, err error)
+	// ObserveLockWait - how long an operation waited for its record lock
+	ObserveLockWait(collection string, wait time.Duration)
+}
+
+// metricsStart - the start of an observed span, the zero time without
+// Metrics so the hot path skips the clock
+func (c *_collection) metricsStart() time.Time {
+	if c.metrics == nil {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// observeOp - reports the operation started at start, meant to be
+// deferred with the named error result
+func (c *_collection) observeOp(op string, start time.Time, err *error) {
+	if c.metrics == nil {
+		return
+	}
+	dur, result := time.Since(start), *err
+	herr := c.callbacks.guard("Metrics", func() error {
+		c.metrics.ObserveOp(c.name, op, dur, result)
+		return nil
+	})
+	if herr != nil {
+		c.logger.Error("metrics hook failed", zap.Error(herr))
+	}
+}
+
+// observeLockWait - reports the wait for a lock requested at start
+func (c *_collection) observeLockWait(start time.Time) {
+	if c.metrics == nil {
+		return
+	}
+	wait := time.Since(start)
+	herr := c.callbacks.guard("Metrics", func() error {
+		c.metrics.ObserveLockWait(c.name, wait)
+		return nil
+	})
+	if herr != nil {
+		c.logger.Error("metrics hook failed", zap.Error(herr))
+	}
+}
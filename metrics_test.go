@@ -0,0 +1,105 @@
+package simplejsondb_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+type recorder struct {
+	mu    sync.Mutex
+	ops   []string
+	fails int
+	waits int
+}
+
+func (r *recorder) ObserveOp(collection, op string, dur time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ops = append(r.ops, collection+"."+op)
+	if err != nil {
+		r.fails++
+	}
+}
+
+func (r *recorder) ObserveLockWait(collection string, wait time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.waits++
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) ObserveOp(string, string, time.Duration, error) {}
+func (nopMetrics) ObserveLockWait(string, time.Duration)          {}
+
+func TestMetrics(t *testing.T) {
+	r := &recorder{}
+	_, collection := dbtest.NewDB(t, &simplejsondb.Options{Metrics: r})
+	c := collection("users")
+
+	if err := c.Create("u1", []byte(`{"n": 1}`)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get("u1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Increment("u1", "/n", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Delete("u1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Delete("u1"); !errors.Is(err, simplejsondb.ErrRecordNotFound) {
+		t.Fatal(err)
+	}
+	want := []string{"users.create", "users.get", "users.update", "users.delete", "users.delete"}
+	if len(r.ops) != len(want) {
+		t.Fatal("Test failed - ", r.ops)
+	}
+	for i := range want {
+		if r.ops[i] != want[i] {
+			t.Error("Test failed - ", r.ops)
+		}
+	}
+	if r.fails != 1 || r.waits != len(want) {
+		t.Error("Test failed - ", r.fails, r.waits)
+	}
+}
+
+func TestMetricsAllocs(t *testing.T) {
+	allocs := func(m simplejsondb.Metrics) float64 {
+		_, collection := dbtest.NewDB(t, &simplejsondb.Options{Metrics: m})
+		c := collection("users")
+		dbtest.Seed(t, c, map[string][]byte{"u1": []byte(`{}`)})
+		return testing.AllocsPerRun(100, func() {
+			if _, err := c.Get("u1"); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+	if off, on := allocs(nil), allocs(nopMetrics{}); on != off {
+		t.Error("Test failed - ", off, on)
+	}
+}
+
+func BenchmarkGetMetrics(b *testing.B) {
+	for name, m := range map[string]simplejsondb.Metrics{"nil": nil, "nop": nopMetrics{}} {
+		b.Run(name, func(b *testing.B) {
+			_, collection := dbtest.NewDB(b, &simplejsondb.Options{Metrics: m})
+			c := collection("users")
+			dbtest.Seed(b, c, map[string][]byte{"u1": []byte(`{}`)})
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := c.Get("u1"); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
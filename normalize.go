@@ -0,0 +1,22 @@
+package simplejsondb
+
+import (
+	"bytes"
+)
+
+// utf8BOM - byte order mark editors such as Notepad put in front of UTF-8
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// normalize - the record as handed to callers: a leading BOM is always
+// dropped and trailing whitespace too with NormalizeOnRead, the file on
+// disk is left as it is
+//
+// Both only reslice data, so zero-copy cache entries keep their backing
+// array and reads stay allocation free.
+func (c *_collection) normalize(data []byte) []byte {
+	data = bytes.TrimPrefix(data, utf8BOM)
+	if c.normalizeOnRead {
+		data = bytes.TrimRight(data, " \t\r\n")
+	}
+	return data
+}
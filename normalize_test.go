@@ -0,0 +1,66 @@
+package simplejsondb_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestReadToleratesBOM(t *testing.T) {
+	bom := "\xEF\xBB\xBF"
+	edited := []byte(bom + "{\r\n  \"name\": \"notepad\"\r\n}\r\n")
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	_, _ = w.Write(edited)
+	_ = w.Close()
+
+	for _, normalize := range []bool{false, true} {
+		for _, pref := range []simplejsondb.ReadPreference{simplejsondb.PreferCompressed, simplejsondb.PreferPlain} {
+			root := t.TempDir()
+			_, collection := dbtest.Open(t, root, &simplejsondb.Options{NormalizeOnRead: normalize, ReadPreference: pref})
+			c := collection("docs")
+			dir := filepath.Join(root, "docs")
+			_ = os.WriteFile(filepath.Join(dir, "bomonly.json"), []byte(bom+`{"a":1}`), 0644)
+			_ = os.WriteFile(filepath.Join(dir, "edited.json"), edited, 0644)
+			_ = os.WriteFile(filepath.Join(dir, "zipped.json.gz"), gz.Bytes(), 0644)
+
+			if data, err := c.Get("bomonly"); err != nil || string(data) != `{"a":1}` {
+				t.Error("Test failed - ", normalize, pref, string(data), err)
+			}
+			want := edited[len(bom):]
+			if normalize {
+				want = bytes.TrimRight(want, "\r\n")
+			}
+			for _, key := range []string{"edited", "zipped"} {
+				data, err := c.Get(key)
+				if err != nil || !bytes.Equal(data, want) {
+					t.Errorf("Test failed - %v %v %s %q %v", normalize, pref, key, data, err)
+				}
+				// what a strict validator sees
+				if !json.Valid(data) {
+					t.Error("Test failed - invalid JSON returned", key)
+				}
+			}
+
+			// records seen by UpdateIf are normalized too
+			_, err := c.UpdateIf("edited", func(current []byte) (bool, error) {
+				if bytes.HasPrefix(current, []byte(bom)) {
+					t.Error("Test failed - BOM passed to condition")
+				}
+				return false, nil
+			}, nil)
+			if err != nil {
+				t.Error("Test failed - ", err)
+			}
+			if disk, _ := os.ReadFile(filepath.Join(dir, "edited.json")); !bytes.Equal(disk, edited) {
+				t.Error("Test failed - file on disk rewritten")
+			}
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package simplejsondb
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// CreateObject - saves v marshalled to JSON like Create, nothing is
+// written when v can't be marshalled
+func (c *_collection) CreateObject(key string, v any, options ...CreateOptions) error {
+	return createObject(c, key, v, options)
+}
+
+// CreateObject - saves v marshalled to JSON into the overlay
+func (c *_overlayCollection) CreateObject(key string, v any, options ...CreateOptions) error {
+	return createObject(c, key, v, options)
+}
+
+// GetObject - reads a record and unmarshals it into out, decode errors
+// carry the record id
+func (c *_collection) GetObject(key string, out any) error {
+	return getObject(c, key, out)
+}
+
+// GetObject - reads the visible record and unmarshals it into out
+func (c *_overlayCollection) GetObject(key string, out any) error {
+	return getObject(c, key, out)
+}
+
+func createObject(c Collection, key string, v any, options []CreateOptions) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("unable to marshal %s: %w", key, err)
+	}
+	return c.Create(key, data, options...)
+}
+
+func getObject(c Collection, key string, out any) error {
+	data, err := c.Get(key)
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("unable to unmarshal %s: %w", key, err)
+	}
+	return nil
+}
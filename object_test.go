@@ -0,0 +1,53 @@
+package simplejsondb_test
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+type person struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+func TestObject(t *testing.T) {
+	dir := t.TempDir()
+	_, collection := dbtest.Open(t, dir, nil)
+	c := collection("admin")
+
+	if err := c.CreateObject("alice", person{Name: "alice", Age: 30}, simplejsondb.CreateOptions{UseGzip: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "admin", "alice.json.gz")); err != nil {
+		t.Error("Test failed - ", err)
+	}
+	var got person
+	if err := c.GetObject("alice", &got); err != nil || got != (person{Name: "alice", Age: 30}) {
+		t.Error("Test failed - ", got, err)
+	}
+
+	var jsonErr *json.UnsupportedTypeError
+	if err := c.CreateObject("chan", make(chan int)); !errors.As(err, &jsonErr) {
+		t.Error("Test failed - ", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "admin", "chan.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Error("Test failed - ", err)
+	}
+	if err := c.GetObject("missing", &got); !errors.Is(err, os.ErrNotExist) {
+		t.Error("Test failed - ", err)
+	}
+
+	dbtest.Seed(t, c, map[string][]byte{"broken": []byte(`{"name": 1}`)})
+	var syntaxErr *json.UnmarshalTypeError
+	err := c.GetObject("broken", &got)
+	if !errors.As(err, &syntaxErr) || !strings.Contains(err.Error(), "broken") {
+		t.Error("Test failed - ", err)
+	}
+}
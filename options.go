@@ -0,0 +1,55 @@
+package simplejsondb
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// ErrIncompatibleOptions - options which cannot be honored together
+var ErrIncompatibleOptions = errors.New("incompatible options")
+
+// hashedNameLength - longest file name of a record with a hashed id
+var hashedNameLength = len(hashedPrefix) + 64 + len(GZipExt)
+
+// checkOptions - rejects option combinations the package cannot support
+//
+// Every pair of options is either supported or listed here, the matrix
+// conformance run in dbtest keeps the two in sync.
+func checkOptions(opts *Options) error {
+	if opts.HashLongIDs && opts.MaxNameLength > 0 && opts.MaxNameLength < hashedNameLength {
+		return fmt.Errorf("%w: HashLongIDs needs MaxNameLength of at least %d", ErrIncompatibleOptions, hashedNameLength)
+	}
+	if opts.IDPolicy != nil && (opts.HashLongIDs || opts.MaxNameLength > 0) {
+		return fmt.Errorf("%w: IDPolicy replaces MaxNameLength and HashLongIDs", ErrIncompatibleOptions)
+	}
+	return nil
+}
+
+// collectionOptions - the Options fields Collection applies to a single
+// handle; the others configure state shared by every handle of the
+// database, such as the record count MaxRecords limits or the usage
+// QuotaBytes does
+var collectionOptions = map[string]bool{
+	"UseGzip":         true,
+	"Codec":           true,
+	"Codecs":          true,
+	"ReadPreference":  true,
+	"SerializeWrites": true,
+	"ValidateJSON":    true,
+	"NoFsync":         true,
+	"MaxRecordSize":   true,
+}
+
+// checkCollectionOptions - refuses the options of a Collection call which
+// set any field it cannot apply to one handle
+func checkCollectionOptions(opts Options) error {
+	v := reflect.ValueOf(opts)
+	for i := 0; i < v.NumField(); i++ {
+		name := v.Type().Field(i).Name
+		if !collectionOptions[name] && !v.Field(i).IsZero() {
+			return fmt.Errorf("%w: %s applies to the whole database, not a collection", ErrIncompatibleOptions, name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package simplejsondb_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestCollectionOptions(t *testing.T) {
+	dir := t.TempDir()
+	db, _ := dbtest.Open(t, dir, &simplejsondb.Options{UseGzip: true})
+	stored := func(name, id string) string {
+		if _, err := os.Stat(filepath.Join(dir, name, id+simplejsondb.GZipExt)); err == nil {
+			return "gzip"
+		}
+		if _, err := os.Stat(filepath.Join(dir, name, id+simplejsondb.Ext)); err == nil {
+			return "plain"
+		}
+		return "missing"
+	}
+	events, err := db.Collection("events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hot, err := db.Collection("hot", simplejsondb.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []simplejsondb.Collection{events, hot} {
+		if err = c.Create("a", []byte(`{}`)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// CreateOptions still take precedence
+	if err = hot.Create("b", []byte(`{}`), simplejsondb.CreateOptions{UseGzip: true}); err != nil {
+		t.Fatal(err)
+	}
+	if stored("events", "a") != "gzip" || stored("hot", "a") != "plain" || stored("hot", "b") != "gzip" {
+		t.Error("Test failed - ", stored("events", "a"), stored("hot", "a"), stored("hot", "b"))
+	}
+
+	// the per handle limits apply to that handle only
+	strict, err := db.Collection("events", simplejsondb.Options{UseGzip: true, ValidateJSON: true, MaxRecordSize: 16})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = strict.Create("bad", []byte(`{`)); !errors.Is(err, simplejsondb.ErrInvalidJSON) {
+		t.Error("Test failed - ", err)
+	}
+	if err = strict.Create("big", []byte(`{"big": "0123456789"}`)); !errors.Is(err, simplejsondb.ErrRecordTooLarge) {
+		t.Error("Test failed - ", err)
+	}
+	if err = events.Create("big", []byte(`{"big": "0123456789"}`)); err != nil {
+		t.Error("Test failed - ", err)
+	}
+
+	// database wide fields are refused rather than ignored
+	for _, opts := range []simplejsondb.Options{{MaxRecords: 1}, {QuotaBytes: 1}, {CacheBytes: 1}, {DegradedCacheSize: 1}} {
+		if _, err = db.Collection("events", opts); !errors.Is(err, simplejsondb.ErrIncompatibleOptions) {
+			t.Error("Test failed - ", opts, err)
+		}
+	}
+}
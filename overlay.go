@@ -0,0 +1,581 @@
+package simplejsondb
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// whiteoutDir - reserved directory inside an overlay collection which
+// records the base records deleted through the overlay
+var whiteoutDir string = ".whiteouts"
+
+type (
+	_overlay struct {
+		base  DB
+		upper *_db
+		txMu  sync.Mutex
+	}
+
+	_overlayCollection struct {
+		mu        sync.Mutex
+		base      _layer
+		upper     *_collection
+		whiteouts string
+		logger    Logger
+	}
+
+	// _layer - a collection which can take part in an overlay
+	_layer interface {
+		Collection
+		keys() ([]string, error)
+		has(string) bool
+		modTime(string) (time.Time, error)
+		measure(string) (int64, int64, error)
+	}
+)
+
+// NewOverlay - a copy-on-write database on top of base
+//
+// Reads fall through to base when the overlay lacks a record, writes and
+// deletes only touch the overlay. Base is never modified.
+func NewOverlay(base DB, overlayPath string) (db DB, err error) {
+	if base == nil {
+		return nil, fmt.Errorf("overlay requires a base database")
+	}
+	opts := Options{}
+	if b, ok := base.(*_db); ok {
+		opts.Codec = b.codec
+		opts.Codecs = b.codecs
+		opts.Checksums = b.checksums
+		opts.VerifyChecksums = b.verifyChecksums
+		opts.ReadPreference = b.readPref
+		opts.Redactor = b.redactor
+		opts.DetailedTimings = b.timings != nil
+		opts.OnOperation = b.onOperation
+		opts.Metrics = b.metrics
+		opts.Tracer = b.tracer
+		opts.IDPolicy = b.ids
+		opts.OnVisible = b.onVisible
+		opts.SerializeWrites = b.serializeWrites
+		opts.ZeroCopy = b.zeroCopy
+		opts.IndexPaths = b.indexPaths
+		opts.NormalizeOnRead = b.normalizeOnRead
+		opts.ValidateJSON = b.validateJSON
+		opts.NoFsync = b.noFsync
+		opts.MaxRecordSize = b.maxRecordSize
+		opts.RecoverCallbacks = b.callbacks.recover
+		opts.QuarantineAfter = b.quarantine.threshold()
+		opts.IgnorePatterns = b.ignore
+		opts.TTL = b.ttl
+		opts.DeleteExpired = b.deleteExpired
+		opts.MaxRecords = b.maxRecords
+		opts.QuotaBytes = b.quotaBytes
+		opts.NoDefaultIgnores = true
+		opts.Logger = b.logger
+	}
+	upper, err := New(overlayPath, &opts)
+	if err != nil {
+		return nil, err
+	}
+	u := upper.(*_db)
+	if b, ok := base.(*_db); ok {
+		// one budget for both layers, entries are keyed by full path
+		u.cache = b.cache
+		u.callbacks = b.callbacks
+		u.enc = b.enc
+	}
+	o := &_overlay{base: base, upper: u}
+	if err = recoverJournals(o, filepath.Join(overlayPath, overlayJournalDir), u.owner != nil, u.logger); err != nil {
+		u.Close()
+		return nil, err
+	}
+	return o, nil
+}
+
+// Collection returns the merged view of the base and overlay collection,
+// options apply to the overlay writes
+func (o *_overlay) Collection(name string, options ...Options) (c Collection, err error) {
+	upper, err := o.upper.Collection(name, options...)
+	if err != nil {
+		return nil, err
+	}
+	base, err := o.baseCollection(name)
+	if err != nil {
+		return nil, err
+	}
+	u := upper.(*_collection)
+	whiteouts := filepath.Join(u.path, whiteoutDir)
+	err = os.MkdirAll(whiteouts, os.ModePerm)
+	if err != nil {
+		o.upper.logger.Error("unable to create whiteout directory", zap.Error(err))
+		return nil, err
+	}
+	err = requireLayout(u.path, featureWhiteouts)
+	if err != nil {
+		o.upper.logger.Error("unable to update collection layout", zap.Error(err))
+		return nil, err
+	}
+	return &_overlayCollection{base: base, upper: u, whiteouts: whiteouts, logger: o.upper.logger}, nil
+}
+
+// HasCollection - reports whether either layer holds the collection
+func (o *_overlay) HasCollection(name string) bool {
+	if o.upper.life.begin() != nil {
+		return false
+	}
+	defer o.upper.life.end()
+	return o.upper.HasCollection(name) || o.base.HasCollection(name)
+}
+
+// DropCollection - removes a collection which only exists in the overlay,
+// base collections cannot be dropped as base is never modified
+func (o *_overlay) DropCollection(name string) error {
+	base, err := o.baseCollection(name)
+	if err != nil {
+		return err
+	}
+	if base != nil {
+		return fmt.Errorf("collection %s exists in the base and cannot be dropped", name)
+	}
+	return o.upper.DropCollection(name)
+}
+
+// Degraded - reports whether the overlay is in degraded mode
+func (o *_overlay) Degraded() bool {
+	return o.upper.Degraded()
+}
+
+// ExportIndexed - writes the merged view into an indexed archive
+func (o *_overlay) ExportIndexed(path string, options ...ExportOptions) (err error) {
+	if err = o.upper.life.begin(); err != nil {
+		return err
+	}
+	defer o.upper.life.end()
+	return exportIndexed(o, path, options...)
+}
+
+// ExportChangedSince - writes the merged records changed since into w
+func (o *_overlay) ExportChangedSince(w io.Writer, since time.Time) (m IncrementalManifest, err error) {
+	if err = o.upper.life.begin(); err != nil {
+		return m, err
+	}
+	defer o.upper.life.end()
+	return exportChangedSince(o, w, since)
+}
+
+// ApplyIncremental - replays an incremental export into the overlay
+func (o *_overlay) ApplyIncremental(r io.Reader) (m IncrementalManifest, err error) {
+	if err = o.upper.life.begin(); err != nil {
+		return m, err
+	}
+	defer o.upper.life.end()
+	return applyIncremental(o, r)
+}
+
+// root - the overlay directory
+func (o *_overlay) root() string {
+	return o.upper.path
+}
+
+// Stats - usage counters of the overlay
+func (o *_overlay) Stats() Stats {
+	return o.upper.Stats()
+}
+
+// collections - merges the collection names of both layers
+func (o *_overlay) collections() ([]string, error) {
+	names, err := o.upper.collections()
+	if err != nil {
+		return nil, err
+	}
+	base, ok := o.base.(_source)
+	if !ok {
+		return names, nil
+	}
+	baseNames, err := base.collections()
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		seen[name] = true
+	}
+	for _, name := range baseNames {
+		if !seen[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// baseCollection - opens the base collection without creating it
+func (o *_overlay) baseCollection(name string) (_layer, error) {
+	if b, ok := o.base.(*_db); ok {
+		info, err := os.Stat(filepath.Join(b.path, name))
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("not a directory")
+		}
+	}
+	c, err := o.base.Collection(name)
+	if err != nil {
+		return nil, err
+	}
+	layer, ok := c.(_layer)
+	if !ok {
+		return nil, fmt.Errorf("base collection does not support overlays")
+	}
+	return layer, nil
+}
+
+// GetAll - returns all records of both layers ordered by id
+func (c *_overlayCollection) GetAll() (data [][]byte) {
+	data, err := c.GetAllCtx(context.Background())
+	if err != nil {
+		c.logger.Error("no data available", zap.Error(err))
+	}
+	return
+}
+
+// GetAllSorted - returns the visible records with their ids ordered by id
+func (c *_overlayCollection) GetAllSorted() []Record {
+	return getAllSorted(c, c.logger)
+}
+
+// Keys - returns the sorted ids visible through the overlay
+func (c *_overlayCollection) Keys() []string {
+	keys, err := c.keys()
+	if err != nil {
+		c.logger.Error("no data available", zap.Error(err))
+		return nil
+	}
+	return keys
+}
+
+// Name - the collection name
+func (c *_overlayCollection) Name() string {
+	return c.upper.name
+}
+
+// Get - returns the overlay record, falling back to the base record
+func (c *_overlayCollection) Get(key string) (data []byte, err error) {
+	return c.GetCtx(context.Background(), key)
+}
+
+// GetCtx - Get of the visible record unless ctx is done
+func (c *_overlayCollection) GetCtx(ctx context.Context, key string) (data []byte, err error) {
+	defer func() { err = wrapNotFound(ErrRecordNotFound, key, err) }()
+	if err = c.upper.checkID(key); err != nil {
+		return nil, err
+	}
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err = c.upper.life.begin(); err != nil {
+		return nil, err
+	}
+	defer c.upper.life.end()
+	if c.upper.has(key) {
+		return c.upper.GetCtx(ctx, key)
+	}
+	if !c.inBase(key) {
+		return nil, c.notFound(key)
+	}
+	return c.base.GetCtx(ctx, key)
+}
+
+// Pin - keeps the visible record in the read cache
+func (c *_overlayCollection) Pin(key string) (err error) {
+	if err = c.upper.checkID(key); err != nil {
+		return err
+	}
+	if err = c.upper.life.begin(); err != nil {
+		return err
+	}
+	defer c.upper.life.end()
+	if c.upper.has(key) {
+		return c.upper.Pin(key)
+	}
+	if !c.inBase(key) {
+		return c.notFound(key)
+	}
+	return c.base.Pin(key)
+}
+
+// Unpin - lets the record of either layer be evicted again
+func (c *_overlayCollection) Unpin(key string) {
+	c.upper.Unpin(key)
+	if c.base != nil {
+		c.base.Unpin(key)
+	}
+}
+
+// GetMany - reads a set of visible records concurrently
+func (c *_overlayCollection) GetMany(ids ...string) (map[string][]byte, error) {
+	return getMany(c.Get, ids)
+}
+
+// Quarantined - ids of either layer refused after repeated corrupt reads
+func (c *_overlayCollection) Quarantined() []string {
+	ids := c.upper.Quarantined()
+	if c.base == nil {
+		return ids
+	}
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		seen[id] = true
+	}
+	for _, id := range c.base.Quarantined() {
+		if !seen[id] {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Unquarantine - lets reads of the record reach either layer again
+func (c *_overlayCollection) Unquarantine(key string) {
+	c.upper.Unquarantine(key)
+	if c.base != nil {
+		c.base.Unquarantine(key)
+	}
+}
+
+// GetAndCompare - compares the visible record with candidate in constant time
+func (c *_overlayCollection) GetAndCompare(key string, candidate []byte) (equal bool, err error) {
+	data, err := c.Get(key)
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare(data, candidate) == 1, nil
+}
+
+// Create - saves the record into the overlay
+func (c *_overlayCollection) Create(key string, data []byte, options ...CreateOptions) (err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.create(context.Background(), key, data, options...)
+}
+
+// CreateNX - saves the record into the overlay unless it is visible
+func (c *_overlayCollection) CreateNX(key string, data []byte, options ...CreateOptions) (err error) {
+	if err = c.upper.checkID(key); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.has(key) {
+		return fmt.Errorf("%w: %s", ErrExists, key)
+	}
+	return c.create(context.Background(), key, data, options...)
+}
+
+// CreateMany - saves a set of records into the overlay
+func (c *_overlayCollection) CreateMany(records map[string][]byte, options ...CreateOptions) map[string]error {
+	return createMany(c.Create, records, options)
+}
+
+// create - saves the record and updates its whiteout, the caller holds mu
+func (c *_overlayCollection) create(ctx context.Context, key string, data []byte, options ...CreateOptions) (err error) {
+	err = c.upper.CreateCtx(ctx, key, data, options...)
+	if err != nil {
+		return err
+	}
+	return c.written(key)
+}
+
+// Update - rewrites the record into the overlay
+func (c *_overlayCollection) Update(key string, fn func(current []byte) ([]byte, error)) error {
+	_, err := c.UpdateIf(key, unconditional, fn)
+	return err
+}
+
+// UpdateIf - conditionally rewrites the record into the overlay
+func (c *_overlayCollection) UpdateIf(key string, condition func(current []byte) (bool, error), mutate func(current []byte) ([]byte, error)) (applied bool, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var current []byte
+	applied, err = c.upper.UpdateIf(key, func(upper []byte) (bool, error) {
+		current = upper
+		if current == nil && c.inBase(key) {
+			base, err := c.base.Get(key)
+			if err != nil {
+				return false, err
+			}
+			current = base
+		}
+		return condition(current)
+	}, func([]byte) ([]byte, error) {
+		return mutate(current)
+	})
+	if err != nil || !applied {
+		return applied, err
+	}
+	return true, c.written(key)
+}
+
+// Delete - removes the overlay record and hides the base record
+func (c *_overlayCollection) Delete(key string) (err error) {
+	return c.DeleteCtx(context.Background(), key)
+}
+
+// DeleteCtx - Delete unless ctx is done before the overlay is locked
+func (c *_overlayCollection) DeleteCtx(ctx context.Context, key string) (err error) {
+	defer func() { err = wrapNotFound(ErrRecordNotFound, key, err) }()
+	if err = c.upper.checkID(key); err != nil {
+		return err
+	}
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+	if err = c.upper.life.begin(); err != nil {
+		return err
+	}
+	defer c.upper.life.end()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+	inUpper := c.upper.has(key)
+	inBase := c.inBase(key)
+	if !inUpper && !inBase {
+		return c.notFound(key)
+	}
+	if inUpper {
+		err = c.upper.DeleteCtx(ctx, key)
+		if err != nil {
+			return err
+		}
+	}
+	if inBase {
+		err = os.WriteFile(c.whiteoutPath(key), nil, os.ModePerm)
+		if err != nil {
+			c.logger.Error("unable to create whiteout", zap.Error(err))
+			return err
+		}
+		if !inUpper {
+			c.upper.visible(VisibleInfo{Op: "delete", ID: key})
+		}
+	}
+	return
+}
+
+// Truncate - empties the overlay and hides every base record
+func (c *_overlayCollection) Truncate() (err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	err = c.upper.Truncate()
+	if err != nil || c.base == nil {
+		return err
+	}
+	keys, err := c.base.keys()
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		err = os.WriteFile(c.whiteoutPath(key), nil, os.ModePerm)
+		if err != nil {
+			c.logger.Error("unable to create whiteout", zap.Error(err))
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteMany - removes a set of records from the merged view
+func (c *_overlayCollection) DeleteMany(ids ...string) map[string]error {
+	return deleteMany(c.Delete, ids)
+}
+
+// keys - merges the keys of both layers, hiding whiteouts
+func (c *_overlayCollection) keys() ([]string, error) {
+	keys, err := c.upper.keys()
+	if err != nil {
+		return nil, err
+	}
+	if c.base == nil {
+		return keys, nil
+	}
+	baseKeys, err := c.base.keys()
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		seen[key] = true
+	}
+	for _, key := range baseKeys {
+		if !seen[key] && !c.isWhiteout(key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (c *_overlayCollection) has(key string) bool {
+	return c.upper.has(key) || c.inBase(key)
+}
+
+func (c *_overlayCollection) modTime(key string) (time.Time, error) {
+	if c.upper.has(key) {
+		return c.upper.modTime(key)
+	}
+	if !c.inBase(key) {
+		return time.Time{}, c.notFound(key)
+	}
+	return c.base.modTime(key)
+}
+
+func (c *_overlayCollection) inBase(key string) bool {
+	return c.base != nil && !c.isWhiteout(key) && c.base.has(key)
+}
+
+func (c *_overlayCollection) isWhiteout(key string) bool {
+	_, err := os.Stat(c.whiteoutPath(key))
+	return err == nil
+}
+
+// written - updates the whiteout of a record just written to the upper
+// layer: cleared, or kept while the record expires so the base copy stays
+// hidden once it lapses or is reaped
+func (c *_overlayCollection) written(key string) (err error) {
+	if c.upper.expiry.expiresOf(c.upper.lockPath(key)).IsZero() || c.base == nil || !c.base.has(key) {
+		err = os.Remove(c.whiteoutPath(key))
+		if errors.Is(err, os.ErrNotExist) {
+			err = nil
+		}
+	} else {
+		err = os.WriteFile(c.whiteoutPath(key), nil, os.ModePerm)
+	}
+	if err != nil {
+		c.logger.Error("unable to update whiteout", zap.Error(err))
+	}
+	return err
+}
+
+func (c *_overlayCollection) whiteoutPath(key string) string {
+	return filepath.Join(c.whiteouts, c.upper.fileKey(key))
+}
+
+func (c *_overlayCollection) notFound(key string) error {
+	return &os.PathError{Op: "open", Path: c.upper.getFullPath(key, PlainCodec), Err: os.ErrNotExist}
+}
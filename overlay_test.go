@@ -0,0 +1,185 @@
+package simplejsondb_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func newOverlay(t *testing.T, base, overlay string) (simplejsondb.DB, simplejsondb.Collection) {
+	bdb, collection := dbtest.Open(t, base, nil)
+	bc := collection("collection1")
+	record := []byte(`{"layer": "base"}`)
+	dbtest.Seed(t, bc, map[string][]byte{"key1": record, "key2": record, "key3": record})
+	db, err := simplejsondb.NewOverlay(bdb, overlay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, bc
+}
+
+func TestOverlayConformance(t *testing.T) {
+	base, overlay := t.TempDir(), t.TempDir()
+	db, _ := newOverlay(t, base, overlay)
+	testConformance(t, db)
+}
+
+func TestOverlayReadThrough(t *testing.T) {
+	base, overlay := t.TempDir(), t.TempDir()
+	db, _ := newOverlay(t, base, overlay)
+	c, err := db.Collection("collection1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := c.Get("key1")
+	if err != nil || string(data) != `{"layer": "base"}` {
+		t.Error("Test failed - ", string(data), err)
+	}
+	if len(c.GetAll()) != 3 {
+		t.Error("Test failed - expected 3 records", len(c.GetAll()))
+	}
+}
+
+func TestOverlayCopyOnWrite(t *testing.T) {
+	base, overlay := t.TempDir(), t.TempDir()
+	db, bc := newOverlay(t, base, overlay)
+	c, err := db.Collection("collection1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.Create("key1", []byte(`{"layer": "overlay"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.Create("key4", []byte(`{"layer": "overlay"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.Delete("key2")
+	if err != nil {
+		t.Error("Test failed - ", err)
+	}
+
+	data, _ := c.Get("key1")
+	if string(data) != `{"layer": "overlay"}` {
+		t.Error("Test failed - overlay record not returned", string(data))
+	}
+	_, err = c.Get("key2")
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Error("Test failed - whiteout not honored", err)
+	}
+	if len(c.GetAll()) != 3 {
+		t.Error("Test failed - expected key1, key3, key4", len(c.GetAll()))
+	}
+
+	// base is untouched
+	data, _ = bc.Get("key1")
+	if string(data) != `{"layer": "base"}` {
+		t.Error("Test failed - base modified", string(data))
+	}
+	if _, err = bc.Get("key2"); err != nil {
+		t.Error("Test failed - base record deleted", err)
+	}
+	if len(bc.GetAll()) != 3 {
+		t.Error("Test failed - base modified", len(bc.GetAll()))
+	}
+
+	// recreating a deleted base record clears the whiteout
+	err = c.Create("key2", []byte(`{"layer": "overlay"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ = c.Get("key2")
+	if string(data) != `{"layer": "overlay"}` {
+		t.Error("Test failed - ", string(data))
+	}
+	err = c.Delete("key2")
+	if err != nil {
+		t.Error("Test failed - ", err)
+	}
+	if _, err = c.Get("key2"); !errors.Is(err, os.ErrNotExist) {
+		t.Error("Test failed - base record visible after delete", err)
+	}
+	if err = c.Delete("key2"); err == nil {
+		t.Error("Test failed - double delete succeeded")
+	}
+}
+
+func TestOverlayMissingBaseCollection(t *testing.T) {
+	base, overlay := t.TempDir(), t.TempDir()
+	db, _ := newOverlay(t, base, overlay)
+	c, err := db.Collection("collection2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.GetAll()) != 0 {
+		t.Error("Test failed - expected empty collection")
+	}
+	if _, err = os.Stat(filepath.Join(base, "collection2")); !errors.Is(err, os.ErrNotExist) {
+		t.Error("Test failed - base collection created", err)
+	}
+}
+
+func TestOverlayCreateNX(t *testing.T) {
+	db, _ := newOverlay(t, t.TempDir(), t.TempDir())
+	c, err := db.Collection("collection1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// records of base are taken
+	if err = c.CreateNX("key1", []byte(`{}`)); !errors.Is(err, simplejsondb.ErrExists) {
+		t.Error("Test failed - ", err)
+	}
+	// deleted ones are free again
+	_ = c.Delete("key1")
+	if err = c.CreateNX("key1", []byte(`{"layer": "overlay"}`)); err != nil {
+		t.Error("Test failed - ", err)
+	}
+	dbtest.RequireRecord(t, c, "key1", []byte(`{"layer": "overlay"}`))
+}
+
+func TestOverlayExpiredHidesBase(t *testing.T) {
+	base, overlay := t.TempDir(), t.TempDir()
+	db, _ := newOverlay(t, base, overlay)
+	c, err := db.Collection("collection1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.Create("key1", []byte(`{"layer": "overlay"}`), simplejsondb.CreateOptions{TTL: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dbtest.RequireRecord(t, c, "key1", []byte(`{"layer": "overlay"}`))
+	time.Sleep(30 * time.Millisecond)
+	if _, err := c.Get("key1"); !errors.Is(err, simplejsondb.ErrRecordNotFound) {
+		t.Error("Test failed - base record resurfaced", err)
+	}
+	if n := len(c.GetAll()); n != 2 {
+		t.Error("Test failed - expected 2 records", n)
+	}
+
+	// once reaped, and from a fresh overlay, the base stays hidden
+	if err := os.Remove(filepath.Join(overlay, "collection1", "key1"+simplejsondb.Ext)); err != nil {
+		t.Fatal(err)
+	}
+	bdb, _ := dbtest.Open(t, base, nil)
+	db, err = simplejsondb.NewOverlay(bdb, overlay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err = db.Collection("collection1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Delete("key1"); !errors.Is(err, simplejsondb.ErrRecordNotFound) {
+		t.Error("Test failed - ", err)
+	}
+	if _, err := c.Get("key1"); !errors.Is(err, simplejsondb.ErrRecordNotFound) {
+		t.Error("Test failed - base record resurfaced", err)
+	}
+}
@@ -0,0 +1,142 @@
+package simplejsondb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrDatabaseLocked - another live process owns the database
+var ErrDatabaseLocked = errors.New("database locked")
+
+// ownerFile - lock file in the database root held under ExclusiveOwner
+const ownerFile = "LOCK"
+
+// defaultStaleLockAfter - age after which a LOCK nobody refreshes is
+// taken over when StaleLockAfter is unset
+const defaultStaleLockAfter = 30 * time.Second
+
+// The LOCK file is created with O_EXCL, which unlike flock also works
+// over NFS, and names its owner. The owner touches it every third of
+// StaleLockAfter, so a LOCK left behind by a crashed process or another
+// host is recognised by its age rather than by its pid.
+
+type _owner struct {
+	path   string
+	token  []byte
+	logger Logger
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// acquireOwner - creates the LOCK file of dir, taking over one not
+// refreshed within staleAfter
+func acquireOwner(dir string, staleAfter time.Duration, logger Logger) (*_owner, error) {
+	if staleAfter <= 0 {
+		staleAfter = defaultStaleLockAfter
+	}
+	host, _ := os.Hostname()
+	o := &_owner{
+		path:   filepath.Join(dir, ownerFile),
+		token:  []byte(fmt.Sprintf("%d@%s %d\n", os.Getpid(), host, time.Now().UnixNano())),
+		logger: logger,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	for attempt := 0; attempt < 2; attempt++ {
+		err := o.create()
+		if !errors.Is(err, os.ErrExist) {
+			if err != nil {
+				return nil, err
+			}
+			go o.heartbeat(staleAfter / 3)
+			return o, nil
+		}
+		held, err := os.ReadFile(o.path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		info, err := os.Stat(o.path)
+		if err == nil && time.Since(info.ModTime()) < staleAfter {
+			return nil, fmt.Errorf("%w: %s held by %s", ErrDatabaseLocked, o.path, bytes.TrimSpace(held))
+		}
+		// stale, only removed while it still names the same owner
+		if current, _ := os.ReadFile(o.path); bytes.Equal(current, held) {
+			logger.Warn("taking over stale database lock", zap.String("path", o.path), zap.ByteString("owner", bytes.TrimSpace(held)))
+			if err = os.Remove(o.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return nil, err
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrDatabaseLocked, o.path)
+}
+
+// create - writes the LOCK file, failing when it exists
+func (o *_owner) create() (err error) {
+	f, err := os.OpenFile(o.path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(o.token)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(o.path)
+	}
+	return err
+}
+
+// heartbeat - keeps the LOCK fresh until release
+func (o *_owner) heartbeat(interval time.Duration) {
+	defer close(o.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-o.stop:
+			return
+		case now := <-ticker.C:
+			if err := os.Chtimes(o.path, now, now); err != nil {
+				o.logger.Error("unable to refresh database lock", zap.Error(err))
+			}
+		}
+	}
+}
+
+// release - stops the heartbeat and removes the LOCK unless another
+// process took it over meanwhile
+func (o *_owner) release() error {
+	if o == nil {
+		return nil
+	}
+	close(o.stop)
+	<-o.done
+	held, err := os.ReadFile(o.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(held, o.token) {
+		o.logger.Warn("database lock was taken over", zap.String("path", o.path))
+		return nil
+	}
+	err = os.Remove(o.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,71 @@
+package simplejsondb_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+)
+
+func TestExclusiveOwner(t *testing.T) {
+	dir := t.TempDir()
+	opts := &simplejsondb.Options{ExclusiveOwner: true, StaleLockAfter: 300 * time.Millisecond}
+	db, err := simplejsondb.New(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the heartbeat keeps the lock fresh past StaleLockAfter
+	time.Sleep(500 * time.Millisecond)
+	if _, err = simplejsondb.New(dir, opts); !errors.Is(err, simplejsondb.ErrDatabaseLocked) {
+		t.Error("Test failed - ", err)
+	}
+	if _, err = simplejsondb.New(dir, nil); err != nil {
+		t.Error("Test failed - ", err)
+	}
+	if err = db.Close(); err != nil {
+		t.Error("Test failed - ", err)
+	}
+	if _, err = os.Stat(filepath.Join(dir, "LOCK")); !errors.Is(err, os.ErrNotExist) {
+		t.Error("Test failed - ", err)
+	}
+
+	db, err = simplejsondb.New(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db.HasCollection("LOCK") {
+		t.Error("Test failed - LOCK listed as a collection")
+	}
+	if err = db.Close(); err != nil {
+		t.Error("Test failed - ", err)
+	}
+}
+
+func TestExclusiveOwnerStale(t *testing.T) {
+	dir := t.TempDir()
+	lock := filepath.Join(dir, "LOCK")
+	if err := os.WriteFile(lock, []byte("1@crashed 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	opts := &simplejsondb.Options{ExclusiveOwner: true, StaleLockAfter: time.Minute}
+	if _, err := simplejsondb.New(dir, opts); !errors.Is(err, simplejsondb.ErrDatabaseLocked) {
+		t.Error("Test failed - ", err)
+	}
+	old := time.Now().Add(-2 * time.Minute)
+	if err := os.Chtimes(lock, old, old); err != nil {
+		t.Fatal(err)
+	}
+	db, err := simplejsondb.New(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(lock); string(data) == "1@crashed 1\n" {
+		t.Error("Test failed - ", string(data))
+	}
+	if err = db.Close(); err != nil {
+		t.Error("Test failed - ", err)
+	}
+}
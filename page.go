@@ -0,0 +1,52 @@
+package simplejsondb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// GetPage - the records at positions offset to offset+limit of the sorted
+// ids, only those records are read
+//
+// Offsets past the end give an empty page. Records deleted between
+// listing and reading are left out, so a page may come back short.
+func (c *_collection) GetPage(offset, limit int) (ids []string, data [][]byte, err error) {
+	return getPage(c, offset, limit)
+}
+
+// GetPage - a page of the visible records
+func (c *_overlayCollection) GetPage(offset, limit int) (ids []string, data [][]byte, err error) {
+	return getPage(c, offset, limit)
+}
+
+func getPage(c _layer, offset, limit int) (ids []string, data [][]byte, err error) {
+	if limit <= 0 || offset < 0 {
+		return nil, nil, fmt.Errorf("invalid page: offset %d, limit %d", offset, limit)
+	}
+	keys, err := c.keys()
+	if err != nil {
+		return nil, nil, err
+	}
+	if offset >= len(keys) {
+		return []string{}, [][]byte{}, nil
+	}
+	keys = keys[offset:]
+	if len(keys) > limit {
+		keys = keys[:limit]
+	}
+	ids = make([]string, 0, len(keys))
+	data = make([][]byte, 0, len(keys))
+	for _, key := range keys {
+		record, err := c.Get(key)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		ids = append(ids, key)
+		data = append(data, record)
+	}
+	return ids, data, nil
+}
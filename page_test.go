@@ -0,0 +1,53 @@
+package simplejsondb_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestGetPage(t *testing.T) {
+	_, collection := dbtest.NewDB(t, nil)
+	c := collection("admin")
+	ids := dbtest.SeedN(t, c, 25)
+
+	var seen []string
+	for offset := 0; ; offset += 10 {
+		page, data, err := c.GetPage(offset, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page) == 0 {
+			break
+		}
+		if len(page) != len(data) {
+			t.Fatal("Test failed - ", len(page), len(data))
+		}
+		for i, id := range page {
+			var n int
+			_, _ = fmt.Sscanf(id, "record%d", &n)
+			if string(data[i]) != fmt.Sprintf(`{"n": %d}`, n) {
+				t.Error("Test failed - ", id, string(data[i]))
+			}
+		}
+		seen = append(seen, page...)
+	}
+	if len(seen) != len(ids) {
+		t.Error("Test failed - ", len(seen))
+	}
+	for i := 1; i < len(seen); i++ {
+		if seen[i-1] >= seen[i] {
+			t.Error("Test failed - not sorted", seen[i-1], seen[i])
+		}
+	}
+
+	if page, data, err := c.GetPage(100, 10); err != nil || len(page) != 0 || len(data) != 0 {
+		t.Error("Test failed - ", page, err)
+	}
+	for _, bad := range [][2]int{{0, 0}, {0, -1}, {-1, 10}} {
+		if _, _, err := c.GetPage(bad[0], bad[1]); err == nil {
+			t.Error("Test failed - accepted", bad)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package simplejsondb
+
+import (
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// Len - number of records, an id stored in several formats counts once
+// while temp, ignored and foreign files do not count
+//
+// The directory is scanned once, later calls return the count kept by
+// the writes of this database unless the directory mtime shows another
+// process changed it. RecalculateUsage forces a recount.
+func (c *_collection) Len() uint64 {
+	n, err := c.records.count(c)
+	if err != nil {
+		c.logger.Error("no data available", zap.Error(err))
+	}
+	return n
+}
+
+// Len - number of records visible through the overlay
+func (c *_overlayCollection) Len() uint64 {
+	return length(c, c.logger)
+}
+
+func length(c _layer, logger Logger) uint64 {
+	n, err := lenPrefix(c, "")
+	if err != nil {
+		logger.Error("no data available", zap.Error(err))
+	}
+	return n
+}
+
+// LenPrefix - number of records whose id starts with prefix
+func (c *_collection) LenPrefix(prefix string) (uint64, error) {
+	return lenPrefix(c, prefix)
+}
+
+// KeysPrefix - sorted ids starting with prefix, at most limit of them
+// (all when limit <= 0) and only those after afterKey, which allows
+// cursor style pagination by passing the last id of the previous page
+func (c *_collection) KeysPrefix(prefix string, limit int, afterKey string) ([]string, error) {
+	return keysPrefix(c, prefix, limit, afterKey)
+}
+
+// GetAllByPrefix - the records whose id starts with prefix by id, only
+// their files are read; the empty prefix returns every record like GetAll
+func (c *_collection) GetAllByPrefix(prefix string) map[string][]byte {
+	return getAllByPrefix(c, c.logger, prefix)
+}
+
+// GetAllByPrefix - the visible records whose id starts with prefix
+func (c *_overlayCollection) GetAllByPrefix(prefix string) map[string][]byte {
+	return getAllByPrefix(c, c.logger, prefix)
+}
+
+// LenPrefix - number of visible records whose id starts with prefix
+func (c *_overlayCollection) LenPrefix(prefix string) (uint64, error) {
+	return lenPrefix(c, prefix)
+}
+
+// KeysPrefix - sorted visible ids starting with prefix, see Collection
+func (c *_overlayCollection) KeysPrefix(prefix string, limit int, afterKey string) ([]string, error) {
+	return keysPrefix(c, prefix, limit, afterKey)
+}
+
+func lenPrefix(c _layer, prefix string) (uint64, error) {
+	keys, err := c.keys()
+	if err != nil {
+		return 0, err
+	}
+	lo, hi := prefixRange(keys, prefix)
+	return uint64(hi - lo), nil
+}
+
+func getAllByPrefix(c _layer, logger Logger, prefix string) map[string][]byte {
+	keys, err := c.keys()
+	if err != nil {
+		logger.Error("no data available", zap.Error(err))
+		return nil
+	}
+	lo, hi := prefixRange(keys, prefix)
+	records := make(map[string][]byte, hi-lo)
+	for _, key := range keys[lo:hi] {
+		data, err := c.Get(key)
+		if err != nil {
+			continue
+		}
+		records[key] = data
+	}
+	return records
+}
+
+func keysPrefix(c _layer, prefix string, limit int, afterKey string) ([]string, error) {
+	keys, err := c.keys()
+	if err != nil {
+		return nil, err
+	}
+	lo, hi := prefixRange(keys, prefix)
+	if afterKey != "" {
+		after := sort.Search(len(keys), func(i int) bool { return keys[i] > afterKey })
+		if after > lo {
+			lo = after
+		}
+	}
+	if lo >= hi {
+		return []string{}, nil
+	}
+	if limit > 0 && hi-lo > limit {
+		hi = lo + limit
+	}
+	return keys[lo:hi], nil
+}
+
+// prefixRange - bounds of the ids starting with prefix in sorted keys
+func prefixRange(keys []string, prefix string) (lo, hi int) {
+	lo = sort.SearchStrings(keys, prefix)
+	hi = lo + sort.Search(len(keys)-lo, func(i int) bool {
+		return !strings.HasPrefix(keys[lo+i], prefix)
+	})
+	return lo, hi
+}
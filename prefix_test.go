@@ -0,0 +1,206 @@
+package simplejsondb_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestPrefixListing(t *testing.T) {
+	_, collection := dbtest.NewDB(t, nil)
+	c := collection("orders")
+	for day := 17; day <= 19; day++ {
+		for i := 0; i < 5; i++ {
+			id := fmt.Sprintf("order:2024-06-%d:%d", day, i)
+			_ = c.Create(id, []byte(`{}`), simplejsondb.CreateOptions{UseGzip: i%2 == 0})
+		}
+	}
+
+	n, err := c.LenPrefix("order:2024-06-18:")
+	if err != nil || n != 5 {
+		t.Error("Test failed - ", n, err)
+	}
+	n, _ = c.LenPrefix("order:")
+	if n != 15 {
+		t.Error("Test failed - ", n)
+	}
+	n, _ = c.LenPrefix("invoice:")
+	if n != 0 {
+		t.Error("Test failed - ", n)
+	}
+
+	all, err := c.KeysPrefix("order:2024-06-18:", 0, "")
+	if err != nil || len(all) != 5 {
+		t.Fatal("Test failed - ", all, err)
+	}
+
+	// paging with a cursor yields the same ids as one full listing
+	var paged []string
+	after := ""
+	for {
+		page, err := c.KeysPrefix("order:2024-06-18:", 2, after)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page) == 0 {
+			break
+		}
+		if len(page) > 2 {
+			t.Error("Test failed - page over limit", page)
+		}
+		paged = append(paged, page...)
+		after = page[len(page)-1]
+	}
+	if !reflect.DeepEqual(paged, all) {
+		t.Error("Test failed - ", paged, all)
+	}
+
+	// a cursor before the prefix range starts at the range
+	page, _ := c.KeysPrefix("order:2024-06-18:", 1, "order:2024-06-17:4")
+	if len(page) != 1 || page[0] != "order:2024-06-18:0" {
+		t.Error("Test failed - ", page)
+	}
+	page, _ = c.KeysPrefix("order:2024-06-18:", 1, "order:2024-06-19:0")
+	if len(page) != 0 {
+		t.Error("Test failed - ", page)
+	}
+}
+
+func TestGetAllByPrefix(t *testing.T) {
+	dir := t.TempDir()
+	var gets int
+	_, collection := dbtest.Open(t, dir, &simplejsondb.Options{DetailedTimings: true, OnOperation: func(tm simplejsondb.OpTimings) {
+		if tm.Op == "get" {
+			gets++
+		}
+	}})
+	c := collection("days")
+	for day := 17; day <= 19; day++ {
+		for i := 0; i < 4; i++ {
+			id := fmt.Sprintf("2024-06-%dT%02d", day, i)
+			_ = c.Create(id, []byte(fmt.Sprintf(`"%s"`, id)), simplejsondb.CreateOptions{UseGzip: i%2 == 0})
+		}
+	}
+	_ = os.WriteFile(filepath.Join(dir, "days", ".tmp-2024-06-18T99"), []byte("partial"), 0644)
+
+	gets = 0
+	records := c.GetAllByPrefix("2024-06-18")
+	if len(records) != 4 || gets != 4 {
+		t.Error("Test failed - ", len(records), gets)
+	}
+	for id, data := range records {
+		if string(data) != `"`+id+`"` {
+			t.Error("Test failed - ", id, string(data))
+		}
+	}
+	if records = c.GetAllByPrefix(""); len(records) != len(c.GetAll()) {
+		t.Error("Test failed - ", len(records))
+	}
+	if records = c.GetAllByPrefix("2025"); len(records) != 0 {
+		t.Error("Test failed - ", records)
+	}
+}
+
+func TestLen(t *testing.T) {
+	for _, noDefaultIgnores := range []bool{false, true} {
+		dir := t.TempDir()
+		_, collection := dbtest.Open(t, dir, &simplejsondb.Options{NoDefaultIgnores: noDefaultIgnores})
+		c := collection("counted")
+		dbtest.SeedN(t, c, 3)
+		if n := c.Len(); n != 3 {
+			t.Error("Test failed - ", n)
+		}
+		stray := map[string]string{
+			".tmp-crashed":             "{}",
+			".tmp-crashed.json":        "{}",
+			"README":                   "hello",
+			"notes.txt":                "hi",
+			"record0.json.gz":          "",
+			"record1.json.bak":         "{}",
+			filepath.Join("sub", "x"):  "{}",
+			filepath.Join("sub2.json"): "",
+		}
+		_ = os.Mkdir(filepath.Join(dir, "counted", "sub"), os.ModePerm)
+		_ = os.Mkdir(filepath.Join(dir, "counted", "sub2.json"), os.ModePerm)
+		for name, data := range stray {
+			_ = os.WriteFile(filepath.Join(dir, "counted", name), []byte(data), 0644)
+		}
+		if n := c.Len(); n != 3 {
+			t.Error("Test failed - ", noDefaultIgnores, n, c.Keys())
+		}
+	}
+}
+
+func TestLenCached(t *testing.T) {
+	dir := t.TempDir()
+	_, collection := dbtest.Open(t, dir, nil)
+	c := collection("counted")
+	dbtest.SeedN(t, c, 3)
+	if n := c.Len(); n != 3 {
+		t.Error("Test failed - ", n)
+	}
+
+	other := collection("counted")
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := other.Create(fmt.Sprint("new", i), []byte(`{}`)); err != nil {
+				t.Error("Test failed - ", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if err := c.Create("record0", []byte(`{"n": 9}`), simplejsondb.CreateOptions{UseGzip: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Delete("record1"); err != nil {
+		t.Fatal(err)
+	}
+	if n := c.Len(); n != 22 {
+		t.Error("Test failed - ", n)
+	}
+
+	// another process writing is noticed by the directory mtime
+	path := filepath.Join(dir, "counted")
+	if err := os.WriteFile(filepath.Join(path, "external.json"), []byte(`{}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if n := c.Len(); n != 23 {
+		t.Error("Test failed - ", n)
+	}
+	// unless the mtime is put back, then only a recount sees the change
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Remove(filepath.Join(path, "external.json")); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Chtimes(path, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+	if n := c.Len(); n != 23 {
+		t.Error("Test failed - count not cached", n)
+	}
+	if n, err := c.RecalculateUsage(); err != nil || n != 22 {
+		t.Error("Test failed - ", n, err)
+	}
+	if n := c.Len(); n != 22 {
+		t.Error("Test failed - ", n)
+	}
+
+	if err = c.Truncate(); err != nil {
+		t.Fatal(err)
+	}
+	if n := c.Len(); n != 0 {
+		t.Error("Test failed - ", n)
+	}
+}
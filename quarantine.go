@@ -0,0 +1,154 @@
+package simplejsondb
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrCorruptRecord - a record file exists but cannot be decoded
+	ErrCorruptRecord = errors.New("corrupt record")
+	// ErrQuarantined - reads of the record are refused after repeated
+	// corruption, until it is rewritten or unquarantined
+	ErrQuarantined = errors.New("record quarantined")
+)
+
+type (
+	// _quarantine - in memory counts of consecutive corrupt reads per
+	// record, keyed by record lock path
+	_quarantine struct {
+		mu      sync.Mutex
+		after   int
+		records map[string]*_suspect
+	}
+
+	_suspect struct {
+		dir      string
+		id       string
+		failures int
+	}
+)
+
+// newQuarantine - quarantines records after failures corrupt reads, nil
+// when disabled
+func newQuarantine(after int) *_quarantine {
+	if after <= 0 {
+		return nil
+	}
+	return &_quarantine{after: after, records: make(map[string]*_suspect)}
+}
+
+// threshold - the configured QuarantineAfter
+func (q *_quarantine) threshold() int {
+	if q == nil {
+		return 0
+	}
+	return q.after
+}
+
+// check - refuses quarantined records
+func (q *_quarantine) check(c *_collection, key string) error {
+	if q == nil {
+		return nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	s, ok := q.records[c.lockPath(key)]
+	if ok && s.failures >= q.after {
+		return fmt.Errorf("%w: %s after %d corrupt reads", ErrQuarantined, key, s.failures)
+	}
+	return nil
+}
+
+// observe - counts corrupt reads and resets on success, other failures
+// such as missing records leave the count alone
+func (q *_quarantine) observe(c *_collection, key string, err error) {
+	if q == nil {
+		return
+	}
+	if err == nil {
+		q.clear(c, key)
+		return
+	}
+	if !errors.Is(err, ErrCorruptRecord) {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	path := c.lockPath(key)
+	s, ok := q.records[path]
+	if !ok {
+		s = &_suspect{dir: c.path, id: key}
+		q.records[path] = s
+	}
+	s.failures++
+}
+
+// clear - forgets the failures of a record
+func (q *_quarantine) clear(c *_collection, key string) {
+	if q == nil {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.records, c.lockPath(key))
+}
+
+// clearDir - forgets every record of a dropped collection
+func (q *_quarantine) clearDir(dir string) {
+	if q == nil {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for path, s := range q.records {
+		if s.dir == dir {
+			delete(q.records, path)
+		}
+	}
+}
+
+// clearRoot - forgets every record below a closed database root
+func (q *_quarantine) clearRoot(root string) {
+	if q == nil {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	prefix := root + string(filepath.Separator)
+	for path := range q.records {
+		if strings.HasPrefix(path, prefix) {
+			delete(q.records, path)
+		}
+	}
+}
+
+// list - the sorted quarantined ids of a collection
+func (q *_quarantine) list(c *_collection) (ids []string) {
+	if q == nil {
+		return nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, s := range q.records {
+		if s.dir == c.path && s.failures >= q.after {
+			ids = append(ids, s.id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Quarantined - ids whose reads are refused after repeated corruption
+func (c *_collection) Quarantined() []string {
+	return c.quarantine.list(c)
+}
+
+// Unquarantine - lets reads of the record reach the disk again
+func (c *_collection) Unquarantine(key string) {
+	c.quarantine.clear(c, key)
+}
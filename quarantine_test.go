@@ -0,0 +1,63 @@
+package simplejsondb_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestQuarantine(t *testing.T) {
+	dir := t.TempDir()
+	_, collection := dbtest.Open(t, dir, &simplejsondb.Options{QuarantineAfter: 3})
+	c := collection("poisoned")
+	corrupt := filepath.Join(dir, "poisoned", "bad.json.gz")
+	if err := os.WriteFile(corrupt, []byte("not gzip at all"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// missing records never count
+	for i := 0; i < 5; i++ {
+		if _, err := c.Get("missing"); !errors.Is(err, os.ErrNotExist) {
+			t.Error("Test failed - ", err)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := c.Get("bad"); !errors.Is(err, simplejsondb.ErrCorruptRecord) {
+			t.Error("Test failed - ", i, err)
+		}
+	}
+	// fast fail without touching the disk
+	_ = os.Remove(corrupt)
+	if _, err := c.Get("bad"); !errors.Is(err, simplejsondb.ErrQuarantined) {
+		t.Error("Test failed - ", err)
+	}
+	if ids := c.Quarantined(); len(ids) != 1 || ids[0] != "bad" {
+		t.Error("Test failed - ", ids)
+	}
+
+	c.Unquarantine("bad")
+	if _, err := c.Get("bad"); !errors.Is(err, os.ErrNotExist) {
+		t.Error("Test failed - ", err)
+	}
+
+	_ = os.WriteFile(corrupt, []byte("not gzip at all"), 0644)
+	for i := 0; i < 3; i++ {
+		_, _ = c.Get("bad")
+	}
+	if len(c.Quarantined()) != 1 {
+		t.Error("Test failed - not quarantined again")
+	}
+	if err := c.Create("bad", []byte(`{"fixed": true}`), simplejsondb.CreateOptions{UseGzip: true}); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := c.Get("bad"); err != nil || string(data) != `{"fixed": true}` {
+		t.Error("Test failed - create did not clear quarantine", string(data), err)
+	}
+	if ids := c.Quarantined(); len(ids) != 0 {
+		t.Error("Test failed - ", ids)
+	}
+}
@@ -0,0 +1,157 @@
+package simplejsondb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// ErrQuotaExceeded - a write would take the collection over QuotaBytes
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// _usage - bytes of the record files of a collection directory, shared
+// by its handles
+//
+// Nothing is tracked until the first write with Options.QuotaBytes or the
+// first Usage sizes the directory, so a restarted process picks up where
+// the last one left. From then on writes and deletes of this database keep
+// it up to date under their record locks, RecalculateUsage drops it to be
+// sized again.
+type _usage struct {
+	mu     sync.Mutex
+	quota  uint64
+	bytes  uint64
+	loaded bool
+}
+
+// usageOf - the shared byte usage of a collection directory
+func (db *_db) usageOf(c *_collection) *_usage {
+	db.indexMu.Lock()
+	defer db.indexMu.Unlock()
+	u, ok := db.usage[c.path]
+	if !ok {
+		u = &_usage{quota: db.quotaBytes}
+		db.usage[c.path] = u
+	}
+	return u
+}
+
+// load - sizes the directory unless done, the caller holds u.mu
+func (u *_usage) load(c *_collection) error {
+	if u.loaded {
+		return nil
+	}
+	size, err := c.Size()
+	if err != nil {
+		return err
+	}
+	u.bytes, u.loaded = size, true
+	return nil
+}
+
+// reserve - accounts size bytes about to replace the files of key,
+// refusing growth over the quota; grown is what undo has to take back on
+// a failed write. The caller holds the record lock.
+func (u *_usage) reserve(c *_collection, key string, size int64) (grown int64, err error) {
+	if u == nil {
+		return 0, nil
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.quota == 0 && !u.loaded {
+		return 0, nil
+	}
+	if err = u.load(c); err != nil {
+		return 0, err
+	}
+	grown = size - c.storedSize(key)
+	if u.quota > 0 && grown > 0 && u.bytes+uint64(grown) > u.quota {
+		return 0, fmt.Errorf("%w: %d bytes used, %d more, quota %d", ErrQuotaExceeded, u.bytes, grown, u.quota)
+	}
+	u.add(grown)
+	return grown, nil
+}
+
+// freed - takes the files of a deleted or failed record off a loaded usage
+func (u *_usage) freed(n int64) {
+	if u == nil {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.loaded {
+		u.add(-n)
+	}
+}
+
+// add - moves bytes by n, never below zero; the caller holds u.mu
+func (u *_usage) add(n int64) {
+	switch {
+	case n >= 0:
+		u.bytes += uint64(n)
+	case uint64(-n) > u.bytes:
+		u.bytes = 0
+	default:
+		u.bytes -= uint64(-n)
+	}
+}
+
+// sizeOf - bytes of the files of key when usage is tracked, 0 otherwise
+func (u *_usage) sizeOf(c *_collection, key string) int64 {
+	if u == nil {
+		return 0
+	}
+	u.mu.Lock()
+	loaded := u.loaded
+	u.mu.Unlock()
+	if !loaded {
+		return 0
+	}
+	return c.storedSize(key)
+}
+
+// set - replaces the usage after a truncate, the caller holds the
+// exclusive collection lock
+func (u *_usage) set(n uint64) {
+	if u != nil {
+		u.mu.Lock()
+		u.bytes, u.loaded = n, true
+		u.mu.Unlock()
+	}
+}
+
+// reset - drops the usage so it is sized again when next needed
+func (u *_usage) reset() {
+	if u != nil {
+		u.mu.Lock()
+		u.loaded = false
+		u.mu.Unlock()
+	}
+}
+
+// storedSize - bytes of every file variant of key on disk
+func (c *_collection) storedSize(key string) (size int64) {
+	for _, codec := range c.codecs {
+		if info, err := os.Stat(c.getFullPath(key, codec)); err == nil {
+			size += info.Size()
+		}
+	}
+	return size
+}
+
+// Usage - bytes of the record files QuotaBytes is checked against, sized
+// from disk by the first call or write and tracked from then on
+func (c *_collection) Usage() (uint64, error) {
+	c.usage.mu.Lock()
+	defer c.usage.mu.Unlock()
+	if err := c.usage.load(c); err != nil {
+		return 0, err
+	}
+	return c.usage.bytes, nil
+}
+
+// Usage - bytes of the overlay layer, which is what QuotaBytes limits
+func (c *_overlayCollection) Usage() (uint64, error) {
+	return c.upper.Usage()
+}
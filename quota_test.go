@@ -0,0 +1,97 @@
+package simplejsondb_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestQuotaBytes(t *testing.T) {
+	dir := t.TempDir()
+	_, collection := dbtest.Open(t, dir, &simplejsondb.Options{QuotaBytes: 100})
+	c := collection("quota")
+	record := append([]byte(`{"k": "`), append(bytes.Repeat([]byte("x"), 32), []byte(`"}`)...)...)
+	dbtest.Seed(t, c, map[string][]byte{"a": record, "b": record})
+	if err := c.Create("c", record); !errors.Is(err, simplejsondb.ErrQuotaExceeded) {
+		t.Error("Test failed - ", err)
+	}
+	if _, err := c.Get("c"); !errors.Is(err, simplejsondb.ErrRecordNotFound) {
+		t.Error("Test failed - ", err)
+	}
+	// overwrites only count what they add
+	if err := c.Create("a", record); err != nil {
+		t.Error("Test failed - ", err)
+	}
+	if used, err := c.Usage(); err != nil || used != uint64(2*len(record)) {
+		t.Error("Test failed - ", used, err)
+	}
+
+	if err := c.Delete("b"); err != nil {
+		t.Fatal(err)
+	}
+	if used, err := c.Usage(); err != nil || used != uint64(len(record)) {
+		t.Error("Test failed - ", used, err)
+	}
+	if err := c.Create("c", record); err != nil {
+		t.Error("Test failed - ", err)
+	}
+
+	// a new process sizes the directory at its first write
+	_, collection = dbtest.Open(t, dir, &simplejsondb.Options{QuotaBytes: 100})
+	c = collection("quota")
+	if err := c.Create("d", record); !errors.Is(err, simplejsondb.ErrQuotaExceeded) {
+		t.Error("Test failed - ", err)
+	}
+	if used, err := c.Usage(); err != nil || used != uint64(2*len(record)) {
+		t.Error("Test failed - ", used, err)
+	}
+	if err := c.Truncate(); err != nil {
+		t.Fatal(err)
+	}
+	if used, err := c.Usage(); err != nil || used != 0 {
+		t.Error("Test failed - ", used, err)
+	}
+}
+
+func TestQuotaDropCollection(t *testing.T) {
+	db, collection := dbtest.NewDB(t, &simplejsondb.Options{QuotaBytes: 100})
+	record := append([]byte(`{"k": "`), append(bytes.Repeat([]byte("x"), 32), []byte(`"}`)...)...)
+	dbtest.Seed(t, collection("quota"), map[string][]byte{"a": record, "b": record})
+	if err := db.DropCollection("quota"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the recreated collection starts from nothing, not from the dropped usage
+	c := collection("quota")
+	if used, err := c.Usage(); err != nil || used != 0 {
+		t.Error("Test failed - ", used, err)
+	}
+	for _, key := range []string{"a", "b"} {
+		if err := c.Create(key, record); err != nil {
+			t.Error("Test failed - ", key, err)
+		}
+	}
+}
+
+func TestUsageWithoutQuota(t *testing.T) {
+	_, collection := dbtest.NewDB(t, nil)
+	c := collection("usage")
+	dbtest.SeedN(t, c, 3)
+	used, err := c.Usage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	size, err := c.Size()
+	if err != nil || used != size || used == 0 {
+		t.Error("Test failed - ", used, size, err)
+	}
+	if err := c.Delete("record0"); err != nil {
+		t.Fatal(err)
+	}
+	if after, _ := c.Usage(); after >= used {
+		t.Error("Test failed - ", after, used)
+	}
+}
@@ -0,0 +1,248 @@
+package simplejsondb
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// _recordReader - a streamed record holding the shared record lock and a
+// place among the running operations until Close
+type _recordReader struct {
+	io.Reader
+	closers []io.Closer
+	once    sync.Once
+	release func()
+}
+
+// Close - closes the file and releases the lock, later calls do nothing
+func (r *_recordReader) Close() (err error) {
+	r.once.Do(func() {
+		for _, c := range r.closers {
+			if cerr := c.Close(); err == nil {
+				err = cerr
+			}
+		}
+		r.release()
+	})
+	return err
+}
+
+// GetReader - streams the record instead of loading it like Get
+//
+// Plain files are read as they are, gzipped ones through a gzip.Reader,
+// so neither is held in memory at once. Records of other codecs, and all
+// of them with VerifyChecksums or NormalizeOnRead, have to be decoded
+// whole and are served from memory.
+//
+// The reader holds the shared record lock, which keeps writers of the
+// record waiting, and counts as a running operation DB.Close waits for.
+// Callers must Close it promptly; closing twice is safe.
+func (c *_collection) GetReader(key string) (reader io.ReadCloser, err error) {
+	defer func() { err = wrapNotFound(ErrRecordNotFound, key, err) }()
+	if err = c.checkID(key); err != nil {
+		return nil, err
+	}
+	if err = c.life.begin(); err != nil {
+		return nil, err
+	}
+	p := c.paths(key)
+	acquire(p.lock, false)
+	r := &_recordReader{release: func() {
+		release(p.lock, false)
+		c.life.end()
+	}}
+	defer func() {
+		if err != nil {
+			r.Close()
+		}
+	}()
+	if c.expiry.expired(p.lock) {
+		return nil, expiredError(p.plain)
+	}
+	if err = c.quarantine.check(c, key); err != nil {
+		return nil, err
+	}
+	if data, ok := c.cache.get(p.lock, c.zeroCopy); ok {
+		r.Reader = bytes.NewReader(data)
+		return r, nil
+	}
+	filename, codec, err := c.resolve(key)
+	if err != nil {
+		return nil, err
+	}
+	if (codec != PlainCodec && codec != GzipCodec) || c.verifyChecksums || c.normalizeOnRead {
+		data, err := c.read(key, filename, codec, nil)
+		c.quarantine.observe(c, key, err)
+		if err != nil {
+			return nil, err
+		}
+		r.Reader = bytes.NewReader(data)
+		return r, nil
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	r.closers = append(r.closers, f)
+	if info, err := f.Stat(); err == nil && codec == PlainCodec {
+		if err = c.checkSize(key, info.Size()); err != nil {
+			return nil, err
+		}
+	}
+	var stream io.Reader = f
+	if codec == GzipCodec {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			err = fmt.Errorf("%w: %w", ErrCorruptRecord, err)
+			c.quarantine.observe(c, key, err)
+			return nil, err
+		}
+		r.closers = append(r.closers, zr)
+		stream = c.limitSize(key, zr)
+	}
+	r.Reader = skipBOM(stream)
+	return r, nil
+}
+
+// GetReader - streams the visible record
+func (c *_overlayCollection) GetReader(key string) (reader io.ReadCloser, err error) {
+	defer func() { err = wrapNotFound(ErrRecordNotFound, key, err) }()
+	if err = c.upper.checkID(key); err != nil {
+		return nil, err
+	}
+	if err = c.upper.life.begin(); err != nil {
+		return nil, err
+	}
+	defer c.upper.life.end()
+	if c.upper.has(key) {
+		return c.upper.GetReader(key)
+	}
+	if !c.inBase(key) {
+		return nil, c.notFound(key)
+	}
+	return c.base.GetReader(key)
+}
+
+// skipBOM - r without a leading UTF-8 byte order mark, as Get returns it
+func skipBOM(r io.Reader) io.Reader {
+	br := bufio.NewReader(r)
+	if head, err := br.Peek(len(utf8BOM)); err == nil && bytes.Equal(head, utf8BOM) {
+		br.Discard(len(utf8BOM))
+	}
+	return br
+}
+
+// CreateFromReader - saves the record read from r without holding it in
+// memory
+//
+// r is copied into a temp file beside the record, through a gzip.Writer
+// for gzip, synced unless NoFsync and renamed into place under the record
+// lock. The record lock is only taken once r is drained, so a slow r never
+// holds up the record. The collection lock writers share is held from
+// before the temp file is created until it is renamed or removed, so a
+// Truncate or DropCollection waits rather than pulling the file away; with
+// SerializeWrites it is exclusive and other writes wait for r as well.
+// When r fails the temp file is removed and any existing
+// record is left as it was. Records of other codecs, and all of them with
+// ValidateJSON or Checksums, are read into memory and saved like Create.
+// A pinned record is dropped from the cache as its content is never in
+// memory.
+func (c *_collection) CreateFromReader(key string, r io.Reader, options ...CreateOptions) (err error) {
+	if err = c.checkID(key); err != nil {
+		return err
+	}
+	codec := c.codec
+	var ttl time.Duration
+	if options != nil {
+		if options[0].UseGzip {
+			codec = c.enc.wrap(GzipCodec)
+		}
+		ttl = options[0].TTL
+	}
+	if (codec != PlainCodec && codec != GzipCodec) || c.validateJSON || c.checksums {
+		data, err := io.ReadAll(c.limitSize(key, r))
+		if err != nil {
+			return err
+		}
+		return c.create(context.Background(), key, data, false, options)
+	}
+	defer c.observeOp("create", c.metricsStart(), &err)
+	if err = c.life.begin(); err != nil {
+		return err
+	}
+	defer c.life.end()
+	collection, serial := c.collectionLockPath(), c.serializeWrites
+	acquire(collection, serial)
+	defer release(collection, serial)
+	staged, err := c.stage(c.limitSize(key, r), codec)
+	if err != nil {
+		return wrapNotFound(ErrCollectionNotFound, c.name, err)
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(staged)
+		}
+	}()
+	// the record lock alone, c.lock would take the collection lock twice
+	path := c.lockPath(key)
+	acquire(path, true)
+	defer release(path, true)
+	return c.storeStaged(key, nil, nil, codec, c.expiresAt(ttl), nil, staged)
+}
+
+// CreateFromReader - saves the record read from r into the overlay
+func (c *_overlayCollection) CreateFromReader(key string, r io.Reader, options ...CreateOptions) (err error) {
+	if err = c.upper.checkID(key); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	err = c.upper.CreateFromReader(key, r, options...)
+	if err != nil {
+		return err
+	}
+	return c.written(key)
+}
+
+// stage - copies r encoded with codec into a temp file of the collection
+func (c *_collection) stage(r io.Reader, codec Codec) (staged string, err error) {
+	f, err := createTemp(c.path, os.ModePerm)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(f.Name())
+			c.logger.Error("unable to stage record", zap.Error(err))
+		}
+	}()
+	if codec == GzipCodec {
+		zw := gzip.NewWriter(f)
+		_, err = io.Copy(zw, r)
+		if cerr := zw.Close(); err == nil {
+			err = cerr
+		}
+	} else {
+		_, err = io.Copy(f, r)
+	}
+	if errors.Is(err, ErrRecordTooLarge) {
+		return "", err
+	}
+	if err == nil && !c.noFsync {
+		err = f.Sync()
+	}
+	return f.Name(), c.health.observe(err)
+}
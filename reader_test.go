@@ -0,0 +1,233 @@
+package simplejsondb_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func readAll(t *testing.T, c simplejsondb.Collection, key string) []byte {
+	t.Helper()
+	r, err := c.GetReader(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestGetReader(t *testing.T) {
+	dir := t.TempDir()
+	_, collection := dbtest.Open(t, dir, nil)
+	c := collection("blobs")
+	big := bytes.Repeat([]byte(`{"k": "value"},`), 100000)
+	big = append(append([]byte("["), big...), []byte(`{}]`)...)
+	if err := c.Create("zipped", big, simplejsondb.CreateOptions{UseGzip: true}); err != nil {
+		t.Fatal(err)
+	}
+	dbtest.Seed(t, c, map[string][]byte{"plain": []byte(`{"a": 1}`)})
+	if err := os.WriteFile(filepath.Join(dir, "blobs", "bom.json"), []byte("\xEF\xBB\xBF{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := readAll(t, c, "zipped"); !bytes.Equal(got, big) {
+		t.Error("Test failed - ", len(got))
+	}
+	if got := readAll(t, c, "plain"); string(got) != `{"a": 1}` {
+		t.Error("Test failed - ", string(got))
+	}
+	if got := readAll(t, c, "bom"); string(got) != `{}` {
+		t.Error("Test failed - ", string(got))
+	}
+
+	_, err := c.GetReader("missing")
+	if !errors.Is(err, simplejsondb.ErrRecordNotFound) || !errors.Is(err, os.ErrNotExist) {
+		t.Error("Test failed - ", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "blobs", "bad"+simplejsondb.GZipExt), []byte("not gzip"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.GetReader("bad"); !errors.Is(err, simplejsondb.ErrCorruptRecord) {
+		t.Error("Test failed - ", err)
+	}
+}
+
+func TestGetReaderLock(t *testing.T) {
+	_, collection := dbtest.NewDB(t, nil)
+	c := collection("blobs")
+	dbtest.Seed(t, c, map[string][]byte{"r1": []byte(`{"v": 1}`)})
+
+	r, err := c.GetReader("r1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	written := make(chan error)
+	go func() {
+		written <- c.Create("r1", []byte(`{"v": 2}`))
+	}()
+	select {
+	case err = <-written:
+		t.Fatal("Test failed - write did not wait for the reader", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	data, err := io.ReadAll(r)
+	if err != nil || string(data) != `{"v": 1}` {
+		t.Error("Test failed - ", string(data), err)
+	}
+	if err = r.Close(); err != nil {
+		t.Error("Test failed - ", err)
+	}
+	if err = r.Close(); err != nil {
+		t.Error("Test failed - second close", err)
+	}
+	if err = <-written; err != nil {
+		t.Fatal(err)
+	}
+	if got := readAll(t, c, "r1"); string(got) != `{"v": 2}` {
+		t.Error("Test failed - ", string(got))
+	}
+	// the lock was released once, so shared and exclusive locks still work
+	r1, _ := c.GetReader("r1")
+	r2, _ := c.GetReader("r1")
+	r1.Close()
+	r2.Close()
+	if err = c.Delete("r1"); err != nil {
+		t.Error("Test failed - ", err)
+	}
+}
+
+type failingReader struct {
+	data []byte
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, errors.New("connection reset")
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestCreateFromReader(t *testing.T) {
+	dir := t.TempDir()
+	_, collection := dbtest.Open(t, dir, nil)
+	c := collection("blobs")
+	big := bytes.Repeat([]byte(`{"k": "value"},`), 100000)
+	big = append(append([]byte("["), big...), []byte(`{}]`)...)
+
+	if err := c.CreateFromReader("plain", bytes.NewReader(big)); err != nil {
+		t.Fatal(err)
+	}
+	dbtest.RequireRecord(t, c, "plain", big)
+	if err := c.CreateFromReader("zipped", bytes.NewReader(big), simplejsondb.CreateOptions{UseGzip: true}); err != nil {
+		t.Fatal(err)
+	}
+	dbtest.RequireRecord(t, c, "zipped", big)
+	info, err := c.Stat("zipped")
+	if err != nil || info.Ext != simplejsondb.GZipExt || info.Size >= int64(len(big)) {
+		t.Error("Test failed - ", info, err)
+	}
+
+	// a plain write replaces the gzipped variant
+	if err = c.CreateFromReader("zipped", bytes.NewReader([]byte(`{}`))); err != nil {
+		t.Fatal(err)
+	}
+	if info, err = c.Stat("zipped"); err != nil || info.Ext != simplejsondb.Ext {
+		t.Error("Test failed - ", info, err)
+	}
+
+	err = c.CreateFromReader("plain", &failingReader{data: []byte(`{"partial": `)})
+	if err == nil || err.Error() != "connection reset" {
+		t.Error("Test failed - ", err)
+	}
+	dbtest.RequireRecord(t, c, "plain", big)
+	entries, err := os.ReadDir(filepath.Join(dir, "blobs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if !e.IsDir() && e.Name() != "plain.json" && e.Name() != "zipped.json" {
+			t.Error("Test failed - left behind", e.Name())
+		}
+	}
+	if err = c.CreateFromReader("bad/id", bytes.NewReader(nil)); !errors.Is(err, simplejsondb.ErrInvalidID) {
+		t.Error("Test failed - ", err)
+	}
+}
+
+func TestCreateFromReaderWaitFor(t *testing.T) {
+	_, collection := dbtest.NewDB(t, nil)
+	c := collection("blobs")
+	got := make(chan []byte)
+	go func() {
+		data, err := c.WaitFor(context.Background(), "late")
+		if err != nil {
+			t.Error("Test failed - ", err)
+		}
+		got <- data
+	}()
+	time.Sleep(20 * time.Millisecond)
+	if err := c.CreateFromReader("late", bytes.NewReader([]byte(`{"late": true}`))); err != nil {
+		t.Fatal(err)
+	}
+	if data := <-got; string(data) != `{"late": true}` {
+		t.Error("Test failed - ", string(data))
+	}
+}
+
+func TestCreateFromReaderTruncate(t *testing.T) {
+	for _, serial := range []bool{false, true} {
+		_, collection := dbtest.NewDB(t, &simplejsondb.Options{SerializeWrites: serial})
+		c := collection("blobs")
+		pr, pw := io.Pipe()
+		created := make(chan error)
+		go func() { created <- c.CreateFromReader("slow", pr) }()
+		// returns once the temp file is being written
+		if _, err := pw.Write([]byte(`{"slow": `)); err != nil {
+			t.Fatal(err)
+		}
+
+		truncated := make(chan error, 1)
+		go func() { truncated <- c.Truncate() }()
+		time.Sleep(50 * time.Millisecond)
+		if len(truncated) != 0 {
+			t.Error("Test failed - truncate ran during the write", serial)
+		}
+		pw.Write([]byte(`true}`))
+		pw.Close()
+		if err := <-created; err != nil {
+			t.Error("Test failed - ", serial, err)
+		}
+		if err := <-truncated; err != nil {
+			t.Error("Test failed - ", serial, err)
+		}
+		if _, err := c.Get("slow"); !errors.Is(err, simplejsondb.ErrRecordNotFound) {
+			t.Error("Test failed - ", serial, err)
+		}
+	}
+}
+
+func TestCreateFromReaderBuffered(t *testing.T) {
+	_, collection := dbtest.NewDB(t, &simplejsondb.Options{ValidateJSON: true, Checksums: true})
+	c := collection("blobs")
+	if err := c.CreateFromReader("bad", bytes.NewReader([]byte(`{`))); !errors.Is(err, simplejsondb.ErrInvalidJSON) {
+		t.Error("Test failed - ", err)
+	}
+	if err := c.CreateFromReader("ok", bytes.NewReader([]byte(`{}`)), simplejsondb.CreateOptions{UseGzip: true}); err != nil {
+		t.Fatal(err)
+	}
+	dbtest.RequireRecord(t, c, "ok", []byte(`{}`))
+}
@@ -0,0 +1,113 @@
+package simplejsondb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"go.uber.org/zap"
+)
+
+// RecompressReport - outcome of a Recompress run
+type RecompressReport struct {
+	Version   int              `json:"version"`
+	Converted int              `json:"converted"`
+	Skipped   int              `json:"skipped"`
+	Failed    int              `json:"failed"`
+	Errors    map[string]error `json:"errors,omitempty"`
+}
+
+// MarshalJSON - renders the collected errors as messages
+func (r RecompressReport) MarshalJSON() ([]byte, error) {
+	type report RecompressReport
+	return json.Marshal(struct {
+		report
+		Errors map[string]string `json:"errors,omitempty"`
+	}{report(r), errorMessages(r.Errors)})
+}
+
+// Recompress - rewrites every record stored in the other format as gzip
+// or plain, leaving a single file per record
+//
+// Each record is converted under its exclusive lock: the new file is
+// written atomically before the old variant is removed, so readers always
+// find one of them and an interrupted run loses nothing. Records of other
+// codecs are converted as well. New records keep following the Options.
+func (c *_collection) Recompress(useGzip bool) (report RecompressReport, err error) {
+	report.Version = ReportSchemaVersion
+	target := c.enc.wrap(PlainCodec)
+	if useGzip {
+		target = c.enc.wrap(GzipCodec)
+	}
+	keys, err := c.keys()
+	if err != nil {
+		return report, err
+	}
+	for _, key := range keys {
+		converted, err := c.recompress(key, target)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			// deleted since listing
+		case err != nil:
+			report.Failed++
+			if report.Errors == nil {
+				report.Errors = make(map[string]error)
+			}
+			report.Errors[key] = err
+		case converted:
+			report.Converted++
+		default:
+			report.Skipped++
+		}
+	}
+	return report, nil
+}
+
+// Recompress - converts the records of the overlay, the base is never
+// modified
+func (c *_overlayCollection) Recompress(useGzip bool) (RecompressReport, error) {
+	return c.upper.Recompress(useGzip)
+}
+
+// recompress - converts one record, stale variants in other formats
+// shadowed by the record are removed
+func (c *_collection) recompress(key string, target Codec) (converted bool, err error) {
+	if err = c.life.begin(); err != nil {
+		return false, err
+	}
+	defer c.life.end()
+	unlock := c.lock(key)
+	defer unlock()
+
+	filename, from, err := c.resolve(key)
+	if err != nil {
+		return false, err
+	}
+	if from == target {
+		for _, other := range c.codecs {
+			if other == target {
+				continue
+			}
+			err = os.Remove(c.getFullPath(key, other))
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return false, err
+			}
+		}
+		return false, nil
+	}
+	stored, err := os.ReadFile(filename)
+	if err != nil {
+		return false, err
+	}
+	content, err := from.Decode(stored)
+	if err != nil {
+		return false, fmt.Errorf("%w: %w", ErrCorruptRecord, err)
+	}
+	stored, err = target.Encode(content)
+	if err != nil {
+		c.logger.Error("unable to encode the record", zap.Error(err))
+		return false, err
+	}
+	return true, c.store(key, content, stored, target, c.expiry.expiresOf(c.lockPath(key)), nil)
+}
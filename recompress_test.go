@@ -0,0 +1,70 @@
+package simplejsondb_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestRecompress(t *testing.T) {
+	dir := t.TempDir()
+	_, collection := dbtest.Open(t, dir, nil)
+	c := collection("mixed")
+	ids := dbtest.SeedN(t, c, 20)
+	for _, id := range ids[:10] {
+		if err := c.Create(id, []byte(`{"zipped": true}`), simplejsondb.CreateOptions{UseGzip: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// already gzip, it only fails once it has to be decompressed
+	if err := os.WriteFile(filepath.Join(dir, "mixed", "corrupt.json.gz"), []byte("not gzip"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// readers never miss a record while it is converted
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			for _, id := range ids {
+				if _, err := c.Get(id); err != nil {
+					t.Error("Test failed - ", id, err)
+				}
+			}
+		}
+	}()
+	report, err := c.Recompress(true)
+	close(done)
+	wg.Wait()
+	if err != nil || report.Converted != 10 || report.Skipped != 11 || report.Failed != 0 {
+		t.Fatal("Test failed - ", report, err)
+	}
+	for _, id := range ids {
+		if _, err := os.Stat(filepath.Join(dir, "mixed", id+simplejsondb.Ext)); !errors.Is(err, os.ErrNotExist) {
+			t.Error("Test failed - plain variant left", id)
+		}
+	}
+	dbtest.RequireRecord(t, c, "record0", []byte(`{"zipped": true}`))
+	dbtest.RequireRecord(t, c, "record19", []byte(`{"n": 19}`))
+
+	report, err = c.Recompress(false)
+	if err != nil || report.Converted != 20 || report.Failed != 1 || report.Errors["corrupt"] == nil {
+		t.Fatal("Test failed - ", report, err)
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "mixed", "record*"+simplejsondb.GZipExt)); len(files) != 0 {
+		t.Error("Test failed - gzip variants left", files)
+	}
+	dbtest.RequireRecord(t, c, "record19", []byte(`{"n": 19}`))
+}
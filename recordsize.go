@@ -0,0 +1,57 @@
+package simplejsondb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrRecordTooLarge - a record is over Options.MaxRecordSize
+var ErrRecordTooLarge = errors.New("record too large")
+
+// checkSize - refuses size bytes of key when over MaxRecordSize
+func (c *_collection) checkSize(key string, size int64) error {
+	if c.maxRecordSize <= 0 || size <= c.maxRecordSize {
+		return nil
+	}
+	return fmt.Errorf("%w: %s: %d bytes, limit %d", ErrRecordTooLarge, key, size, c.maxRecordSize)
+}
+
+// limitSize - r failing with ErrRecordTooLarge once more than
+// MaxRecordSize bytes came through, r itself without a limit
+func (c *_collection) limitSize(key string, r io.Reader) io.Reader {
+	if c.maxRecordSize <= 0 {
+		return r
+	}
+	return &_sizeLimit{r: r, c: c, key: key}
+}
+
+// unGzipLimited - UnGzip reading no more than MaxRecordSize bytes of the
+// decompressed record
+func (c *_collection) unGzipLimited(key string, stored []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(stored))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(c.limitSize(key, zr))
+}
+
+// _sizeLimit - reader counting what came through against MaxRecordSize
+type _sizeLimit struct {
+	r   io.Reader
+	c   *_collection
+	key string
+	n   int64
+}
+
+func (l *_sizeLimit) Read(p []byte) (n int, err error) {
+	n, err = l.r.Read(p)
+	l.n += int64(n)
+	if serr := l.c.checkSize(l.key, l.n); serr != nil {
+		return 0, serr
+	}
+	return n, err
+}
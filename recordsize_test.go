@@ -0,0 +1,80 @@
+package simplejsondb_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestMaxRecordSizeWrite(t *testing.T) {
+	dir := t.TempDir()
+	_, collection := dbtest.Open(t, dir, &simplejsondb.Options{MaxRecordSize: 16})
+	c := collection("limited")
+	big := []byte(`{"k": "0123456789"}`)
+	if err := c.Create("big", big); !errors.Is(err, simplejsondb.ErrRecordTooLarge) {
+		t.Error("Test failed - ", err)
+	}
+	if err := c.Create("big", big, simplejsondb.CreateOptions{UseGzip: true}); !errors.Is(err, simplejsondb.ErrRecordTooLarge) {
+		t.Error("Test failed - ", err)
+	}
+	if err := c.CreateFromReader("big", bytes.NewReader(big)); !errors.Is(err, simplejsondb.ErrRecordTooLarge) {
+		t.Error("Test failed - ", err)
+	}
+	if err := c.CreateFromReader("big", bytes.NewReader(big), simplejsondb.CreateOptions{UseGzip: true}); !errors.Is(err, simplejsondb.ErrRecordTooLarge) {
+		t.Error("Test failed - ", err)
+	}
+	entries, err := os.ReadDir(filepath.Join(dir, "limited"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if !strings.HasPrefix(e.Name(), ".") {
+			t.Error("Test failed - left behind", e.Name())
+		}
+	}
+
+	dbtest.Seed(t, c, map[string][]byte{"small": []byte(`{"k": 1}`)})
+	if err := c.CreateFromReader("streamed", strings.NewReader(`{"k": 2}`)); err != nil {
+		t.Error("Test failed - ", err)
+	}
+	dbtest.RequireRecord(t, c, "streamed", []byte(`{"k": 2}`))
+}
+
+func TestMaxRecordSizeRead(t *testing.T) {
+	dir := t.TempDir()
+	_, collection := dbtest.Open(t, dir, nil)
+	big := bytes.Repeat([]byte(" "), 1024)
+	c := collection("limited")
+	dbtest.Seed(t, c, map[string][]byte{"small": []byte(`{"k": 1}`), "big": append([]byte(`{}`), big...)})
+	if err := c.Create("zipped", append([]byte(`{}`), big...), simplejsondb.CreateOptions{UseGzip: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, collection = dbtest.Open(t, dir, &simplejsondb.Options{MaxRecordSize: 64})
+	c = collection("limited")
+	for _, key := range []string{"big", "zipped"} {
+		if _, err := c.Get(key); !errors.Is(err, simplejsondb.ErrRecordTooLarge) {
+			t.Error("Test failed - ", key, err)
+		}
+		r, err := c.GetReader(key)
+		if err == nil {
+			_, err = io.ReadAll(r)
+			r.Close()
+		}
+		if !errors.Is(err, simplejsondb.ErrRecordTooLarge) {
+			t.Error("Test failed - ", key, err)
+		}
+	}
+	dbtest.RequireRecord(t, c, "small", []byte(`{"k": 1}`))
+	if _, err := c.GetAllCtx(context.Background()); !errors.Is(err, simplejsondb.ErrRecordTooLarge) {
+		t.Error("Test failed - ", err)
+	}
+}
@@ -0,0 +1,103 @@
+package simplejsondb_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+	"go.uber.org/zap/zapcore"
+)
+
+// testLogger - records every log line with its encoded fields
+type testLogger struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *testLogger) log(msg string, fields []zapcore.Field) {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, fmt.Sprint(msg, enc.Fields))
+}
+
+func (l *testLogger) Error(msg string, fields ...zapcore.Field) { l.log(msg, fields) }
+func (l *testLogger) Warn(msg string, fields ...zapcore.Field)  { l.log(msg, fields) }
+func (l *testLogger) Info(msg string, fields ...zapcore.Field)  { l.log(msg, fields) }
+func (l *testLogger) Debug(msg string, fields ...zapcore.Field) { l.log(msg, fields) }
+
+func (l *testLogger) contains(s string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, line := range l.lines {
+		if strings.Contains(line, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestGetAndCompare(t *testing.T) {
+	_, collection := dbtest.NewDB(t, nil)
+	c := collection("tokens")
+	err := c.Create("api", []byte("secret-token"), simplejsondb.CreateOptions{UseGzip: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	equal, err := c.GetAndCompare("api", []byte("secret-token"))
+	if err != nil || !equal {
+		t.Error("Test failed - ", equal, err)
+	}
+	equal, err = c.GetAndCompare("api", []byte("secret-tokem"))
+	if err != nil || equal {
+		t.Error("Test failed - ", equal, err)
+	}
+	equal, err = c.GetAndCompare("api", []byte("secret"))
+	if err != nil || equal {
+		t.Error("Test failed - prefix matched", equal, err)
+	}
+	if _, err = c.GetAndCompare("missing", nil); !errors.Is(err, os.ErrNotExist) {
+		t.Error("Test failed - ", err)
+	}
+}
+
+func TestRedactor(t *testing.T) {
+	dir := t.TempDir()
+	logger := &testLogger{}
+	redacted := 0
+	_, collection := dbtest.Open(t, dir, &simplejsondb.Options{
+		Logger: logger,
+		Redactor: func(id string, data []byte) []byte {
+			redacted++
+			return bytes.ReplaceAll(data, []byte("secret-token"), []byte("[REDACTED]"))
+		},
+	})
+	c := collection("tokens")
+
+	// a .json.gz file which is not gzip reaches the decompress diagnostics
+	err := os.WriteFile(filepath.Join(dir, "tokens", "api"+simplejsondb.GZipExt), []byte("secret-token"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.Get("api"); err == nil {
+		t.Error("Test failed - corrupt record read")
+	}
+	_ = c.GetAll()
+
+	if redacted == 0 || !logger.contains("[REDACTED]") {
+		t.Error("Test failed - redactor not applied", logger.lines)
+	}
+	if logger.contains("secret-token") {
+		t.Error("Test failed - secret logged", logger.lines)
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !windows
+
+package simplejsondb
+
+import (
+	"os"
+)
+
+// replaceFile - rename replaces an existing target atomically on POSIX
+func replaceFile(from, to string) error {
+	return os.Rename(from, to)
+}
@@ -0,0 +1,38 @@
+//go:build windows
+
+package simplejsondb
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	movefileReplaceExisting = 0x1
+	movefileWriteThrough    = 0x8
+)
+
+var procMoveFileExW = syscall.NewLazyDLL("kernel32.dll").NewProc("MoveFileExW")
+
+// replaceFile - moves from over to with MoveFileEx, replacing an existing
+// record and returning once the move reached the disk as directories
+// cannot be synced here
+//
+// A target another process holds open fails with a sharing violation,
+// which writeAtomic retries.
+func replaceFile(from, to string) error {
+	src, err := syscall.UTF16PtrFromString(from)
+	if err != nil {
+		return &os.LinkError{Op: "rename", Old: from, New: to, Err: err}
+	}
+	dst, err := syscall.UTF16PtrFromString(to)
+	if err != nil {
+		return &os.LinkError{Op: "rename", Old: from, New: to, Err: err}
+	}
+	r, _, err := procMoveFileExW.Call(uintptr(unsafe.Pointer(src)), uintptr(unsafe.Pointer(dst)), movefileReplaceExisting|movefileWriteThrough)
+	if r == 0 {
+		return &os.LinkError{Op: "rename", Old: from, New: to, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+//go:build windows
+
+package simplejsondb_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestReplaceConcurrentOverwrites(t *testing.T) {
+	_, collection := dbtest.NewDB(t, nil)
+	c := collection("windows")
+	_ = c.Create("shared", []byte(`{"n": -1}`))
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				if err := c.Create("shared", []byte(fmt.Sprintf(`{"n": %d}`, g*100+i))); err != nil {
+					t.Error("Test failed - ", err)
+					return
+				}
+				if _, err := c.Get("shared"); err != nil {
+					t.Error("Test failed - ", err)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	if keys := c.Keys(); len(keys) != 1 {
+		t.Error("Test failed - ", keys)
+	}
+}
@@ -0,0 +1,17 @@
+package simplejsondb
+
+// ReportSchemaVersion - version of the JSON shape of every report type,
+// bumped on breaking changes such as renamed or removed fields
+const ReportSchemaVersion = 1
+
+// errorMessages - JSON friendly form of per record errors
+func errorMessages(errs map[string]error) map[string]string {
+	if len(errs) == 0 {
+		return nil
+	}
+	messages := make(map[string]string, len(errs))
+	for id, err := range errs {
+		messages[id] = err.Error()
+	}
+	return messages
+}
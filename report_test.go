@@ -0,0 +1,82 @@
+package simplejsondb_test
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+// checkGolden - compares v marshalled as JSON with testdata/name
+func checkGolden(t *testing.T, name string, v interface{}) {
+	got, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden := filepath.Join("testdata", name)
+	if *update {
+		err = os.WriteFile(golden, append(got, '\n'), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(want) != string(got)+"\n" {
+		t.Errorf("Test failed - %s changed shape\ngot:\n%s\nwant:\n%s", name, got, want)
+	}
+}
+
+func TestReportSchema(t *testing.T) {
+	for golden, report := range map[string]interface{}{
+		"transform_report.json": simplejsondb.TransformReport{
+			Version: simplejsondb.ReportSchemaVersion,
+			Changed: 3,
+			Skipped: 2,
+			Failed:  1,
+			Errors:  map[string]error{"u2": errors.New("bad record")},
+		},
+		"verify_report.json": simplejsondb.VerifyReport{
+			Version:     simplejsondb.ReportSchemaVersion,
+			Checked:     5,
+			Unchecked:   1,
+			Corrupt:     []string{"u1"},
+			Mismatched:  []string{"u2"},
+			Unparseable: []string{"u3"},
+			BOM:         []string{"u4"},
+			Quarantined: []string{"u5"},
+			Errors:      map[string]error{"u1": errors.New("unexpected EOF")},
+		},
+		"recompress_report.json": simplejsondb.RecompressReport{
+			Version:   simplejsondb.ReportSchemaVersion,
+			Converted: 3,
+			Skipped:   2,
+			Failed:    1,
+			Errors:    map[string]error{"u2": errors.New("bad record")},
+		},
+		"advisor_report.json": simplejsondb.AdvisorReport{
+			Version:                  simplejsondb.ReportSchemaVersion,
+			Records:                  10,
+			Sampled:                  4,
+			Failed:                   1,
+			Compress:                 []string{"u1"},
+			CompressBytes:            120,
+			Decompress:               []string{"u2"},
+			DecompressBytes:          8,
+			EstimatedCompressBytes:   300,
+			EstimatedDecompressBytes: 20,
+		},
+	} {
+		t.Run(golden, func(t *testing.T) {
+			checkGolden(t, golden, report)
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package simplejsondb
+
+import (
+	"errors"
+	"os"
+)
+
+// ReadPreference - resolves a record stored both as .json and .json.gz
+type ReadPreference int
+
+const (
+	// PreferPlain - the .json file wins
+	PreferPlain ReadPreference = iota
+	// PreferNewest - the most recently modified file wins
+	PreferNewest
+	// PreferCompressed - the .json.gz file wins
+	PreferCompressed
+)
+
+// resolve - locates the record file of key honoring the read preference
+//
+// PreferPlain stops at the first existing candidate, the other strategies
+// stat both files to compare them. Files of further codecs are only
+// looked for when neither exists.
+func (c *_collection) resolve(key string) (filename string, codec Codec, err error) {
+	plain := c.getFullPath(key, PlainCodec)
+	compressed := c.getFullPath(key, GzipCodec)
+
+	plainInfo, err := statRecord(plain)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", nil, err
+	}
+	if plainInfo != nil && c.readPref == PreferPlain {
+		return plain, PlainCodec, nil
+	}
+	compressedInfo, err := statRecord(compressed)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", nil, err
+	}
+
+	switch {
+	case plainInfo == nil && compressedInfo == nil:
+		return c.resolveOther(key, err)
+	case compressedInfo == nil:
+		return plain, PlainCodec, nil
+	case plainInfo == nil:
+		return compressed, GzipCodec, nil
+	case c.readPref == PreferCompressed:
+		return compressed, GzipCodec, nil
+	case c.readPref == PreferNewest && compressedInfo.ModTime().After(plainInfo.ModTime()):
+		return compressed, GzipCodec, nil
+	}
+	return plain, PlainCodec, nil
+}
+
+// resolveOther - the first file of the non built-in codecs in order,
+// notExist is returned when there is none
+func (c *_collection) resolveOther(key string, notExist error) (filename string, codec Codec, err error) {
+	for _, codec = range c.codecs[2:] {
+		filename = c.getFullPath(key, codec)
+		_, err = statRecord(filename)
+		if err == nil {
+			return filename, codec, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", nil, err
+		}
+	}
+	return "", nil, notExist
+}
+
+// statRecord - stats a record file, directories count as missing
+func statRecord(filename string) (os.FileInfo, error) {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, &os.PathError{Op: "stat", Path: filename, Err: os.ErrNotExist}
+	}
+	return info, nil
+}
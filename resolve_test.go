@@ -0,0 +1,78 @@
+package simplejsondb_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+// plantDuplicate - writes key both as .json and .json.gz with the given ages
+func plantDuplicate(t *testing.T, dir, key string, plainAge, compressedAge time.Duration) {
+	now := time.Now()
+	plain := filepath.Join(dir, key+simplejsondb.Ext)
+	err := os.WriteFile(plain, []byte(`"plain"`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, _ = w.Write([]byte(`"compressed"`))
+	_ = w.Close()
+	compressed := filepath.Join(dir, key+simplejsondb.GZipExt)
+	err = os.WriteFile(compressed, buf.Bytes(), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = os.Chtimes(plain, now.Add(-plainAge), now.Add(-plainAge))
+	_ = os.Chtimes(compressed, now.Add(-compressedAge), now.Add(-compressedAge))
+}
+
+func TestReadPreference(t *testing.T) {
+	cases := []struct {
+		pref       simplejsondb.ReadPreference
+		stalePlain string
+		staleGzip  string
+	}{
+		{simplejsondb.PreferPlain, `"plain"`, `"plain"`},
+		{simplejsondb.PreferCompressed, `"compressed"`, `"compressed"`},
+		{simplejsondb.PreferNewest, `"compressed"`, `"plain"`},
+	}
+	for _, tc := range cases {
+		root := t.TempDir()
+		_, collection := dbtest.Open(t, root, &simplejsondb.Options{ReadPreference: tc.pref})
+		c := collection("collection1")
+		dir := filepath.Join(root, "collection1")
+		plantDuplicate(t, dir, "stale-plain", time.Hour, time.Minute)
+		plantDuplicate(t, dir, "stale-gzip", time.Minute, time.Hour)
+
+		data, err := c.Get("stale-plain")
+		if err != nil || string(data) != tc.stalePlain {
+			t.Error("Test failed - ", tc.pref, string(data), err)
+		}
+		data, err = c.Get("stale-gzip")
+		if err != nil || string(data) != tc.staleGzip {
+			t.Error("Test failed - ", tc.pref, string(data), err)
+		}
+		records := c.GetAll()
+		if len(records) != 2 || string(records[0]) != tc.staleGzip || string(records[1]) != tc.stalePlain {
+			t.Error("Test failed - GetAll", tc.pref, len(records))
+		}
+
+		for _, key := range []string{"stale-plain", "stale-gzip"} {
+			err = c.Delete(key)
+			if err != nil {
+				t.Error("Test failed - ", err)
+			}
+			if _, err = c.Get(key); !errors.Is(err, os.ErrNotExist) {
+				t.Error("Test failed - duplicate left behind", tc.pref, key, err)
+			}
+		}
+	}
+}
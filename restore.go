@@ -0,0 +1,157 @@
+package simplejsondb
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+// ErrUnsafeEntry - an archive entry name escaping the collection
+var ErrUnsafeEntry = errors.New("unsafe archive entry")
+
+// RestoreError - the archive entries which could not be restored
+type RestoreError struct {
+	Failed map[string]error
+}
+
+func (e *RestoreError) Error() string {
+	names := make([]string, 0, len(e.Failed))
+	for name := range e.Failed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		names[i] = fmt.Sprintf("%s: %v", name, e.Failed[name])
+	}
+	return fmt.Sprintf("%d entries not restored: %s", len(names), strings.Join(names, "; "))
+}
+
+// Restore - writes the records of a tar.gz backup into the collection
+//
+// Entries are named [<collection>/]<id> plus the extension of a codec the
+// collection reads, the collection directory is ignored so a backup
+// restores under any name. Encoded entries such as .json.gz are stored as
+// is, plain ones follow the collection codec.
+// Existing records are kept unless overwrite is set. A failing entry does
+// not stop the restore, the failures are returned as a *RestoreError once
+// the whole archive is read.
+func (c *_collection) Restore(r io.Reader, overwrite bool) error {
+	return restore(r, c.codecs, func(key string, data []byte, codec Codec) error {
+		return c.restore(key, data, codec, overwrite)
+	})
+}
+
+// Restore - writes the records of a tar.gz backup into the overlay, a
+// record visible in the base counts as existing
+func (c *_overlayCollection) Restore(r io.Reader, overwrite bool) error {
+	return restore(r, c.upper.codecs, func(key string, data []byte, codec Codec) (err error) {
+		data, err = codec.Decode(data)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrCorruptRecord, err)
+		}
+		options := CreateOptions{UseGzip: codec == GzipCodec}
+		if overwrite {
+			return c.Create(key, data, options)
+		}
+		err = c.CreateNX(key, data, options)
+		if errors.Is(err, ErrExists) {
+			return nil
+		}
+		return err
+	})
+}
+
+// restore - feeds every regular entry of a tar.gz stream to put
+func restore(r io.Reader, codecs []Codec, put func(key string, data []byte, codec Codec) error) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+	failed := make(map[string]error)
+	tr := tar.NewReader(gz)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err = restoreEntry(tr, h.Name, codecs, put); err != nil {
+			failed[h.Name] = err
+		}
+	}
+	if len(failed) != 0 {
+		return &RestoreError{Failed: failed}
+	}
+	return nil
+}
+
+// restoreEntry - hands the current entry of tr to put
+func restoreEntry(tr *tar.Reader, name string, codecs []Codec, put func(string, []byte, Codec) error) error {
+	key, codec, err := entryKey(name, codecs)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(tr)
+	if err != nil {
+		return err
+	}
+	return put(key, data, codec)
+}
+
+// entryKey - the record id and codec of an archive entry name
+func entryKey(name string, codecs []Codec) (key string, codec Codec, err error) {
+	if path.IsAbs(name) || strings.Contains(name, "\\") {
+		return "", nil, fmt.Errorf("%w: %s", ErrUnsafeEntry, name)
+	}
+	for _, part := range strings.Split(name, "/") {
+		if part == ".." {
+			return "", nil, fmt.Errorf("%w: %s", ErrUnsafeEntry, name)
+		}
+	}
+	if i := strings.IndexByte(name, '/'); i >= 0 {
+		name = name[i+1:]
+	}
+	key, codec, ok := splitExt(codecs, name)
+	if !ok {
+		return "", nil, fmt.Errorf("not a record: %s", name)
+	}
+	if key == "" || strings.Contains(key, "/") {
+		return "", nil, fmt.Errorf("%w: %s", ErrUnsafeEntry, name)
+	}
+	return key, codec, nil
+}
+
+// restore - stores one archived record under its exclusive lock
+func (c *_collection) restore(key string, data []byte, codec Codec, overwrite bool) (err error) {
+	if err = c.checkID(key); err != nil {
+		return err
+	}
+	if err = c.life.begin(); err != nil {
+		return err
+	}
+	defer c.life.end()
+	content, err := codec.Decode(data)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrCorruptRecord, err)
+	}
+	unlock := c.lock(key)
+	defer unlock()
+	if !overwrite && c.has(key) {
+		return nil
+	}
+	if codec != PlainCodec {
+		return c.store(key, content, data, codec, c.expiresAt(0), nil)
+	}
+	return c.write(key, data, c.codec, c.expiresAt(0), nil)
+}
@@ -0,0 +1,105 @@
+package simplejsondb_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+// tarGz - a tar.gz holding files in the given order
+func tarGz(t *testing.T, files ...[2]string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, f := range files {
+		err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: f[0], Mode: 0644, Size: int64(len(f[1]))})
+		if err == nil {
+			_, err = tw.Write([]byte(f[1]))
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestRestore(t *testing.T) {
+	dir := t.TempDir()
+	_, collection := dbtest.Open(t, dir, nil)
+	src := collection("orders")
+	dbtest.SeedN(t, src, 5)
+	if err := src.Create("zipped", []byte(`{"z": 1}`), simplejsondb.CreateOptions{UseGzip: true}); err != nil {
+		t.Fatal(err)
+	}
+	var backup bytes.Buffer
+	if err := src.Backup(&backup); err != nil {
+		t.Fatal(err)
+	}
+
+	// restored under another name, existing records are kept
+	dst := collection("copy")
+	dbtest.Seed(t, dst, map[string][]byte{"record0": []byte(`{"kept": true}`)})
+	if err := dst.Restore(bytes.NewReader(backup.Bytes()), false); err != nil {
+		t.Fatal(err)
+	}
+	dbtest.RequireRecord(t, dst, "record0", []byte(`{"kept": true}`))
+	dbtest.RequireRecord(t, dst, "record4", []byte(`{"n": 4}`))
+	dbtest.RequireRecord(t, dst, "zipped", []byte(`{"z": 1}`))
+	if err := dst.Restore(bytes.NewReader(backup.Bytes()), true); err != nil {
+		t.Fatal(err)
+	}
+	dbtest.RequireRecord(t, dst, "record0", []byte(`{"n": 0}`))
+
+	// gzip entries are stored as is, bad entries are reported each
+	zipped, _ := gzipString(`{"g": 1}`)
+	archive := tarGz(t,
+		[2]string{"../evil.json", `{}`},
+		[2]string{"orders/../../evil.json", `{}`},
+		[2]string{"orders/stored.json.gz", zipped},
+		[2]string{"orders/broken.json.gz", `{"not": "gzip"}`},
+		[2]string{"orders/notes.txt", `hello`},
+		[2]string{"plain.json", `{"p": 1}`},
+	)
+	err := dst.Restore(bytes.NewReader(archive), false)
+	var re *simplejsondb.RestoreError
+	if !errors.As(err, &re) || len(re.Failed) != 4 {
+		t.Fatal("Test failed - ", err)
+	}
+	if !errors.Is(re.Failed["../evil.json"], simplejsondb.ErrUnsafeEntry) || !errors.Is(re.Failed["orders/../../evil.json"], simplejsondb.ErrUnsafeEntry) {
+		t.Error("Test failed - ", re.Failed)
+	}
+	if !errors.Is(re.Failed["orders/broken.json.gz"], simplejsondb.ErrCorruptRecord) {
+		t.Error("Test failed - ", re.Failed)
+	}
+	dbtest.RequireRecord(t, dst, "stored", []byte(`{"g": 1}`))
+	dbtest.RequireRecord(t, dst, "plain", []byte(`{"p": 1}`))
+	if stored, err := os.ReadFile(filepath.Join(dir, "copy", "stored.json.gz")); err != nil || string(stored) != zipped {
+		t.Error("Test failed - gzip entry not stored as is", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "evil.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Error("Test failed - escaped the collection", err)
+	}
+}
+
+func gzipString(s string) (string, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write([]byte(s)); err != nil {
+		return "", err
+	}
+	err := gz.Close()
+	return buf.String(), err
+}
@@ -0,0 +1,128 @@
+// Orders - a users/orders/events/sessions workload showing batches,
+// conditional creates, merge patches, pagination, expiring records and
+// backups taken while writing
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+)
+
+const (
+	users      = 20
+	writers    = 4
+	orders     = 25
+	sessionTTL = 50 * time.Millisecond
+)
+
+type order struct {
+	User   string `json:"user"`
+	Amount int    `json:"amount"`
+	Status string `json:"status"`
+}
+
+func main() {
+	dir, err := os.MkdirTemp("", "orders")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := simplejsondb.New(filepath.Join(dir, "db"), &simplejsondb.Options{CacheBytes: 1 << 20, DeleteExpired: true})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer db.Close()
+	collection := func(name string) simplejsondb.Collection {
+		c, err := db.Collection(name)
+		if err != nil {
+			panic(err)
+		}
+		return c
+	}
+	u, o, e, s := collection("users"), collection("orders"), collection("events"), collection("sessions")
+
+	// users - one batch
+	batch := make(map[string][]byte, users)
+	for i := 0; i < users; i++ {
+		batch[fmt.Sprintf("user-%02d", i)] = []byte(fmt.Sprintf(`{"name": "user %d"}`, i))
+	}
+	if errs := u.CreateMany(batch); len(errs) != 0 {
+		fmt.Println("users", errs)
+		return
+	}
+
+	// orders - concurrent writers, each order is created once, paid with
+	// a merge patch and logged as an event; every write opens a session
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < orders; i++ {
+				id := fmt.Sprintf("order-%d-%03d", w, i)
+				data, _ := json.Marshal(order{User: fmt.Sprintf("user-%02d", rand.Intn(users)), Amount: 1 + rand.Intn(100), Status: "new"})
+				if err := o.CreateNX(id, data); err != nil {
+					fmt.Println(err)
+					return
+				}
+				_ = o.Merge(id, []byte(`{"status": "paid"}`))
+				_, _ = e.CreateAuto([]byte(`{"order": "` + id + `"}`))
+				_ = s.Create(fmt.Sprint("session-", w), []byte(`{}`), simplejsondb.CreateOptions{TTL: sessionTTL})
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	// backup - a random access archive of every collection
+	backup := filepath.Join(dir, "backup.sjdb")
+	if err = db.ExportIndexed(backup); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// pagination - 30 orders per page, totalling the amounts
+	pages, total, after := 0, 0, ""
+	for {
+		ids, err := o.KeysPrefix("order-", 30, after)
+		if err != nil || len(ids) == 0 {
+			break
+		}
+		records, _ := o.GetMany(ids...)
+		for _, data := range records {
+			var v order
+			if json.Unmarshal(data, &v) == nil {
+				total += v.Amount
+			}
+		}
+		pages++
+		after = ids[len(ids)-1]
+	}
+	fmt.Printf("%d orders in %d pages, total amount %d\n", writers*orders, pages, total)
+
+	// sessions expire, reads remove them
+	fmt.Println("sessions open", len(s.Keys()))
+	time.Sleep(sessionTTL)
+	for w := 0; w < writers; w++ {
+		_, _ = s.Get(fmt.Sprint("session-", w))
+	}
+	fmt.Println("sessions after expiry", len(s.Keys()))
+
+	archive, err := simplejsondb.OpenArchive(backup)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer archive.Close()
+	ids, _ := archive.List("orders")
+	fmt.Println("orders in backup", len(ids))
+}
@@ -0,0 +1,98 @@
+package simplejsondb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidSequence - a sequence name which is not a plain file name, or
+// a counter file which does not hold a number
+var ErrInvalidSequence = errors.New("invalid sequence")
+
+// sequencesDir - counter files of NextSequence in the database root
+const sequencesDir = ".sequences"
+
+// NextSequence - increments the named counter and returns its new value,
+// the first call returns 1
+//
+// The counter is a file under the database root rewritten through a synced
+// temp file and rename while the sequence is locked, so callers of this
+// process never share a value and a crash leaves either the old or the new
+// value, never a value handed out twice.
+func (db *_db) NextSequence(name string) (uint64, error) {
+	return db.nextSequence(name, func() (uint64, error) { return 0, nil })
+}
+
+// NextSequence - increments a counter of the overlay, starting from the
+// value the base database reached
+func (o *_overlay) NextSequence(name string) (uint64, error) {
+	return o.upper.nextSequence(name, func() (uint64, error) {
+		if b, ok := o.base.(*_db); ok {
+			return b.sequence(name)
+		}
+		return 0, nil
+	})
+}
+
+// nextSequence - increments the counter, start is its value when the
+// database has no counter file yet
+func (db *_db) nextSequence(name string, start func() (uint64, error)) (n uint64, err error) {
+	if err = db.life.begin(); err != nil {
+		return 0, err
+	}
+	defer db.life.end()
+	filename, err := db.sequencePath(name)
+	if err != nil {
+		return 0, err
+	}
+	acquire(filename, true)
+	defer release(filename, true)
+
+	n, err = db.sequence(name)
+	if errors.Is(err, os.ErrNotExist) {
+		n, err = start()
+	}
+	if err != nil {
+		return 0, err
+	}
+	n++
+	dir := filepath.Dir(filename)
+	if _, err = getOrCreateDir(dir); err != nil {
+		return 0, err
+	}
+	if err = writeAtomic(filename, []byte(strconv.FormatUint(n, 10)+"\n"), 0644); err != nil {
+		return 0, err
+	}
+	// the rename must survive a crash before the value is handed out
+	return n, syncDir(dir)
+}
+
+// sequence - the current value of a counter, an os.ErrNotExist error when
+// it was never incremented
+func (db *_db) sequence(name string) (uint64, error) {
+	filename, err := db.sequencePath(name)
+	if err != nil {
+		return 0, err
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s: %w", ErrInvalidSequence, filename, err)
+	}
+	return n, nil
+}
+
+// sequencePath - the counter file of a sequence
+func (db *_db) sequencePath(name string) (string, error) {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`+"\x00") {
+		return "", fmt.Errorf("%w: %q", ErrInvalidSequence, name)
+	}
+	return filepath.Join(db.path, sequencesDir, name), nil
+}
@@ -0,0 +1,81 @@
+package simplejsondb_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestNextSequence(t *testing.T) {
+	dir := t.TempDir()
+	db, _ := dbtest.Open(t, dir, nil)
+
+	const callers = 500
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		values []uint64
+	)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n, err := db.NextSequence("invoices")
+			if err != nil {
+				t.Error("Test failed - ", err)
+			}
+			mu.Lock()
+			values = append(values, n)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	for i, n := range values {
+		if n != uint64(i+1) {
+			t.Fatal("Test failed - ", i, n)
+		}
+	}
+
+	if n, err := db.NextSequence("orders"); err != nil || n != 1 {
+		t.Error("Test failed - ", n, err)
+	}
+	db, _ = dbtest.Open(t, dir, nil)
+	if n, err := db.NextSequence("invoices"); err != nil || n != callers+1 {
+		t.Error("Test failed - ", n, err)
+	}
+	for _, name := range []string{"", "..", "a/b", `a\b`} {
+		if _, err := db.NextSequence(name); !errors.Is(err, simplejsondb.ErrInvalidSequence) {
+			t.Error("Test failed - ", name, err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".sequences", "torn"), []byte("12x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.NextSequence("torn"); !errors.Is(err, simplejsondb.ErrInvalidSequence) {
+		t.Error("Test failed - ", err)
+	}
+}
+
+func TestNextSequenceOverlay(t *testing.T) {
+	bdb, _ := dbtest.NewDB(t, nil)
+	for i := 0; i < 3; i++ {
+		_, _ = bdb.NextSequence("invoices")
+	}
+	db, err := simplejsondb.NewOverlay(bdb, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := db.NextSequence("invoices"); err != nil || n != 4 {
+		t.Error("Test failed - ", n, err)
+	}
+	if n, err := bdb.NextSequence("invoices"); err != nil || n != 4 {
+		t.Error("Test failed - base changed", n, err)
+	}
+}
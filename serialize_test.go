@@ -0,0 +1,79 @@
+package simplejsondb_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+// concurrentWrites - creates distinct ids from many goroutines, returns
+// the peak number of writes in flight and the order writes reached the
+// disk
+func concurrentWrites(t *testing.T, dir string, serialize bool) (peak int32, order []string) {
+	_, collection := dbtest.Open(t, dir, &simplejsondb.Options{SerializeWrites: serialize})
+	c := collection("events")
+
+	var (
+		inFlight int32
+		mu       sync.Mutex
+	)
+	restore := simplejsondb.SetWriteFile(func(filename string, data []byte, perm os.FileMode) error {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		mu.Lock()
+		if n > peak {
+			peak = n
+		}
+		order = append(order, filepath.Base(filename))
+		mu.Unlock()
+		time.Sleep(2 * time.Millisecond)
+		return os.WriteFile(filename, data, 0644)
+	})
+	defer restore()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 24; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := c.Create(fmt.Sprint("event", i), []byte(fmt.Sprint(i))); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	return peak, order
+}
+
+func TestSerializeWrites(t *testing.T) {
+	dir := t.TempDir()
+	peak, order := concurrentWrites(t, dir, true)
+	if peak != 1 {
+		t.Error("Test failed - writes overlapped", peak)
+	}
+	mtimes := make(map[string]time.Time, len(order))
+	for _, name := range order {
+		info, err := os.Stat(filepath.Join(dir, "events", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		mtimes[name] = info.ModTime()
+	}
+	for i := 1; i < len(order); i++ {
+		if mtimes[order[i]].Before(mtimes[order[i-1]]) {
+			t.Error("Test failed - mtimes out of write order", order[i-1], order[i])
+		}
+	}
+
+	// without the option writes to different ids run in parallel
+	if peak, _ = concurrentWrites(t, t.TempDir(), false); peak < 2 {
+		t.Error("Test failed - writes unexpectedly serialized", peak)
+	}
+}
@@ -3,14 +3,20 @@ package simplejsondb
 import (
 	"bytes"
 	"compress/gzip"
+	"context"
+	"crypto/subtle"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
-	zrl "github.com/pnkj-kmr/zap-rotate-logger"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -18,29 +24,243 @@ import (
 var Ext string = ".json"
 var GZipExt string = ".json.gz"
 
+// ZstdExt and SnappyExt - extensions of the zstdcodec and snappycodec
+// Codecs, which live in subpackages so only their users build them
+const (
+	ZstdExt   = ".json.zst"
+	SnappyExt = ".json.sz"
+)
+
+// tempPrefix - prefix of the temp files used by atomic writes
+var tempPrefix string = ".tmp-"
+
 type (
 	// Options - extra configuration
 	Options struct {
 		UseGzip bool
+		// ReadPreference - which file wins when a record exists both as
+		// .json and .json.gz, defaults to PreferPlain
+		ReadPreference ReadPreference
+		// DegradedRecoverWrites - successful writes needed to leave the
+		// degraded mode entered on a full disk, defaults to 3
+		DegradedRecoverWrites int
+		// DegradedCacheSize - records the read cache holds at most while
+		// degraded, evicted down to it on entry; 0 keeps the cache as is
+		DegradedCacheSize int
+		// Redactor - scrubs record content before it reaches the logger or
+		// an error message, never affects what is stored
+		Redactor func(id string, data []byte) []byte
+		// DetailedTimings - time the phases of every Get and Create, for
+		// OnOperation, GetTimings of a WithTimings ctx and the
+		// percentiles of Stats.Timings
+		DetailedTimings bool
+		OnOperation     func(OpTimings)
+		// Metrics - counts and latencies of every get, create, delete and
+		// update, nil costs nothing; see the expvarmetrics package
+		Metrics Metrics
+		// Tracer - spans around Get, Create, Delete and GetAll, the ctx
+		// variants parent them to the span of their ctx
+		Tracer Tracer
+		// MaxNameLength - limit in bytes of record file names, defaults
+		// to 255, longer ids fail with ErrIDTooLong
+		MaxNameLength int
+		// MaxRecordSize - limit in bytes of a record: Create and
+		// CreateFromReader refuse bigger ones with ErrRecordTooLarge before
+		// writing, Get and GetAll refuse to load records over it on disk or
+		// once decompressed; zero is unlimited
+		MaxRecordSize int64
+		// HashLongIDs - store ids over MaxNameLength under a hashed file
+		// name instead of rejecting them
+		HashLongIDs bool
+		// IDPolicy - validates, encodes and generates record ids, replacing
+		// the default policy built from MaxNameLength and HashLongIDs; see
+		// StrictFilename and HashedIDs
+		IDPolicy IDPolicy
+		// IgnorePatterns - glob patterns of file names in collection
+		// directories which are never treated as records, added to the
+		// defaults (editor swap and backup files, .DS_Store, Thumbs.db)
+		IgnorePatterns []string
+		// NoDefaultIgnores - drop the default ignore patterns
+		NoDefaultIgnores bool
+		// OnVisible - called after a record write or delete is on disk,
+		// once the file is synced and renamed into place; it runs while
+		// the record is locked and must not access the same record
+		OnVisible func(VisibleInfo) error
+		// SerializeWrites - writes to a collection run one at a time, so
+		// record mtimes follow the order writes acquire the collection,
+		// at the cost of write parallelism; reads stay parallel
+		SerializeWrites bool
+		// NormalizeOnRead - trims trailing whitespace such as the CRLF of
+		// hand edited records from what reads return, a leading UTF-8 BOM
+		// is always dropped
+		NormalizeOnRead bool
+		// RecoverCallbacks - panics in user callbacks are returned as
+		// ErrCallbackPanic instead of propagating
+		RecoverCallbacks bool
+		// IndexPaths - remembers the file paths of records read, trading
+		// memory for fewer allocations per Get
+		IndexPaths bool
+		// ZeroCopy - Get returns cached records without copying them, the
+		// result is shared and must not be modified
+		ZeroCopy bool
+		// QuarantineAfter - consecutive corrupt reads of a record after
+		// which Get fails fast with ErrQuarantined, 0 disables it
+		QuarantineAfter int
+		// CacheBytes - budget of the read cache in bytes, 0 disables it
+		// unless CacheSize is set
+		CacheBytes int64
+		// CacheSize - records the read cache holds at most, least recently
+		// used first out; 0 leaves only CacheBytes limiting it
+		CacheSize int
+		// CacheMaxEntryFraction - largest share of CacheBytes a single
+		// record may take to be cached, defaults to 0.1
+		CacheMaxEntryFraction float64
+		// CacheTTL - age after which a cached record is read again from
+		// disk, bounding how long writes of other processes to the same
+		// directory stay unseen; 0 trusts the cache until this process
+		// writes the record
+		CacheTTL time.Duration
+		// TTL - lifetime of every record written, renewed by each write;
+		// expired records read as missing. 0 keeps records forever
+		TTL time.Duration
+		// DeleteExpired - removes expired records from disk when a read
+		// finds them instead of only hiding them
+		DeleteExpired bool
+		// MaxRecords - records a collection may hold, new ids beyond it
+		// fail with ErrCollectionFull while overwrites keep working; 0 is
+		// unlimited. The count is tracked per database, writes of other
+		// processes can overshoot it until RecalculateUsage
+		MaxRecords uint64
+		// QuotaBytes - bytes of record files a collection may hold, writes
+		// growing it beyond fail with ErrQuotaExceeded while deletes free
+		// their files; 0 is unlimited. Like MaxRecords it is tracked per
+		// database, sized from disk by the first write
+		QuotaBytes uint64
+		// Codec - encodes new records, overriding UseGzip; PlainCodec and
+		// GzipCodec are built in
+		Codec Codec
+		// Codecs - further codecs read by their file extension, after the
+		// built-in .json and .json.gz
+		Codecs []Codec
+		// EncryptionKey - 32 byte AES-256 key sealing every record written
+		// with AES-GCM after the codec, such as gzip, encoded it; the files
+		// carry EncryptedExt so unencrypted records stay readable
+		EncryptionKey []byte
+		// Checksums - keep a CRC-32C of every record file written, a
+		// collection keeps them from then on whatever later opens ask
+		Checksums bool
+		// VerifyChecksums - reads fail with ErrCorruptRecord wrapping
+		// ErrChecksumMismatch when a record file differs from its checksum
+		VerifyChecksums bool
+		// ValidateJSON - Create refuses records which are not valid JSON
+		// with ErrInvalidJSON before anything is encoded or written
+		ValidateJSON bool
+		// ExclusiveOwner - New holds a LOCK file in the database directory
+		// until Close and fails with ErrDatabaseLocked while another
+		// process holds it
+		ExclusiveOwner bool
+		// NoFsync - record writes skip fsync, trading durability for
+		// throughput: a crash can lose or empty records written since the
+		// last Collection.Sync, though they stay atomic while running
+		NoFsync bool
+		// StaleLockAfter - age after which a LOCK file its owner stopped
+		// refreshing, such as one left by a crashed process, is taken
+		// over; defaults to 30s
+		StaleLockAfter time.Duration
+		// Logger - receives every internal log line, nil is silent; see
+		// SlogLogger for log/slog on go1.21 and later
 		Logger
 	}
 
+	// Record - a record together with its id
+	Record struct {
+		ID   string
+		Data []byte
+	}
+
 	CreateOptions struct {
 		UseGzip bool
+		// TTL - lifetime of this record, overrides Options.TTL
+		TTL time.Duration
 	}
 
 	_db struct {
-		useGzip bool
-		path    string
-		logger  Logger
+		owner           *_owner
+		txMu            sync.Mutex
+		codec           Codec
+		codecs          []Codec
+		enc             *_encryption
+		checksums       bool
+		verifyChecksums bool
+		readPref        ReadPreference
+		redactor        func(string, []byte) []byte
+		onOperation     func(OpTimings)
+		timings         *_timings
+		metrics         Metrics
+		tracer          Tracer
+		ids             IDPolicy
+		ignore          []string
+		path            string
+		logger          Logger
+		health          *_health
+		cache           *_cache
+		onVisible       func(VisibleInfo) error
+		quarantine      *_quarantine
+		serializeWrites bool
+		zeroCopy        bool
+		indexPaths      bool
+		normalizeOnRead bool
+		validateJSON    bool
+		noFsync         bool
+		maxRecordSize   int64
+		callbacks       *_callbacks
+		life            *_lifecycle
+		ttl             time.Duration
+		deleteExpired   bool
+		maxRecords      uint64
+		indexMu         sync.Mutex
+		indexes         map[string]*_pathIndex
+		expiry          map[string]*_expiries
+		records         map[string]*_recordCount
+		quotaBytes      uint64
+		usage           map[string]*_usage
 	}
 
 	_collection struct {
-		useGzip bool
-		mu      sync.Mutex
-		name    string
-		path    string
-		logger  Logger
+		codec           Codec
+		codecs          []Codec
+		enc             *_encryption
+		checksums       bool
+		verifyChecksums bool
+		readPref        ReadPreference
+		redactor        func(string, []byte) []byte
+		onOperation     func(OpTimings)
+		timings         *_timings
+		metrics         Metrics
+		tracer          Tracer
+		ids             IDPolicy
+		ignore          []string
+		name            string
+		path            string
+		logger          Logger
+		health          *_health
+		cache           *_cache
+		onVisible       func(VisibleInfo) error
+		quarantine      *_quarantine
+		serializeWrites bool
+		zeroCopy        bool
+		pathIndex       *_pathIndex
+		normalizeOnRead bool
+		validateJSON    bool
+		noFsync         bool
+		maxRecordSize   int64
+		callbacks       *_callbacks
+		life            *_lifecycle
+		ttl             time.Duration
+		deleteExpired   bool
+		expiry          *_expiries
+		records         *_recordCount
+		usage           *_usage
 	}
 )
 
@@ -55,14 +275,147 @@ type (
 
 	// Collection - it's like a table name
 	Collection interface {
+		Name() string
 		Get(string) ([]byte, error)
+		// GetReader streams a record, the reader must be closed promptly
+		GetReader(string) (io.ReadCloser, error)
+		// CreateFromReader saves a record streamed from a reader
+		CreateFromReader(string, io.Reader, ...CreateOptions) error
+		GetAndCompare(string, []byte) (bool, error)
+		// GetWithVersion returns the record and a version for
+		// CreateIfVersion
+		GetWithVersion(string) ([]byte, RecordVersion, error)
+		// CreateIfVersion fails with ErrVersionMismatch once the record
+		// changed from the expected version
+		CreateIfVersion(string, []byte, RecordVersion) error
+		// CompareAndSwap writes the new record only while it holds old,
+		// nil meaning missing
+		CompareAndSwap(string, []byte, []byte) (bool, error)
+		// AppendTo appends an element to a JSON array record
+		AppendTo(string, []byte, ...AppendOptions) error
+		// Increment adds to the number at an RFC 6901 pointer
+		Increment(string, string, float64) (float64, error)
+		// Stat returns the size, modification time and format of a record
+		Stat(string) (RecordInfo, error)
+		// GetField returns the raw JSON value at an RFC 6901 pointer
+		GetField(string, string) ([]byte, error)
+		WaitFor(context.Context, string) ([]byte, error)
 		GetAll() [][]byte
+		// GetAllCtx is GetAll stopping with ctx.Err() once ctx is done
+		GetAllCtx(context.Context) ([][]byte, error)
+		// GetCtx, CreateCtx and DeleteCtx give up when ctx is done before
+		// they touch the record, a started write always completes
+		GetCtx(context.Context, string) ([]byte, error)
+		CreateCtx(context.Context, string, []byte, ...CreateOptions) error
+		DeleteCtx(context.Context, string) error
+		// GetAllSorted returns the records with their ids, ordered by id
+		GetAllSorted() []Record
+		// Each calls fn with every record in id order, one at a time
+		Each(func(string, []byte) error) error
+		// GetPage reads the records at offset..offset+limit of the sorted
+		// ids
+		GetPage(int, int) ([]string, [][]byte, error)
+		// Find returns the records match accepts by id
+		Find(func(string, []byte) bool) map[string][]byte
+		// GetMany returns the records found, missing ids are reported
+		// through a *MissingError
+		GetMany(...string) (map[string][]byte, error)
+		// Keys lists the sorted record ids without reading the records
+		Keys() []string
+		// Len counts the records, not the files of the directory
+		Len() uint64
+		LenPrefix(string) (uint64, error)
+		// Size sums the bytes of the record files on disk
+		Size(...SizeOptions) (uint64, error)
+		// Stats aggregates counts, sizes and times of the records
+		Stats() (CollectionStats, error)
+		// GetAllByPrefix reads only the records whose id has the prefix
+		GetAllByPrefix(string) map[string][]byte
+		KeysPrefix(string, int, string) ([]string, error)
 		Create(string, []byte, ...CreateOptions) error
+		// CreateNX fails with ErrExists instead of overwriting
+		CreateNX(string, []byte, ...CreateOptions) error
+		// CreateMany returns the errors of the ids which failed
+		CreateMany(map[string][]byte, ...CreateOptions) map[string]error
+		CreateAuto([]byte, ...AutoOptions) (AutoResult, error)
+		// CreateObject saves a value marshalled to JSON
+		CreateObject(string, any, ...CreateOptions) error
+		// GetObject unmarshals a record into the value pointed to
+		GetObject(string, any) error
+		// Pin keeps a record in the read cache until Unpin
+		Pin(string) error
+		Unpin(string)
+		// Quarantined lists the ids refused after repeated corrupt reads
+		Quarantined() []string
+		Unquarantine(string)
+		Update(string, func([]byte) ([]byte, error)) error
+		UpdateIf(string, func([]byte) (bool, error), func([]byte) ([]byte, error)) (bool, error)
+		// Merge applies an RFC 7386 JSON merge patch
+		Merge(string, []byte, ...MergeOptions) error
+		TransformAll(func(string, []byte) ([]byte, bool, error), ...TransformOptions) (TransformReport, error)
 		Delete(string) error
+		// DeleteMany returns the errors of the ids which failed
+		DeleteMany(...string) map[string]error
+		// Truncate removes every record, keeping the collection
+		Truncate() error
+		// Sync flushes the records written with NoFsync to disk
+		Sync() error
+		// NewBatch starts writes and deletes committed together
+		NewBatch() Batch
+		// CopyTo writes a record into another collection, MoveTo also
+		// deletes the source
+		CopyTo(string, Collection) error
+		MoveTo(string, Collection) error
+		// RecalculateUsage recounts the records limited by MaxRecords
+		RecalculateUsage() (uint64, error)
+		// Usage reports the bytes of record files limited by QuotaBytes
+		Usage() (uint64, error)
+		// CompressionAdvisor reports sampled records which would be
+		// smaller in the other storage format
+		CompressionAdvisor(context.Context, float64, ...AdvisorOptions) (AdvisorReport, error)
+		// Backup streams the records as a tar.gz of <collection>/<id>.json
+		Backup(io.Writer) error
+		// Restore writes the records of a backup, existing ones are only
+		// replaced when overwrite is set
+		Restore(r io.Reader, overwrite bool) error
+		// ExportJSONL writes one {"_id", "data"} line per record,
+		// ImportJSONL creates the records of such a dump
+		ExportJSONL(io.Writer) error
+		ImportJSONL(io.Reader) error
+		// Recompress converts every record to gzip or plain storage
+		Recompress(bool) (RecompressReport, error)
+		// Verify checks every record against its checksum and codec and
+		// for valid JSON
+		Verify() (VerifyReport, error)
 	}
 	// DB - a database
 	DB interface {
-		Collection(string) (Collection, error)
+		// Collection opens or creates a collection, Options passed here
+		// override the database settings for it
+		Collection(string, ...Options) (Collection, error)
+		// HasCollection reports whether a collection exists without
+		// creating it
+		HasCollection(string) bool
+		// DropCollection removes a collection and all of its records
+		DropCollection(string) error
+		// Degraded reports whether writes are failing on a full disk
+		Degraded() bool
+		// ExportIndexed writes a random access archive of all collections
+		ExportIndexed(string, ...ExportOptions) error
+		// ExportChangedSince writes the records changed since a time as
+		// NDJSON, ApplyIncremental replays such an export
+		ExportChangedSince(io.Writer, time.Time) (IncrementalManifest, error)
+		ApplyIncremental(io.Reader) (IncrementalManifest, error)
+		// Backup streams every collection as a tar.gz
+		Backup(io.Writer) error
+		// Begin starts a transaction whose writes are applied together
+		Begin() (Tx, error)
+		// NextSequence increments a persistent counter and returns it
+		NextSequence(string) (uint64, error)
+		Stats() Stats
+		// Close refuses further use with ErrClosed, closing twice is a
+		// no-op
+		Close() error
 	}
 )
 
@@ -73,7 +426,10 @@ func New(dbname string, options *Options) (db DB, err error) {
 		opts = *options
 	}
 	if opts.Logger == nil {
-		opts.Logger = zrl.New()
+		opts.Logger = zap.NewNop()
+	}
+	if err = checkOptions(&opts); err != nil {
+		return nil, err
 	}
 	// initiating db
 	dbpath := filepath.Join(dbname)
@@ -82,172 +438,865 @@ func New(dbname string, options *Options) (db DB, err error) {
 		fmt.Println(err)
 		return nil, err
 	}
-	return &_db{path: dbpath, logger: opts.Logger, useGzip: opts.UseGzip}, nil
+	cache := newCache(opts.CacheBytes, opts.CacheSize, opts.CacheMaxEntryFraction, opts.CacheTTL, opts.DegradedCacheSize)
+	d := &_db{path: dbpath, logger: opts.Logger, codec: writeCodec(opts), readPref: opts.ReadPreference, redactor: opts.Redactor, health: newHealth(opts.DegradedRecoverWrites, cache), cache: cache, onVisible: opts.OnVisible, quarantine: newQuarantine(opts.QuarantineAfter), serializeWrites: opts.SerializeWrites, zeroCopy: opts.ZeroCopy, indexPaths: opts.IndexPaths, indexes: make(map[string]*_pathIndex), normalizeOnRead: opts.NormalizeOnRead, callbacks: &_callbacks{recover: opts.RecoverCallbacks}, life: &_lifecycle{}, ttl: opts.TTL, deleteExpired: opts.DeleteExpired, expiry: make(map[string]*_expiries), maxRecords: opts.MaxRecords, records: make(map[string]*_recordCount), quotaBytes: opts.QuotaBytes, usage: make(map[string]*_usage), checksums: opts.Checksums, verifyChecksums: opts.VerifyChecksums, validateJSON: opts.ValidateJSON, noFsync: opts.NoFsync, maxRecordSize: opts.MaxRecordSize, metrics: opts.Metrics, tracer: opts.Tracer}
+	if opts.DetailedTimings {
+		d.onOperation = opts.OnOperation
+		d.timings = &_timings{}
+	}
+	d.enc, err = newEncryption(opts.EncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	d.codec = d.enc.wrap(d.codec)
+	d.codecs, err = codecList(d.codec, append(opts.Codecs, d.enc.wrap(PlainCodec), d.enc.wrap(GzipCodec)))
+	if err != nil {
+		return nil, err
+	}
+	d.ids = fitCodecs(idPolicy(&opts), d.codecs)
+	d.ignore, err = ignorePatterns(opts.IgnorePatterns, opts.NoDefaultIgnores)
+	if err != nil {
+		return nil, err
+	}
+	if opts.ExclusiveOwner {
+		d.owner, err = acquireOwner(dbpath, opts.StaleLockAfter, opts.Logger)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err = recoverJournals(d, filepath.Join(dbpath, journalDir), d.owner != nil, d.logger); err != nil {
+		d.owner.release()
+		return nil, err
+	}
+	d.removeOrphans()
+	return d, nil
+}
+
+// OpenCollection - opens a directory directly as a collection
+//
+// The directory is created when missing and named after its base path,
+// the options apply as they would to a database in its parent directory.
+func OpenCollection(path string, options *Options) (c Collection, err error) {
+	path = filepath.Clean(path)
+	db, err := New(filepath.Dir(path), options)
+	if err != nil {
+		return nil, err
+	}
+	return db.Collection(filepath.Base(path))
 }
 
 // Collection returns the collection or table
-func (db *_db) Collection(name string) (c Collection, err error) {
+//
+// The UseGzip or Codec of options replaces the database codec for this
+// handle and its Codecs are read in addition. ReadPreference,
+// SerializeWrites, ValidateJSON, NoFsync and MaxRecordSize replace those
+// of the database when set, any other field configures the whole database
+// and fails with ErrIncompatibleOptions. CreateOptions still win per
+// record.
+func (db *_db) Collection(name string, options ...Options) (c Collection, err error) {
+	if err = db.life.begin(); err != nil {
+		return nil, err
+	}
+	defer db.life.end()
+	if options != nil {
+		if err = checkCollectionOptions(options[0]); err != nil {
+			return nil, err
+		}
+	}
 	collection := filepath.Join(db.path, name)
 	dir, err := getOrCreateDir(collection)
 	if err != nil {
 		db.logger.Error("unable to create db directory", zap.Error(err))
-		return nil, err
+		return nil, wrapNotFound(ErrCollectionNotFound, name, err)
 	}
 	if !dir.IsDir() {
 		db.logger.Error("not a db directory")
 		return nil, fmt.Errorf("not a directory")
 	}
-	return &_collection{name: name, path: collection, logger: db.logger, useGzip: db.useGzip}, nil
+	layout, err := checkLayout(collection)
+	if err != nil {
+		db.logger.Error("unable to open collection", zap.String("name", name), zap.Error(err))
+		return nil, err
+	}
+	coll := &_collection{name: name, path: collection, logger: db.logger, codec: db.codec, codecs: db.codecs, enc: db.enc, checksums: db.checksums || layout.has(featureChecksums), verifyChecksums: db.verifyChecksums, validateJSON: db.validateJSON, noFsync: db.noFsync, maxRecordSize: db.maxRecordSize, readPref: db.readPref, redactor: db.redactor, onOperation: db.onOperation, timings: db.timings, metrics: db.metrics, tracer: db.tracer, ids: db.ids, ignore: db.ignore, health: db.health, cache: db.cache, onVisible: db.onVisible, quarantine: db.quarantine, serializeWrites: db.serializeWrites, zeroCopy: db.zeroCopy, pathIndex: db.pathIndex(collection), normalizeOnRead: db.normalizeOnRead, callbacks: db.callbacks, life: db.life, ttl: db.ttl, deleteExpired: db.deleteExpired}
+	if db.checksums && !layout.has(featureChecksums) {
+		// later opens keep the checksums current
+		err = requireLayout(collection, featureChecksums)
+		if err != nil {
+			db.logger.Error("unable to update collection layout", zap.String("name", name), zap.Error(err))
+			return nil, err
+		}
+	}
+	if options != nil {
+		o := options[0]
+		coll.codec = db.enc.wrap(writeCodec(o))
+		coll.codecs, err = codecList(coll.codec, append(db.codecs, o.Codecs...))
+		if err != nil {
+			return nil, err
+		}
+		coll.ids = fitCodecs(coll.ids, coll.codecs)
+		if o.ReadPreference != PreferPlain {
+			coll.readPref = o.ReadPreference
+		}
+		if o.MaxRecordSize > 0 {
+			coll.maxRecordSize = o.MaxRecordSize
+		}
+		coll.serializeWrites = coll.serializeWrites || o.SerializeWrites
+		coll.validateJSON = coll.validateJSON || o.ValidateJSON
+		coll.noFsync = coll.noFsync || o.NoFsync
+	}
+	coll.expiry = db.expiries(coll)
+	coll.records, err = db.recordCount(coll)
+	if err != nil {
+		db.logger.Error("unable to count records", zap.String("name", name), zap.Error(err))
+		return nil, err
+	}
+	coll.usage = db.usageOf(coll)
+	return coll, nil
+}
+
+// HasCollection - reports whether the collection directory exists, unlike
+// Collection it never touches the filesystem
+func (db *_db) HasCollection(name string) bool {
+	if !isCollectionName(name) || db.life.begin() != nil {
+		return false
+	}
+	defer db.life.end()
+	info, err := os.Stat(filepath.Join(db.path, name))
+	return err == nil && info.IsDir()
+}
+
+// IsCollectionName - reports whether name is a single path element below
+// the database root and not reserved, the rule HasCollection and
+// DropCollection apply
+func IsCollectionName(name string) bool {
+	return isCollectionName(name)
+}
+
+// isCollectionName - a single path element below the database root which
+// is neither a dot-prefixed internal directory, such as .journal or
+// .sequences, nor the LOCK file of ExclusiveOwner
+func isCollectionName(name string) bool {
+	return name != "" && !strings.HasPrefix(name, ".") && name != ownerFile && filepath.Base(name) == name && !strings.ContainsAny(name, `/\`)
 }
 
-// GetAll - returns all records
+// DropCollection - removes the collection directory with every record
+//
+// Writers are held off by the exclusive collection lock while the
+// directory is removed. The name must be a single path element so nothing
+// outside the database root can be removed.
+func (db *_db) DropCollection(name string) (err error) {
+	if err = db.life.begin(); err != nil {
+		return err
+	}
+	defer db.life.end()
+	if !isCollectionName(name) {
+		return fmt.Errorf("invalid collection name %q", name)
+	}
+	path := filepath.Join(db.path, name)
+	info, err := os.Stat(path)
+	if err != nil {
+		return wrapNotFound(ErrCollectionNotFound, name, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("not a directory")
+	}
+	c := &_collection{name: name, path: path}
+	collection := c.collectionLockPath()
+	acquire(collection, true)
+	defer release(collection, true)
+
+	err = os.RemoveAll(path)
+	if err != nil {
+		db.logger.Error("unable to drop collection", zap.String("name", name), zap.Error(err))
+		return err
+	}
+	db.cache.removeDir(path)
+	db.quarantine.clearDir(path)
+	db.indexMu.Lock()
+	delete(db.indexes, path)
+	delete(db.expiry, path)
+	delete(db.records, path)
+	delete(db.usage, path)
+	db.indexMu.Unlock()
+	return nil
+}
+
+// Degraded - reports whether the database is in degraded mode
+func (db *_db) Degraded() bool {
+	return db.health.isDegraded()
+}
+
+// Name - the collection name
+func (c *_collection) Name() string {
+	return c.name
+}
+
+// GetAll - returns all records ordered by id
 func (c *_collection) GetAll() (data [][]byte) {
-	records, err := os.ReadDir(c.path)
+	data, err := c.GetAllCtx(context.Background())
 	if err != nil {
-		c.logger.Error("no data available")
-		return
+		c.logger.Error("no data available", zap.Error(err))
 	}
-	for _, r := range records {
-		if !r.IsDir() {
-			fPath := filepath.Join(c.path, r.Name())
-			record, err := os.ReadFile(fPath)
-			if err != nil {
-				c.logger.Error("unable to read the data file", zap.String("path", fPath))
-				continue
-			}
+	return
+}
 
-			if strings.LastIndex(r.Name(), GZipExt) > 0 {
-				record, err = UnGzip(record)
-				if err != nil {
-					c.logger.Error("unable to unzip the data file", zap.String("path", fPath))
-				}
-			}
+// GetAllSorted - returns all records with their ids ordered by id, a
+// record stored as both .json and .json.gz appears once
+func (c *_collection) GetAllSorted() []Record {
+	return getAllSorted(c, c.logger)
+}
 
-			data = append(data, record)
+func getAllSorted(c _layer, logger Logger) (records []Record) {
+	keys, err := c.keys()
+	if err != nil {
+		logger.Error("no data available", zap.Error(err))
+		return nil
+	}
+	records = make([]Record, 0, len(keys))
+	for _, key := range keys {
+		data, err := c.Get(key)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				logger.Error("unable to read record", zap.String("id", key), zap.Error(err))
+			}
+			continue
 		}
+		records = append(records, Record{ID: key, Data: data})
 	}
-	return
+	return records
+}
+
+// Keys - returns the sorted record ids
+func (c *_collection) Keys() []string {
+	keys, err := c.keys()
+	if err != nil {
+		c.logger.Error("no data available", zap.Error(err))
+		return nil
+	}
+	return keys
 }
 
 // Get help to retrive key based record
 func (c *_collection) Get(key string) (data []byte, err error) {
-	filename, err, isGzip := c.getPathIfExist(key, err)
-	data, err = os.ReadFile(filename)
-	if err != nil {
-		c.logger.Error("unable to read the record", zap.Error(err))
+	return c.GetCtx(context.Background(), key)
+}
+
+// get - reads the record
+func (c *_collection) get(ctx context.Context, key string) (data []byte, err error) {
+	defer c.observeOp("get", c.metricsStart(), &err)
+	defer func() { err = wrapNotFound(ErrRecordNotFound, key, err) }()
+	if err = c.checkID(key); err != nil {
+		return nil, err
 	}
+	if err = c.life.begin(); err != nil {
+		return nil, err
+	}
+	defer c.life.end()
+	tm := c.startTimings(ctx, "get", key)
+	start := tm.begin()
+	defer c.report(tm, start)
 
-	if isGzip {
-		data, err = UnGzip(data)
+	p := c.paths(key)
+	if c.expiry.expired(p.lock) {
+		c.reapExpired(key)
+		return nil, expiredError(p.plain)
+	}
+	lockStart := c.metricsStart()
+	acquire(p.lock, false)
+	tm.end(phaseLockWait, start)
+	c.observeLockWait(lockStart)
+	defer release(p.lock, false)
+	if err = c.quarantine.check(c, key); err != nil {
+		return nil, err
+	}
+	if data, ok := c.cache.get(p.lock, c.zeroCopy); ok {
+		return data, nil
+	}
+	ok := false
+	if c.readPref == PreferPlain && !c.verifyChecksums && c.maxRecordSize <= 0 {
+		data, ok, err = c.readPlain(p.plain, tm)
+	}
+	if !ok {
+		var filename string
+		var codec Codec
+		filename, codec, err = c.resolve(key)
 		if err != nil {
-			c.logger.Error("unable to unzip the data file", zap.String("path", filename))
+			return nil, err
 		}
+		data, err = c.read(key, filename, codec, tm)
 	}
-
-	return
+	c.quarantine.observe(c, key, err)
+	if err == nil {
+		c.cache.put(p.lock, data)
+	}
+	return data, err
 }
 
 // Insert - helps to save data into model dir
 func (c *_collection) Create(key string, data []byte, options ...CreateOptions) (err error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	var useGzip bool = c.useGzip
-	if !c.useGzip {
-		if options != nil && options[0].UseGzip {
-			useGzip = options[0].UseGzip
+	return c.create(context.Background(), key, data, false, options)
+}
+
+// CreateNX - saves the record only when the id is free, ErrExists
+// otherwise
+//
+// Both file variants are checked under the exclusive record lock, so of
+// concurrent CreateNX calls for one id exactly one succeeds. An expired
+// record counts as free.
+func (c *_collection) CreateNX(key string, data []byte, options ...CreateOptions) (err error) {
+	return c.create(context.Background(), key, data, true, options)
+}
+
+// create - writes the record, refusing existing ones when exclusive is set
+// and giving up when ctx is done before the write starts
+func (c *_collection) create(ctx context.Context, key string, data []byte, exclusive bool, options []CreateOptions) (err error) {
+	ctx, span := c.startSpan(ctx, SpanCreate, key, options != nil && options[0].UseGzip)
+	defer func() { endSpan(span, len(data), err) }()
+	defer c.observeOp("create", c.metricsStart(), &err)
+	if err = c.checkID(key); err != nil {
+		return err
+	}
+	if err = c.validate(key, data); err != nil {
+		return err
+	}
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+	if err = c.life.begin(); err != nil {
+		return err
+	}
+	defer c.life.end()
+	tm := c.startTimings(ctx, "create", key)
+	start := tm.begin()
+	defer c.report(tm, start)
+
+	lockStart := c.metricsStart()
+	unlock := c.lock(key)
+	tm.end(phaseLockWait, start)
+	c.observeLockWait(lockStart)
+	defer unlock()
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+	if exclusive && c.has(key) {
+		return fmt.Errorf("%w: %s", ErrExists, key)
+	}
+	codec := c.codec
+	var ttl time.Duration
+	if options != nil {
+		if options[0].UseGzip {
+			codec = c.enc.wrap(GzipCodec)
 		}
+		ttl = options[0].TTL
 	}
-	filename := c.getFullPath(key, c.useGzip)
+	err = c.write(key, data, codec, c.expiresAt(ttl), tm)
+	return wrapNotFound(ErrCollectionNotFound, c.name, err)
+}
+
+// GetAndCompare - compares the record with candidate in constant time
+func (c *_collection) GetAndCompare(key string, candidate []byte) (equal bool, err error) {
+	data, err := c.Get(key)
 	if err != nil {
-		c.logger.Error("unable to create record", zap.Error(err))
+		return false, err
 	}
+	return subtle.ConstantTimeCompare(data, candidate) == 1, nil
+}
+
+// Update - atomically rewrites a record with the result of fn
+//
+// fn receives the current record, nil when missing. Nothing is written
+// when fn returns an error.
+func (c *_collection) Update(key string, fn func(current []byte) ([]byte, error)) error {
+	_, err := c.UpdateIf(key, unconditional, fn)
+	return err
+}
+
+// unconditional - an UpdateIf condition which always holds
+func unconditional([]byte) (bool, error) {
+	return true, nil
+}
 
-	if useGzip {
-		data, err = c.Gzip(data)
+// UpdateIf - atomically rewrites a record when condition holds
+//
+// The record is read, checked and rewritten under its exclusive lock, a
+// missing record is passed to both funcs as nil. The existing storage
+// format is preserved. Nothing is written when condition returns false or
+// either func returns an error.
+func (c *_collection) UpdateIf(key string, condition func(current []byte) (bool, error), mutate func(current []byte) ([]byte, error)) (applied bool, err error) {
+	defer c.observeOp("update", c.metricsStart(), &err)
+	if err = c.checkID(key); err != nil {
+		return false, err
 	}
-	err = os.WriteFile(filename, data, os.ModePerm)
+	if err = c.life.begin(); err != nil {
+		return false, err
+	}
+	defer c.life.end()
+	lockStart := c.metricsStart()
+	unlock := c.lock(key)
+	c.observeLockWait(lockStart)
+	defer unlock()
+
+	codec := c.codec
+	var current []byte
+	filename, stored, err := c.resolve(key)
+	if err == nil && c.expiry.expired(c.lockPath(key)) {
+		// an expired record reads as missing, the write replaces it
+		err = expiredError(filename)
+	}
+	if err == nil {
+		codec = stored
+		current, err = c.read(key, filename, stored, nil)
+		if err != nil {
+			return false, err
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return false, err
+	}
+
+	var ok bool
+	err = c.callbacks.guard("condition", func() (err error) {
+		ok, err = condition(current)
+		return err
+	})
+	if err != nil || !ok {
+		return false, err
+	}
+	var data []byte
+	err = c.callbacks.guard("mutate", func() (err error) {
+		data, err = mutate(current)
+		return err
+	})
 	if err != nil {
-		c.logger.Error("unable to create record", zap.Error(err))
+		return false, err
 	}
-	return
+	if err = c.checkSize(key, int64(len(data))); err != nil {
+		return false, err
+	}
+	err = c.write(key, data, codec, c.expiresAt(0), nil)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
 }
 
 // Delete - helps to delete model dir record
 func (c *_collection) Delete(key string) (err error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	return c.DeleteCtx(context.Background(), key)
+}
 
-	filename, err, _ := c.getPathIfExist(key, err)
+// remove - deletes every file variant of a record, the caller holds the lock
+func (c *_collection) remove(key string) (err error) {
+	_, _, err = c.resolve(key)
 	if err != nil {
 		return err
 	}
 
-	err = os.Remove(filename)
-	if err != nil {
-		c.logger.Error("unable to delete record", zap.Error(err))
+	freed := c.usage.sizeOf(c, key)
+	// all variants go so a stale duplicate cannot resurface
+	for _, codec := range c.codecs {
+		filename := c.getFullPath(key, codec)
+		err = os.Remove(filename)
+		if err == nil || errors.Is(err, os.ErrNotExist) {
+			err = c.removeChecksum(filename)
+		}
+		if err != nil {
+			c.logger.Error("unable to delete record", zap.Error(err))
+			return err
+		}
 	}
 
-	return
+	c.cache.remove(c.lockPath(key))
+	c.quarantine.clear(c, key)
+	c.records.removed()
+	c.records.touched(c.path)
+	c.usage.freed(freed)
+	c.visible(VisibleInfo{Op: "delete", ID: key})
+
+	err = c.setExpiry(key, time.Time{})
+	if err != nil {
+		c.logger.Error("unable to delete record expiry", zap.Error(err))
+		return err
+	}
+	err = c.removeID(key)
+	if err != nil {
+		c.logger.Error("unable to delete record id", zap.Error(err))
+	}
+	return err
 }
 
-func getOrCreateDir(path string) (os.FileInfo, error) {
-	f, err := os.Stat(path)
+// Truncate - removes every record and stale temp file of the collection
+//
+// Writers are held off by the exclusive collection lock, readers simply
+// start finding nothing. Foreign and ignored files are left in place.
+func (c *_collection) Truncate() (err error) {
+	if err = c.life.begin(); err != nil {
+		return err
+	}
+	defer c.life.end()
+	collection := c.collectionLockPath()
+	acquire(collection, true)
+	defer release(collection, true)
+
+	entries, err := os.ReadDir(c.path)
 	if err != nil {
-		if os.IsNotExist(err) {
-			cwd, err := os.Getwd()
-			if err != nil {
-				return nil, err
+		return err
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() {
+			continue
+		}
+		key := ""
+		if !strings.HasPrefix(name, tempPrefix) {
+			fileKey, _, ok := c.codecOf(name)
+			if !ok || c.isIgnored(name) {
+				continue
 			}
-			newDir := filepath.Join(cwd, path)
-			err = os.Mkdir(filepath.Join(cwd, path), os.ModePerm)
-			if err != nil {
+			key = c.logicalKey(fileKey)
+		}
+		err = os.Remove(filepath.Join(c.path, name))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			c.logger.Error("unable to truncate collection", zap.Error(err))
+			return err
+		}
+		if key != "" {
+			c.cache.remove(c.lockPath(key))
+			c.quarantine.clear(c, key)
+		}
+	}
+	err = os.RemoveAll(filepath.Join(c.path, expiryDir))
+	if err != nil {
+		c.logger.Error("unable to remove record expiry", zap.Error(err))
+		return err
+	}
+	c.expiry.reset()
+	c.records.set(0)
+	c.usage.set(0)
+	err = os.RemoveAll(filepath.Join(c.path, checksumDir))
+	if err != nil {
+		c.logger.Error("unable to remove record checksums", zap.Error(err))
+		return err
+	}
+	err = os.RemoveAll(filepath.Join(c.path, idsDir))
+	if err != nil {
+		c.logger.Error("unable to remove record ids", zap.Error(err))
+	}
+	c.records.touched(c.path)
+	return err
+}
+
+// read - reads and decodes a record file, the caller holds the lock
+func (c *_collection) read(key, filename string, codec Codec, tm *OpTimings) (data []byte, err error) {
+	start := tm.begin()
+	if c.maxRecordSize > 0 {
+		if info, serr := os.Stat(filename); serr == nil {
+			if err = c.checkSize(key, info.Size()); err != nil {
 				return nil, err
 			}
-			return os.Stat(newDir)
 		}
-		return f, err
 	}
-	return f, nil
+	data, err = os.ReadFile(filename)
+	tm.end(phaseRead, start)
+	if err != nil {
+		c.logger.Error("unable to read the record", zap.Error(err))
+		return nil, err
+	}
+	if c.verifyChecksums {
+		if _, err = c.checkSum(filename, data); err != nil {
+			c.logger.Error("unable to verify the record", zap.String("path", filename), zap.Error(err))
+			return nil, fmt.Errorf("%w: %w", ErrCorruptRecord, err)
+		}
+	}
+
+	if codec != PlainCodec {
+		raw := data
+		start = tm.begin()
+		if codec == GzipCodec && c.maxRecordSize > 0 {
+			data, err = c.unGzipLimited(key, data)
+		} else {
+			data, err = codec.Decode(data)
+		}
+		tm.end(phaseDecompress, start)
+		if errors.Is(err, ErrRecordTooLarge) {
+			return nil, err
+		}
+		if err != nil {
+			c.logger.Error("unable to decode the data file", zap.String("path", filename), zap.ByteString("data", c.excerpt(key, raw)))
+			err = fmt.Errorf("%w: %w", ErrCorruptRecord, err)
+			return
+		}
+		if err = c.checkSize(key, int64(len(data))); err != nil {
+			return nil, err
+		}
+	}
+
+	return c.normalize(data), nil
 }
 
-func (c *_collection) getFullPath(key string, isGzip bool) string {
-	var record string
-	if isGzip {
-		record = key + GZipExt
-	} else {
-		record = key + Ext
+// excerpt - a redacted, size limited view of record content for diagnostics
+func (c *_collection) excerpt(key string, data []byte) []byte {
+	if c.redactor != nil {
+		raw := data
+		err := c.callbacks.guard("redactor", func() error {
+			data = c.redactor(key, raw)
+			return nil
+		})
+		if err != nil {
+			data = nil
+		}
 	}
-	filename := filepath.Join(c.path, record)
+	if len(data) > 64 {
+		data = data[:64]
+	}
+	return data
+}
 
-	return filename
+// write - atomically saves a record file expiring at expires, zero for
+// never, the caller holds the lock
+func (c *_collection) write(key string, data []byte, codec Codec, expires time.Time, tm *OpTimings) (err error) {
+	content := data
+	if codec != PlainCodec {
+		start := tm.begin()
+		data, err = codec.Encode(data)
+		tm.end(phaseCompress, start)
+		if err != nil {
+			c.logger.Error("unable to encode the record", zap.Error(err))
+			return err
+		}
+	}
+	return c.store(key, content, data, codec, expires, tm)
+}
+
+// store - writes the encoded record file, content is the decoded record
+// handed to the cache and waiters, the caller holds the lock; both are
+// nil for a streamed record
+func (c *_collection) store(key string, content, data []byte, codec Codec, expires time.Time, tm *OpTimings) (err error) {
+	return c.storeStaged(key, content, data, codec, expires, tm, "")
 }
 
-func (c *_collection) getPathIfExist(key string, err error) (string, error, bool) {
-	record := key + Ext
-	filename := filepath.Join(c.path, record)
+// storeStaged - store, renaming the staged temp file holding data into
+// place unless staged is empty
+func (c *_collection) storeStaged(key string, content, data []byte, codec Codec, expires time.Time, tm *OpTimings, staged string) (err error) {
+	filename := c.getFullPath(key, codec)
+	added, err := c.records.reserve(c, key)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil && added {
+			c.records.removed()
+		}
+	}()
+	size := int64(len(data))
+	if staged != "" {
+		if info, err := os.Stat(staged); err == nil {
+			size = info.Size()
+		}
+	}
+	grown, err := c.usage.reserve(c, key, size)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			c.usage.freed(grown)
+		}
+	}()
+	err = c.saveID(key)
+	if err != nil {
+		c.logger.Error("unable to save record id", zap.Error(err))
+		return err
+	}
+	start := tm.begin()
+	switch {
+	case staged != "":
+		err = renameFile(staged, filename)
+	case c.noFsync:
+		err = writeUnsynced(filename, data, os.ModePerm)
+	default:
+		err = writeFile(filename, data, os.ModePerm, tm)
+	}
+	tm.end(phaseWrite, start)
+	err = c.health.observe(err)
+	if err != nil {
+		c.logger.Error("unable to create record", zap.Error(err))
+		return
+	}
+	err = c.setChecksum(filename, data)
+	if err != nil {
+		c.logger.Error("unable to save record checksum", zap.Error(err))
+		return err
+	}
+	err = c.setExpiry(key, expires)
+	if err != nil {
+		c.logger.Error("unable to save record expiry", zap.Error(err))
+		return err
+	}
+	if content == nil && staged != "" {
+		// streamed, there is nothing to cache
+		c.cache.remove(c.lockPath(key))
+	} else {
+		c.cache.written(c.lockPath(key), content)
+	}
+	c.quarantine.clear(c, key)
+	// a variant in another format would shadow or outlive this write
+	for _, other := range c.codecs {
+		if other == codec {
+			continue
+		}
+		stale := c.getFullPath(key, other)
+		err = os.Remove(stale)
+		if err == nil || errors.Is(err, os.ErrNotExist) {
+			err = c.removeChecksum(stale)
+		}
+		if err != nil {
+			c.logger.Error("unable to remove stale record", zap.Error(err))
+			return err
+		}
+	}
+	err = nil
+	c.records.touched(c.path)
+	notify(c.lockPath(key), content)
+	c.visible(VisibleInfo{Op: "write", ID: key, Size: int(size), Gzip: codec == GzipCodec})
+	return
+}
 
-	if success, err := c.isExist(filename, err); !success {
-		record = key + GZipExt
-		filename = filepath.Join(c.path, record)
-		if success, err := c.isExist(filename, err); !success {
-			return "", err, false
+// keys - returns the sorted record keys available in the collection
+func (c *_collection) keys() (keys []string, err error) {
+	if err = c.life.begin(); err != nil {
+		return nil, err
+	}
+	defer c.life.end()
+	records, err := os.ReadDir(c.path)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool, len(records))
+	filterExpired := !c.expiry.empty()
+	for _, r := range records {
+		if r.IsDir() || strings.HasPrefix(r.Name(), tempPrefix) || c.isIgnored(r.Name()) {
+			continue
+		}
+		key, _, ok := c.codecOf(r.Name())
+		if !ok {
+			continue
+		}
+		key = c.logicalKey(key)
+		if seen[key] {
+			continue
 		}
+		seen[key] = true
+		if filterExpired && c.expiry.expired(c.lockPath(key)) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// has - reports whether an unexpired record exists for key
+func (c *_collection) has(key string) bool {
+	_, _, err := c.resolve(key)
+	return err == nil && !c.expiry.expired(c.lockPath(key))
+}
+
+// writeAtomic - writes through a temp file renamed over filename so readers
+// never observe a partially written record
+//
+// A rename refused because another process holds the target open is
+// retried with a fresh temp file, see isSharingViolation.
+func writeAtomic(filename string, data []byte, perm os.FileMode) (err error) {
+	return replaceWith(filename, data, perm, true, nil)
+}
+
+// writeTimed - writeAtomic attributing the fsync to tm
+func writeTimed(filename string, data []byte, perm os.FileMode, tm *OpTimings) (err error) {
+	return replaceWith(filename, data, perm, true, tm)
+}
 
-		return filename, nil, true
+// writeUnsynced - writeAtomic without syncing the temp file, a crash can
+// lose the write or leave the record empty on some filesystems
+func writeUnsynced(filename string, data []byte, perm os.FileMode) (err error) {
+	return replaceWith(filename, data, perm, false, nil)
+}
+
+// replaceWith - renames a fresh temp file over filename, retrying renames
+// refused by contention
+func replaceWith(filename string, data []byte, perm os.FileMode, sync bool, tm *OpTimings) (err error) {
+	for attempt := 1; ; attempt++ {
+		err = writeAtomicOnce(filename, data, perm, sync, tm)
+		if !isRenameContention(err) {
+			return err
+		}
+		if attempt == renameAttempts {
+			contention.failures.Add(1)
+			return fmt.Errorf("%w: %w", ErrConcurrentModification, err)
+		}
+		contention.retries.Add(1)
+		time.Sleep(time.Duration(attempt) * renameBackoff)
 	}
+}
 
-	return filename, nil, false
+// writeAtomicOnce - writes a fresh temp file and renames it over filename
+func writeAtomicOnce(filename string, data []byte, perm os.FileMode, sync bool, tm *OpTimings) (err error) {
+	f, err := createTemp(filepath.Dir(filename), perm)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(f.Name())
+		}
+	}()
+	_, err = f.Write(data)
+	if err == nil && sync {
+		start := tm.begin()
+		err = f.Sync()
+		tm.end(phaseFsync, start)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return renameFile(f.Name(), filename)
 }
 
-func (c *_collection) isExist(filename string, err error) (bool, error) {
-	info, err := os.Stat(filename)
-	if os.IsNotExist(err) {
-		return false, err
+// createTemp - like os.CreateTemp but honoring perm and the umask
+func createTemp(dir string, perm os.FileMode) (*os.File, error) {
+	for i := 0; i < 10000; i++ {
+		name := filepath.Join(dir, tempPrefix+strconv.FormatUint(uint64(rand.Uint32()), 36))
+		f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, perm)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		return f, err
 	}
-	if !info.IsDir() {
-		return true, nil
+	return nil, fmt.Errorf("unable to create temp file in %s", dir)
+}
+
+func getOrCreateDir(path string) (os.FileInfo, error) {
+	f, err := os.Stat(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			err = os.Mkdir(path, os.ModePerm)
+			if err != nil {
+				return nil, err
+			}
+			return os.Stat(path)
+		}
+		return f, err
 	}
-	return false, nil
+	return f, nil
+}
+
+func (c *_collection) getFullPath(key string, codec Codec) string {
+	filename := filepath.Join(c.path, c.fileKey(key)+codec.Ext())
+
+	return filename
 }
 
 func UnGzip(record []byte) (result []byte, err error) {
@@ -257,6 +1306,9 @@ func UnGzip(record []byte) (result []byte, err error) {
 		return record, err
 	}
 	reader, err := gzip.NewReader(&buffer)
+	if err != nil {
+		return record, err
+	}
 
 	result, err = io.ReadAll(reader)
 	if err != nil {
@@ -272,6 +1324,10 @@ func UnGzip(record []byte) (result []byte, err error) {
 }
 
 func (c *_collection) Gzip(data []byte) (result []byte, err error) {
+	return gzipBytes(data)
+}
+
+func gzipBytes(data []byte) (result []byte, err error) {
 	var buffer bytes.Buffer
 	writer := gzip.NewWriter(&buffer)
 	_, err = writer.Write(data)
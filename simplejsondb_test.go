@@ -1,142 +1,98 @@
 package simplejsondb_test
 
 import (
+	"errors"
 	"os"
+	"path/filepath"
 	"testing"
 
 	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
 )
 
 func TestNew(t *testing.T) {
-	path := "database1"
-	_, err := simplejsondb.New(path, nil)
+	_, err := simplejsondb.New(t.TempDir(), nil)
 	if err != nil {
 		t.Error(err)
 	}
 }
 
 func TestNewCollection(t *testing.T) {
-	path := "database1"
-	db, err := simplejsondb.New(path, nil)
-	if err != nil {
-		t.Error(err)
-	}
-	_, err = db.Collection("collection1")
+	db, _ := dbtest.NewDB(t, nil)
+	_, err := db.Collection("collection1")
 	if err != nil {
 		t.Error(err)
 	}
 }
 
 func TestGetAll(t *testing.T) {
-	path := "database1"
-	db, err := simplejsondb.New(path, nil)
-	if err != nil {
-		t.Error(err)
+	_, collection := dbtest.NewDB(t, nil)
+	c := collection("collection1")
+	dbtest.SeedN(t, c, 3)
+	if all := c.GetAll(); len(all) != 3 {
+		t.Error("Test failed - ", len(all))
 	}
-	c, err := db.Collection("collection1")
-	if err != nil {
-		t.Error(err)
-	}
-
-	_ = c.GetAll()
 }
 
 func TestGet(t *testing.T) {
-	path := "database1"
-	db, err := simplejsondb.New(path, nil)
-	if err != nil {
-		t.Error(err)
-	}
-	c, err := db.Collection("collection1")
-	if err != nil {
-		t.Error(err)
-	}
-	_, err = c.Get("ip-dummy")
-	if os.IsExist(err) {
+	_, collection := dbtest.NewDB(t, nil)
+	c := collection("collection1")
+	_, err := c.Get("ip-dummy")
+	if !errors.Is(err, os.ErrNotExist) {
 		t.Error("Test failed - ", err)
 	}
 }
 
 func TestInsert(t *testing.T) {
-	path := "database1"
-	db, err := simplejsondb.New(path, nil)
-	if err != nil {
-		t.Error(err)
-	}
-	c, err := db.Collection("collection1")
-	if err != nil {
-		t.Error(err)
-	}
+	_, collection := dbtest.NewDB(t, nil)
+	c := collection("collection1")
 	var data []byte
 	data = append(data, 99)
-	err = c.Create("ip-dummy", data)
+	err := c.Create("ip-dummy", data)
 	if err != nil {
 		t.Error("Test failed - ", err)
 	}
+	dbtest.RequireRecord(t, c, "ip-dummy", data)
 }
 
 func TestGZipInsert(t *testing.T) {
-	path := "database1"
-	db, err := simplejsondb.New(path, &simplejsondb.Options{UseGzip: true})
-	if err != nil {
-		t.Error(err)
-	}
-	c, err := db.Collection("collection1")
-	if err != nil {
-		t.Error(err)
-	}
+	dir := t.TempDir()
+	_, collection := dbtest.Open(t, dir, &simplejsondb.Options{UseGzip: true})
+	c := collection("collection1")
 	var data []byte
 	data = append(data, 99)
-	err = c.Create("ip-dummy", data)
+	err := c.Create("ip-dummy", data)
 	if err != nil {
 		t.Error("Test failed - ", err)
 	}
+	if _, err = os.Stat(filepath.Join(dir, "collection1", "ip-dummy"+simplejsondb.GZipExt)); err != nil {
+		t.Error("Test failed - ", err)
+	}
 }
 
 func TestGet2(t *testing.T) {
-	path := "database1"
-	db, err := simplejsondb.New(path, nil)
-	if err != nil {
-		t.Error(err)
-	}
-	c, err := db.Collection("collection1")
-	if err != nil {
-		t.Error(err)
-	}
-	_, err = c.Get("ip-dummy")
-	if os.IsNotExist(err) {
+	_, collection := dbtest.NewDB(t, nil)
+	c := collection("collection1")
+	dbtest.Seed(t, c, map[string][]byte{"ip-dummy": {99}})
+	_, err := c.Get("ip-dummy")
+	if errors.Is(err, os.ErrNotExist) {
 		t.Error("Test failed - ", err)
 	}
 }
 
 func TestGetGZip(t *testing.T) {
-	path := "database1"
-	db, err := simplejsondb.New(path, &simplejsondb.Options{UseGzip: true})
-	if err != nil {
-		t.Error(err)
-	}
-	c, err := db.Collection("collection1")
-	if err != nil {
-		t.Error(err)
-	}
-	_, err = c.Get("ip-dummy")
-	if os.IsNotExist(err) {
-		t.Error("Test failed - ", err)
-	}
+	_, collection := dbtest.NewDB(t, &simplejsondb.Options{UseGzip: true})
+	c := collection("collection1")
+	dbtest.Seed(t, c, map[string][]byte{"ip-dummy": {99}})
+	dbtest.RequireRecord(t, c, "ip-dummy", []byte{99})
 }
 
 func TestDelete(t *testing.T) {
-	path := "database1"
-	db, err := simplejsondb.New(path, nil)
+	_, collection := dbtest.NewDB(t, nil)
+	c := collection("collection1")
+	dbtest.Seed(t, c, map[string][]byte{"test_dummp": {99}})
+	err := c.Delete("test_dummp")
 	if err != nil {
-		t.Error(err)
-	}
-	c, err := db.Collection("collection1")
-	if err != nil {
-		t.Error(err)
-	}
-	err = c.Delete("test_dummp")
-	if os.IsExist(err) {
 		t.Error("Test failed - ", err)
 	}
 
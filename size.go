@@ -0,0 +1,134 @@
+package simplejsondb
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// walkBatch - directory entries read at a time by walkRecords
+const walkBatch = 256
+
+// SizeOptions - Size configuration
+type SizeOptions struct {
+	// Uncompressed - count gzip records by the size recorded in their
+	// trailer instead of their file size, an estimate as the trailer
+	// holds the size modulo 4 GiB; other encoded files count as stored
+	Uncompressed bool
+}
+
+// Size - bytes of the record files on disk
+//
+// The directory is read in batches and files are only stat'ed, no record
+// lock is taken so writers are never held up; records written meanwhile
+// may or may not be counted. Temp and ignored files are left out, expired
+// records count until they are removed.
+func (c *_collection) Size(options ...SizeOptions) (size uint64, err error) {
+	opts := SizeOptions{}
+	if options != nil {
+		opts = options[0]
+	}
+	err = c.walkRecords(func(name string, codec Codec, info fs.FileInfo) error {
+		n := info.Size()
+		if opts.Uncompressed && codec == GzipCodec {
+			if isize, err := gzipSize(filepath.Join(c.path, name)); err == nil {
+				n = isize
+			}
+		}
+		size += uint64(n)
+		return nil
+	})
+	return size, err
+}
+
+// Size - bytes of the visible records in the layers holding them
+func (c *_overlayCollection) Size(options ...SizeOptions) (size uint64, err error) {
+	opts := SizeOptions{}
+	if options != nil {
+		opts = options[0]
+	}
+	keys, err := c.keys()
+	if err != nil {
+		return 0, err
+	}
+	for _, key := range keys {
+		info, err := c.Stat(key)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return size, err
+		}
+		if opts.Uncompressed {
+			size += uint64(info.ContentSize)
+		} else {
+			size += uint64(info.Size)
+		}
+	}
+	return size, nil
+}
+
+// walkRecords - calls fn with every record file of the collection,
+// reading the directory in batches; files removed meanwhile are skipped
+func (c *_collection) walkRecords(fn func(name string, codec Codec, info fs.FileInfo) error) error {
+	if err := c.life.begin(); err != nil {
+		return err
+	}
+	defer c.life.end()
+	dir, err := os.Open(c.path)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	for {
+		entries, err := dir.ReadDir(walkBatch)
+		for _, e := range entries {
+			name := e.Name()
+			if e.IsDir() || strings.HasPrefix(name, tempPrefix) || c.isIgnored(name) {
+				continue
+			}
+			_, codec, ok := c.codecOf(name)
+			if !ok {
+				continue
+			}
+			info, err := e.Info()
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if err = fn(name, codec, info); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// gzipSize - the uncompressed size modulo 4 GiB from the gzip trailer
+func gzipSize(filename string) (int64, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	var trailer [4]byte
+	if _, err = f.ReadAt(trailer[:], info.Size()-4); err != nil {
+		return 0, err
+	}
+	return int64(binary.LittleEndian.Uint32(trailer[:])), nil
+}
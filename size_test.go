@@ -0,0 +1,62 @@
+package simplejsondb_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestSize(t *testing.T) {
+	dir := t.TempDir()
+	_, collection := dbtest.Open(t, dir, nil)
+	c := collection("usage")
+	data := []byte(`{"pad": "` + strings.Repeat("x", 1000) + `"}`)
+	for i := 0; i < 600; i++ {
+		if err := c.Create(fmt.Sprint("plain", i), data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.Create("zipped", data, simplejsondb.CreateOptions{UseGzip: true}); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{".tmp-123", "notes.swp"} {
+		if err := os.WriteFile(filepath.Join(dir, "usage", name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	zipped, err := os.Stat(filepath.Join(dir, "usage", "zipped.json.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	size, err := c.Size()
+	if want := uint64(600*len(data)) + uint64(zipped.Size()); err != nil || size != want {
+		t.Error("Test failed - ", size, want, err)
+	}
+	size, err = c.Size(simplejsondb.SizeOptions{Uncompressed: true})
+	if want := uint64(601 * len(data)); err != nil || size != want {
+		t.Error("Test failed - ", size, want, err)
+	}
+
+	// monitoring alongside writers
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			_ = c.Create(fmt.Sprint("new", i), data)
+		}
+	}()
+	for i := 0; i < 5; i++ {
+		if _, err = c.Size(); err != nil {
+			t.Error(err)
+		}
+	}
+	wg.Wait()
+}
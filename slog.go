@@ -0,0 +1,61 @@
+//go:build go1.21
+
+package simplejsondb
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// _slogLogger - a Logger writing to a *slog.Logger
+type _slogLogger struct {
+	l *slog.Logger
+}
+
+// SlogLogger - a Logger for Options writing to l, zap fields become slog
+// attributes
+//
+// Like log/slog it needs go1.21, the module itself builds with go1.20.
+func SlogLogger(l *slog.Logger) Logger {
+	return &_slogLogger{l: l}
+}
+
+// Error - logs at slog.LevelError
+func (s *_slogLogger) Error(msg string, fields ...zapcore.Field) {
+	s.log(slog.LevelError, msg, fields)
+}
+
+// Warn - logs at slog.LevelWarn
+func (s *_slogLogger) Warn(msg string, fields ...zapcore.Field) {
+	s.log(slog.LevelWarn, msg, fields)
+}
+
+// Info - logs at slog.LevelInfo
+func (s *_slogLogger) Info(msg string, fields ...zapcore.Field) {
+	s.log(slog.LevelInfo, msg, fields)
+}
+
+// Debug - logs at slog.LevelDebug
+func (s *_slogLogger) Debug(msg string, fields ...zapcore.Field) {
+	s.log(slog.LevelDebug, msg, fields)
+}
+
+func (s *_slogLogger) log(level slog.Level, msg string, fields []zapcore.Field) {
+	ctx := context.Background()
+	if !s.l.Enabled(ctx, level) {
+		return
+	}
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	attrs := make([]slog.Attr, 0, len(enc.Fields))
+	for key, value := range enc.Fields {
+		attrs = append(attrs, slog.Any(key, value))
+	}
+	sort.Slice(attrs, func(i, j int) bool { return attrs[i].Key < attrs[j].Key })
+	s.l.LogAttrs(ctx, level, msg, attrs...)
+}
@@ -0,0 +1,39 @@
+//go:build go1.21
+
+package simplejsondb_test
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestSlogLogger(t *testing.T) {
+	var out bytes.Buffer
+	l := slog.New(slog.NewTextHandler(&out, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	dir := t.TempDir()
+	_, collection := dbtest.Open(t, dir, &simplejsondb.Options{Logger: simplejsondb.SlogLogger(l)})
+	c := collection("users")
+	dbtest.Seed(t, c, map[string][]byte{"u1": []byte(`{}`)})
+	err := os.WriteFile(filepath.Join(dir, "users", "bad"+simplejsondb.GZipExt), []byte("not gzip"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := c.GetAll(); len(got) != 1 {
+		t.Error("Test failed - ", len(got))
+	}
+	logged := out.String()
+	if !strings.Contains(logged, "level=ERROR") || !strings.Contains(logged, "id=bad") {
+		t.Error("Test failed - ", logged)
+	}
+	if strings.Contains(logged, "level=INFO") || strings.Contains(logged, "level=DEBUG") {
+		t.Error("Test failed - level not honored", logged)
+	}
+}
@@ -0,0 +1,152 @@
+package snappycodec
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// A snappy block is the varint length of the decoded data followed by
+// elements, each a literal or a copy of earlier output; the low two bits
+// of an element's tag tell which.
+const (
+	tagLiteral = 0x00
+	tagCopy1   = 0x01
+	tagCopy2   = 0x02
+	tagCopy4   = 0x03
+
+	minMatch = 4
+	hashLog  = 14
+)
+
+// encodeBlock - src, at most maxChunk bytes, as one snappy block
+func encodeBlock(dst, src []byte) []byte {
+	dst = binary.AppendUvarint(dst, uint64(len(src)))
+	var table [1 << hashLog]uint16
+	anchor := 0
+	for i := 0; i+minMatch <= len(src); {
+		cur := binary.LittleEndian.Uint32(src[i:])
+		h := (cur * 0x1e35a7bd) >> (32 - hashLog)
+		cand := int(table[h]) - 1
+		table[h] = uint16(i + 1)
+		if cand < 0 || binary.LittleEndian.Uint32(src[cand:]) != cur {
+			i++
+			continue
+		}
+		n := minMatch
+		for i+n < len(src) && src[cand+n] == src[i+n] {
+			n++
+		}
+		dst = appendLiteral(dst, src[anchor:i])
+		dst = appendCopy(dst, i-cand, n)
+		i += n
+		anchor = i
+	}
+	return appendLiteral(dst, src[anchor:])
+}
+
+func appendLiteral(dst, lit []byte) []byte {
+	n := len(lit) - 1
+	switch {
+	case n < 0:
+		return dst
+	case n < 60:
+		dst = append(dst, byte(n)<<2|tagLiteral)
+	case n < 1<<8:
+		dst = append(dst, 60<<2|tagLiteral, byte(n))
+	default:
+		// a block never exceeds maxChunk, so two length bytes do
+		dst = append(dst, 61<<2|tagLiteral, byte(n), byte(n>>8))
+	}
+	return append(dst, lit...)
+}
+
+// appendCopy - a match of n bytes offset bytes back, split into copies of
+// at most 64 bytes
+func appendCopy(dst []byte, offset, n int) []byte {
+	for n > 0 {
+		if n >= 4 && n <= 11 && offset < 2048 {
+			return append(dst, byte(offset>>8)<<5|byte(n-4)<<2|tagCopy1, byte(offset))
+		}
+		l := n
+		if l > 64 {
+			l = 64
+			// leave at least 4 bytes so the rest may still use copy1
+			if n-l < 4 {
+				l = n - 4
+			}
+		}
+		dst = append(dst, byte(l-1)<<2|tagCopy2, byte(offset), byte(offset>>8))
+		n -= l
+	}
+	return dst
+}
+
+// decodeBlock - appends the data of block to dst
+func decodeBlock(dst, block []byte) ([]byte, error) {
+	size, k := binary.Uvarint(block)
+	if k <= 0 || size > maxChunk {
+		return nil, fmt.Errorf("%w: bad block length", ErrCorrupt)
+	}
+	start := len(dst)
+	want := start + int(size)
+	for s := block[k:]; len(s) > 0; {
+		tag := s[0]
+		var n, offset int
+		switch tag & 0x03 {
+		case tagLiteral:
+			n = int(tag >> 2)
+			s = s[1:]
+			if n >= 60 {
+				extra := n - 59
+				if len(s) < extra {
+					return nil, ErrCorrupt
+				}
+				n = 0
+				for j := extra - 1; j >= 0; j-- {
+					n = n<<8 | int(s[j])
+				}
+				s = s[extra:]
+			}
+			n++
+			if n > len(s) || n > want-len(dst) {
+				return nil, ErrCorrupt
+			}
+			dst = append(dst, s[:n]...)
+			s = s[n:]
+			continue
+		case tagCopy1:
+			if len(s) < 2 {
+				return nil, ErrCorrupt
+			}
+			n = int(tag>>2&0x07) + 4
+			offset = int(tag>>5)<<8 | int(s[1])
+			s = s[2:]
+		case tagCopy2:
+			if len(s) < 3 {
+				return nil, ErrCorrupt
+			}
+			n = int(tag>>2) + 1
+			offset = int(binary.LittleEndian.Uint16(s[1:]))
+			s = s[3:]
+		case tagCopy4:
+			if len(s) < 5 {
+				return nil, ErrCorrupt
+			}
+			n = int(tag>>2) + 1
+			offset = int(binary.LittleEndian.Uint32(s[1:]))
+			s = s[5:]
+		}
+		if offset <= 0 || offset > len(dst)-start || n > want-len(dst) {
+			return nil, ErrCorrupt
+		}
+		// byte by byte, copies may overlap what they produce
+		for from := len(dst) - offset; n > 0; n-- {
+			dst = append(dst, dst[from])
+			from++
+		}
+	}
+	if len(dst) != want {
+		return nil, fmt.Errorf("%w: block length mismatch", ErrCorrupt)
+	}
+	return dst, nil
+}
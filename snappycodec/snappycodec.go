@@ -0,0 +1,129 @@
+// Package snappycodec - a simplejsondb.Codec storing records snappy
+// compressed under simplejsondb.SnappyExt
+//
+// Records are written in the snappy framing format, the one of the snzip
+// and python-snappy tools and of snappy.NewBufferedWriter in
+// github.com/golang/snappy, so every file carries CRC-32C checksums of its
+// chunks.
+//
+//	db, err := simplejsondb.New("data", &simplejsondb.Options{Codec: snappycodec.Codec})
+package snappycodec
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+)
+
+const (
+	chunkCompressed   = 0x00
+	chunkUncompressed = 0x01
+	chunkPadding      = 0xfe
+	chunkStream       = 0xff
+
+	streamID = "sNaPpY"
+	// maxChunk - most uncompressed bytes in one chunk
+	maxChunk = 1 << 16
+)
+
+// ErrCorrupt - a record is no valid snappy framed stream
+var ErrCorrupt = errors.New("snappy: corrupt input")
+
+type _codec struct{}
+
+// Codec - records stored snappy compressed under simplejsondb.SnappyExt
+var Codec simplejsondb.Codec = _codec{}
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+func (_codec) Ext() string { return simplejsondb.SnappyExt }
+
+func (_codec) Encode(data []byte) ([]byte, error) {
+	dst := make([]byte, 0, 10+len(data)+len(data)/maxChunk*8)
+	dst = appendChunkHeader(dst, chunkStream, len(streamID))
+	dst = append(dst, streamID...)
+	var block []byte
+	for len(data) > 0 {
+		n := len(data)
+		if n > maxChunk {
+			n = maxChunk
+		}
+		chunk := data[:n]
+		data = data[n:]
+		block = encodeBlock(block[:0], chunk)
+		kind, body := byte(chunkCompressed), block
+		if len(block) >= len(chunk) {
+			kind, body = chunkUncompressed, chunk
+		}
+		dst = appendChunkHeader(dst, kind, 4+len(body))
+		dst = binary.LittleEndian.AppendUint32(dst, checksum(chunk))
+		dst = append(dst, body...)
+	}
+	return dst, nil
+}
+
+func (_codec) Decode(stored []byte) ([]byte, error) {
+	var out []byte
+	for first := true; len(stored) > 0; first = false {
+		if len(stored) < 4 {
+			return nil, ErrCorrupt
+		}
+		kind := stored[0]
+		n := int(stored[1]) | int(stored[2])<<8 | int(stored[3])<<16
+		if len(stored) < 4+n {
+			return nil, ErrCorrupt
+		}
+		body := stored[4 : 4+n]
+		stored = stored[4+n:]
+		if first && kind != chunkStream {
+			return nil, fmt.Errorf("%w: missing stream identifier", ErrCorrupt)
+		}
+
+		switch {
+		case kind == chunkStream:
+			if string(body) != streamID {
+				return nil, fmt.Errorf("%w: bad stream identifier", ErrCorrupt)
+			}
+		case kind == chunkCompressed || kind == chunkUncompressed:
+			if n < 4 {
+				return nil, ErrCorrupt
+			}
+			start := len(out)
+			if kind == chunkUncompressed {
+				out = append(out, body[4:]...)
+			} else {
+				var err error
+				if out, err = decodeBlock(out, body[4:]); err != nil {
+					return nil, err
+				}
+			}
+			if len(out)-start > maxChunk {
+				return nil, fmt.Errorf("%w: chunk over %d bytes", ErrCorrupt, maxChunk)
+			}
+			if checksum(out[start:]) != binary.LittleEndian.Uint32(body) {
+				return nil, fmt.Errorf("%w: checksum mismatch", ErrCorrupt)
+			}
+		case kind < 0x80:
+			// reserved unskippable chunks
+			return nil, fmt.Errorf("%w: unsupported chunk type %#x", ErrCorrupt, kind)
+		}
+		// padding and reserved skippable chunks are skipped
+	}
+	if out == nil {
+		out = []byte{}
+	}
+	return out, nil
+}
+
+func appendChunkHeader(dst []byte, kind byte, n int) []byte {
+	return append(dst, kind, byte(n), byte(n>>8), byte(n>>16))
+}
+
+// checksum - the masked CRC-32C of the framing format
+func checksum(data []byte) uint32 {
+	c := crc32.Checksum(data, crcTable)
+	return (c>>15 | c<<17) + 0xa282ead8
+}
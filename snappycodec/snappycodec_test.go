@@ -0,0 +1,100 @@
+package snappycodec_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+	"github.com/pnkj-kmr/simple-json-db/snappycodec"
+)
+
+func TestRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	noise := make([]byte, 100<<10)
+	r.Read(noise)
+	text := make([]byte, 150<<10)
+	for i := range text {
+		text[i] = `abcdef{}":, `[r.Intn(12)]
+	}
+	inputs := [][]byte{
+		{},
+		[]byte(`{}`),
+		[]byte(`{"name": "a", "tags": ["x", "x", "x", "x"]}`),
+		bytes.Repeat([]byte(`{"id": 12, "name": "repeated"},`), 10000),
+		noise,
+		text,
+	}
+	for i, in := range inputs {
+		stored, err := snappycodec.Codec.Encode(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := snappycodec.Codec.Decode(stored)
+		if err != nil || !bytes.Equal(out, in) {
+			t.Error("Test failed - ", i, len(out), len(in), err)
+		}
+	}
+	if stored, _ := snappycodec.Codec.Encode(inputs[3]); len(stored) > len(inputs[3])/10 {
+		t.Error("Test failed - ", len(stored))
+	}
+}
+
+// a stream put together by hand from the framing format description
+func TestDecodeFramed(t *testing.T) {
+	crc := func(data string) []byte {
+		c := crc32.Checksum([]byte(data), crc32.MakeTable(crc32.Castagnoli))
+		return binary.LittleEndian.AppendUint32(nil, (c>>15|c<<17)+0xa282ead8)
+	}
+	stream := []byte{0xff, 6, 0, 0, 's', 'N', 'a', 'P', 'p', 'Y'}
+	// varint 10, literal "ab", copy of 8 bytes 2 back
+	block := []byte{10, 1 << 2, 'a', 'b', (8-4)<<2 | 1, 2}
+	stream = append(stream, 0x00, byte(4+len(block)), 0, 0)
+	stream = append(append(stream, crc("ababababab")...), block...)
+	// padding is skipped
+	stream = append(stream, 0xfe, 2, 0, 0, 0, 0)
+	stream = append(stream, 0x01, 4+3, 0, 0)
+	stream = append(append(stream, crc(`{"}`)...), `{"}`...)
+
+	data, err := snappycodec.Codec.Decode(stream)
+	if err != nil || string(data) != `ababababab{"}` {
+		t.Error("Test failed - ", string(data), err)
+	}
+
+	corrupt := append([]byte(nil), stream...)
+	corrupt[len(corrupt)-1] = '!'
+	if _, err = snappycodec.Codec.Decode(corrupt); !errors.Is(err, snappycodec.ErrCorrupt) {
+		t.Error("Test failed - ", err)
+	}
+	if _, err = snappycodec.Codec.Decode(stream[10:]); !errors.Is(err, snappycodec.ErrCorrupt) {
+		t.Error("Test failed - ", err)
+	}
+}
+
+func TestMixedCollection(t *testing.T) {
+	dir := t.TempDir()
+	_, collection := dbtest.Open(t, dir, &simplejsondb.Options{Codec: snappycodec.Codec})
+	c := collection("mixed")
+	if err := c.Create("snappy", []byte(`{"s": 1}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "mixed", "plain.json"), []byte(`{"p": 1}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "mixed", "snappy"+simplejsondb.SnappyExt)); err != nil {
+		t.Error("Test failed - ", err)
+	}
+
+	_, collection = dbtest.Open(t, dir, &simplejsondb.Options{Codecs: []simplejsondb.Codec{snappycodec.Codec}})
+	c = collection("mixed")
+	all := c.GetAll()
+	if len(all) != 2 || string(all[0]) != `{"p": 1}` || string(all[1]) != `{"s": 1}` {
+		t.Error("Test failed - ", all)
+	}
+}
@@ -0,0 +1,35 @@
+package simplejsondb_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestGetAllSorted(t *testing.T) {
+	dir := t.TempDir()
+	_, collection := dbtest.Open(t, dir, nil)
+	c := collection("golden")
+	dbtest.Seed(t, c, map[string][]byte{"b": []byte(`"b"`), "a10": []byte(`"a10"`), "a2": []byte(`"a2"`)})
+	// a hand copied duplicate in the other format is listed once
+	_ = os.WriteFile(filepath.Join(dir, "golden", "b.json.gz"), []byte("stale"), 0644)
+
+	records := c.GetAllSorted()
+	want := []string{"a10", "a2", "b"}
+	if len(records) != len(want) {
+		t.Fatal("Test failed - ", records)
+	}
+	for i, r := range records {
+		if r.ID != want[i] || string(r.Data) != `"`+want[i]+`"` {
+			t.Error("Test failed - ", i, r.ID, string(r.Data))
+		}
+	}
+	all := c.GetAll()
+	for i := range all {
+		if string(all[i]) != string(records[i].Data) {
+			t.Error("Test failed - GetAll order differs", i)
+		}
+	}
+}
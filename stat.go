@@ -0,0 +1,77 @@
+package simplejsondb
+
+import (
+	"os"
+	"time"
+)
+
+// RecordInfo - metadata of a record file
+type RecordInfo struct {
+	ID string
+	// Size - bytes of the record file on disk
+	Size int64
+	// ContentSize - bytes of the decoded record, equal to Size for plain
+	// files
+	ContentSize int64
+	ModTime     time.Time
+	// Compressed - the file is gzipped, encrypted or not
+	Compressed bool
+	// Ext - the file extension naming the codec of the record
+	Ext string
+}
+
+// Stat - metadata of the file Get would read for key
+//
+// A missing or expired record fails like Get, matching os.ErrNotExist.
+// Encoded records are decoded to measure ContentSize.
+func (c *_collection) Stat(key string) (info RecordInfo, err error) {
+	if err = c.checkID(key); err != nil {
+		return info, err
+	}
+	if err = c.life.begin(); err != nil {
+		return info, err
+	}
+	defer c.life.end()
+	defer c.rlock(key)()
+	filename, codec, err := c.resolve(key)
+	if err != nil {
+		return info, err
+	}
+	if c.expiry.expired(c.lockPath(key)) {
+		return info, expiredError(filename)
+	}
+	fi, err := os.Stat(filename)
+	if err != nil {
+		return info, err
+	}
+	info = RecordInfo{
+		ID:          key,
+		Size:        fi.Size(),
+		ContentSize: fi.Size(),
+		ModTime:     fi.ModTime(),
+		Compressed:  codec == GzipCodec || codec == c.enc.wrap(GzipCodec),
+		Ext:         codec.Ext(),
+	}
+	if codec != PlainCodec {
+		data, err := c.read(key, filename, codec, nil)
+		if err != nil {
+			return info, err
+		}
+		info.ContentSize = int64(len(data))
+	}
+	return info, nil
+}
+
+// Stat - metadata of the visible record in the layer holding it
+func (c *_overlayCollection) Stat(key string) (RecordInfo, error) {
+	if err := c.upper.checkID(key); err != nil {
+		return RecordInfo{}, err
+	}
+	if c.upper.has(key) {
+		return c.upper.Stat(key)
+	}
+	if !c.inBase(key) {
+		return RecordInfo{}, c.notFound(key)
+	}
+	return c.base.Stat(key)
+}
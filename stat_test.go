@@ -0,0 +1,39 @@
+package simplejsondb_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestStat(t *testing.T) {
+	dir := t.TempDir()
+	_, collection := dbtest.Open(t, dir, nil)
+	c := collection("admin")
+	data := []byte(`{"pad": "` + strings.Repeat("x", 1000) + `"}`)
+	if err := c.Create("zipped", data, simplejsondb.CreateOptions{UseGzip: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Create("plain", data); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := c.Stat("zipped")
+	fi, _ := os.Stat(filepath.Join(dir, "admin", "zipped.json.gz"))
+	if err != nil || !info.Compressed || info.Ext != simplejsondb.GZipExt || info.Size != fi.Size() || info.ContentSize != int64(len(data)) || !info.ModTime.Equal(fi.ModTime()) {
+		t.Error("Test failed - ", info, err)
+	}
+	info, err = c.Stat("plain")
+	if err != nil || info.Compressed || info.Size != int64(len(data)) || info.ContentSize != info.Size || time.Since(info.ModTime) > time.Minute {
+		t.Error("Test failed - ", info, err)
+	}
+	if _, err = c.Stat("missing"); !errors.Is(err, os.ErrNotExist) {
+		t.Error("Test failed - ", err)
+	}
+}
@@ -0,0 +1,53 @@
+package simplejsondb
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Sync - flushes the record files and directory entries of the collection
+// to disk
+//
+// Records are synced as they are written unless NoFsync is set, a bulk
+// loader using it calls Sync at its durability points instead. Every
+// record file is synced, those already on disk cost little.
+func (c *_collection) Sync() error {
+	err := c.walkRecords(func(name string, _ Codec, _ fs.FileInfo) error {
+		return syncFile(filepath.Join(c.path, name))
+	})
+	if err != nil {
+		return err
+	}
+	return syncDir(c.path)
+}
+
+// Sync - flushes the overlay records, base is never written
+func (c *_overlayCollection) Sync() error {
+	return c.upper.Sync()
+}
+
+// syncHook - sees every file and directory syncFile and syncDir flush,
+// set by tests
+var syncHook func(path string)
+
+// syncFile - fsyncs a file, one removed meanwhile has nothing to flush
+func syncFile(filename string) error {
+	if syncHook != nil {
+		syncHook(filename)
+	}
+	// opened for writing as Windows refuses to flush read-only handles
+	f, err := os.OpenFile(filename, os.O_RDWR, 0)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	err = f.Sync()
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,12 @@
+//go:build !unix
+
+package simplejsondb
+
+// syncDir - directories cannot be synced here, renames are durable once
+// the call returns
+func syncDir(dir string) error {
+	if syncHook != nil {
+		syncHook(dir)
+	}
+	return nil
+}
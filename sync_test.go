@@ -0,0 +1,51 @@
+package simplejsondb_test
+
+import (
+	"fmt"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestNoFsync(t *testing.T) {
+	dir := t.TempDir()
+	_, collection := dbtest.Open(t, dir, &simplejsondb.Options{NoFsync: true})
+	c := collection("bulk")
+	dbtest.SeedN(t, c, 20)
+	if err := c.Create("zipped", []byte(`{"z": 1}`), simplejsondb.CreateOptions{UseGzip: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Sync(); err != nil {
+		t.Error("Test failed - ", err)
+	}
+	_, collection = dbtest.Open(t, dir, nil)
+	reopened := collection("bulk")
+	if keys := reopened.Keys(); len(keys) != 21 {
+		t.Error("Test failed - ", len(keys))
+	}
+	dbtest.RequireRecord(t, reopened, "zipped", []byte(`{"z": 1}`))
+	if err := reopened.Sync(); err != nil {
+		t.Error("Test failed - ", err)
+	}
+}
+
+func BenchmarkCreateFsync(b *testing.B) {
+	for _, noFsync := range []bool{false, true} {
+		b.Run(fmt.Sprint("NoFsync=", noFsync), func(b *testing.B) {
+			_, collection := dbtest.NewDB(b, &simplejsondb.Options{NoFsync: noFsync})
+			c := collection("bulk")
+			data := []byte(`{"seeded": true}`)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := c.Create(fmt.Sprint("r", i%200), data); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.StopTimer()
+			if err := c.Sync(); err != nil {
+				b.Fatal(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,18 @@
+//go:build unix
+
+package simplejsondb
+
+import "os"
+
+// syncDir - flushes directory entries such as freshly renamed records
+func syncDir(dir string) error {
+	if syncHook != nil {
+		syncHook(dir)
+	}
+	f, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return f.Sync()
+}
@@ -0,0 +1,213 @@
+package simplejsondb
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// timingSamples - most recent durations per phase Stats computes
+// percentiles from
+const timingSamples = 1024
+
+type (
+	// OpTimings - where the time of a single Get or Create was spent
+	//
+	// Write covers the temp file write and rename, Fsync the sync of the
+	// temp file in between.
+	OpTimings struct {
+		Op         string
+		Collection string
+		ID         string
+		LockWait   time.Duration
+		Read       time.Duration
+		Decompress time.Duration
+		Compress   time.Duration
+		Write      time.Duration
+		Fsync      time.Duration
+		Total      time.Duration
+	}
+
+	// PhaseTimings - percentiles of a phase over the most recent
+	// operations which went through it
+	PhaseTimings struct {
+		Count uint64        `json:"count"`
+		P50   time.Duration `json:"p50"`
+		P95   time.Duration `json:"p95"`
+		P99   time.Duration `json:"p99"`
+	}
+
+	timingPhase int
+
+	// _timingKey - context key of the OpTimings WithTimings asks for
+	_timingKey struct{}
+
+	// _timings - per phase samples of a database with DetailedTimings,
+	// nil when disabled
+	_timings struct {
+		mu      sync.Mutex
+		samples [phaseCount]_samples
+	}
+
+	// _samples - ring of the most recent durations of a phase
+	_samples struct {
+		count uint64
+		ring  []time.Duration
+	}
+)
+
+const (
+	phaseLockWait timingPhase = iota
+	phaseRead
+	phaseDecompress
+	phaseCompress
+	phaseWrite
+	phaseFsync
+	phaseTotal
+	phaseCount
+)
+
+// phaseNames - Stats.Timings keys
+var phaseNames = [phaseCount]string{"lock_wait", "read", "decompress", "compress", "write", "fsync", "total"}
+
+// WithTimings - a ctx which collects the timings of the Get or Create it
+// is handed to, read back with GetTimings; needs Options.DetailedTimings
+func WithTimings(ctx context.Context) context.Context {
+	return context.WithValue(ctx, _timingKey{}, &OpTimings{})
+}
+
+// GetTimings - the timings collected by the operation ctx, made by
+// WithTimings, was handed to; false when ctx collects none
+func GetTimings(ctx context.Context) (OpTimings, bool) {
+	t, ok := ctx.Value(_timingKey{}).(*OpTimings)
+	if !ok || t.Op == "" {
+		return OpTimings{}, false
+	}
+	return *t, true
+}
+
+// startTimings - a timings record when detailed timings are enabled, the
+// nil result turns every later call into a no-op
+func (c *_collection) startTimings(ctx context.Context, op, key string) *OpTimings {
+	if c.timings == nil {
+		return nil
+	}
+	t, ok := ctx.Value(_timingKey{}).(*OpTimings)
+	if !ok {
+		t = &OpTimings{}
+	}
+	*t = OpTimings{Op: op, Collection: c.name, ID: key}
+	return t
+}
+
+// begin - the start of a phase
+func (t *OpTimings) begin() time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// end - attributes the time since start to phase
+func (t *OpTimings) end(phase timingPhase, start time.Time) {
+	if t == nil {
+		return
+	}
+	d := time.Since(start)
+	switch phase {
+	case phaseLockWait:
+		t.LockWait += d
+	case phaseRead:
+		t.Read += d
+	case phaseDecompress:
+		t.Decompress += d
+	case phaseCompress:
+		t.Compress += d
+	case phaseWrite:
+		t.Write += d
+	case phaseFsync:
+		// the sync runs inside the write phase, it is moved out of Write
+		// once that ends
+		t.Fsync += d
+		t.Write -= d
+	case phaseTotal:
+		t.Total += d
+	}
+}
+
+// report - hands finished timings to Stats and the OnOperation callback
+func (c *_collection) report(t *OpTimings, start time.Time) {
+	if t == nil {
+		return
+	}
+	t.end(phaseTotal, start)
+	c.timings.observe(t)
+	if c.onOperation == nil {
+		return
+	}
+	err := c.callbacks.guard("OnOperation", func() error {
+		c.onOperation(*t)
+		return nil
+	})
+	if err != nil {
+		c.logger.Error("timings hook failed", zap.Error(err))
+	}
+}
+
+// observe - samples the phases an operation went through
+func (ts *_timings) observe(t *OpTimings) {
+	if ts == nil {
+		return
+	}
+	phases := [phaseCount]time.Duration{t.LockWait, t.Read, t.Decompress, t.Compress, t.Write, t.Fsync, t.Total}
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	for phase, d := range phases {
+		if d <= 0 && timingPhase(phase) != phaseTotal {
+			continue
+		}
+		s := &ts.samples[phase]
+		if len(s.ring) < timingSamples {
+			s.ring = append(s.ring, d)
+		} else {
+			s.ring[s.count%timingSamples] = d
+		}
+		s.count++
+	}
+}
+
+// stats - percentiles by phase name of the phases seen so far
+func (ts *_timings) stats() map[string]PhaseTimings {
+	if ts == nil {
+		return nil
+	}
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	stats := make(map[string]PhaseTimings)
+	for phase, s := range ts.samples {
+		if s.count == 0 {
+			continue
+		}
+		sorted := append([]time.Duration(nil), s.ring...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		stats[phaseNames[phase]] = PhaseTimings{
+			Count: s.count,
+			P50:   percentile(sorted, 50),
+			P95:   percentile(sorted, 95),
+			P99:   percentile(sorted, 99),
+		}
+	}
+	return stats
+}
+
+// percentile - nearest rank percentile of sorted durations
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (len(sorted)*p + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
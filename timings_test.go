@@ -0,0 +1,129 @@
+package simplejsondb_test
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestDetailedTimings(t *testing.T) {
+	var mu sync.Mutex
+	var timings []simplejsondb.OpTimings
+	_, collection := dbtest.NewDB(t, &simplejsondb.Options{
+		DetailedTimings: true,
+		OnOperation: func(tm simplejsondb.OpTimings) {
+			mu.Lock()
+			timings = append(timings, tm)
+			mu.Unlock()
+		},
+	})
+	c := collection("collection1")
+
+	// a slow filesystem shows up in the write phase only
+	restore := simplejsondb.SetWriteFile(func(name string, data []byte, perm os.FileMode) error {
+		time.Sleep(30 * time.Millisecond)
+		return os.WriteFile(name, data, perm)
+	})
+	err := c.Create("key1", []byte(`{"key": 1}`), simplejsondb.CreateOptions{UseGzip: true})
+	restore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tm := timings[0]
+	if tm.Op != "create" || tm.Collection != "collection1" || tm.ID != "key1" {
+		t.Error("Test failed - ", tm)
+	}
+	if tm.Write < 30*time.Millisecond || tm.LockWait > 20*time.Millisecond || tm.Total < tm.Write+tm.Compress {
+		t.Error("Test failed - write attribution", tm)
+	}
+
+	// a held record lock shows up as lock wait
+	locked := make(chan bool)
+	go func() {
+		_, _ = c.UpdateIf("key1", func([]byte) (bool, error) {
+			locked <- true
+			time.Sleep(30 * time.Millisecond)
+			return false, nil
+		}, nil)
+	}()
+	<-locked
+	data, err := c.Get("key1")
+	if err != nil || string(data) != `{"key": 1}` {
+		t.Error("Test failed - ", string(data), err)
+	}
+	mu.Lock()
+	tm = timings[len(timings)-1]
+	mu.Unlock()
+	if tm.Op != "get" || tm.LockWait < 20*time.Millisecond || tm.Write != 0 || tm.Total < tm.LockWait+tm.Read+tm.Decompress {
+		t.Error("Test failed - lock wait attribution", tm)
+	}
+}
+
+func TestDetailedTimingsDisabled(t *testing.T) {
+	called := false
+	_, collection := dbtest.NewDB(t, &simplejsondb.Options{
+		OnOperation: func(simplejsondb.OpTimings) { called = true },
+	})
+	c := collection("collection1")
+	_ = c.Create("key1", []byte(`{}`))
+	_, _ = c.Get("key1")
+	if called {
+		t.Error("Test failed - timings reported while disabled")
+	}
+}
+
+func TestTimingsContext(t *testing.T) {
+	db, collection := dbtest.NewDB(t, &simplejsondb.Options{DetailedTimings: true})
+	c := collection("collection1")
+	ctx := simplejsondb.WithTimings(context.Background())
+	if _, ok := simplejsondb.GetTimings(ctx); ok {
+		t.Error("Test failed - timings before any operation")
+	}
+	if err := c.CreateCtx(ctx, "key1", []byte(`{"key": 1}`)); err != nil {
+		t.Fatal(err)
+	}
+	tm, ok := simplejsondb.GetTimings(ctx)
+	if !ok || tm.Op != "create" || tm.ID != "key1" {
+		t.Error("Test failed - ", tm, ok)
+	}
+	if tm.Fsync <= 0 || tm.Write < 0 || tm.Total < tm.Write+tm.Fsync {
+		t.Error("Test failed - fsync attribution", tm)
+	}
+	if _, err := c.GetCtx(ctx, "key1"); err != nil {
+		t.Fatal(err)
+	}
+	if tm, _ = simplejsondb.GetTimings(ctx); tm.Op != "get" || tm.Read <= 0 {
+		t.Error("Test failed - ", tm)
+	}
+	if _, ok := simplejsondb.GetTimings(context.Background()); ok {
+		t.Error("Test failed - timings without WithTimings")
+	}
+
+	stats := db.Stats().Timings
+	if stats["total"].Count != 2 || stats["fsync"].Count != 1 || stats["read"].Count != 1 {
+		t.Error("Test failed - ", stats)
+	}
+	if p := stats["total"]; p.P50 > p.P95 || p.P95 > p.P99 || p.P99 <= 0 {
+		t.Error("Test failed - ", p)
+	}
+}
+
+func TestTimingsContextDisabled(t *testing.T) {
+	db, collection := dbtest.NewDB(t, nil)
+	c := collection("collection1")
+	ctx := simplejsondb.WithTimings(context.Background())
+	if err := c.CreateCtx(ctx, "key1", []byte(`{"key": 1}`)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := simplejsondb.GetTimings(ctx); ok {
+		t.Error("Test failed - timings without DetailedTimings")
+	}
+	if db.Stats().Timings != nil {
+		t.Error("Test failed - ", db.Stats().Timings)
+	}
+}
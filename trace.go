@@ -0,0 +1,58 @@
+package simplejsondb
+
+import (
+	"context"
+)
+
+// The package carries no tracing dependency. An OpenTelemetry
+// trace.Tracer fits Tracer in a few lines: Start calls tracer.Start with
+// the attributes, End calls span.RecordError and span.SetStatus on
+// failure, sets the size attribute and ends the span.
+
+type (
+	// Tracer - starts a span around a collection operation, its context is
+	// the parent of the spans started while the operation runs
+	Tracer interface {
+		Start(ctx context.Context, name string, attrs SpanAttributes) (context.Context, Span)
+	}
+
+	// Span - a traced operation in progress
+	Span interface {
+		// End - finishes the span, size is the payload read or written in
+		// bytes and err the failure of the operation
+		End(size int, err error)
+	}
+
+	// SpanAttributes - what a span is about, ID is empty for GetAll and
+	// Gzip is only set for Create
+	SpanAttributes struct {
+		Collection string
+		ID         string
+		Gzip       bool
+	}
+)
+
+// Span names, one per traced operation
+const (
+	SpanGet    = "simplejsondb.Get"
+	SpanCreate = "simplejsondb.Create"
+	SpanDelete = "simplejsondb.Delete"
+	SpanGetAll = "simplejsondb.GetAll"
+)
+
+// startSpan - a span under ctx when a Tracer is set, nil otherwise so an
+// untraced call only pays for the check
+func (c *_collection) startSpan(ctx context.Context, name, key string, gzip bool) (context.Context, Span) {
+	if c.tracer == nil {
+		return ctx, nil
+	}
+	return c.tracer.Start(ctx, name, SpanAttributes{Collection: c.name, ID: key, Gzip: gzip})
+}
+
+// endSpan - finishes a span from startSpan
+func endSpan(span Span, size int, err error) {
+	if span == nil {
+		return
+	}
+	span.End(size, err)
+}
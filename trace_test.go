@@ -0,0 +1,82 @@
+package simplejsondb_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+type spanKey struct{}
+
+type span struct {
+	name   string
+	parent string
+	attrs  simplejsondb.SpanAttributes
+	size   int
+	err    error
+}
+
+type tracer struct {
+	mu    sync.Mutex
+	spans []*span
+}
+
+func (t *tracer) Start(ctx context.Context, name string, attrs simplejsondb.SpanAttributes) (context.Context, simplejsondb.Span) {
+	s := &span{name: name, attrs: attrs}
+	if parent, ok := ctx.Value(spanKey{}).(*span); ok {
+		s.parent = parent.name
+	}
+	t.mu.Lock()
+	t.spans = append(t.spans, s)
+	t.mu.Unlock()
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+func (s *span) End(size int, err error) {
+	s.size, s.err = size, err
+}
+
+func TestTracer(t *testing.T) {
+	tr := &tracer{}
+	_, collection := dbtest.NewDB(t, &simplejsondb.Options{Tracer: tr})
+	c := collection("users")
+
+	root := &span{name: "request"}
+	ctx := context.WithValue(context.Background(), spanKey{}, root)
+	if err := c.CreateCtx(ctx, "u1", []byte(`{"a": 1}`), simplejsondb.CreateOptions{UseGzip: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetAllCtx(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Delete("u1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get("u1"); !errors.Is(err, simplejsondb.ErrRecordNotFound) {
+		t.Fatal(err)
+	}
+
+	want := []span{
+		{name: simplejsondb.SpanCreate, parent: "request", attrs: simplejsondb.SpanAttributes{Collection: "users", ID: "u1", Gzip: true}, size: 8},
+		{name: simplejsondb.SpanGetAll, parent: "request", attrs: simplejsondb.SpanAttributes{Collection: "users"}, size: 8},
+		{name: simplejsondb.SpanGet, parent: simplejsondb.SpanGetAll, attrs: simplejsondb.SpanAttributes{Collection: "users", ID: "u1"}, size: 8},
+		{name: simplejsondb.SpanDelete, attrs: simplejsondb.SpanAttributes{Collection: "users", ID: "u1"}},
+		{name: simplejsondb.SpanGet, attrs: simplejsondb.SpanAttributes{Collection: "users", ID: "u1"}},
+	}
+	if len(tr.spans) != len(want) {
+		t.Fatal("Test failed - ", len(tr.spans))
+	}
+	for i, w := range want {
+		s := tr.spans[i]
+		if s.name != w.name || s.parent != w.parent || s.attrs != w.attrs || s.size != w.size {
+			t.Error("Test failed - ", i, *s)
+		}
+	}
+	if last := tr.spans[len(tr.spans)-1]; !errors.Is(last.err, simplejsondb.ErrRecordNotFound) {
+		t.Error("Test failed - error not recorded", last.err)
+	}
+}
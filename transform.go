@@ -0,0 +1,196 @@
+package simplejsondb
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"sync"
+)
+
+// errDryRunChange - internal signal that a dry run would change a record
+var errDryRunChange = errors.New("dry run change")
+
+type (
+	// TransformOptions - TransformAll configuration
+	TransformOptions struct {
+		// DryRun - run fn on every record without writing anything
+		DryRun bool
+		// FailFast - stop at the first error instead of collecting it
+		FailFast bool
+		// Parallelism - number of records transformed concurrently,
+		// defaults to 1
+		Parallelism int
+		// Progress - called after each record with the processed count
+		Progress func(done, total int)
+	}
+
+	// TransformReport - outcome of a TransformAll run
+	TransformReport struct {
+		Version int              `json:"version"`
+		Changed int              `json:"changed"`
+		Skipped int              `json:"skipped"`
+		Failed  int              `json:"failed"`
+		Errors  map[string]error `json:"errors,omitempty"`
+	}
+)
+
+// MarshalJSON - renders the collected errors as messages
+func (r TransformReport) MarshalJSON() ([]byte, error) {
+	type report TransformReport
+	return json.Marshal(struct {
+		report
+		Errors map[string]string `json:"errors,omitempty"`
+	}{report(r), errorMessages(r.Errors)})
+}
+
+// TransformAll - rewrites every record through fn
+//
+// Each record is re-read and rewritten under its exclusive lock so
+// concurrent writers are never lost. Records for which fn returns skip or
+// unchanged data are left untouched.
+func (c *_collection) TransformAll(fn func(id string, data []byte) ([]byte, bool, error), options ...TransformOptions) (TransformReport, error) {
+	return transformAll(c, c.callbacks, fn, options...)
+}
+
+// TransformAll - rewrites every visible record through fn into the overlay
+func (c *_overlayCollection) TransformAll(fn func(id string, data []byte) ([]byte, bool, error), options ...TransformOptions) (TransformReport, error) {
+	return transformAll(c, c.upper.callbacks, fn, options...)
+}
+
+// transformAll - a panic of fn or Progress in a worker stops the run and is
+// raised again on the calling goroutine once every worker returned, so the
+// record locks released by deferred calls are the only state left behind
+func transformAll(c _layer, g *_callbacks, fn func(id string, data []byte) ([]byte, bool, error), options ...TransformOptions) (report TransformReport, err error) {
+	report.Version = ReportSchemaVersion
+	opts := TransformOptions{}
+	if options != nil {
+		opts = options[0]
+	}
+	if opts.Parallelism <= 0 {
+		opts.Parallelism = 1
+	}
+	keys, err := c.keys()
+	if err != nil {
+		return report, err
+	}
+
+	var (
+		mu         sync.Mutex
+		progressMu sync.Mutex
+		wg         sync.WaitGroup
+		done       int
+		first      error
+		hookErr    error
+		panicked   any
+	)
+	step := func(key string) {
+		defer func() {
+			if r := recover(); r != nil {
+				mu.Lock()
+				if panicked == nil {
+					panicked = r
+				}
+				mu.Unlock()
+			}
+		}()
+		changed, err := transformOne(c, key, fn, opts.DryRun)
+
+		mu.Lock()
+		switch {
+		case err != nil:
+			report.Failed++
+			if report.Errors == nil {
+				report.Errors = make(map[string]error)
+			}
+			report.Errors[key] = err
+			if first == nil {
+				first = err
+			}
+		case changed:
+			report.Changed++
+		default:
+			report.Skipped++
+		}
+		done++
+		n := done
+		mu.Unlock()
+
+		if opts.Progress != nil {
+			progressMu.Lock()
+			defer progressMu.Unlock()
+			err = g.guard("Progress", func() error {
+				opts.Progress(n, len(keys))
+				return nil
+			})
+			if err != nil {
+				mu.Lock()
+				if hookErr == nil {
+					hookErr = err
+				}
+				mu.Unlock()
+			}
+		}
+	}
+	queue := make(chan string)
+	for i := 0; i < opts.Parallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for key := range queue {
+				step(key)
+			}
+		}()
+	}
+
+	for _, key := range keys {
+		mu.Lock()
+		stop := panicked != nil || hookErr != nil || (opts.FailFast && first != nil)
+		mu.Unlock()
+		if stop {
+			break
+		}
+		queue <- key
+	}
+	close(queue)
+	wg.Wait()
+
+	if panicked != nil {
+		panic(panicked)
+	}
+	if hookErr != nil {
+		return report, hookErr
+	}
+	if opts.FailFast && first != nil {
+		return report, first
+	}
+	return report, nil
+}
+
+// transformOne - applies fn to a single record under its exclusive lock
+func transformOne(c _layer, key string, fn func(id string, data []byte) ([]byte, bool, error), dryRun bool) (changed bool, err error) {
+	var next []byte
+	applied, err := c.UpdateIf(key, func(current []byte) (bool, error) {
+		if current == nil {
+			// deleted since listing
+			return false, nil
+		}
+		data, skip, err := fn(key, current)
+		if err != nil {
+			return false, err
+		}
+		if skip || bytes.Equal(data, current) {
+			return false, nil
+		}
+		if dryRun {
+			return false, errDryRunChange
+		}
+		next = data
+		return true, nil
+	}, func([]byte) ([]byte, error) {
+		return next, nil
+	})
+	if err == errDryRunChange {
+		return true, nil
+	}
+	return applied, err
+}
@@ -0,0 +1,131 @@
+package simplejsondb_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestTransformAll(t *testing.T) {
+	_, collection := dbtest.NewDB(t, nil)
+	c := collection("users")
+	_ = c.Create("u1", []byte(`{"email": "A@X.COM"}`))
+	_ = c.Create("u2", []byte(`{"email": "b@x.com"}`), simplejsondb.CreateOptions{UseGzip: true})
+	_ = c.Create("u3", []byte(`{"email": "C@X.COM"}`))
+	_ = c.Create("admin", []byte(`{"email": "ROOT@X.COM"}`))
+
+	lower := func(id string, data []byte) ([]byte, bool, error) {
+		if id == "admin" {
+			return nil, true, nil
+		}
+		return bytes.ToLower(data), false, nil
+	}
+
+	progress := 0
+	report, err := c.TransformAll(lower, simplejsondb.TransformOptions{DryRun: true, Progress: func(done, total int) {
+		progress = done
+		if total != 4 {
+			t.Error("Test failed - total", total)
+		}
+	}})
+	if err != nil || report.Changed != 2 || report.Skipped != 2 || progress != 4 {
+		t.Error("Test failed - dry run", report, err, progress)
+	}
+	data, _ := c.Get("u1")
+	if string(data) != `{"email": "A@X.COM"}` {
+		t.Error("Test failed - dry run wrote", string(data))
+	}
+
+	report, err = c.TransformAll(lower, simplejsondb.TransformOptions{Parallelism: 3})
+	if err != nil || report.Changed != 2 || report.Skipped != 2 || report.Failed != 0 {
+		t.Error("Test failed - ", report, err)
+	}
+	if report.Version != simplejsondb.ReportSchemaVersion {
+		t.Error("Test failed - report version", report.Version)
+	}
+	for id, want := range map[string]string{"u1": "a@x.com", "u2": "b@x.com", "u3": "c@x.com", "admin": "ROOT@X.COM"} {
+		data, _ := c.Get(id)
+		if string(data) != fmt.Sprintf(`{"email": %q}`, want) {
+			t.Error("Test failed - ", id, string(data))
+		}
+	}
+
+	failure := errors.New("bad record")
+	failing := func(id string, data []byte) ([]byte, bool, error) {
+		if id == "u2" {
+			return nil, false, failure
+		}
+		return append(data, ' '), false, nil
+	}
+	report, err = c.TransformAll(failing)
+	if err != nil || report.Failed != 1 || report.Changed != 3 || !errors.Is(report.Errors["u2"], failure) {
+		t.Error("Test failed - collected errors", report, err)
+	}
+	_, err = c.TransformAll(failing, simplejsondb.TransformOptions{FailFast: true})
+	if !errors.Is(err, failure) {
+		t.Error("Test failed - fail fast", err)
+	}
+}
+
+func TestTransformAllConcurrentWriter(t *testing.T) {
+	_, collection := dbtest.NewDB(t, nil)
+	c := collection("counters")
+	type counter struct {
+		N int  `json:"n"`
+		T bool `json:"t"`
+	}
+	for i := 0; i < 10; i++ {
+		_ = c.Create(fmt.Sprint("c", i), []byte(`{"n": 0, "t": false}`))
+	}
+
+	mark := func(id string, data []byte) ([]byte, bool, error) {
+		var v counter
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, false, err
+		}
+		v.T = true
+		out, err := json.Marshal(v)
+		return out, false, err
+	}
+	increment := func(current []byte) ([]byte, error) {
+		var v counter
+		if err := json.Unmarshal(current, &v); err != nil {
+			return nil, err
+		}
+		v.N++
+		return json.Marshal(v)
+	}
+	always := func([]byte) (bool, error) { return true, nil }
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for n := 0; n < 20; n++ {
+			for i := 0; i < 10; i++ {
+				if _, err := c.UpdateIf(fmt.Sprint("c", i), always, increment); err != nil {
+					t.Error(err)
+				}
+			}
+		}
+	}()
+	_, err := c.TransformAll(mark, simplejsondb.TransformOptions{Parallelism: 4})
+	if err != nil {
+		t.Error(err)
+	}
+	wg.Wait()
+
+	for i := 0; i < 10; i++ {
+		var v counter
+		data, _ := c.Get(fmt.Sprint("c", i))
+		if err = json.Unmarshal(data, &v); err != nil || v.N != 20 || !v.T {
+			t.Error("Test failed - lost update", string(data), err)
+		}
+	}
+}
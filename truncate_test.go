@@ -0,0 +1,63 @@
+package simplejsondb_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestTruncate(t *testing.T) {
+	root := t.TempDir()
+	_, collection := dbtest.Open(t, root, &simplejsondb.Options{HashLongIDs: true, MaxNameLength: 80})
+	c := collection("emptied")
+	for i := 0; i < 10; i++ {
+		err := c.Create(fmt.Sprint("r", i), []byte(`{}`), simplejsondb.CreateOptions{UseGzip: i%2 == 0})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	dbtest.Seed(t, c, map[string][]byte{strings.Repeat("long", 20): []byte(`{}`)})
+	dir := filepath.Join(root, "emptied")
+	_ = os.WriteFile(filepath.Join(dir, ".tmp-stale"), []byte("x"), 0644)
+	_ = os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("x"), 0644)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				_, err := c.Get(fmt.Sprint("r", j))
+				if err != nil && !errors.Is(err, os.ErrNotExist) {
+					t.Error("Test failed - ", err)
+				}
+			}
+		}()
+	}
+	if err := c.Truncate(); err != nil {
+		t.Error("Test failed - ", err)
+	}
+	wg.Wait()
+
+	if n, err := c.LenPrefix(""); err != nil || n != 0 {
+		t.Error("Test failed - ", n, err)
+	}
+	if _, err := c.Get("r1"); !errors.Is(err, os.ErrNotExist) {
+		t.Error("Test failed - ", err)
+	}
+	// the layout descriptor belongs to the collection, not its records
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 || entries[0].Name() != ".layout.json" || entries[1].Name() != "notes.txt" {
+		t.Error("Test failed - ", entries)
+	}
+	if err := c.Create("r1", []byte(`{}`)); err != nil {
+		t.Error("Test failed - collection unusable after truncate", err)
+	}
+}
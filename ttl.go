@@ -0,0 +1,193 @@
+package simplejsondb
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// expiryDir - reserved directory holding the expiry time of records
+// written with a TTL, one file per record file name
+var expiryDir string = ".expiry"
+
+const featureExpiry = "expiry"
+
+// _expiries - expiry times of the records of a collection directory,
+// shared by its handles and loaded from expiryDir when first opened
+//
+// Expiry written by another process is seen once the database reopens.
+type _expiries struct {
+	mu sync.RWMutex
+	// at - expiry by record lock path, records without a TTL are absent
+	at map[string]time.Time
+	// required - the expiry feature is recorded in the layout
+	required bool
+}
+
+// expiries - the shared expiry times of a collection directory
+func (db *_db) expiries(c *_collection) *_expiries {
+	db.indexMu.Lock()
+	defer db.indexMu.Unlock()
+	e, ok := db.expiry[c.path]
+	if !ok {
+		e = loadExpiries(c)
+		db.expiry[c.path] = e
+	}
+	return e
+}
+
+// loadExpiries - reads expiryDir, unreadable entries are logged and
+// treated as records without a TTL
+func loadExpiries(c *_collection) *_expiries {
+	e := &_expiries{at: make(map[string]time.Time)}
+	entries, err := os.ReadDir(filepath.Join(c.path, expiryDir))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			c.logger.Error("unable to load record expiry", zap.Error(err))
+		}
+		return e
+	}
+	e.required = true
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, tempPrefix) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(c.path, expiryDir, name))
+		if err != nil {
+			c.logger.Error("unable to load record expiry", zap.String("file", name), zap.Error(err))
+			continue
+		}
+		nanos, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+		if err != nil {
+			c.logger.Error("invalid record expiry", zap.String("file", name), zap.Error(err))
+			continue
+		}
+		e.at[c.lockPath(c.logicalKey(name))] = time.Unix(0, nanos)
+	}
+	return e
+}
+
+// expiresAt - expiry of a record written now with ttl, falling back to
+// Options.TTL; zero when the record does not expire
+func (c *_collection) expiresAt(ttl time.Duration) time.Time {
+	if ttl == 0 {
+		ttl = c.ttl
+	}
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(ttl)
+}
+
+// expiresOf - expiry of the record at lock path, zero when none
+func (e *_expiries) expiresOf(lockPath string) time.Time {
+	if e == nil {
+		return time.Time{}
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.at[lockPath]
+}
+
+// expired - whether the record at lock path is past its expiry
+func (e *_expiries) expired(lockPath string) bool {
+	at := e.expiresOf(lockPath)
+	return !at.IsZero() && !time.Now().Before(at)
+}
+
+// empty - whether no record of the collection has a TTL
+func (e *_expiries) empty() bool {
+	if e == nil {
+		return true
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return len(e.at) == 0
+}
+
+// reset - forgets every expiry, used once the records are gone
+func (e *_expiries) reset() {
+	e.mu.Lock()
+	e.at = make(map[string]time.Time)
+	e.mu.Unlock()
+}
+
+// setExpiry - records when a record expires, a zero time clears it; the
+// caller holds the lock
+func (c *_collection) setExpiry(key string, at time.Time) error {
+	e := c.expiry
+	if e == nil {
+		return nil
+	}
+	lockPath := c.lockPath(key)
+	filename := filepath.Join(c.path, expiryDir, c.fileKey(key))
+	if at.IsZero() {
+		if e.expiresOf(lockPath).IsZero() {
+			return nil
+		}
+		err := os.Remove(filename)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		e.mu.Lock()
+		delete(e.at, lockPath)
+		e.mu.Unlock()
+		return nil
+	}
+
+	e.mu.RLock()
+	required := e.required
+	e.mu.RUnlock()
+	if !required {
+		// older versions would serve expired records as live
+		err := requireLayout(c.path, featureExpiry)
+		if err != nil {
+			return err
+		}
+		e.mu.Lock()
+		e.required = true
+		e.mu.Unlock()
+	}
+	err := os.MkdirAll(filepath.Dir(filename), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	err = writeAtomic(filename, []byte(strconv.FormatInt(at.UnixNano(), 10)), 0644)
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	e.at[lockPath] = at
+	e.mu.Unlock()
+	return nil
+}
+
+// reapExpired - deletes a record found expired by a read when
+// Options.DeleteExpired is set, unless a write renewed it meanwhile
+func (c *_collection) reapExpired(key string) {
+	if !c.deleteExpired {
+		return
+	}
+	unlock := c.lock(key)
+	defer unlock()
+	if !c.expiry.expired(c.lockPath(key)) {
+		return
+	}
+	err := c.remove(key)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		c.logger.Error("unable to delete expired record", zap.String("id", key), zap.Error(err))
+	}
+}
+
+// expiredError - what reads of an expired record fail with, matching a
+// missing record
+func expiredError(filename string) error {
+	return &os.PathError{Op: "open", Path: filename, Err: os.ErrNotExist}
+}
@@ -0,0 +1,92 @@
+package simplejsondb_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestRecordTTL(t *testing.T) {
+	root := t.TempDir()
+	_, collection := dbtest.Open(t, root, nil)
+	c := collection("sessions")
+	if err := c.Create("short", []byte(`{"a":1}`), simplejsondb.CreateOptions{TTL: 20 * time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Create("forever", []byte(`{"b":2}`)); err != nil {
+		t.Fatal(err)
+	}
+	dbtest.RequireRecord(t, c, "short", []byte(`{"a":1}`))
+	time.Sleep(30 * time.Millisecond)
+
+	if _, err := c.Get("short"); !errors.Is(err, os.ErrNotExist) {
+		t.Error("Test failed - expired record readable", err)
+	}
+	if keys := c.Keys(); len(keys) != 1 || keys[0] != "forever" {
+		t.Error("Test failed - expired record listed", keys)
+	}
+	if n, err := c.LenPrefix(""); err != nil || n != 1 {
+		t.Error("Test failed - expired record counted", n, err)
+	}
+	if all := c.GetAll(); len(all) != 1 {
+		t.Error("Test failed - expired record returned", len(all))
+	}
+	// without DeleteExpired the file stays until overwritten
+	if _, err := os.Stat(filepath.Join(root, "sessions", "short.json")); err != nil {
+		t.Error("Test failed - expired record deleted", err)
+	}
+
+	// an update sees the expired record as missing and renews it
+	_, err := c.UpdateIf("short", func(current []byte) (bool, error) {
+		if current != nil {
+			t.Error("Test failed - expired record passed to condition", string(current))
+		}
+		return true, nil
+	}, func([]byte) ([]byte, error) { return []byte(`{"a":2}`), nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	dbtest.RequireRecord(t, c, "short", []byte(`{"a":2}`))
+}
+
+func TestDefaultTTL(t *testing.T) {
+	root := t.TempDir()
+	opts := &simplejsondb.Options{TTL: 20 * time.Millisecond, DeleteExpired: true}
+	db, collection := dbtest.Open(t, root, opts)
+	c := collection("cache")
+	dbtest.Seed(t, c, map[string][]byte{"a": []byte(`1`)})
+	if err := c.Create("b", []byte(`2`), simplejsondb.CreateOptions{TTL: time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(30 * time.Millisecond)
+
+	if _, err := c.Get("a"); !errors.Is(err, os.ErrNotExist) {
+		t.Error("Test failed - expired record readable", err)
+	}
+	// the read deleted it
+	if _, err := os.Stat(filepath.Join(root, "cache", "a.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Error("Test failed - expired record kept", err)
+	}
+	dbtest.RequireRecord(t, c, "b", []byte(`2`))
+
+	// expiry survives reopening
+	_ = db.Close()
+	_, collection = dbtest.Open(t, root, nil)
+	if keys := collection("cache").Keys(); len(keys) != 1 || keys[0] != "b" {
+		t.Error("Test failed - ", keys)
+	}
+
+	// Truncate forgets the expiry with the records
+	c = collection("cache")
+	if err := c.Truncate(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "cache", ".expiry")); !errors.Is(err, os.ErrNotExist) {
+		t.Error("Test failed - expiry kept after truncate", err)
+	}
+}
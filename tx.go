@@ -0,0 +1,462 @@
+package simplejsondb
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrTxDone - the transaction was already committed or rolled back
+var ErrTxDone = errors.New("transaction already committed or rolled back")
+
+const (
+	// journalDir - journals of the transactions of a database
+	journalDir = ".journal"
+	// overlayJournalDir - journals of overlay transactions, kept apart
+	// as they replay through the overlay rather than its upper layer
+	overlayJournalDir = ".journal-overlay"
+	journalExt        = ".jsonl"
+	// failedJournalExt - appended to committed journals New could not
+	// replay, kept for inspection but never replayed again
+	failedJournalExt = ".failed"
+
+	opCreate = "create"
+	opDelete = "delete"
+	opCommit = "commit"
+)
+
+// Tx - writes to several records applied together by Commit
+//
+// Create and Delete are staged in a journal file, reads do not see them
+// before Commit. Commit checks the collections have room for the writes
+// under MaxRecords and QuotaBytes, marks the journal committed, applies it
+// and removes it; a process dying in between leaves the journal behind and
+// the next New replays it. A journal New fails to replay is logged and
+// renamed with a .failed suffix rather than failing New. Journals never
+// committed are discarded by New once an hour old, or right away when it
+// holds ExclusiveOwner, as younger ones may belong to a Tx another process
+// is still staging. A Tx is not safe for concurrent use.
+type Tx interface {
+	Create(collection, id string, data []byte, options ...CreateOptions) error
+	// Delete removes the record on Commit, a record already missing by
+	// then is no error
+	Delete(collection, id string) error
+	Commit() error
+	Rollback() error
+}
+
+// _journalOp - one line of a journal, Raw holds a record which is not
+// compact JSON so it is replayed byte for byte
+type _journalOp struct {
+	Op         string          `json:"op"`
+	Collection string          `json:"collection,omitempty"`
+	ID         string          `json:"id,omitempty"`
+	Data       json.RawMessage `json:"data,omitempty"`
+	Raw        []byte          `json:"_raw,omitempty"`
+	Gzip       bool            `json:"gzip,omitempty"`
+	TTL        time.Duration   `json:"ttl,omitempty"`
+}
+
+type _tx struct {
+	db      DB
+	mu      *sync.Mutex
+	logger  Logger
+	journal *os.File
+	ops     []_journalOp
+	done    bool
+}
+
+// Begin - starts a transaction journaled in the database directory
+func (db *_db) Begin() (Tx, error) {
+	if err := db.life.begin(); err != nil {
+		return nil, err
+	}
+	defer db.life.end()
+	return begin(db, &db.txMu, filepath.Join(db.path, journalDir), db.logger)
+}
+
+// Begin - starts a transaction applied through the overlay
+func (o *_overlay) Begin() (Tx, error) {
+	if err := o.upper.life.begin(); err != nil {
+		return nil, err
+	}
+	defer o.upper.life.end()
+	return begin(o, &o.txMu, filepath.Join(o.upper.path, overlayJournalDir), o.upper.logger)
+}
+
+func begin(db DB, mu *sync.Mutex, dir string, logger Logger) (Tx, error) {
+	if _, err := getOrCreateDir(dir); err != nil {
+		return nil, err
+	}
+	// named by start time so pending journals replay in order
+	f, err := os.CreateTemp(dir, fmt.Sprintf("%020d-*%s", time.Now().UnixNano(), journalExt))
+	if err != nil {
+		return nil, err
+	}
+	return &_tx{db: db, mu: mu, logger: logger, journal: f}, nil
+}
+
+// Create - stages a record write, the id and with ValidateJSON the data
+// are checked right away
+func (tx *_tx) Create(collection, id string, data []byte, options ...CreateOptions) error {
+	c, err := tx.collection(collection)
+	if err != nil {
+		return err
+	}
+	if err = c.checkID(id); err != nil {
+		return err
+	}
+	if err = c.validate(id, data); err != nil {
+		return err
+	}
+	op := _journalOp{Op: opCreate, Collection: collection, ID: id}
+	var compact bytes.Buffer
+	if json.Compact(&compact, data) == nil && bytes.Equal(compact.Bytes(), data) {
+		op.Data = data
+	} else {
+		op.Raw = data
+	}
+	if options != nil {
+		op.Gzip = options[0].UseGzip
+		op.TTL = options[0].TTL
+	}
+	return tx.stage(op)
+}
+
+// Delete - stages a record removal
+func (tx *_tx) Delete(collection, id string) error {
+	c, err := tx.collection(collection)
+	if err != nil {
+		return err
+	}
+	if err = c.checkID(id); err != nil {
+		return err
+	}
+	return tx.stage(_journalOp{Op: opDelete, Collection: collection, ID: id})
+}
+
+// collection - the collection an op is applied to, overlay ids and data
+// are checked by its upper layer
+func (tx *_tx) collection(name string) (*_collection, error) {
+	if tx.done {
+		return nil, ErrTxDone
+	}
+	return writable(tx.db, name)
+}
+
+// writable - the collection writes to name end up in, the upper layer of
+// an overlay
+func writable(db DB, name string) (*_collection, error) {
+	c, err := db.Collection(name)
+	if err != nil {
+		return nil, err
+	}
+	if o, ok := c.(*_overlayCollection); ok {
+		return o.upper, nil
+	}
+	return c.(*_collection), nil
+}
+
+// stage - appends op to the journal
+func (tx *_tx) stage(op _journalOp) error {
+	line, err := json.Marshal(op)
+	if err != nil {
+		return err
+	}
+	if _, err = tx.journal.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	tx.ops = append(tx.ops, op)
+	return nil
+}
+
+// Commit - makes the staged writes durable as one and applies them
+//
+// Once the commit line is synced the transaction is committed: a failure
+// applying it is returned but the journal stays, so the next New
+// completes it.
+func (tx *_tx) Commit() (err error) {
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.done = true
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if err = tx.check(); err == nil {
+		err = tx.stage(_journalOp{Op: opCommit})
+	}
+	if err == nil {
+		err = tx.journal.Sync()
+	}
+	if cerr := tx.journal.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		// a journal created by this Tx exists only once its entry is synced
+		err = syncDir(filepath.Dir(tx.journal.Name()))
+	}
+	if err != nil {
+		os.Remove(tx.journal.Name())
+		return err
+	}
+	return replay(tx.db, tx.journal.Name(), tx.ops)
+}
+
+// check - refuses a transaction its collections have no room for, so it
+// is not left half applied by ErrCollectionFull or ErrQuotaExceeded;
+// writers outside the transaction can still take the room meanwhile
+func (tx *_tx) check() error {
+	collections := make(map[string]*_collection)
+	final := make(map[string]map[string]_journalOp)
+	for _, op := range tx.ops {
+		c, ok := collections[op.Collection]
+		if !ok {
+			var err error
+			if c, err = writable(tx.db, op.Collection); err != nil {
+				return err
+			}
+			collections[op.Collection] = c
+			final[op.Collection] = make(map[string]_journalOp)
+		}
+		final[op.Collection][op.ID] = op
+	}
+	for name, c := range collections {
+		if err := c.room(final[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// room - checks the final op of every id a transaction touches fits the
+// MaxRecords and QuotaBytes of the collection
+func (c *_collection) room(ops map[string]_journalOp) error {
+	limit, quota := c.records.max(), c.usage.quota
+	if limit == 0 && quota == 0 {
+		return nil
+	}
+	var added, grown int64
+	for id, op := range ops {
+		_, _, err := c.resolve(id)
+		exists := err == nil
+		if op.Op == opDelete {
+			if exists {
+				added--
+				grown -= c.storedSize(id)
+			}
+			continue
+		}
+		if !exists {
+			added++
+		}
+		if quota > 0 {
+			size, err := c.encodedSize(op)
+			if err != nil {
+				return err
+			}
+			grown += size - c.storedSize(id)
+		}
+	}
+	if limit > 0 && added > 0 {
+		n, err := c.records.count(c)
+		if err != nil {
+			return err
+		}
+		if n+uint64(added) > limit {
+			return fmt.Errorf("%w: %s: %d records, %d more, limit %d", ErrCollectionFull, c.name, n, added, limit)
+		}
+	}
+	if quota > 0 && grown > 0 {
+		used, err := c.Usage()
+		if err != nil {
+			return err
+		}
+		if used+uint64(grown) > quota {
+			return fmt.Errorf("%w: %s: %d bytes used, %d more, quota %d", ErrQuotaExceeded, c.name, used, grown, quota)
+		}
+	}
+	return nil
+}
+
+// encodedSize - bytes the record of a journaled create takes on disk
+func (c *_collection) encodedSize(op _journalOp) (int64, error) {
+	data := []byte(op.Data)
+	if op.Raw != nil {
+		data = op.Raw
+	}
+	codec := c.codec
+	if op.Gzip {
+		codec = c.enc.wrap(GzipCodec)
+	}
+	stored, err := codec.Encode(data)
+	return int64(len(stored)), err
+}
+
+// Rollback - discards the staged writes
+func (tx *_tx) Rollback() error {
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.done = true
+	tx.journal.Close()
+	return os.Remove(tx.journal.Name())
+}
+
+// replay - applies the ops of a committed journal and removes it once
+// they are on disk
+func replay(db DB, journal string, ops []_journalOp) error {
+	for _, op := range ops {
+		if err := op.apply(db); err != nil {
+			return fmt.Errorf("%s %s/%s: %w", op.Op, op.Collection, op.ID, err)
+		}
+	}
+	if err := settle(db, ops); err != nil {
+		return err
+	}
+	if err := os.Remove(journal); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(journal))
+}
+
+// settle - syncs the records applied ops wrote and the directories of
+// their collections, NoFsync or not, so a crash never keeps the removal
+// of the journal but loses the writes it covered
+func settle(db DB, ops []_journalOp) error {
+	dirs := make(map[string]bool)
+	for _, op := range ops {
+		if op.Op == opCommit {
+			continue
+		}
+		c, err := writable(db, op.Collection)
+		if err != nil {
+			return err
+		}
+		if op.Op == opCreate {
+			for _, codec := range c.codecs {
+				if err = syncFile(c.getFullPath(op.ID, codec)); err != nil {
+					return err
+				}
+			}
+		}
+		dirs[c.path] = true
+	}
+	for dir := range dirs {
+		if err := syncDir(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// apply - performs a journaled op, replaying it again is harmless
+func (op _journalOp) apply(db DB) error {
+	if op.Op == opCommit {
+		return nil
+	}
+	c, err := db.Collection(op.Collection)
+	if err != nil {
+		return err
+	}
+	switch op.Op {
+	case opCreate:
+		data := []byte(op.Data)
+		if op.Raw != nil {
+			data = op.Raw
+		}
+		return c.Create(op.ID, data, CreateOptions{UseGzip: op.Gzip, TTL: op.TTL})
+	case opDelete:
+		err = c.Delete(op.ID)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	return fmt.Errorf("unknown journal op %q", op.Op)
+}
+
+// recoverJournals - replays the committed journals left in dir by a
+// process which died committing and discards the uncommitted ones left by
+// dead processes, all of them when exclusive
+func recoverJournals(db DB, dir string, exclusive bool, logger Logger) error {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), journalExt) {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		journal := filepath.Join(dir, name)
+		ops, committed, err := readJournal(journal)
+		if err == nil && !committed {
+			if !exclusive && !staleJournal(journal) {
+				// may be staged by a live Tx of another process
+				continue
+			}
+			logger.Warn("discarding uncommitted transaction", zap.String("journal", journal))
+			if err = os.Remove(journal); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+			continue
+		}
+		if err == nil {
+			logger.Warn("replaying committed transaction", zap.String("journal", journal), zap.Int("ops", len(ops)))
+			err = replay(db, journal, ops)
+		}
+		if err != nil {
+			logger.Error("unable to replay committed transaction", zap.String("journal", journal+failedJournalExt), zap.Error(err))
+			if err = os.Rename(journal, journal+failedJournalExt); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// staleJournal - the journal was last written longer ago than any live
+// Tx would take to stage it
+func staleJournal(journal string) bool {
+	info, err := os.Stat(journal)
+	return err == nil && time.Since(info.ModTime()) >= orphanAge
+}
+
+// readJournal - decodes a journal, committed once its commit line made it
+// to disk; a torn last line means the commit did not
+func readJournal(journal string) (ops []_journalOp, committed bool, err error) {
+	f, err := os.Open(journal)
+	if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+	dec := json.NewDecoder(f)
+	for {
+		var op _journalOp
+		if err = dec.Decode(&op); err != nil {
+			// io.EOF or a line cut short by the crash
+			if err != io.EOF && committed {
+				return nil, false, fmt.Errorf("%s: %w", journal, err)
+			}
+			return ops, committed, nil
+		}
+		ops = append(ops, op)
+		committed = op.Op == opCommit
+	}
+}
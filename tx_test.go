@@ -0,0 +1,226 @@
+package simplejsondb_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestTx(t *testing.T) {
+	dir := t.TempDir()
+	db, collection := dbtest.Open(t, dir, nil)
+	users, index := collection("users"), collection("index")
+	dbtest.Seed(t, index, map[string][]byte{"stale": []byte(`{}`)})
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = tx.Create("users", "u1", []byte(`{"name": "alice"}`), simplejsondb.CreateOptions{UseGzip: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err = tx.Create("index", "alice", []byte(`"u1"`)); err != nil {
+		t.Fatal(err)
+	}
+	if err = tx.Delete("index", "stale"); err != nil {
+		t.Fatal(err)
+	}
+	if err = tx.Create("users", strings.Repeat("x", 300), []byte(`{}`)); !errors.Is(err, simplejsondb.ErrIDTooLong) {
+		t.Error("Test failed - ", err)
+	}
+	if _, err = users.Get("u1"); !errors.Is(err, os.ErrNotExist) {
+		t.Error("Test failed - staged write visible", err)
+	}
+	if err = tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	dbtest.RequireRecord(t, users, "u1", []byte(`{"name": "alice"}`))
+	dbtest.RequireRecord(t, index, "alice", []byte(`"u1"`))
+	if _, err = index.Get("stale"); !errors.Is(err, os.ErrNotExist) {
+		t.Error("Test failed - ", err)
+	}
+	if _, err = os.Stat(filepath.Join(dir, "users", "u1.json.gz")); err != nil {
+		t.Error("Test failed - ", err)
+	}
+	if entries, _ := os.ReadDir(filepath.Join(dir, ".journal")); len(entries) != 0 {
+		t.Error("Test failed - ", len(entries))
+	}
+	if err = tx.Commit(); !errors.Is(err, simplejsondb.ErrTxDone) {
+		t.Error("Test failed - ", err)
+	}
+
+	tx, _ = db.Begin()
+	_ = tx.Create("users", "u2", []byte(`{}`))
+	if err = tx.Rollback(); err != nil {
+		t.Error("Test failed - ", err)
+	}
+	if err = tx.Create("users", "u3", []byte(`{}`)); !errors.Is(err, simplejsondb.ErrTxDone) {
+		t.Error("Test failed - ", err)
+	}
+	if _, err = users.Get("u2"); !errors.Is(err, os.ErrNotExist) {
+		t.Error("Test failed - ", err)
+	}
+}
+
+func TestTxRecovery(t *testing.T) {
+	dir := t.TempDir()
+	journals := filepath.Join(dir, ".journal")
+	if err := os.MkdirAll(journals, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	committed := `{"op":"create","collection":"users","id":"u1","_raw":"eyAibiI6IDEgfQ=="}
+{"op":"create","collection":"index","id":"one","data":"u1"}
+{"op":"delete","collection":"index","id":"missing"}
+{"op":"commit"}
+`
+	uncommitted := `{"op":"create","collection":"users","id":"u2","data":{}}
+{"op":"comm`
+	if err := os.WriteFile(filepath.Join(journals, "1-a.jsonl"), []byte(committed), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(journals, "2-b.jsonl"), []byte(uncommitted), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// only an old uncommitted journal is known to be abandoned
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(filepath.Join(journals, "2-b.jsonl"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(journals, "3-c.jsonl"), []byte(uncommitted), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, collection := dbtest.Open(t, dir, nil)
+	dbtest.RequireRecord(t, collection("users"), "u1", []byte(`{ "n": 1 }`))
+	dbtest.RequireRecord(t, collection("index"), "one", []byte(`"u1"`))
+	if _, err := collection("users").Get("u2"); !errors.Is(err, os.ErrNotExist) {
+		t.Error("Test failed - ", err)
+	}
+	if entries, _ := os.ReadDir(journals); len(entries) != 1 || entries[0].Name() != "3-c.jsonl" {
+		t.Error("Test failed - ", entries)
+	}
+
+	// the owner knows no other process stages transactions
+	_, _ = dbtest.Open(t, dir, &simplejsondb.Options{ExclusiveOwner: true})
+	if entries, _ := os.ReadDir(journals); len(entries) != 0 {
+		t.Error("Test failed - ", entries)
+	}
+}
+
+func TestTxRecoveryFailure(t *testing.T) {
+	dir := t.TempDir()
+	journals := filepath.Join(dir, ".journal")
+	if err := os.MkdirAll(journals, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	committed := `{"op":"create","collection":"users","id":"a","data":{}}
+{"op":"create","collection":"users","id":"b","data":{}}
+{"op":"commit"}
+`
+	if err := os.WriteFile(filepath.Join(journals, "1-a.jsonl"), []byte(committed), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		_, collection := dbtest.Open(t, dir, &simplejsondb.Options{MaxRecords: 1})
+		dbtest.RequireRecord(t, collection("users"), "a", []byte(`{}`))
+	}
+	entries, _ := os.ReadDir(journals)
+	if len(entries) != 1 || entries[0].Name() != "1-a.jsonl.failed" {
+		t.Error("Test failed - ", entries)
+	}
+}
+
+func TestTxLimits(t *testing.T) {
+	dir := t.TempDir()
+	db, collection := dbtest.Open(t, dir, &simplejsondb.Options{MaxRecords: 2, QuotaBytes: 64})
+	users := collection("users")
+	dbtest.Seed(t, users, map[string][]byte{"u0": []byte(`{}`)})
+
+	tx, _ := db.Begin()
+	_ = tx.Create("users", "a", []byte(`{}`))
+	_ = tx.Create("users", "b", []byte(`{}`))
+	if err := tx.Commit(); !errors.Is(err, simplejsondb.ErrCollectionFull) {
+		t.Error("Test failed - ", err)
+	}
+	if keys := users.Keys(); len(keys) != 1 {
+		t.Error("Test failed - half applied", keys)
+	}
+
+	// a delete in the same transaction makes room
+	tx, _ = db.Begin()
+	_ = tx.Delete("users", "u0")
+	_ = tx.Create("users", "a", []byte(`{}`))
+	_ = tx.Create("users", "b", []byte(`{}`))
+	if err := tx.Commit(); err != nil {
+		t.Error("Test failed - ", err)
+	}
+
+	tx, _ = db.Begin()
+	_ = tx.Create("users", "a", []byte(`{"padding": "0123456789012345678901234567890123456789"}`))
+	_ = tx.Create("users", "b", []byte(`{"padding": "0123456789012345678901234567890123456789"}`))
+	if err := tx.Commit(); !errors.Is(err, simplejsondb.ErrQuotaExceeded) {
+		t.Error("Test failed - ", err)
+	}
+	dbtest.RequireRecord(t, users, "a", []byte(`{}`))
+	dbtest.RequireRecord(t, users, "b", []byte(`{}`))
+	if entries, _ := os.ReadDir(filepath.Join(dir, ".journal")); len(entries) != 0 {
+		t.Error("Test failed - ", entries)
+	}
+}
+
+func TestTxDurable(t *testing.T) {
+	dir := t.TempDir()
+	db, _ := dbtest.Open(t, dir, &simplejsondb.Options{NoFsync: true})
+	var synced []string
+	defer simplejsondb.SetSyncHook(func(path string) { synced = append(synced, path) })()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = tx.Create("users", "u1", []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err = tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	// the journal entry before any write, then the writes even under
+	// NoFsync, then the removal of the journal
+	journal, users := filepath.Join(dir, ".journal"), filepath.Join(dir, "users")
+	want := []string{journal, filepath.Join(users, "u1.json"), users, journal}
+	at := 0
+	for _, path := range synced {
+		if at < len(want) && path == want[at] {
+			at++
+		}
+	}
+	if at != len(want) {
+		t.Error("Test failed - ", synced)
+	}
+}
+
+func TestTxOverlay(t *testing.T) {
+	bdb, collection := dbtest.NewDB(t, nil)
+	dbtest.Seed(t, collection("users"), map[string][]byte{"u1": []byte(`{}`)})
+	db, err := simplejsondb.NewOverlay(bdb, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, _ := db.Begin()
+	_ = tx.Delete("users", "u1")
+	_ = tx.Create("users", "u2", []byte(`{}`))
+	if err = tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	users, _ := db.Collection("users")
+	if keys := users.Keys(); len(keys) != 1 || keys[0] != "u2" {
+		t.Error("Test failed - ", keys)
+	}
+	dbtest.RequireRecord(t, collection("users"), "u1", []byte(`{}`))
+}
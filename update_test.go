@@ -0,0 +1,140 @@
+package simplejsondb_test
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestUpdateIf(t *testing.T) {
+	dir := t.TempDir()
+	_, collection := dbtest.Open(t, dir, nil)
+	c := collection("collection1")
+	err := c.Create("order-1", []byte(`{"status": "packed"}`), simplejsondb.CreateOptions{UseGzip: true})
+	if err != nil {
+		t.Error(err)
+	}
+	isPacked := func(current []byte) (bool, error) {
+		return string(current) == `{"status": "packed"}`, nil
+	}
+	ship := func([]byte) ([]byte, error) {
+		return []byte(`{"status": "shipped"}`), nil
+	}
+
+	applied, err := c.UpdateIf("order-1", isPacked, ship)
+	if err != nil || !applied {
+		t.Error("Test failed - ", applied, err)
+	}
+	data, _ := c.Get("order-1")
+	if string(data) != `{"status": "shipped"}` {
+		t.Error("Test failed - ", string(data))
+	}
+	if _, err = os.Stat(filepath.Join(dir, "collection1", "order-1.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Error("Test failed - gzip format not preserved", err)
+	}
+
+	applied, err = c.UpdateIf("order-1", isPacked, ship)
+	if err != nil || applied {
+		t.Error("Test failed - condition not honored", applied, err)
+	}
+
+	failure := errors.New("mutate failed")
+	applied, err = c.UpdateIf("order-1", func([]byte) (bool, error) { return true, nil }, func([]byte) ([]byte, error) {
+		return []byte(`{"status": "lost"}`), failure
+	})
+	if !errors.Is(err, failure) || applied {
+		t.Error("Test failed - mutate error not propagated", applied, err)
+	}
+	data, _ = c.Get("order-1")
+	if string(data) != `{"status": "shipped"}` {
+		t.Error("Test failed - record written after error", string(data))
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	_, collection := dbtest.NewDB(t, nil)
+	c := collection("collection1")
+	increment := func(current []byte) ([]byte, error) {
+		n := 0
+		if current != nil {
+			if err := json.Unmarshal(current, &n); err != nil {
+				return nil, err
+			}
+		}
+		return json.Marshal(n + 1)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := c.Update("counter", increment); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	data, _ := c.Get("counter")
+	if string(data) != "20" {
+		t.Error("Test failed - lost update", string(data))
+	}
+
+	failure := errors.New("fn failed")
+	err := c.Update("counter", func([]byte) ([]byte, error) {
+		return []byte("0"), failure
+	})
+	if !errors.Is(err, failure) {
+		t.Error("Test failed - fn error not propagated", err)
+	}
+	data, _ = c.Get("counter")
+	if string(data) != "20" {
+		t.Error("Test failed - record written after error", string(data))
+	}
+}
+
+func TestUpdateIfConcurrent(t *testing.T) {
+	db, collection := dbtest.NewDB(t, nil)
+	dbtest.Seed(t, collection("collection1"), map[string][]byte{"order-2": []byte(`{"status": "packed"}`)})
+
+	var wg sync.WaitGroup
+	var wins int32
+	for i := 0; i < 20; i++ {
+		status := "shipped"
+		if i%2 == 1 {
+			status = "cancelled"
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// every goroutine uses its own handle, the lock is per record
+			c, err := db.Collection("collection1")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			applied, err := c.UpdateIf("order-2", func(current []byte) (bool, error) {
+				return string(current) == `{"status": "packed"}`, nil
+			}, func([]byte) ([]byte, error) {
+				return []byte(`{"status": "` + status + `"}`), nil
+			})
+			if err != nil {
+				t.Error(err)
+			}
+			if applied {
+				atomic.AddInt32(&wins, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	if wins != 1 {
+		t.Error("Test failed - expected exactly one transition", wins)
+	}
+}
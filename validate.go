@@ -0,0 +1,23 @@
+package simplejsondb
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidJSON - a record handed to Create is not valid JSON
+var ErrInvalidJSON = errors.New("invalid JSON")
+
+// validate - checks the record against MaxRecordSize and is JSON when
+// ValidateJSON is set, a leading BOM is accepted as reads drop it anyway
+func (c *_collection) validate(key string, data []byte) error {
+	if err := c.checkSize(key, int64(len(data))); err != nil {
+		return err
+	}
+	if !c.validateJSON || json.Valid(bytes.TrimPrefix(data, utf8BOM)) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrInvalidJSON, key)
+}
@@ -0,0 +1,40 @@
+package simplejsondb_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestValidateJSON(t *testing.T) {
+	dir := t.TempDir()
+	_, collection := dbtest.Open(t, dir, &simplejsondb.Options{ValidateJSON: true})
+	c := collection("admin")
+
+	err := c.Create("broken", []byte(`{"name": `), simplejsondb.CreateOptions{UseGzip: true})
+	if !errors.Is(err, simplejsondb.ErrInvalidJSON) {
+		t.Error("Test failed - ", err)
+	}
+	for _, name := range []string{"broken.json", "broken.json.gz"} {
+		if _, err := os.Stat(filepath.Join(dir, "admin", name)); !errors.Is(err, os.ErrNotExist) {
+			t.Error("Test failed - ", name, err)
+		}
+	}
+	errs := c.CreateMany(map[string][]byte{"good": []byte(`{"n": 1}`), "bad": []byte(`nope`)})
+	if len(errs) != 1 || !errors.Is(errs["bad"], simplejsondb.ErrInvalidJSON) {
+		t.Error("Test failed - ", errs)
+	}
+	if err := c.Create("bom", []byte("\xEF\xBB\xBF[1, 2]")); err != nil {
+		t.Error("Test failed - ", err)
+	}
+	dbtest.RequireRecord(t, c, "good", []byte(`{"n": 1}`))
+
+	_, collection = dbtest.Open(t, t.TempDir(), nil)
+	if err := collection("admin").Create("broken", []byte(`{"name": `)); err != nil {
+		t.Error("Test failed - ", err)
+	}
+}
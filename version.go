@@ -0,0 +1,57 @@
+package simplejsondb
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Version - release of this package, stamped into archives and layout
+// descriptors; override at build time with
+// -ldflags "-X github.com/pnkj-kmr/simple-json-db.Version=v1.2.3"
+var Version string = "v0.2.0"
+
+// BuildInfo - what this build of the package supports
+type BuildInfo struct {
+	Version       string   `json:"version"`
+	LayoutVersion int      `json:"layout_version"`
+	Codecs        []string `json:"codecs"`
+	Features      []string `json:"features"`
+}
+
+// Info - version and capabilities of this build
+func Info() BuildInfo {
+	info := BuildInfo{
+		Version:       Version,
+		LayoutVersion: LayoutVersion,
+		Codecs:        []string{"json", "gzip"},
+	}
+	for feature := range layoutFeatures {
+		info.Features = append(info.Features, feature)
+	}
+	sort.Strings(info.Features)
+	return info
+}
+
+// compareVersions - orders two vMAJOR.MINOR.PATCH versions, unparsable
+// parts compare as zero
+func compareVersions(a, b string) int {
+	pa := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	pb := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < 3; i++ {
+		var x, y int
+		if i < len(pa) {
+			x, _ = strconv.Atoi(strings.SplitN(pa[i], "-", 2)[0])
+		}
+		if i < len(pb) {
+			y, _ = strconv.Atoi(strings.SplitN(pb[i], "-", 2)[0])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,80 @@
+package simplejsondb_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+)
+
+func TestInfo(t *testing.T) {
+	info := simplejsondb.Info()
+	if info.Version != simplejsondb.Version || info.LayoutVersion != simplejsondb.LayoutVersion {
+		t.Error("Test failed - ", info)
+	}
+	if len(info.Codecs) == 0 || len(info.Features) == 0 {
+		t.Error("Test failed - ", info)
+	}
+}
+
+func TestVersionStamps(t *testing.T) {
+	base, overlay := t.TempDir(), t.TempDir()
+	archive := filepath.Join(t.TempDir(), "overlay.sjdb")
+	db, _ := newOverlay(t, base, overlay)
+	if _, err := db.Collection("collection1"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(overlay, "collection1", ".layout.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var layout struct {
+		WrittenBy string `json:"written_by"`
+	}
+	if err = json.Unmarshal(data, &layout); err != nil || layout.WrittenBy != simplejsondb.Version {
+		t.Error("Test failed - layout", string(data), err)
+	}
+
+	err = db.ExportIndexed(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := simplejsondb.OpenArchive(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Manifest().WrittenBy != simplejsondb.Version || len(a.Warnings()) != 0 {
+		t.Error("Test failed - manifest", a.Manifest(), a.Warnings())
+	}
+	a.Close()
+}
+
+func TestNewerArchiveWarning(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "archive.sjdb")
+	db := seedArchiveDB(t)
+
+	current := simplejsondb.Version
+	simplejsondb.Version = "v99.0.0"
+	err := db.ExportIndexed(archive)
+	simplejsondb.Version = current
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := simplejsondb.OpenArchive(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	warnings := a.Warnings()
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "v99.0.0") {
+		t.Error("Test failed - no warning for a newer archive", warnings)
+	}
+	if _, err = a.Get("users", "users-1"); err != nil {
+		t.Error("Test failed - newer archive unreadable", err)
+	}
+}
@@ -0,0 +1,33 @@
+package simplejsondb
+
+import (
+	"go.uber.org/zap"
+)
+
+// VisibleInfo - a write which reached the disk
+type VisibleInfo struct {
+	// Op is "write" or "delete"
+	Op         string
+	Collection string
+	ID         string
+	// Size of the stored file, 0 for deletes
+	Size int
+	Gzip bool
+}
+
+// visible - hands a finished write to the OnVisible hook
+//
+// The caller holds the record lock, so calls for one record follow the
+// order of its writes. Hook errors are logged and never fail the write.
+func (c *_collection) visible(info VisibleInfo) {
+	if c.onVisible == nil {
+		return
+	}
+	info.Collection = c.name
+	err := c.callbacks.guard("OnVisible", func() error {
+		return c.onVisible(info)
+	})
+	if err != nil {
+		c.logger.Error("visibility hook failed", zap.String("op", info.Op), zap.String("id", info.ID), zap.Error(err))
+	}
+}
@@ -0,0 +1,89 @@
+package simplejsondb_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestOnVisible(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		events []string
+	)
+	record := func(event string) {
+		mu.Lock()
+		events = append(events, event)
+		mu.Unlock()
+	}
+	restore := simplejsondb.SetRenameFile(func(from, to string) error {
+		err := os.Rename(from, to)
+		if err == nil {
+			record("rename " + filepath.Base(to))
+		}
+		return err
+	})
+	defer restore()
+
+	_, collection := dbtest.NewDB(t, &simplejsondb.Options{
+		OnVisible: func(info simplejsondb.VisibleInfo) error {
+			record(fmt.Sprint(info.Op, " ", info.ID, " ", info.Gzip))
+			if info.ID == "broken" {
+				return errors.New("publish failed")
+			}
+			return nil
+		},
+	})
+	c := collection("events")
+	if err := c.Create("a", []byte(`1`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Create("a", []byte(`2`), simplejsondb.CreateOptions{UseGzip: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Delete("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Create("broken", []byte(`1`)); err != nil {
+		t.Error("Test failed - hook error failed the write", err)
+	}
+	want := []string{
+		"rename a.json", "write a false",
+		"rename a.json.gz", "write a true",
+		"delete a false",
+		"rename broken.json", "write broken false",
+	}
+	if fmt.Sprint(events) != fmt.Sprint(want) {
+		t.Error("Test failed - ", events)
+	}
+
+	// per record ordering matches write order under concurrency
+	events = nil
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_ = c.Create("counter", []byte(fmt.Sprint(i)))
+		}(i)
+	}
+	wg.Wait()
+	writes := 0
+	for i, event := range events {
+		if event == "write counter false" {
+			writes++
+			if i == 0 || events[i-1] != "rename counter.json" {
+				t.Error("Test failed - hook before rename", events[:i+1])
+			}
+		}
+	}
+	if writes != 20 {
+		t.Error("Test failed - ", writes)
+	}
+}
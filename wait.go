@@ -0,0 +1,105 @@
+package simplejsondb
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	// waiters - WaitFor subscriptions keyed by record lock path
+	waiters   = make(map[string]map[chan []byte]bool)
+	waitersMu sync.Mutex
+
+	// waitForHook - called by tests between the existence check and the wait
+	waitForHook func()
+)
+
+const (
+	waitPollMin = 5 * time.Millisecond
+	waitPollMax = time.Second
+)
+
+// subscribe - registers for the next write of path, returns the cancel func
+func subscribe(path string) (chan []byte, func()) {
+	ch := make(chan []byte, 1)
+	waitersMu.Lock()
+	if waiters[path] == nil {
+		waiters[path] = make(map[chan []byte]bool)
+	}
+	waiters[path][ch] = true
+	waitersMu.Unlock()
+	return ch, func() {
+		waitersMu.Lock()
+		delete(waiters[path], ch)
+		if len(waiters[path]) == 0 {
+			delete(waiters, path)
+		}
+		waitersMu.Unlock()
+	}
+}
+
+// notify - hands the written content to every subscriber of path
+func notify(path string, data []byte) {
+	waitersMu.Lock()
+	defer waitersMu.Unlock()
+	for ch := range waiters[path] {
+		select {
+		case ch <- data:
+		default:
+		}
+	}
+}
+
+// waitFor - blocks until get finds the record or ctx is done
+//
+// The subscription is made before every existence check so a write landing
+// in between is never missed. Writes from this process wake the waiter with
+// the written content, writes from elsewhere are caught by polling which
+// backs off from waitPollMin up to waitPollMax.
+func waitFor(ctx context.Context, path string, get func() ([]byte, error)) ([]byte, error) {
+	delay := waitPollMin
+	for {
+		ch, cancel := subscribe(path)
+		data, err := get()
+		if err == nil || !errors.Is(err, os.ErrNotExist) {
+			cancel()
+			return data, err
+		}
+		if waitForHook != nil {
+			waitForHook()
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			cancel()
+			return nil, ctx.Err()
+		case data = <-ch:
+			timer.Stop()
+			cancel()
+			if data == nil {
+				// a streamed write, read it back
+				continue
+			}
+			return data, nil
+		case <-timer.C:
+			cancel()
+		}
+		if delay *= 2; delay > waitPollMax {
+			delay = waitPollMax
+		}
+	}
+}
+
+// WaitFor - returns the record, waiting for it to be created if missing
+func (c *_collection) WaitFor(ctx context.Context, key string) ([]byte, error) {
+	return waitFor(ctx, c.lockPath(key), func() ([]byte, error) { return c.Get(key) })
+}
+
+// WaitFor - returns the visible record, waiting for it to be created
+func (c *_overlayCollection) WaitFor(ctx context.Context, key string) ([]byte, error) {
+	return waitFor(ctx, c.upper.lockPath(key), func() ([]byte, error) { return c.Get(key) })
+}
@@ -0,0 +1,76 @@
+package simplejsondb_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+)
+
+func TestWaitFor(t *testing.T) {
+	dir := t.TempDir()
+	_, collection := dbtest.Open(t, dir, nil)
+	c := collection("results")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// pre-existing
+	_ = c.Create("ready", []byte(`"ready"`))
+	data, err := c.WaitFor(ctx, "ready")
+	if err != nil || string(data) != `"ready"` {
+		t.Error("Test failed - ", string(data), err)
+	}
+
+	// created later by this process
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		_ = c.Create("later", []byte(`"later"`), simplejsondb.CreateOptions{UseGzip: true})
+	}()
+	data, err = c.WaitFor(ctx, "later")
+	if err != nil || string(data) != `"later"` {
+		t.Error("Test failed - ", string(data), err)
+	}
+
+	// created later by another process
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		_ = os.WriteFile(filepath.Join(dir, "results", "external.json"), []byte(`"external"`), 0644)
+	}()
+	data, err = c.WaitFor(ctx, "external")
+	if err != nil || string(data) != `"external"` {
+		t.Error("Test failed - ", string(data), err)
+	}
+
+	// timeout
+	short, cancelShort := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancelShort()
+	_, err = c.WaitFor(short, "never")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("Test failed - ", err)
+	}
+}
+
+func TestWaitForRaceWindow(t *testing.T) {
+	_, collection := dbtest.NewDB(t, nil)
+	c := collection("results")
+
+	// the record appears and vanishes between the existence check and the
+	// wait, only the subscription made before the check can observe it
+	restore := simplejsondb.SetWaitForHook(func() {
+		_ = c.Create("flash", []byte(`"flash"`))
+		_ = c.Delete("flash")
+	})
+	defer restore()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	data, err := c.WaitFor(ctx, "flash")
+	if err != nil || string(data) != `"flash"` {
+		t.Error("Test failed - creation missed", string(data), err)
+	}
+}
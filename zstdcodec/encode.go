@@ -0,0 +1,328 @@
+package zstdcodec
+
+import (
+	"encoding/binary"
+	"math/bits"
+	"sort"
+)
+
+// The encoder writes single segment frames without checksum, split into
+// blocks of at most maxBlockSize. Each block is either stored raw or
+// compressed: its literals are stored raw and its matches are coded with
+// the predefined FSE tables of RFC 8878, so no table is ever written. That
+// leaves out the entropy coding of literals, which zstd at its lower
+// levels mostly skips for JSON as well.
+
+const (
+	frameMagic   = 0xFD2FB528
+	maxBlockSize = 128 << 10
+	// minMatch - shortest match worth a sequence
+	minMatch = 4
+	// maxOffset - farthest match, well inside the 8MB window decoders
+	// such as Go's cap frames to
+	maxOffset = 4 << 20
+	hashLog   = 15
+
+	blockRaw        = 0
+	blockCompressed = 2
+)
+
+var (
+	// llBase, mlBase - baselines of the literal length and match length
+	// codes, llBits and mlBits their extra bits
+	llBase = []uint32{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15,
+		16, 18, 20, 22, 24, 28, 32, 40, 48, 64, 128, 256, 512, 1024, 2048, 4096,
+		8192, 16384, 32768, 65536}
+	llBits = []uint8{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		1, 1, 1, 1, 2, 2, 3, 3, 4, 6, 7, 8, 9, 10, 11, 12,
+		13, 14, 15, 16}
+	mlBase = []uint32{3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18,
+		19, 20, 21, 22, 23, 24, 25, 26, 27, 28, 29, 30, 31, 32, 33, 34,
+		35, 37, 39, 41, 43, 47, 51, 59, 67, 83, 99, 131, 259, 515, 1027, 2051,
+		4099, 8195, 16387, 32771, 65539}
+	mlBits = []uint8{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		1, 1, 1, 1, 2, 2, 3, 3, 4, 4, 5, 7, 8, 9, 10, 11,
+		12, 13, 14, 15, 16}
+
+	// the predefined distributions, -1 marks low probability symbols
+	llTable = newFSETable([]int16{4, 3, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 1, 1, 1,
+		2, 2, 2, 2, 2, 2, 2, 2, 2, 3, 2, 1, 1, 1, 1, 1,
+		-1, -1, -1, -1}, 6)
+	mlTable = newFSETable([]int16{1, 4, 3, 2, 2, 2, 2, 2, 2, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, -1, -1,
+		-1, -1, -1, -1, -1}, 6)
+	ofTable = newFSETable([]int16{1, 1, 1, 1, 1, 1, 2, 2, 2, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, -1, -1, -1, -1, -1}, 5)
+)
+
+type (
+	// _sequence - literals followed by a match
+	_sequence struct {
+		litLen, matchLen, offset uint32
+	}
+
+	// _fseTable - FSE encoding table of a normalized distribution
+	_fseTable struct {
+		log     uint8
+		states  []uint16
+		symbols []_fseSymbol
+	}
+
+	_fseSymbol struct {
+		deltaNbBits    uint32
+		deltaFindState int32
+	}
+
+	// _fseState - an FSE encoder state
+	_fseState struct {
+		value uint32
+		table *_fseTable
+	}
+
+	// _bitWriter - the little endian bit stream sequences are written to,
+	// read backwards by decoders
+	_bitWriter struct {
+		out []byte
+		acc uint64
+		n   uint
+	}
+)
+
+// encode - src as one zstd frame
+func encode(src []byte) []byte {
+	dst := make([]byte, 4, 16+len(src)/2)
+	binary.LittleEndian.PutUint32(dst, frameMagic)
+	size := len(src)
+	switch {
+	case size < 256:
+		dst = append(dst, 1<<5, byte(size))
+	case size < 256+1<<16:
+		dst = append(dst, 1<<6|1<<5)
+		dst = binary.LittleEndian.AppendUint16(dst, uint16(size-256))
+	case uint64(size) < 1<<32:
+		dst = append(dst, 2<<6|1<<5)
+		dst = binary.LittleEndian.AppendUint32(dst, uint32(size))
+	default:
+		dst = append(dst, 3<<6|1<<5)
+		dst = binary.LittleEndian.AppendUint64(dst, uint64(size))
+	}
+
+	table := make([]int32, 1<<hashLog)
+	var seqs []_sequence
+	var literals, block []byte
+	start := 0
+	for {
+		end := start + maxBlockSize
+		if end > size {
+			end = size
+		}
+		last := uint32(0)
+		if end == size {
+			last = 1
+		}
+		seqs, literals = match(src, start, end, table, seqs[:0], literals[:0])
+		block = compressBlock(block[:0], seqs, literals)
+		if len(seqs) == 0 || len(block) >= end-start {
+			dst = appendBlockHeader(dst, last|blockRaw<<1|uint32(end-start)<<3)
+			dst = append(dst, src[start:end]...)
+		} else {
+			dst = appendBlockHeader(dst, last|blockCompressed<<1|uint32(len(block))<<3)
+			dst = append(dst, block...)
+		}
+		if last == 1 {
+			return dst
+		}
+		start = end
+	}
+}
+
+func appendBlockHeader(dst []byte, header uint32) []byte {
+	return append(dst, byte(header), byte(header>>8), byte(header>>16))
+}
+
+// match - greedy matches of src[start:end] against everything before,
+// table holds the last position plus one of every hash
+func match(src []byte, start, end int, table []int32, seqs []_sequence, literals []byte) ([]_sequence, []byte) {
+	anchor := start
+	for i := start; i+minMatch <= end; {
+		cur := binary.LittleEndian.Uint32(src[i:])
+		h := (cur * 2654435761) >> (32 - hashLog)
+		cand := int(table[h]) - 1
+		table[h] = int32(i + 1)
+		if cand < 0 || i-cand > maxOffset || binary.LittleEndian.Uint32(src[cand:]) != cur {
+			i++
+			continue
+		}
+		n := minMatch
+		for i+n < end && src[cand+n] == src[i+n] {
+			n++
+		}
+		literals = append(literals, src[anchor:i]...)
+		seqs = append(seqs, _sequence{litLen: uint32(i - anchor), matchLen: uint32(n), offset: uint32(i - cand)})
+		i += n
+		anchor = i
+	}
+	return seqs, append(literals, src[anchor:end]...)
+}
+
+// compressBlock - the content of a compressed block
+func compressBlock(dst []byte, seqs []_sequence, literals []byte) []byte {
+	// raw literals section
+	switch n := len(literals); {
+	case n < 1<<5:
+		dst = append(dst, byte(n<<3))
+	case n < 1<<12:
+		dst = append(dst, byte(1<<2|n<<4), byte(n>>4))
+	default:
+		dst = append(dst, byte(3<<2|n<<4), byte(n>>4), byte(n>>12))
+	}
+	dst = append(dst, literals...)
+
+	switch n := len(seqs); {
+	case n < 128:
+		dst = append(dst, byte(n))
+	case n < 0x7F00:
+		dst = append(dst, byte(n>>8+128), byte(n))
+	default:
+		dst = append(dst, 0xFF, byte(n-0x7F00), byte((n-0x7F00)>>8))
+	}
+	if len(seqs) == 0 {
+		return dst
+	}
+	// predefined modes for literal lengths, offsets and match lengths
+	dst = append(dst, 0)
+
+	w := _bitWriter{out: dst}
+	var ll, of, ml _fseState
+	last := seqs[len(seqs)-1]
+	llc, mlc, ofc := codes(last)
+	ml.init(mlTable, mlc)
+	of.init(ofTable, ofc)
+	ll.init(llTable, llc)
+	w.extras(last, llc, mlc, ofc)
+	for i := len(seqs) - 2; i >= 0; i-- {
+		s := seqs[i]
+		llc, mlc, ofc = codes(s)
+		of.encode(&w, ofc)
+		ml.encode(&w, mlc)
+		ll.encode(&w, llc)
+		w.extras(s, llc, mlc, ofc)
+	}
+	ml.flush(&w)
+	of.flush(&w)
+	ll.flush(&w)
+	return w.close()
+}
+
+// codes - the literal length, match length and offset codes of s; the
+// offset is always sent as is, never as a repeat
+func codes(s _sequence) (llc, mlc, ofc uint8) {
+	return code(llBase, s.litLen), code(mlBase, s.matchLen), uint8(bits.Len32(s.offset+3) - 1)
+}
+
+// code - the code whose baseline is the largest not above v
+func code(base []uint32, v uint32) uint8 {
+	return uint8(sort.Search(len(base), func(i int) bool { return base[i] > v }) - 1)
+}
+
+// extras - the extra bits of a sequence, in the order decoders read
+// them backwards: offset, match length, literal length
+func (w *_bitWriter) extras(s _sequence, llc, mlc, ofc uint8) {
+	w.add(uint64(s.litLen-llBase[llc]), uint(llBits[llc]))
+	w.add(uint64(s.matchLen-mlBase[mlc]), uint(mlBits[mlc]))
+	w.add(uint64(s.offset+3-1<<ofc), uint(ofc))
+}
+
+func (w *_bitWriter) add(v uint64, n uint) {
+	w.acc |= (v & (1<<n - 1)) << w.n
+	w.n += n
+	for w.n >= 8 {
+		w.out = append(w.out, byte(w.acc))
+		w.acc >>= 8
+		w.n -= 8
+	}
+}
+
+// close - ends the stream with the mark bit decoders search for
+func (w *_bitWriter) close() []byte {
+	w.add(1, 1)
+	if w.n > 0 {
+		w.out = append(w.out, byte(w.acc))
+	}
+	return w.out
+}
+
+// newFSETable - the encoding table of norm, spread the way decoders build
+// their tables from the same distribution
+func newFSETable(norm []int16, log uint8) *_fseTable {
+	size := 1 << log
+	high := size - 1
+	symbolAt := make([]int, size)
+	first := make([]int, len(norm))
+	cumul := 0
+	for s, n := range norm {
+		first[s] = cumul
+		if n == -1 {
+			symbolAt[high] = s
+			high--
+			cumul++
+		} else {
+			cumul += int(n)
+		}
+	}
+	step := size>>1 + size>>3 + 3
+	pos := 0
+	for s, n := range norm {
+		for i := 0; i < int(n); i++ {
+			symbolAt[pos] = s
+			pos = (pos + step) & (size - 1)
+			for pos > high {
+				pos = (pos + step) & (size - 1)
+			}
+		}
+	}
+
+	t := &_fseTable{log: log, states: make([]uint16, size), symbols: make([]_fseSymbol, len(norm))}
+	next := append([]int(nil), first...)
+	for u, s := range symbolAt {
+		t.states[next[s]] = uint16(size + u)
+		next[s]++
+	}
+	total := 0
+	for s, n := range norm {
+		switch n {
+		case 0:
+		case -1, 1:
+			t.symbols[s] = _fseSymbol{deltaNbBits: uint32(log)<<16 - uint32(size), deltaFindState: int32(total - 1)}
+			total++
+		default:
+			maxBitsOut := uint32(log) - uint32(bits.Len32(uint32(n-1))-1)
+			t.symbols[s] = _fseSymbol{deltaNbBits: maxBitsOut<<16 - uint32(n)<<maxBitsOut, deltaFindState: int32(total - int(n))}
+			total += int(n)
+		}
+	}
+	return t
+}
+
+// init - the state of the first symbol coded, which is the last decoded
+func (st *_fseState) init(t *_fseTable, symbol uint8) {
+	st.table = t
+	tt := t.symbols[symbol]
+	nbBitsOut := (tt.deltaNbBits + 1<<15) >> 16
+	value := nbBitsOut<<16 - tt.deltaNbBits
+	st.value = uint32(t.states[int32(value>>nbBitsOut)+tt.deltaFindState])
+}
+
+func (st *_fseState) encode(w *_bitWriter, symbol uint8) {
+	tt := st.table.symbols[symbol]
+	nbBitsOut := (st.value + tt.deltaNbBits) >> 16
+	w.add(uint64(st.value), uint(nbBitsOut))
+	st.value = uint32(st.table.states[int32(st.value>>nbBitsOut)+tt.deltaFindState])
+}
+
+// flush - the final state, the first decoders read
+func (st *_fseState) flush(w *_bitWriter) {
+	w.add(uint64(st.value), uint(st.table.log))
+}
@@ -0,0 +1,31 @@
+// Package zstdcodec - a simplejsondb.Codec storing records zstd compressed
+// under simplejsondb.ZstdExt
+//
+// Records are written as standard zstd frames any zstd tool reads, and
+// frames of any zstd encoder are read back. The encoder trades ratio for
+// having no dependency: it finds matches greedily and leaves literals
+// uncompressed, which still shrinks repetitive JSON well.
+//
+//	db, err := simplejsondb.New("data", &simplejsondb.Options{Codec: zstdcodec.Codec})
+package zstdcodec
+
+import (
+	"bytes"
+	"io"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/zstdcodec/internal/zstd"
+)
+
+type _codec struct{}
+
+// Codec - records stored zstd compressed under simplejsondb.ZstdExt
+var Codec simplejsondb.Codec = _codec{}
+
+func (_codec) Encode(data []byte) ([]byte, error) { return encode(data), nil }
+
+func (_codec) Decode(stored []byte) ([]byte, error) {
+	return io.ReadAll(zstd.NewReader(bytes.NewReader(stored)))
+}
+
+func (_codec) Ext() string { return simplejsondb.ZstdExt }
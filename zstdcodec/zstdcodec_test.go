@@ -0,0 +1,145 @@
+package zstdcodec_test
+
+import (
+	"bytes"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	simplejsondb "github.com/pnkj-kmr/simple-json-db"
+	"github.com/pnkj-kmr/simple-json-db/dbtest"
+	"github.com/pnkj-kmr/simple-json-db/zstdcodec"
+)
+
+// samples - records of every shape the encoder handles differently: empty,
+// short, repetitive, incompressible and over one block
+func samples(t testing.TB) [][]byte {
+	r := rand.New(rand.NewSource(1))
+	noise := make([]byte, 200<<10)
+	r.Read(noise)
+	text := make([]byte, 300<<10)
+	for i := range text {
+		text[i] = `abcdef{}":, `[r.Intn(12)]
+	}
+	records, err := os.ReadFile(filepath.Join("internal", "zstd", "testdata", "records.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return [][]byte{
+		nil,
+		[]byte(`{}`),
+		[]byte(`{"name": "a", "tags": ["x", "x", "x", "x"]}`),
+		bytes.Repeat([]byte(`{"id": 12, "name": "repeated"},`), 20000),
+		noise,
+		text,
+		records,
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	inputs := samples(t)
+	noise := inputs[4]
+	for i, in := range inputs {
+		stored, err := zstdcodec.Codec.Encode(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := zstdcodec.Codec.Decode(stored)
+		if err != nil || !bytes.Equal(out, in) {
+			t.Error("Test failed - ", i, len(out), len(in), err)
+		}
+	}
+	// repetitive records compress, random ones grow by the framing only
+	if stored, _ := zstdcodec.Codec.Encode(inputs[3]); len(stored) > len(inputs[3])/100 {
+		t.Error("Test failed - ", len(stored))
+	}
+	if stored, _ := zstdcodec.Codec.Encode(noise); len(stored) > len(noise)+32 {
+		t.Error("Test failed - ", len(stored))
+	}
+}
+
+// the reference implementation reads what the encoder writes
+func TestZstdToolDecodes(t *testing.T) {
+	zstd, err := exec.LookPath("zstd")
+	if err != nil {
+		t.Skip("skipping because zstd not found")
+	}
+	for i, in := range samples(t) {
+		stored, err := zstdcodec.Codec.Encode(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cmd := exec.Command(zstd, "-d", "-c")
+		cmd.Stdin = bytes.NewReader(stored)
+		var out, stderr bytes.Buffer
+		cmd.Stdout, cmd.Stderr = &out, &stderr
+		if err = cmd.Run(); err != nil || !bytes.Equal(out.Bytes(), in) {
+			t.Error("Test failed - ", i, out.Len(), len(in), err, stderr.String())
+		}
+	}
+}
+
+func FuzzRoundTrip(f *testing.F) {
+	for _, in := range samples(f)[:3] {
+		f.Add(in)
+	}
+	f.Fuzz(func(t *testing.T, in []byte) {
+		stored, err := zstdcodec.Codec.Encode(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := zstdcodec.Codec.Decode(stored)
+		if err != nil || !bytes.Equal(out, in) {
+			t.Error("Test failed - ", len(out), len(in), err)
+		}
+	})
+}
+
+// testdata/record.json.zst comes from the zstd tool at level 19, with
+// compressed literals and a checksum this encoder never writes
+func TestDecodeZstdTool(t *testing.T) {
+	stored, err := os.ReadFile(filepath.Join("testdata", "record.json.zst"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := zstdcodec.Codec.Decode(stored)
+	if err != nil || string(data) != `{"name":"zstd","tags":["a","b","a","b","a","b"],"n":1}` {
+		t.Error("Test failed - ", string(data), err)
+	}
+	if _, err = zstdcodec.Codec.Decode(stored[:len(stored)-4]); err == nil {
+		t.Error("Test failed - truncated frame decoded")
+	}
+}
+
+func TestMixedCollection(t *testing.T) {
+	dir := t.TempDir()
+	_, collection := dbtest.Open(t, dir, &simplejsondb.Options{Codec: zstdcodec.Codec})
+	c := collection("mixed")
+	if err := c.Create("zstd", []byte(`{"z": 1}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Create("gzip", []byte(`{"g": 1}`), simplejsondb.CreateOptions{UseGzip: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "mixed", "plain.json"), []byte(`{"p": 1}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "mixed", "zstd"+simplejsondb.ZstdExt)); err != nil {
+		t.Error("Test failed - ", err)
+	}
+
+	// a database writing plain keeps reading zstd through Codecs
+	_, collection = dbtest.Open(t, dir, &simplejsondb.Options{Codecs: []simplejsondb.Codec{zstdcodec.Codec}})
+	c = collection("mixed")
+	all := c.GetAll()
+	if len(all) != 3 || string(all[0]) != `{"g": 1}` || string(all[1]) != `{"p": 1}` || string(all[2]) != `{"z": 1}` {
+		t.Error("Test failed - ", all)
+	}
+	dbtest.RequireRecord(t, c, "zstd", []byte(`{"z": 1}`))
+	if err := c.Update("zstd", func([]byte) ([]byte, error) { return []byte(`{"z": 2}`), nil }); err != nil {
+		t.Fatal(err)
+	}
+	dbtest.RequireRecord(t, c, "zstd", []byte(`{"z": 2}`))
+}